@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	_ "github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
@@ -70,6 +72,23 @@ type Builder struct {
 
 	// Skip index 0 in order to reserve it to indicate the "unknown" column.
 	colMap []columnProps
+
+	// explain is true if the statement is an EXPLAIN statement, in which case
+	// explainFlags are the properties that it should show.
+	explain      bool
+	explainFlags opt.ExplainFlags
+
+	// views holds the names of the views whose queries are being built, in
+	// order to detect a view that refers to itself.
+	views map[cat.TableName]bool
+
+	// view is the innermost view whose query is being built, or nil if the
+	// statement's own query is being built.
+	view *cat.View
+
+	// names maps each table or view name in the statement, outside of the
+	// queries of views, to the fully qualified name that it refers to.
+	names map[cat.TableName]cat.TableName
 }
 
 func NewBuilder(factory *opt.Factory, stmt tree.Statement) *Builder {
@@ -82,7 +101,13 @@ func NewBuilder(factory *opt.Factory, stmt tree.Statement) *Builder {
 	return b
 }
 
-func (b *Builder) Build() (root opt.GroupID, required *opt.PhysicalProps) {
+// Build constructs the expression for the statement using the factory, and
+// returns its group along with the physical properties that are required of
+// it. If the statement is invalid or uses an unsupported feature, Build
+// returns an error with a code such as pgerror.CodeUndefinedColumnError.
+func (b *Builder) Build() (root opt.GroupID, required *opt.PhysicalProps, err error) {
+	defer catchError(&err)
+
 	out, outScope := b.buildStmt(b.stmt, &scope{builder: b})
 
 	// Construct the set of physical properties that are required of the root
@@ -95,12 +120,37 @@ func (b *Builder) Build() (root opt.GroupID, required *opt.PhysicalProps) {
 
 	root = out
 	required = &opt.PhysicalProps{Ordering: outScope.ordering, Projection: opt.Projection{Columns: labeledCols}}
-	return
+	return root, required, nil
+}
+
+// TableNames returns a map from each table or view name in the statement to the
+// fully qualified name of the table or view that it refers to. The names in
+// the queries of the views that the statement uses are not included. TableNames
+// can only be called after Build.
+func (b *Builder) TableNames() map[cat.TableName]cat.TableName {
+	return b.names
+}
+
+// Explain returns the properties that an EXPLAIN statement should show, and
+// false if the statement is not an EXPLAIN statement. The expression to
+// explain is the one that was built for the explained statement. Explain can
+// only be called after Build.
+func (b *Builder) Explain() (flags opt.ExplainFlags, ok bool) {
+	return b.explainFlags, b.explain
 }
 
 func (b *Builder) buildStmt(stmt tree.Statement, inScope *scope) (out opt.GroupID, outScope *scope) {
 	// NB: The case statements are sorted lexicographically.
 	switch stmt := stmt.(type) {
+	case *tree.Explain:
+		flags, err := opt.ParseExplainFlags(stmt.Options)
+		if err != nil {
+			raise(pgerror.CodeSyntaxError, err)
+		}
+		b.explain = true
+		b.explainFlags = flags
+		return b.buildStmt(stmt.Statement, inScope)
+
 	case *tree.ParenSelect:
 		return b.buildSelect(stmt.Select, inScope)
 
@@ -133,7 +183,6 @@ func (b *Builder) buildStmt(stmt tree.Statement, inScope *scope) (out opt.GroupI
 		// *tree.DropUser
 		// *tree.DropView
 		// *tree.Execute
-		// *tree.Explain
 		// *tree.Grant
 		// *tree.Import
 		// *tree.Insert
@@ -189,7 +238,7 @@ func (b *Builder) buildStmt(stmt tree.Statement, inScope *scope) (out opt.GroupI
 		// tree.SelectStatement
 
 	default:
-		fatalf("unexpected statement: %T", stmt)
+		unimplemented("%T", stmt)
 		return 0, nil
 	}
 }
@@ -203,11 +252,12 @@ func (b *Builder) buildTable(texpr tree.TableExpr, inScope *scope) (out opt.Grou
 		// Overwrite output properties with any alias information.
 		if source.As.Alias != "" {
 			if n := len(source.As.Cols); n > 0 && n != len(outScope.cols) {
-				fatalf("rename specified %d columns, but table contains %d", n, len(outScope.cols))
+				errorf(pgerror.CodeInvalidColumnReferenceError,
+					"rename specified %d columns, but table contains %d", n, len(outScope.cols))
 			}
 
 			for i := range outScope.cols {
-				outScope.cols[i].table = cat.TableName(source.As.Alias)
+				outScope.cols[i].table = cat.MakeTableName(string(source.As.Alias))
 				if i < len(source.As.Cols) {
 					outScope.cols[i].name = cat.ColumnName(source.As.Cols[i])
 				}
@@ -238,12 +288,30 @@ func (b *Builder) buildTable(texpr tree.TableExpr, inScope *scope) (out opt.Grou
 	case *tree.NormalizableTableName:
 		tn, err := source.Normalize()
 		if err != nil {
-			fatalf("%s", err)
+			raise(pgerror.CodeSyntaxError, err)
+		}
+
+		// The names in the query of a view refer to the tables and views that
+		// they referred to when the view was created.
+		name := cat.NewTableName(tn)
+		if b.view != nil {
+			if qualified, ok := b.view.Names[name]; ok {
+				name = qualified
+			}
 		}
 
-		name := cat.TableName(tn.Table())
-		tbl := b.factory.Metadata().Catalog().Table(name)
+		catalog := b.factory.Metadata().Catalog()
+		tbl, err := catalog.Table(name)
+		if err != nil {
+			view, viewErr := catalog.View(name)
+			if viewErr != nil {
+				raise(pgerror.CodeUndefinedTableError, err)
+			}
+			b.addTableName(name, view.Name)
+			return b.buildView(view, inScope)
+		}
 
+		b.addTableName(name, tbl.TabName())
 		return b.buildScan(tbl, inScope)
 
 	case *tree.ParenTableExpr:
@@ -265,24 +333,77 @@ func (b *Builder) buildTable(texpr tree.TableExpr, inScope *scope) (out opt.Grou
 	return 0, nil
 }
 
-func (b *Builder) buildScan(tbl *cat.Table, inScope *scope) (out opt.GroupID, outScope *scope) {
+// buildView inlines the query of the view, so that normalization can merge it
+// with the query that uses the view. The view's query is built in a scope of
+// its own, since it can't refer to the columns of the outer query, and its
+// columns are renamed to those of the view.
+func (b *Builder) buildView(view *cat.View, inScope *scope) (out opt.GroupID, outScope *scope) {
+	if b.views[view.Name] {
+		errorf(pgerror.CodeInvalidObjectDefinitionError, "view %s refers to itself", view.Name)
+	}
+
+	stmt, err := parser.ParseOne(view.Query)
+	if err != nil {
+		raise(pgerror.CodeSyntaxError, err)
+	}
+
+	if b.views == nil {
+		b.views = make(map[cat.TableName]bool)
+	}
+	b.views[view.Name] = true
+	outer := b.view
+	b.view = view
+	out, viewScope := b.buildStmt(stmt, &scope{builder: b})
+	b.view = outer
+	delete(b.views, view.Name)
+
+	if len(viewScope.cols) != len(view.Columns) {
+		errorf(pgerror.CodeInvalidObjectDefinitionError,
+			"view %s has %d columns, but its query now returns %d", view.Name, len(view.Columns), len(viewScope.cols))
+	}
+
+	outScope = inScope.push()
+	for i, col := range viewScope.cols {
+		col.name = view.Columns[i]
+		col.table = view.Name
+		outScope.cols = append(outScope.cols, col)
+	}
+
+	return out, outScope
+}
+
+// addTableName records the fully qualified name of the table or view that a
+// name in the statement refers to. Names in the queries of views are not
+// recorded.
+func (b *Builder) addTableName(name, qualified cat.TableName) {
+	if b.view != nil {
+		return
+	}
+	if b.names == nil {
+		b.names = make(map[cat.TableName]cat.TableName)
+	}
+	b.names[name] = qualified
+}
+
+func (b *Builder) buildScan(tbl cat.Table, inScope *scope) (out opt.GroupID, outScope *scope) {
 	tblIndex := b.factory.Metadata().AddTable(tbl)
 
 	outScope = inScope.push()
-	for i, col := range tbl.Columns {
-		colIndex := b.factory.Metadata().TableColumn(tblIndex, cat.ColumnOrdinal(i))
+	for i := 0; i < tbl.ColumnCount(); i++ {
+		ord := cat.ColumnOrdinal(i)
+		colIndex := b.factory.Metadata().TableColumn(tblIndex, ord)
 		col := columnProps{
 			index: colIndex,
-			name:  col.Name,
-			table: tbl.Name,
-			typ:   col.Type,
+			name:  tbl.Column(ord).Name,
+			table: tbl.TabName(),
+			typ:   tbl.Column(ord).Type,
 		}
 
 		b.colMap = append(b.colMap, col)
 		outScope.cols = append(outScope.cols, col)
 	}
 
-	return b.factory.ConstructScan(b.factory.InternPrivate(tblIndex)), outScope
+	return b.factory.ConstructScan(tblIndex), outScope
 }
 
 func (b *Builder) buildOnJoin(
@@ -350,19 +471,19 @@ func (b *Builder) buildUsingJoinParts(
 		// For every adjacent pair of tables, add an equality predicate.
 		leftCol := findColByName(leftCols, name)
 		if leftCol == nil {
-			fatalf("unable to resolve name %s", name)
+			errorf(pgerror.CodeUndefinedColumnError, "unable to resolve name %s", name)
 		}
 
 		rightCol := findColByName(rightCols, name)
 		if rightCol == nil {
-			fatalf("unable to resolve name %s", name)
+			errorf(pgerror.CodeUndefinedColumnError, "unable to resolve name %s", name)
 		}
 
 		outScope.cols = append(outScope.cols, *leftCol)
 		joined[name] = &outScope.cols[len(outScope.cols)-1]
 
-		leftVar := b.factory.ConstructVariable(b.factory.InternPrivate(leftCol.index))
-		rightVar := b.factory.ConstructVariable(b.factory.InternPrivate(rightCol.index))
+		leftVar := b.factory.ConstructVariable(leftCol.index)
+		rightVar := b.factory.ConstructVariable(rightCol.index)
 		eq := b.factory.ConstructEq(leftVar, rightVar)
 
 		conditions = append(conditions, eq)
@@ -395,8 +516,10 @@ func (b *Builder) buildScalarProjection(texpr tree.TypedExpr, inScope, outScope
 	switch t := texpr.(type) {
 	case *columnProps:
 		colIndex := opt.ColumnIndex(t.index)
-		out := b.factory.ConstructVariable(b.factory.InternPrivate(colIndex))
-		outScope.cols = append(outScope.cols, b.colMap[colIndex])
+		out := b.factory.ConstructVariable(colIndex)
+		// Use the column from the scope rather than from colMap, so that it
+		// keeps any name that was given to it by a table alias.
+		outScope.cols = append(outScope.cols, *t)
 		return out
 
 	case *tree.FuncExpr:
@@ -414,7 +537,7 @@ func (b *Builder) buildScalarProjection(texpr tree.TypedExpr, inScope, outScope
 	case *tree.UnresolvedName:
 		vn, err := t.NormalizeVarName()
 		if err != nil {
-			panic(err)
+			raise(pgerror.CodeSyntaxError, err)
 		}
 		return b.buildScalarProjection(vn, inScope, outScope)
 
@@ -428,7 +551,7 @@ func (b *Builder) buildScalarProjection(texpr tree.TypedExpr, inScope, outScope
 func (b *Builder) buildScalar(scalar tree.TypedExpr, inScope *scope) opt.GroupID {
 	switch t := scalar.(type) {
 	case *columnProps:
-		return b.factory.ConstructVariable(b.factory.InternPrivate(t.index))
+		return b.factory.ConstructVariable(t.index)
 
 	case *tree.AllColumnsSelector:
 		fatalf("unexpected unresolved scalar expr: %T", scalar)
@@ -497,7 +620,7 @@ func (b *Builder) buildScalar(scalar tree.TypedExpr, inScope *scope) opt.GroupID
 		return b.buildScalar(t.TypedInnerExpr(), inScope)
 
 	case *tree.Placeholder:
-		return b.factory.ConstructPlaceholder(b.factory.InternPrivate(t))
+		return b.factory.ConstructPlaceholder(t)
 
 	case *tree.RangeCond:
 		unimplemented("%T", scalar)
@@ -507,7 +630,7 @@ func (b *Builder) buildScalar(scalar tree.TypedExpr, inScope *scope) opt.GroupID
 			return b.factory.ConstructExists(t.out)
 		}
 
-		v := b.factory.ConstructVariable(b.factory.InternPrivate(t.cols[0].index))
+		v := b.factory.ConstructVariable(t.cols[0].index)
 		return b.factory.ConstructSubquery(t.out, v)
 
 	case *tree.Tuple:
@@ -551,17 +674,17 @@ func (b *Builder) buildScalar(scalar tree.TypedExpr, inScope *scope) opt.GroupID
 		// *DTuple
 		// *DUuid
 		// dNull
-		return b.factory.ConstructConst(b.factory.InternPrivate(t))
+		return b.factory.ConstructConst(t)
 	}
 
-	fatalf("unexpected scalar expr: %T", scalar)
+	unimplemented("%T", scalar)
 	return 0
 }
 
 func (b *Builder) buildFunction(f *tree.FuncExpr, inScope *scope) (out opt.GroupID, col *columnProps) {
 	def, err := f.Func.Resolve(b.semaCtx.SearchPath)
 	if err != nil {
-		fatalf("%v", err)
+		raise(pgerror.CodeUndefinedFunctionError, err)
 	}
 
 	isAgg := isAggregate(def)
@@ -574,7 +697,7 @@ func (b *Builder) buildFunction(f *tree.FuncExpr, inScope *scope) (out opt.Group
 	for _, pexpr := range f.Exprs {
 		var arg opt.GroupID
 		if _, ok := pexpr.(tree.UnqualifiedStar); ok {
-			arg = b.factory.ConstructConst(b.factory.InternPrivate(tree.NewDInt(1)))
+			arg = b.factory.ConstructConst(tree.NewDInt(1))
 		} else {
 			arg = b.buildScalar(pexpr.(tree.TypedExpr), inScope)
 		}
@@ -582,7 +705,7 @@ func (b *Builder) buildFunction(f *tree.FuncExpr, inScope *scope) (out opt.Group
 		argList = append(argList, arg)
 	}
 
-	out = b.factory.ConstructFunction(b.factory.StoreList(argList), b.factory.InternPrivate(def))
+	out = b.factory.ConstructFunction(b.factory.StoreList(argList), def)
 
 	if isAgg {
 		refScope := inScope.endAggFunc(out)
@@ -590,8 +713,6 @@ func (b *Builder) buildFunction(f *tree.FuncExpr, inScope *scope) (out opt.Group
 		// If the aggregate already exists as a column, use that. Otherwise
 		// create a new column and add it the list of aggregates that need to
 		// be computed by the groupby expression.
-		// TODO(andy): turns out this doesn't really do anything because the
-		//             list passed to ConstructFunction isn't interned.
 		col = refScope.findAggregate(out)
 		if col == nil {
 			col = b.synthesizeColumn(refScope, "", f.ResolvedType())
@@ -602,7 +723,7 @@ func (b *Builder) buildFunction(f *tree.FuncExpr, inScope *scope) (out opt.Group
 		}
 
 		// Replace the function call with a reference to the column.
-		out = b.factory.ConstructVariable(b.factory.InternPrivate(col.index))
+		out = b.factory.ConstructVariable(col.index)
 	}
 
 	return
@@ -648,9 +769,9 @@ func (b *Builder) buildValuesClause(values *tree.ValuesClause, inScope *scope) (
 
 	for _, tuple := range values.Tuples {
 		if numCols != len(tuple.Exprs) {
-			panic(fmt.Errorf(
+			errorf(pgerror.CodeSyntaxError,
 				"VALUES lists must all be the same length, expected %d columns, found %d",
-				numCols, len(tuple.Exprs)))
+				numCols, len(tuple.Exprs))
 		}
 
 		elems := make([]opt.GroupID, numCols)
@@ -664,14 +785,15 @@ func (b *Builder) buildValuesClause(values *tree.ValuesClause, inScope *scope) (
 			if outScope.cols[i].typ == types.Null {
 				outScope.cols[i].typ = typ
 			} else if typ != types.Null && !typ.Equivalent(outScope.cols[i].typ) {
-				panic(fmt.Errorf("VALUES list type mismatch, %s for %s", typ, outScope.cols[i].typ))
+				errorf(pgerror.CodeDatatypeMismatchError,
+					"VALUES list type mismatch, %s for %s", typ, outScope.cols[i].typ)
 			}
 		}
 
 		rows = append(rows, b.factory.ConstructTuple(b.factory.StoreList(elems)))
 	}
 
-	out = b.factory.ConstructValues(b.factory.StoreList(rows), b.factory.InternPrivate(makeColSet(outScope.cols)))
+	out = b.factory.ConstructValues(b.factory.StoreList(rows), makeColSet(outScope.cols))
 	return
 }
 
@@ -708,8 +830,9 @@ func (b *Builder) buildSelectClause(stmt *tree.Select, inScope *scope) (out opt.
 		having = b.buildScalar(groupingsScope.resolveType(sel.Having.Expr, types.Bool), groupingsScope)
 	}
 
-	// If the projection is empty or a simple pass-through, then
-	// buildProjectionList will return nil values.
+	// If the projection is empty, then buildProjectionList will return nil
+	// values. If it's a simple pass-through, then it will return nil
+	// projections, along with the scope that names the columns.
 	var projections []opt.GroupID
 	var projectionsScope *scope
 	if groupings == nil {
@@ -727,7 +850,7 @@ func (b *Builder) buildSelectClause(stmt *tree.Select, inScope *scope) (out opt.
 
 		var groupingCols []columnProps
 		if groupings != nil {
-			groupingCols = groupingsScope.cols
+			groupingCols = groupingsScope.cols[:len(groupingsScope.cols)-len(aggCols)]
 		}
 
 		groupingList := b.constructProjectionList(groupings, groupingCols)
@@ -754,7 +877,11 @@ func (b *Builder) buildSelectClause(stmt *tree.Select, inScope *scope) (out opt.
 		out = b.buildDistinct(out, sel.Distinct, outScope.cols, orderByScope)
 
 		// Build projection containing any additional synthetic order by
-		// columns and set the ordering on the output scope.
+		// columns and set the ordering on the output scope. The output
+		// columns are the columns of the projection list, if there is one.
+		if projectionsScope != nil {
+			outScope = projectionsScope
+		}
 		out, outScope.ordering = b.buildOrderBy(out, stmt.OrderBy, orderByScope)
 		return
 	}
@@ -762,6 +889,8 @@ func (b *Builder) buildSelectClause(stmt *tree.Select, inScope *scope) (out opt.
 	// Wrap with project operator if it exists.
 	if projections != nil {
 		out = b.factory.ConstructProject(out, b.constructProjectionList(projections, projectionsScope.cols))
+	}
+	if projectionsScope != nil {
 		outScope = projectionsScope
 	}
 
@@ -792,7 +921,7 @@ func (b *Builder) buildFrom(from *tree.From, where *tree.Where, inScope *scope)
 		// TODO(peter): This should be a table with 1 row and 0 columns to match
 		// current cockroach behavior.
 		rows := []opt.GroupID{b.factory.ConstructTuple(b.factory.StoreList(nil))}
-		out = b.factory.ConstructValues(b.factory.StoreList(rows), b.factory.InternPrivate(&opt.ColSet{}))
+		out = b.factory.ConstructValues(b.factory.StoreList(rows), &opt.ColSet{})
 		outScope = inScope
 	} else {
 		out = left
@@ -842,7 +971,7 @@ func (b *Builder) buildProjectionList(
 		// Update the name of the column if there is an alias defined.
 		if e.As != "" {
 			for i := range outScope.cols[end:] {
-				outScope.cols[i].name = cat.ColumnName(e.As)
+				outScope.cols[end+i].name = cat.ColumnName(e.As)
 			}
 		}
 	}
@@ -863,7 +992,8 @@ func (b *Builder) buildProjectionList(
 		}
 
 		if matches {
-			return nil, nil
+			// The projection may still rename the columns.
+			return nil, outScope
 		}
 	}
 
@@ -877,36 +1007,36 @@ func (b *Builder) buildProjection(projection tree.Expr, inScope, outScope *scope
 	// NB: The case statements are sorted lexicographically.
 	switch t := projection.(type) {
 	case *tree.AllColumnsSelector:
-		tableName := cat.TableName(t.TableName.Table())
+		tableName := cat.NewTableName(&t.TableName)
 		for _, col := range inScope.cols {
-			if col.table == tableName && !col.hidden {
-				v := b.factory.ConstructVariable(b.factory.InternPrivate(col.index))
+			if col.matchesTable(tableName) && !col.hidden {
+				v := b.factory.ConstructVariable(col.index)
 				projections = append(projections, v)
 				outScope.cols = append(outScope.cols, col)
 			}
 		}
 		if len(projections) == 0 {
-			fatalf("unknown table %s", t)
+			errorf(pgerror.CodeUndefinedTableError, "unknown table %s", t)
 		}
 		return projections
 
 	case tree.UnqualifiedStar:
 		for _, col := range inScope.cols {
 			if !col.hidden {
-				v := b.factory.ConstructVariable(b.factory.InternPrivate(col.index))
+				v := b.factory.ConstructVariable(col.index)
 				projections = append(projections, v)
 				outScope.cols = append(outScope.cols, col)
 			}
 		}
 		if len(projections) == 0 {
-			fatalf("failed to expand *")
+			errorf(pgerror.CodeInvalidColumnReferenceError, "failed to expand *")
 		}
 		return
 
 	case *tree.UnresolvedName:
 		vn, err := t.NormalizeVarName()
 		if err != nil {
-			panic(err)
+			raise(pgerror.CodeSyntaxError, err)
 		}
 		return b.buildProjection(vn, inScope, outScope)
 
@@ -924,7 +1054,7 @@ func (b *Builder) buildDistinct(in opt.GroupID, distinct bool, byCols []columnPr
 	// Distinct is equivalent to group by without any aggregations.
 	groupings := make([]opt.GroupID, 0, len(byCols))
 	for i := range byCols {
-		v := b.factory.ConstructVariable(b.factory.InternPrivate(byCols[i].index))
+		v := b.factory.ConstructVariable(byCols[i].index)
 		groupings = append(groupings, v)
 	}
 
@@ -944,7 +1074,7 @@ func (b *Builder) buildOrderBy(
 
 	orderScope := inScope.push()
 
-	projections := make([]opt.GroupID, len(orderBy))
+	projections := make([]opt.GroupID, 0, len(orderBy))
 	for _, order := range orderBy {
 		scalar := b.buildScalarProjection(inScope.resolveType(order.Expr, types.Any), inScope, orderScope)
 		projections = append(projections, scalar)
@@ -960,7 +1090,7 @@ func (b *Builder) buildOrderBy(
 	for i := range orderScope.cols {
 		index := orderScope.cols[i].index
 		if orderBy[i].Direction == tree.Descending {
-			index = -(index + 1)
+			index = -index
 		}
 
 		ordering = append(ordering, index)
@@ -985,16 +1115,17 @@ func (b *Builder) buildAppendingProject(
 	for i := range inScope.cols {
 		col := &inScope.cols[i]
 		outScope.cols = append(outScope.cols, *col)
-		combined = append(combined, b.factory.ConstructVariable(b.factory.InternPrivate(col.index)))
+		combined = append(combined, b.factory.ConstructVariable(col.index))
 	}
 
 	for i := range projectionsScope.cols {
 		col := &projectionsScope.cols[i]
 
-		// Only append projection columns that aren't already present.
+		// Only append projection columns that aren't already present. Each
+		// projection column is computed by the corresponding projection.
 		if findColByIndex(outScope.cols, col.index) == nil {
 			outScope.cols = append(outScope.cols, *col)
-			combined = append(combined, b.factory.ConstructVariable(b.factory.InternPrivate(col.index)))
+			combined = append(combined, projections[i])
 		}
 	}
 
@@ -1020,7 +1151,7 @@ func (b *Builder) buildUnion(clause *tree.UnionClause, inScope *scope) (out opt.
 
 	switch clause.Type {
 	case tree.UnionOp:
-		out = b.factory.ConstructUnion(left, right, b.factory.InternPrivate(&colMap))
+		out = b.factory.ConstructUnion(left, right, &colMap)
 	case tree.IntersectOp:
 		out = b.factory.ConstructIntersect(left, right)
 	case tree.ExceptOp:
@@ -1060,7 +1191,7 @@ func (b *Builder) constructJoin(joinType string, left, right, filter opt.GroupID
 }
 
 func (b *Builder) constructProjectionList(items []opt.GroupID, cols []columnProps) opt.GroupID {
-	return b.factory.ConstructProjections(b.factory.StoreList(items), b.factory.InternPrivate(makeColSet(cols)))
+	return b.factory.ConstructProjections(b.factory.StoreList(items), makeColSet(cols))
 }
 
 func (b *Builder) IndexedVarEval(idx int, ctx *tree.EvalContext) (tree.Datum, error) {
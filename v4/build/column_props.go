@@ -25,19 +25,39 @@ var _ tree.TypedExpr = &columnProps{}
 var _ tree.VariableExpr = &columnProps{}
 
 func (c columnProps) String() string {
-	if c.table == "" {
+	if c.table.Table == "" {
 		return tree.NameString(string(c.name))
 	}
-	return fmt.Sprintf("%s.%s",
-		tree.NameString(string(c.table)), tree.NameString(string(c.name)))
+	return fmt.Sprintf("%s.%s", c.table, tree.NameString(string(c.name)))
 }
 
 func (c columnProps) matches(tblName cat.TableName, colName cat.ColumnName) bool {
 	if colName != c.name {
 		return false
 	}
-	if tblName == "" {
+	if tblName.Table == "" {
 		return true
 	}
-	return c.table == tblName
+	return c.matchesTable(tblName)
+}
+
+// matchesTable returns true if the column belongs to the table with the given
+// name. The name can omit the database, or both the database and the schema,
+// in which case only the parts that it includes are compared. As in
+// cat.MemCatalog, a two part name s.t can also refer to a table in the public
+// schema of database s.
+func (c columnProps) matchesTable(tblName cat.TableName) bool {
+	if tblName.Table != c.table.Table {
+		return false
+	}
+
+	switch {
+	case tblName.Database != "":
+		return tblName.Database == c.table.Database && tblName.Schema == c.table.Schema
+
+	case tblName.Schema != "":
+		return tblName.Schema == c.table.Schema ||
+			(tblName.Schema == c.table.Database && c.table.Schema == cat.PublicSchema)
+	}
+	return true
 }
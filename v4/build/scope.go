@@ -5,6 +5,7 @@ import (
 
 	"fmt"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/petermattis/opttoy/v4/cat"
@@ -75,7 +76,7 @@ func (s *scope) resolveColumnName(tblName cat.TableName, colName cat.ColumnName)
 		}
 	}
 
-	fatalf("unknown column %s", columnProps{name: colName, table: tblName})
+	errorf(pgerror.CodeUndefinedColumnError, "unknown column %s", columnProps{name: colName, table: tblName})
 	return nil
 }
 
@@ -93,7 +94,7 @@ func (s *scope) resolveColumnIndex(index opt.ColumnIndex) *columnProps {
 			if col.index == index {
 				if aggScope != nil {
 					if aggScope.groupby.groupingsScope == nil {
-						panic("aggregate is not allowed in this context")
+						errorf(pgerror.CodeGroupingError, "aggregate is not allowed in this context")
 					}
 
 					// Ensure that all column references within the same
@@ -101,7 +102,8 @@ func (s *scope) resolveColumnIndex(index opt.ColumnIndex) *columnProps {
 					if curr.groupby.refScope == nil {
 						curr.groupby.refScope = curr
 					} else if curr.groupby.refScope != curr {
-						panic("multiple column references within same aggregate must refer to same scope")
+						errorf(pgerror.CodeGroupingError,
+							"multiple column references within same aggregate must refer to same scope")
 					}
 				}
 
@@ -110,7 +112,7 @@ func (s *scope) resolveColumnIndex(index opt.ColumnIndex) *columnProps {
 		}
 	}
 
-	fatalf("unknown column index %s", index)
+	fatalf("unknown column index %d", index)
 	return nil
 }
 
@@ -133,7 +135,7 @@ func (s *scope) startAggFunc() {
 	var found bool
 	for curr := s; curr != nil; curr = curr.parent {
 		if curr.groupby.inAgg {
-			panic("aggregate function cannot be nested within another aggregate function")
+			errorf(pgerror.CodeGroupingError, "aggregate function cannot be nested within another aggregate function")
 		}
 
 		if curr.groupby.groupingsScope != nil {
@@ -143,7 +145,7 @@ func (s *scope) startAggFunc() {
 	}
 
 	if !found {
-		panic("aggregate function is not allowed in this context")
+		errorf(pgerror.CodeGroupingError, "aggregate function is not allowed in this context")
 	}
 
 	s.groupby.inAgg = true
@@ -184,7 +186,7 @@ func (s *scope) resolveType(expr tree.Expr, desired types.T) tree.TypedExpr {
 	expr, _ = tree.WalkExpr(s, expr)
 	texpr, err := tree.TypeCheck(expr, &s.builder.semaCtx, desired)
 	if err != nil {
-		panic(err)
+		raise(pgerror.CodeDatatypeMismatchError, err)
 	}
 
 	return texpr
@@ -196,21 +198,21 @@ func (s *scope) VisitPre(expr tree.Expr) (recurse bool, newExpr tree.Expr) {
 	case *tree.UnresolvedName:
 		vn, err := t.NormalizeVarName()
 		if err != nil {
-			panic(err)
+			raise(pgerror.CodeSyntaxError, err)
 		}
 		return s.VisitPre(vn)
 
 	case *tree.ColumnItem:
-		tblName := cat.TableName(t.TableName.Table())
+		tblName := cat.NewTableName(&t.TableName)
 		colName := cat.ColumnName(t.ColumnName)
 
 		for curr := s; curr != nil; curr = curr.parent {
 			for i := range curr.cols {
 				col := &curr.cols[i]
 				if col.matches(tblName, colName) {
-					if tblName == "" && col.table != "" {
+					if tblName.Table == "" && col.table.Table != "" {
 						// TODO(andy): why is this necessary??
-						t.TableName.TableName = tree.Name(col.table)
+						t.TableName.TableName = tree.Name(col.table.Table)
 						t.TableName.DBNameOriginallyOmitted = true
 					}
 					return false, col
@@ -218,13 +220,13 @@ func (s *scope) VisitPre(expr tree.Expr) (recurse bool, newExpr tree.Expr) {
 			}
 		}
 
-		fatalf("unknown column %s", columnProps{name: colName, table: tblName})
+		errorf(pgerror.CodeUndefinedColumnError, "unknown column %s", columnProps{name: colName, table: tblName})
 		return false, nil
 
 	case *tree.FuncExpr:
 		def, err := t.Func.Resolve(s.builder.semaCtx.SearchPath)
 		if err != nil {
-			fatalf("%v", err)
+			raise(pgerror.CodeUndefinedFunctionError, err)
 		}
 		if len(t.Exprs) != 1 {
 			break
@@ -235,7 +237,7 @@ func (s *scope) VisitPre(expr tree.Expr) (recurse bool, newExpr tree.Expr) {
 		}
 		vn, err = vn.NormalizeVarName()
 		if err != nil {
-			panic(err)
+			raise(pgerror.CodeSyntaxError, err)
 		}
 		t.Exprs[0] = vn
 
@@ -311,7 +313,7 @@ func (s *scope) IndexedVarResolvedType(idx int) types.T {
 		}
 	}
 
-	fatalf("unknown column index %s", idx)
+	fatalf("unknown column index %d", idx)
 	return nil
 }
 
@@ -332,9 +334,9 @@ func (s *scope) replaceSubquery(sub *tree.Subquery, multiRow bool, desiredColumn
 		n := len(outScope.cols)
 		switch desiredColumns {
 		case 1:
-			panic(fmt.Errorf("subquery must return one column, found %d", n))
+			errorf(pgerror.CodeSyntaxError, "subquery must return one column, found %d", n)
 		default:
-			panic(fmt.Errorf("subquery must return %d columns, found %d", desiredColumns, n))
+			errorf(pgerror.CodeSyntaxError, "subquery must return %d columns, found %d", desiredColumns, n)
 		}
 	}
 
@@ -2,12 +2,48 @@ package build
 
 import (
 	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 )
 
+// unimplemented raises an error with the CodeFeatureNotSupportedError code for
+// a statement that uses a feature which isn't supported yet.
 func unimplemented(format string, args ...interface{}) {
-	panic("unimplemented: " + fmt.Sprintf(format, args...))
+	panic(pgerror.NewError(pgerror.CodeFeatureNotSupportedError, "unimplemented: "+fmt.Sprintf(format, args...)))
+}
+
+// errorf raises an error with the given code for a statement that is invalid,
+// such as one that refers to an unknown column. The error is recovered by
+// catchError and returned by the public API.
+func errorf(code string, format string, args ...interface{}) {
+	panic(pgerror.NewErrorf(code, format, args...))
 }
 
+// raise raises an error that was returned by another package. If the error
+// doesn't have a code, then it is given the code.
+func raise(code string, err error) {
+	if pgErr, ok := pgerror.GetPGCause(err); ok {
+		panic(pgErr)
+	}
+	panic(pgerror.NewError(code, err.Error()))
+}
+
+// fatalf panics because an internal invariant has been violated.
 func fatalf(format string, args ...interface{}) {
 	panic(fmt.Sprintf(format, args...))
 }
+
+// catchError recovers from a panic in a public API function, and stores the
+// error in err. It must be deferred directly by the function. Errors raised by
+// errorf, raise and unimplemented are returned as is, and any other panic is
+// due to a violated internal invariant, so it is returned as an error with the
+// CodeInternalError code.
+func catchError(err *error) {
+	if r := recover(); r != nil {
+		if pgErr, ok := r.(*pgerror.Error); ok {
+			*err = pgErr
+			return
+		}
+		*err = pgerror.NewErrorf(pgerror.CodeInternalError, "internal error: %v", r)
+	}
+}
@@ -1,28 +1,431 @@
 package cat
 
-type Catalog struct {
-	// tables maps from name to table metadata.
-	tables map[TableName]*Table
+import (
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+)
+
+// DefaultDatabase is the current database of a new MemCatalog.
+const DefaultDatabase = "defaultdb"
+
+// PublicSchema is the schema that every database is created with. It is the
+// only schema in the search path of a new MemCatalog.
+const PublicSchema = "public"
+
+// Catalog resolves the tables that are referenced by queries. The optimizer
+// only depends on this interface, so that it can be backed by any source of
+// schema information. MemCatalog is the default implementation, which is used
+// by exec.Engine and the tests.
+type Catalog interface {
+	// Table returns the table with the given name, or an error with the
+	// CodeUndefinedTableError code if there is no such table. The name does
+	// not need to be fully qualified, in which case it is resolved using the
+	// catalog's current database and search path.
+	Table(name TableName) (Table, error)
+
+	// View returns the view with the given name, or an error with the
+	// CodeUndefinedTableError code if there is no such view. The name is
+	// resolved the same way as by Table, and tables and views share the same
+	// names, so at most one of them finds a table or view for a name.
+	View(name TableName) (*View, error)
+}
+
+// MemCatalog is a Catalog that stores its databases, schemas, tables and views
+// in memory. It starts out with DefaultDatabase as its current database, which
+// contains PublicSchema.
+//
+// A name is resolved the same way for a query and for a new table or view,
+// except that a query needs the table or view to exist:
+//
+//   - A fully qualified name d.s.t refers to table t in schema s of database d.
+//   - A two part name s.t refers to table t in schema s of the current
+//     database. If there is no such schema, but there is a database s, then
+//     it refers to table t in the public schema of database s.
+//   - An unqualified name t refers to table t in the first schema of the
+//     search path that contains it. A new table is added to the first schema
+//     of the search path that exists.
+type MemCatalog struct {
+	// tables maps from fully qualified name to table metadata.
+	tables map[TableName]*MemTable
+
+	// views maps from fully qualified name to view definition. A name can't
+	// belong to both a table and a view.
+	views map[TableName]*View
+
+	// schemas maps from the name of each database to the set of its schemas.
+	schemas map[string]map[string]bool
+
+	database   string
+	searchPath []string
+}
+
+var _ Catalog = &MemCatalog{}
+
+func NewCatalog() *MemCatalog {
+	c := &MemCatalog{
+		tables:     make(map[TableName]*MemTable),
+		views:      make(map[TableName]*View),
+		schemas:    make(map[string]map[string]bool),
+		searchPath: []string{PublicSchema},
+	}
+	if err := c.CreateDatabase(DefaultDatabase); err != nil {
+		fatalf("%v", err)
+	}
+	c.database = DefaultDatabase
+	return c
+}
+
+// Database returns the current database.
+func (c *MemCatalog) Database() string {
+	return c.database
 }
 
-func NewCatalog() *Catalog {
-	return &Catalog{tables: make(map[TableName]*Table)}
+// SetDatabase changes the current database, or returns an error with the
+// CodeInvalidCatalogNameError code if there is no such database.
+func (c *MemCatalog) SetDatabase(database string) error {
+	if _, ok := c.schemas[database]; !ok {
+		return undefinedDatabase(database)
+	}
+
+	c.database = database
+	return nil
+}
+
+// SearchPath returns the schemas that are searched for unqualified names.
+func (c *MemCatalog) SearchPath() []string {
+	return c.searchPath
+}
+
+// SetSearchPath changes the schemas that are searched for unqualified names.
+// As in Postgres, the schemas don't need to exist, and the ones that don't
+// are skipped.
+func (c *MemCatalog) SetSearchPath(schemas []string) {
+	c.searchPath = schemas
+}
+
+// CreateDatabase adds a database with a public schema to the catalog, or
+// returns an error with the CodeDuplicateDatabaseError code if there is
+// already a database with its name.
+func (c *MemCatalog) CreateDatabase(database string) error {
+	if _, ok := c.schemas[database]; ok {
+		return pgerror.NewErrorf(pgerror.CodeDuplicateDatabaseError, "database already exists: %s", database)
+	}
+
+	c.schemas[database] = map[string]bool{PublicSchema: true}
+	return nil
 }
 
-func (c *Catalog) Table(name TableName) *Table {
-	tbl, ok := c.tables[name]
+// CreateSchema adds a schema to the given database. It returns an error with
+// the CodeInvalidCatalogNameError code if there is no such database, or with
+// the CodeDuplicateSchemaError code if the database already has the schema.
+// The SQL parser doesn't support CREATE SCHEMA yet, so exec.Engine can't
+// execute it, and schemas other than public are only created by this method.
+func (c *MemCatalog) CreateSchema(database, schema string) error {
+	schemas, ok := c.schemas[database]
 	if !ok {
-		fatalf("unable to find table: %s", name)
+		return undefinedDatabase(database)
+	}
+	if schemas[schema] {
+		return pgerror.NewErrorf(pgerror.CodeDuplicateSchemaError, "schema already exists: %s.%s", database, schema)
+	}
+
+	schemas[schema] = true
+	return nil
+}
+
+// Table is part of the Catalog interface.
+func (c *MemCatalog) Table(name TableName) (Table, error) {
+	tbl, err := c.MemTable(name)
+	if err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
+
+// MemTable returns the table with the given name, or an error with the
+// CodeUndefinedTableError code if there is no such table. Unlike Table, it
+// returns the table's concrete type, so that it can be changed.
+func (c *MemCatalog) MemTable(name TableName) (*MemTable, error) {
+	if qualified, ok := c.resolve(name); ok {
+		if tbl, ok := c.tables[qualified]; ok {
+			return tbl, nil
+		}
+	}
+
+	return nil, pgerror.NewErrorf(pgerror.CodeUndefinedTableError, "unable to find table: %s", name)
+}
+
+// View is part of the Catalog interface.
+func (c *MemCatalog) View(name TableName) (*View, error) {
+	if qualified, ok := c.resolve(name); ok {
+		if view, ok := c.views[qualified]; ok {
+			return view, nil
+		}
 	}
 
-	return tbl
+	return nil, pgerror.NewErrorf(pgerror.CodeUndefinedTableError, "unable to find view: %s", name)
 }
 
-func (c *Catalog) AddTable(tbl *Table) {
-	_, ok := c.tables[tbl.Name]
-	if ok {
-		fatalf("table already exists: %s", tbl.Name)
+// QualifyName returns the fully qualified name of a new table with the given
+// name. It returns an error with the CodeInvalidCatalogNameError or
+// CodeInvalidSchemaNameError code if the database or schema of the name don't
+// exist.
+func (c *MemCatalog) QualifyName(name TableName) (TableName, error) {
+	if name.Qualified() {
+		if _, ok := c.schemas[name.Database]; !ok {
+			return TableName{}, undefinedDatabase(name.Database)
+		}
+	}
+
+	for _, candidate := range c.candidates(name) {
+		if c.schemas[candidate.Database][candidate.Schema] {
+			return candidate, nil
+		}
+	}
+
+	if name.Schema == "" {
+		return TableName{}, pgerror.NewErrorf(pgerror.CodeInvalidSchemaNameError,
+			"no schema in the search path exists: %s", name)
+	}
+	return TableName{}, pgerror.NewErrorf(pgerror.CodeInvalidSchemaNameError,
+		"schema does not exist: %s", name)
+}
+
+// AddTable adds the table to the catalog, or returns an error with the
+// CodeDuplicateRelationError code if there is already a table with its name.
+// The name of the table must be fully qualified, and its schema must exist.
+func (c *MemCatalog) AddTable(tbl *MemTable) error {
+	if !tbl.Name.Qualified() || !c.schemas[tbl.Name.Database][tbl.Name.Schema] {
+		return pgerror.NewErrorf(pgerror.CodeInvalidSchemaNameError,
+			"table name is not qualified by an existing schema: %s", tbl.Name)
+	}
+
+	if c.exists(tbl.Name) {
+		return pgerror.NewErrorf(pgerror.CodeDuplicateRelationError, "table already exists: %s", tbl.Name)
 	}
 
 	c.tables[tbl.Name] = tbl
+	return nil
+}
+
+// AddView adds the view to the catalog, or returns an error with the
+// CodeDuplicateRelationError code if there is already a table or view with its
+// name. The name of the view must be fully qualified, and its schema must
+// exist.
+func (c *MemCatalog) AddView(view *View) error {
+	if !view.Name.Qualified() || !c.schemas[view.Name.Database][view.Name.Schema] {
+		return pgerror.NewErrorf(pgerror.CodeInvalidSchemaNameError,
+			"view name is not qualified by an existing schema: %s", view.Name)
+	}
+
+	if c.exists(view.Name) {
+		return pgerror.NewErrorf(pgerror.CodeDuplicateRelationError, "relation already exists: %s", view.Name)
+	}
+
+	c.views[view.Name] = view
+	return nil
+}
+
+// DropTable removes the table from the catalog. It returns an error with the
+// CodeDependentObjectsStillExistError code if a foreign key of another table
+// references it, or if a view depends on it.
+func (c *MemCatalog) DropTable(tbl *MemTable) error {
+	for _, ref := range c.ReferencingKeys(tbl) {
+		if ref.Table != tbl {
+			return pgerror.NewErrorf(pgerror.CodeDependentObjectsStillExistError,
+				"table '%s' is referenced by a foreign key of table '%s'", tbl.Name, ref.Table.Name)
+		}
+	}
+	if err := c.checkDependentViews("table", tbl.Name); err != nil {
+		return err
+	}
+
+	delete(c.tables, tbl.Name)
+	return nil
+}
+
+// DropView removes the view from the catalog. It returns an error with the
+// CodeDependentObjectsStillExistError code if another view depends on it.
+func (c *MemCatalog) DropView(view *View) error {
+	if err := c.checkDependentViews("view", view.Name); err != nil {
+		return err
+	}
+
+	delete(c.views, view.Name)
+	return nil
+}
+
+// DropColumn removes the column with the given name from the table, and
+// renumbers the columns that follow it in the table's keys and check
+// constraints, and in the foreign keys that reference the table. It returns
+// an error with the CodeDependentObjectsStillExistError code if a key, check
+// constraint or foreign key refers to the column, or if a view depends on the
+// table, since those have to be dropped first. Views don't record which columns
+// of a table they use, so a view prevents any column of the table from being
+// dropped.
+func (c *MemCatalog) DropColumn(tbl *MemTable, name ColumnName) error {
+	ord, err := tbl.ColumnOrdinal(name)
+	if err != nil {
+		return err
+	}
+	for _, view := range c.Views() {
+		if view.DependsOn(tbl.Name) {
+			return pgerror.NewErrorf(pgerror.CodeDependentObjectsStillExistError,
+				"column '%s' can't be dropped, since table '%s' is used by view '%s'", name, tbl.Name, view.Name)
+		}
+	}
+
+	for i := range tbl.Keys {
+		if tbl.Keys[i].HasColumn(ord) {
+			return pgerror.NewErrorf(pgerror.CodeDependentObjectsStillExistError,
+				"column '%s' is referenced by key '%s'", name, tbl.Keys[i].Name)
+		}
+	}
+	for i := range tbl.Checks {
+		if tbl.Checks[i].HasColumn(ord) {
+			return pgerror.NewErrorf(pgerror.CodeDependentObjectsStillExistError,
+				"column '%s' is referenced by check constraint '%s'", name, tbl.Checks[i].Name)
+		}
+	}
+	refs := c.ReferencingKeys(tbl)
+	for _, ref := range refs {
+		if containsColumn(ref.Key.Fkey.Columns, ord) {
+			return pgerror.NewErrorf(pgerror.CodeDependentObjectsStillExistError,
+				"column '%s' is referenced by a foreign key of table '%s'", name, ref.Table.Name)
+		}
+	}
+
+	tbl.dropColumn(ord)
+	for _, ref := range refs {
+		renumberColumns(ref.Key.Fkey.Columns, ord)
+	}
+	return nil
+}
+
+// KeyRef identifies a key of a table in the catalog.
+type KeyRef struct {
+	Table *MemTable
+	Key   *TableKey
+}
+
+// ReferencingKeys returns the keys of tables in the catalog, including the
+// table itself, whose foreign keys reference the table. The keys are ordered
+// by the name of their table.
+func (c *MemCatalog) ReferencingKeys(tbl *MemTable) []KeyRef {
+	var refs []KeyRef
+	for _, other := range c.MemTables() {
+		for i := range other.Keys {
+			if fkey := other.Keys[i].Fkey; fkey != nil && fkey.Referenced == Table(tbl) {
+				refs = append(refs, KeyRef{Table: other, Key: &other.Keys[i]})
+			}
+		}
+	}
+	return refs
+}
+
+// MemTables returns the tables in the catalog, ordered by name.
+func (c *MemCatalog) MemTables() []*MemTable {
+	tables := make([]*MemTable, 0, len(c.tables))
+	for _, tbl := range c.tables {
+		tables = append(tables, tbl)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name.String() < tables[j].Name.String()
+	})
+	return tables
+}
+
+// Views returns the views in the catalog, ordered by name.
+func (c *MemCatalog) Views() []*View {
+	views := make([]*View, 0, len(c.views))
+	for _, view := range c.views {
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Name.String() < views[j].Name.String()
+	})
+	return views
+}
+
+// DependentViews returns the views that depend on the tables or views with the
+// given fully qualified names, either directly or through other views. Each
+// view comes before the views that it depends on, so that the views can be
+// dropped in order.
+func (c *MemCatalog) DependentViews(names ...TableName) []*View {
+	var res []*View
+	seen := make(map[TableName]bool)
+	var visit func(name TableName)
+	visit = func(name TableName) {
+		for _, view := range c.Views() {
+			if !seen[view.Name] && view.DependsOn(name) {
+				seen[view.Name] = true
+				visit(view.Name)
+				res = append(res, view)
+			}
+		}
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return res
+}
+
+// checkDependentViews returns an error with the
+// CodeDependentObjectsStillExistError code if a view depends on the table or
+// view with the given name. kind is "table" or "view".
+func (c *MemCatalog) checkDependentViews(kind string, name TableName) error {
+	for _, view := range c.Views() {
+		if view.DependsOn(name) {
+			return pgerror.NewErrorf(pgerror.CodeDependentObjectsStillExistError,
+				"%s '%s' is used by view '%s'", kind, name, view.Name)
+		}
+	}
+	return nil
+}
+
+// resolve returns the fully qualified name of the table or view that the given
+// name refers to, and false if there is none.
+func (c *MemCatalog) resolve(name TableName) (TableName, bool) {
+	for _, candidate := range c.candidates(name) {
+		if c.exists(candidate) {
+			return candidate, true
+		}
+	}
+	return TableName{}, false
+}
+
+// exists returns true if there is a table or view with the fully qualified
+// name.
+func (c *MemCatalog) exists(name TableName) bool {
+	_, isTable := c.tables[name]
+	_, isView := c.views[name]
+	return isTable || isView
+}
+
+// candidates returns the fully qualified names that the given name can refer
+// to, in the order in which they are tried.
+func (c *MemCatalog) candidates(name TableName) []TableName {
+	switch {
+	case name.Qualified():
+		return []TableName{name}
+
+	case name.Schema != "":
+		// The schema only names a database if the current database has no
+		// schema with that name.
+		if c.schemas[c.database][name.Schema] {
+			return []TableName{{Database: c.database, Schema: name.Schema, Table: name.Table}}
+		}
+		return []TableName{{Database: name.Schema, Schema: PublicSchema, Table: name.Table}}
+	}
+
+	res := make([]TableName, len(c.searchPath))
+	for i, schema := range c.searchPath {
+		res[i] = TableName{Database: c.database, Schema: schema, Table: name.Table}
+	}
+	return res
+}
+
+func undefinedDatabase(database string) error {
+	return pgerror.NewErrorf(pgerror.CodeInvalidCatalogNameError, "database does not exist: %s", database)
 }
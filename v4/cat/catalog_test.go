@@ -0,0 +1,154 @@
+package cat
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+)
+
+func TestCreateSchema(t *testing.T) {
+	c := NewCatalog()
+	if err := c.CreateSchema(DefaultDatabase, "s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateDatabase("other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateSchema("other", "s"); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		database string
+		schema   string
+		code     string
+	}{
+		{DefaultDatabase, "s", pgerror.CodeDuplicateSchemaError},
+		{DefaultDatabase, PublicSchema, pgerror.CodeDuplicateSchemaError},
+		{"other", "s", pgerror.CodeDuplicateSchemaError},
+		{"missing", "s", pgerror.CodeInvalidCatalogNameError},
+	}
+	for _, tc := range testCases {
+		err := c.CreateSchema(tc.database, tc.schema)
+		if code := errorCode(err); code != tc.code {
+			t.Errorf("%s.%s: expected error code %s, but found %s (%v)", tc.database, tc.schema, tc.code, code, err)
+		}
+	}
+}
+
+func TestResolveSearchPath(t *testing.T) {
+	c := NewCatalog()
+	for _, database := range []string{"s1", "other"} {
+		if err := c.CreateDatabase(database); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, schema := range []string{"s1", "s2"} {
+		if err := c.CreateSchema(DefaultDatabase, schema); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []TableName{
+		{Database: DefaultDatabase, Schema: PublicSchema, Table: "t"},
+		{Database: DefaultDatabase, Schema: "s1", Table: "t"},
+		{Database: DefaultDatabase, Schema: "s2", Table: "t"},
+		{Database: DefaultDatabase, Schema: "s2", Table: "u"},
+		{Database: "s1", Schema: PublicSchema, Table: "u"},
+		{Database: "other", Schema: PublicSchema, Table: "u"},
+	} {
+		if err := c.AddTable(&MemTable{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		searchPath []string
+		name       TableName
+		expected   string
+	}{
+		// Unqualified names are resolved using the first schema in the search
+		// path that has the table, and schemas that don't exist are skipped.
+		{[]string{PublicSchema}, MakeTableName("t"), "defaultdb.public.t"},
+		{[]string{"s1", "s2", PublicSchema}, MakeTableName("t"), "defaultdb.s1.t"},
+		{[]string{"s2", "s1", PublicSchema}, MakeTableName("t"), "defaultdb.s2.t"},
+		{[]string{"missing", "s2"}, MakeTableName("t"), "defaultdb.s2.t"},
+		{[]string{"s1", "s2"}, MakeTableName("u"), "defaultdb.s2.u"},
+		{[]string{"s1", PublicSchema}, MakeTableName("u"), ""},
+		{nil, MakeTableName("t"), ""},
+
+		// Two part names are resolved using the schema of the current
+		// database if it exists, and otherwise the public schema of the
+		// database with the same name. The search path isn't used.
+		{[]string{"s2"}, TableName{Schema: "s1", Table: "t"}, "defaultdb.s1.t"},
+		{[]string{"s2"}, TableName{Schema: "s1", Table: "u"}, ""},
+		{[]string{"s2"}, TableName{Schema: "other", Table: "u"}, "other.public.u"},
+		{[]string{"s2"}, TableName{Schema: PublicSchema, Table: "t"}, "defaultdb.public.t"},
+		{[]string{"s2"}, TableName{Schema: "missing", Table: "t"}, ""},
+
+		// Fully qualified names are resolved as is.
+		{[]string{"s1"}, TableName{Database: DefaultDatabase, Schema: "s2", Table: "t"}, "defaultdb.s2.t"},
+		{[]string{"s1"}, TableName{Database: "s1", Schema: "s1", Table: "t"}, ""},
+	}
+	for _, tc := range testCases {
+		c.SetSearchPath(tc.searchPath)
+		tbl, err := c.MemTable(tc.name)
+		if tc.expected == "" {
+			if code := errorCode(err); code != pgerror.CodeUndefinedTableError {
+				t.Errorf("%s %v: expected error code %s, but found %s (%v)",
+					tc.name, tc.searchPath, pgerror.CodeUndefinedTableError, code, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %v: %v", tc.name, tc.searchPath, err)
+			continue
+		}
+		if actual := tbl.Name.String(); actual != tc.expected {
+			t.Errorf("%s %v: expected %s, but found %s", tc.name, tc.searchPath, tc.expected, actual)
+		}
+	}
+}
+
+func TestQualifyNameSearchPath(t *testing.T) {
+	c := NewCatalog()
+	if err := c.CreateSchema(DefaultDatabase, "s"); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		searchPath []string
+		expected   string
+		code       string
+	}{
+		// A new table goes in the first schema of the search path that exists,
+		// even if the table exists in a later schema.
+		{[]string{"s", PublicSchema}, "defaultdb.s.t", ""},
+		{[]string{"missing", PublicSchema, "s"}, "defaultdb.public.t", ""},
+		{[]string{"missing"}, "", pgerror.CodeInvalidSchemaNameError},
+		{nil, "", pgerror.CodeInvalidSchemaNameError},
+	}
+	for _, tc := range testCases {
+		c.SetSearchPath(tc.searchPath)
+		name, err := c.QualifyName(MakeTableName("t"))
+		if tc.code != "" {
+			if code := errorCode(err); code != tc.code {
+				t.Errorf("%v: expected error code %s, but found %s (%v)", tc.searchPath, tc.code, code, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tc.searchPath, err)
+			continue
+		}
+		if actual := name.String(); actual != tc.expected {
+			t.Errorf("%v: expected %s, but found %s", tc.searchPath, tc.expected, actual)
+		}
+	}
+}
+
+func errorCode(err error) string {
+	if pgErr, ok := pgerror.GetPGCause(err); ok {
+		return pgErr.Code
+	}
+	return ""
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go/constant"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/gogo/protobuf/sortkeys"
@@ -91,8 +92,10 @@ func (h *Histogram) GetUpperBound() int64 {
 	return (int64)(*h.Buckets[len(h.Buckets)-1].UpperBound.(*tree.DInt))
 }
 
-func (h *Histogram) Validate() {
-	checkBucketsValid(h.Buckets)
+// Validate returns an error with the CodeInvalidParameterValueError code if
+// the histogram's buckets are not valid.
+func (h *Histogram) Validate() error {
+	return validateBuckets(h.Buckets)
 }
 
 // FilterHistogramLtOpLeOp applies a filter to the histogram that compares
@@ -397,22 +400,33 @@ func (b Bucket) splitBucket(splitPoint, lowerBound int64) (Bucket, Bucket) {
 // checkBucketsValid checks that the given buckets
 // are valid histogram buckets, and panics if they are not valid.
 func checkBucketsValid(buckets []Bucket) {
+	if err := validateBuckets(buckets); err != nil {
+		panic(err)
+	}
+}
+
+// validateBuckets returns an error if the given buckets are not valid
+// histogram buckets.
+func validateBuckets(buckets []Bucket) error {
 	if len(buckets) == 0 {
-		return
+		return nil
 	}
 
 	if buckets[0].NumRange != 0 {
-		panic("First bucket must have NumRange = 0")
+		return pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+			"First bucket must have NumRange = 0")
 	}
 
 	prev := buckets[0].UpperBound
 	for i := 1; i < len(buckets); i++ {
 		cur := buckets[i].UpperBound
 		if prev.Compare(nil /* ctx */, cur) >= 0 {
-			panic("Buckets must be disjoint and ordered by UpperBound")
+			return pgerror.NewError(pgerror.CodeInvalidParameterValueError,
+				"Buckets must be disjoint and ordered by UpperBound")
 		}
 		prev = cur
 	}
+	return nil
 }
 
 func makeDatum(val int64) tree.Datum {
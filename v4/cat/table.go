@@ -3,79 +3,292 @@ package cat
 import (
 	"bytes"
 	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 )
 
 var implicitPrimaryKey = &TableKey{Name: "primary", Primary: true}
 
-type TableName string
+// TableName is the name of a table, qualified by the schema and database that
+// contain it. Queries can omit the database, or both the database and the
+// schema, in which case the catalog resolves the name using its current
+// database and search path. The names of tables in the catalog are always
+// fully qualified.
+type TableName struct {
+	Database string
+	Schema   string
+	Table    string
+}
+
+// MakeTableName returns the unqualified name of a table.
+func MakeTableName(table string) TableName {
+	return TableName{Table: table}
+}
+
+// NewTableName returns the name of the table that is written as tn in a
+// statement. The parts of a tree.TableName are PrefixName.DatabaseName.TableName,
+// which hold the database, schema and table, so a two part name such as s.t
+// gives the schema and table.
+func NewTableName(tn *tree.TableName) TableName {
+	return TableName{
+		Database: string(tn.PrefixName),
+		Schema:   string(tn.DatabaseName),
+		Table:    string(tn.TableName),
+	}
+}
 
-type Table struct {
+// Qualified returns true if the name includes the database and schema.
+func (tn TableName) Qualified() bool {
+	return tn.Database != "" && tn.Schema != ""
+}
+
+// String returns the parts of the name that are set, separated by dots.
+func (tn TableName) String() string {
+	var buf bytes.Buffer
+	for _, part := range []string{tn.Database, tn.Schema} {
+		if part != "" {
+			buf.WriteString(part)
+			buf.WriteString(".")
+		}
+	}
+	buf.WriteString(tn.Table)
+	return buf.String()
+}
+
+// Table describes the columns and keys of a table, along with the statistics
+// of its columns. Columns are identified by their ordinal position in the
+// table.
+type Table interface {
+	// TabName returns the name of the table.
+	TabName() TableName
+
+	// ColumnCount returns the number of columns in the table.
+	ColumnCount() int
+
+	// Column returns the column at the given ordinal position. Its Stats are
+	// nil if there are no statistics for the column.
+	Column(ord ColumnOrdinal) *Column
+
+	// KeyCount returns the number of keys of the table.
+	KeyCount() int
+
+	// Key returns the i'th key of the table. The keys include the primary
+	// key, the unique constraints and indexes, and the foreign keys.
+	Key(i int) *TableKey
+}
+
+// PrimaryKey returns the primary key of the table. If the table doesn't
+// declare one, PrimaryKey returns a key without columns.
+func PrimaryKey(tbl Table) *TableKey {
+	for i := 0; i < tbl.KeyCount(); i++ {
+		k := tbl.Key(i)
+		if k.Primary {
+			return k
+		}
+	}
+
+	return implicitPrimaryKey
+}
+
+// MemTable is the Table that is stored by MemCatalog.
+type MemTable struct {
 	Name    TableName
 	Columns []Column
 	Keys    []TableKey
+	Checks  []CheckConstraint
 
 	// colMap indexes all columns by mapping their name to their ordinal
 	// position in the table.
 	colMap map[ColumnName]ColumnOrdinal
 }
 
-func (t *Table) AddColumn(col *Column) ColumnOrdinal {
+var _ Table = &MemTable{}
+
+// TabName is part of the Table interface.
+func (t *MemTable) TabName() TableName {
+	return t.Name
+}
+
+// ColumnCount is part of the Table interface.
+func (t *MemTable) ColumnCount() int {
+	return len(t.Columns)
+}
+
+// Column is part of the Table interface.
+func (t *MemTable) Column(ord ColumnOrdinal) *Column {
+	return &t.Columns[ord]
+}
+
+// KeyCount is part of the Table interface.
+func (t *MemTable) KeyCount() int {
+	return len(t.Keys)
+}
+
+// Key is part of the Table interface.
+func (t *MemTable) Key(i int) *TableKey {
+	return &t.Keys[i]
+}
+
+func (t *MemTable) AddColumn(col *Column) (ColumnOrdinal, error) {
 	if t.colMap == nil {
 		t.colMap = make(map[ColumnName]ColumnOrdinal)
 	}
 
 	ord, ok := t.colMap[col.Name]
 	if ok {
-		fatalf("table '%s' already has column '%s'", t.Name, col.Name)
+		return 0, pgerror.NewErrorf(pgerror.CodeDuplicateColumnError,
+			"table '%s' already has column '%s'", t.Name, col.Name)
 	}
 
 	ord = ColumnOrdinal(len(t.Columns))
 	t.Columns = append(t.Columns, *col)
 	t.colMap[col.Name] = ord
-	return ord
+	return ord, nil
 }
 
-func (t *Table) AddKey(key *TableKey) *TableKey {
+func (t *MemTable) AddKey(key *TableKey) (*TableKey, error) {
 	for i := range t.Keys {
 		existing := &t.Keys[i]
 		if existing.Name == key.Name {
-			fatalf("table '%s' already has key '%s'", t.Name, key.Name)
+			return nil, pgerror.NewErrorf(pgerror.CodeDuplicateObjectError,
+				"table '%s' already has key '%s'", t.Name, key.Name)
 		}
 	}
 
 	t.Keys = append(t.Keys, *key)
-	return &t.Keys[len(t.Keys)-1]
+	return &t.Keys[len(t.Keys)-1], nil
 }
 
-func (t *Table) Column(name ColumnName) *Column {
-	ord := t.ColumnOrdinal(name)
-	return &t.Columns[ord]
+// KeyByName returns the key with the given name, or an error with the
+// CodeUndefinedObjectError code if there is no such key.
+func (t *MemTable) KeyByName(name string) (*TableKey, error) {
+	for i := range t.Keys {
+		if t.Keys[i].Name == name {
+			return &t.Keys[i], nil
+		}
+	}
+
+	return nil, pgerror.NewErrorf(pgerror.CodeUndefinedObjectError,
+		"key '%s' not found in table '%s'", name, t.Name)
 }
 
-func (t *Table) ColumnOrdinal(name ColumnName) ColumnOrdinal {
-	if t.colMap != nil {
-		ord, ok := t.colMap[name]
-		if ok {
-			return ord
+// DropKey removes the key with the given name, or returns an error with the
+// CodeUndefinedObjectError code if there is no such key. The foreign key of
+// the key, if any, is removed with it. Pointers to the table's keys are no
+// longer valid once a key is dropped.
+func (t *MemTable) DropKey(name string) error {
+	for i := range t.Keys {
+		if t.Keys[i].Name == name {
+			t.Keys = append(t.Keys[:i], t.Keys[i+1:]...)
+			return nil
 		}
 	}
 
-	fatalf("column name '%s' not found in table '%s'", name, t.Name)
-	return 0
+	return pgerror.NewErrorf(pgerror.CodeUndefinedObjectError,
+		"key '%s' not found in table '%s'", name, t.Name)
 }
 
-func (t *Table) PrimaryKey() *TableKey {
+// AddCheck adds a check constraint to the table, or returns an error with the
+// CodeDuplicateObjectError code if the table already has a check constraint
+// with its name.
+func (t *MemTable) AddCheck(check *CheckConstraint) error {
+	for i := range t.Checks {
+		if t.Checks[i].Name == check.Name {
+			return pgerror.NewErrorf(pgerror.CodeDuplicateObjectError,
+				"table '%s' already has check constraint '%s'", t.Name, check.Name)
+		}
+	}
+
+	t.Checks = append(t.Checks, *check)
+	return nil
+}
+
+// Copy returns a copy of the table that can be changed without changing the
+// table. The foreign keys of the copy reference the same tables.
+func (t *MemTable) Copy() *MemTable {
+	cp := &MemTable{
+		Name:    t.Name,
+		Columns: append([]Column(nil), t.Columns...),
+		Keys:    make([]TableKey, len(t.Keys)),
+		Checks:  make([]CheckConstraint, len(t.Checks)),
+		colMap:  make(map[ColumnName]ColumnOrdinal, len(t.colMap)),
+	}
+
+	for i, key := range t.Keys {
+		key.Columns = append([]ColumnOrdinal(nil), key.Columns...)
+		if key.Fkey != nil {
+			key.Fkey = &ForeignKey{
+				Referenced: key.Fkey.Referenced,
+				Columns:    append([]ColumnOrdinal(nil), key.Fkey.Columns...),
+			}
+		}
+		cp.Keys[i] = key
+	}
+	for i, check := range t.Checks {
+		check.Columns = append([]ColumnOrdinal(nil), check.Columns...)
+		cp.Checks[i] = check
+	}
+	for name, ord := range t.colMap {
+		cp.colMap[name] = ord
+	}
+	return cp
+}
+
+// dropColumn removes the column at the given ordinal position, and renumbers
+// the columns of the table's keys and check constraints that follow it. The
+// column must not be part of a key or check constraint.
+func (t *MemTable) dropColumn(ord ColumnOrdinal) {
+	t.Columns = append(t.Columns[:ord], t.Columns[ord+1:]...)
+
+	t.colMap = make(map[ColumnName]ColumnOrdinal)
+	for i := range t.Columns {
+		t.colMap[t.Columns[i].Name] = ColumnOrdinal(i)
+	}
+
 	for i := range t.Keys {
-		k := &t.Keys[i]
-		if k.Primary {
-			return k
+		renumberColumns(t.Keys[i].Columns, ord)
+	}
+	for i := range t.Checks {
+		renumberColumns(t.Checks[i].Columns, ord)
+	}
+}
+
+// renumberColumns decrements the ordinals that follow a dropped column.
+func renumberColumns(cols []ColumnOrdinal, dropped ColumnOrdinal) {
+	for i := range cols {
+		if cols[i] > dropped {
+			cols[i]--
 		}
 	}
+}
 
-	return implicitPrimaryKey
+// ColumnByName returns the column with the given name, or an error with the
+// CodeUndefinedColumnError code if there is no such column.
+func (t *MemTable) ColumnByName(name ColumnName) (*Column, error) {
+	ord, err := t.ColumnOrdinal(name)
+	if err != nil {
+		return nil, err
+	}
+	return &t.Columns[ord], nil
+}
+
+// ColumnOrdinal returns the position of the column with the given name in the
+// table, or an error with the CodeUndefinedColumnError code if there is no
+// such column.
+func (t *MemTable) ColumnOrdinal(name ColumnName) (ColumnOrdinal, error) {
+	if t.colMap != nil {
+		ord, ok := t.colMap[name]
+		if ok {
+			return ord, nil
+		}
+	}
+
+	return 0, pgerror.NewErrorf(pgerror.CodeUndefinedColumnError,
+		"column name '%s' not found in table '%s'", name, t.Name)
 }
 
-func (t *Table) String() string {
+func (t *MemTable) String() string {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "table %s\n", t.Name)
 	for _, col := range t.Columns {
@@ -100,12 +313,12 @@ func (t *Table) String() string {
 		buf.WriteString(")")
 
 		if fkey := key.Fkey; fkey != nil {
-			fmt.Fprintf(&buf, " -> %s(", fkey.Referenced.Name)
+			fmt.Fprintf(&buf, " -> %s(", fkey.Referenced.TabName())
 			for i, colIdx := range fkey.Columns {
 				if i > 0 {
 					buf.WriteString(",")
 				}
-				buf.WriteString(string(fkey.Referenced.Columns[colIdx].Name))
+				buf.WriteString(string(fkey.Referenced.Column(colIdx).Name))
 			}
 			buf.WriteString(")")
 		}
@@ -121,6 +334,10 @@ func (t *Table) String() string {
 		buf.WriteString("\n")
 	}
 
+	for _, check := range t.Checks {
+		fmt.Fprintf(&buf, "  CHECK (%s)\n", check.Expr)
+	}
+
 	return buf.String()
 }
 
@@ -133,6 +350,11 @@ type TableKey struct {
 	Fkey    *ForeignKey
 }
 
+// HasColumn returns true if the column is one of the key's columns.
+func (k *TableKey) HasColumn(ord ColumnOrdinal) bool {
+	return containsColumn(k.Columns, ord)
+}
+
 func (k *TableKey) EqualColumns(other *TableKey) bool {
 	if len(k.Columns) != len(other.Columns) {
 		return false
@@ -145,7 +367,30 @@ func (k *TableKey) EqualColumns(other *TableKey) bool {
 	return true
 }
 
+// CheckConstraint is a boolean expression that every row of a table must
+// satisfy. Columns holds the ordinal positions of the columns that the
+// expression refers to.
+type CheckConstraint struct {
+	Name    string
+	Expr    tree.Expr
+	Columns []ColumnOrdinal
+}
+
+// HasColumn returns true if the check constraint refers to the column.
+func (c *CheckConstraint) HasColumn(ord ColumnOrdinal) bool {
+	return containsColumn(c.Columns, ord)
+}
+
 type ForeignKey struct {
-	Referenced *Table
+	Referenced Table
 	Columns    []ColumnOrdinal
 }
+
+func containsColumn(cols []ColumnOrdinal, ord ColumnOrdinal) bool {
+	for _, c := range cols {
+		if c == ord {
+			return true
+		}
+	}
+	return false
+}
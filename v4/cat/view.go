@@ -0,0 +1,44 @@
+package cat
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// View is a named query. Query holds the SQL text of the query, which is
+// parsed and built again each time the view is used, like a subquery in its
+// place. Columns names the columns of the query, in order.
+type View struct {
+	Name    TableName
+	Query   string
+	Columns []ColumnName
+
+	// Names maps each table or view name in the query to the fully qualified
+	// name that it referred to when the view was created. The names in the
+	// query are resolved using this map, rather than the current database and
+	// search path of the catalog when the view is used. The view depends on
+	// the tables and views that it maps to.
+	Names map[TableName]TableName
+}
+
+// DependsOn returns true if the query of the view refers to the table or view
+// with the given fully qualified name. Views that are only referred to through
+// other views don't count.
+func (v *View) DependsOn(name TableName) bool {
+	for _, qualified := range v.Names {
+		if qualified == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *View) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "view %s\n", v.Name)
+	for _, col := range v.Columns {
+		fmt.Fprintf(&buf, "  %s\n", col)
+	}
+	fmt.Fprintf(&buf, "  AS %s\n", v.Query)
+	return buf.String()
+}
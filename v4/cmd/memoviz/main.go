@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/petermattis/opttoy/v4/build"
+	"github.com/petermattis/opttoy/v4/cat"
+	"github.com/petermattis/opttoy/v4/exec"
+	"github.com/petermattis/opttoy/v4/opt"
+)
+
+var (
+	errInvalidArgCount = errors.New("invalid number of arguments")
+	errNoQuery         = errors.New("no query to optimize")
+)
+
+var (
+	format   = flag.String("format", "html", "output format: dot or html")
+	out      = flag.String("out", "", "output file name (default stdout)")
+	maxSteps = flag.Int("steps", math.MaxInt32, "maximum number of normalization and exploration steps")
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		exit(errInvalidArgCount)
+	}
+
+	if *format != "dot" && *format != "html" {
+		flag.Usage()
+		exit(fmt.Errorf("unrecognized format: %s", *format))
+	}
+
+	sql, err := ioutil.ReadFile(flag.Arg(0))
+	if err != nil {
+		exit(err)
+	}
+
+	stmts, err := parser.Parse(string(sql))
+	if err != nil {
+		exit(err)
+	}
+	if len(stmts) == 0 {
+		exit(errNoQuery)
+	}
+
+	// All but the last statement define the schema, and the last statement is
+	// the query to optimize.
+	catalog := cat.NewCatalog()
+	engine := exec.NewEngine(catalog)
+	for _, stmt := range stmts[:len(stmts)-1] {
+		if _, err := engine.Execute(stmt); err != nil {
+			exit(err)
+		}
+	}
+
+	p := opt.NewPlanner(catalog, *maxSteps)
+	var trace opt.OptimizeTrace
+	p.SetOptimizeTrace(&trace)
+
+	root, required, err := build.NewBuilder(p.Factory(), stmts[len(stmts)-1]).Build()
+	if err != nil {
+		exit(err)
+	}
+	if _, err := p.Optimize(root, required); err != nil {
+		exit(err)
+	}
+
+	var writer io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			exit(err)
+		}
+
+		defer file.Close()
+		writer = file
+	}
+
+	switch *format {
+	case "dot":
+		_, err = io.WriteString(writer, p.MemoDOT())
+
+	case "html":
+		err = p.WriteMemoHTML(writer, &trace)
+	}
+
+	if err != nil {
+		exit(err)
+	}
+}
+
+// usage is a replacement usage function for the flags package.
+func usage() {
+	fmt.Fprintf(os.Stderr, "Memoviz renders the memo that results from optimizing a query.\n\n")
+
+	fmt.Fprintf(os.Stderr, "Usage:\n")
+
+	fmt.Fprintf(os.Stderr, "\tmemoviz [flags] file.sql\n\n")
+
+	fmt.Fprintf(os.Stderr, "The file contains the statements that define the schema, such as CREATE\n")
+	fmt.Fprintf(os.Stderr, "TABLE, followed by the query to optimize.\n\n")
+
+	fmt.Fprintf(os.Stderr, "The formats are:\n\n")
+	fmt.Fprintf(os.Stderr, "\tdot    a Graphviz graph of the memo groups and expressions\n")
+	fmt.Fprintf(os.Stderr, "\thtml   a page that replays optimization step by step\n")
+	fmt.Fprintf(os.Stderr, "\n")
+
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+
+	flag.PrintDefaults()
+
+	fmt.Fprintf(os.Stderr, "\n")
+}
+
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+	os.Exit(2)
+}
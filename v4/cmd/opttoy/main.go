@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/build"
+	"github.com/petermattis/opttoy/v4/cat"
+	"github.com/petermattis/opttoy/v4/exec"
+	"github.com/petermattis/opttoy/v4/opt"
+)
+
+// mode determines what the REPL shows for each query.
+type mode int
+
+const (
+	// buildMode shows the expression that is built for the query, before it
+	// is normalized.
+	buildMode mode = iota
+
+	// normalizeMode shows the normalized expression, before it is explored.
+	normalizeMode
+
+	// memoMode shows the memo after the query has been optimized.
+	memoMode
+
+	// optMode shows the optimized expression.
+	optMode
+
+	// explainMode shows the optimized expression, annotated with the
+	// properties selected by the explain flags.
+	explainMode
+)
+
+var modeNames = map[string]mode{
+	"build":     buildMode,
+	"normalize": normalizeMode,
+	"memo":      memoMode,
+	"opt":       optMode,
+	"explain":   explainMode,
+}
+
+type repl struct {
+	catalog      *cat.MemCatalog
+	engine       *exec.Engine
+	mode         mode
+	explainFlags opt.ExplainFlags
+	out          io.Writer
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	catalog := cat.NewCatalog()
+	r := &repl{
+		catalog:      catalog,
+		engine:       exec.NewEngine(catalog),
+		mode:         optMode,
+		explainFlags: opt.ExplainDefault,
+		out:          os.Stdout,
+	}
+
+	// The files define the schema and statistics, so they can only contain
+	// statements that are executed by the engine.
+	for _, path := range flag.Args() {
+		if err := r.loadFile(path); err != nil {
+			exit(err)
+		}
+	}
+
+	if err := r.run(os.Stdin); err != nil {
+		exit(err)
+	}
+}
+
+// loadFile executes the statements in the file at the given path.
+func (r *repl) loadFile(path string) error {
+	sql, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	stmts, err := parser.Parse(string(sql))
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	for _, stmt := range stmts {
+		if !r.engine.CanExecute(stmt) {
+			return fmt.Errorf("%s: not a schema or statistics statement: %s", path, stmt)
+		}
+		if _, err := r.execute(stmt); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// run reads statements and commands from in until it is exhausted or the quit
+// command is given. Statements can span multiple lines, and end with a
+// semicolon. Commands start with a backslash and take up a single line.
+func (r *repl) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	var buf bytes.Buffer
+
+	for {
+		if buf.Len() == 0 {
+			fmt.Fprint(r.out, "opttoy> ")
+		} else {
+			fmt.Fprint(r.out, "     -> ")
+		}
+
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if buf.Len() == 0 && strings.HasPrefix(line, `\`) {
+			if quit := r.command(line); quit {
+				return nil
+			}
+			continue
+		}
+
+		if line == "" {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if !strings.HasSuffix(line, ";") {
+			continue
+		}
+
+		sql := buf.String()
+		buf.Reset()
+
+		stmts, err := parser.Parse(sql)
+		if err != nil {
+			fmt.Fprintf(r.out, "ERROR: %v\n", err)
+			continue
+		}
+
+		for _, stmt := range stmts {
+			res, err := r.execute(stmt)
+			if err != nil {
+				fmt.Fprintf(r.out, "ERROR: %v\n", err)
+				break
+			}
+			fmt.Fprint(r.out, res)
+		}
+	}
+}
+
+// command runs a REPL command, and returns true if the REPL should quit.
+func (r *repl) command(line string) (quit bool) {
+	fields := strings.Fields(line[1:])
+	if len(fields) == 0 {
+		fmt.Fprintf(r.out, "ERROR: missing command; try \\help\n")
+		return false
+	}
+
+	name, args := fields[0], fields[1:]
+	switch name {
+	case "q", "quit":
+		return true
+
+	case "?", "help":
+		help(r.out)
+		return false
+	}
+
+	m, ok := modeNames[name]
+	if !ok {
+		fmt.Fprintf(r.out, "ERROR: unrecognized command: \\%s; try \\help\n", name)
+		return false
+	}
+
+	if m == explainMode {
+		flags, err := opt.ParseExplainFlags(args)
+		if err != nil {
+			fmt.Fprintf(r.out, "ERROR: %v\n", err)
+			return false
+		}
+		r.explainFlags = flags
+	} else if len(args) != 0 {
+		fmt.Fprintf(r.out, "ERROR: \\%s does not take arguments\n", name)
+		return false
+	}
+
+	r.mode = m
+	return false
+}
+
+// execute runs the statement through the engine if it defines the schema or
+// statistics, and otherwise optimizes it according to the current mode.
+func (r *repl) execute(stmt tree.Statement) (string, error) {
+	if r.engine.CanExecute(stmt) {
+		return r.engine.Execute(stmt)
+	}
+	return r.optimize(stmt)
+}
+
+func (r *repl) optimize(stmt tree.Statement) (string, error) {
+	maxSteps := int(math.MaxInt32)
+	if r.mode == buildMode {
+		maxSteps = 0
+	}
+
+	p := opt.NewPlanner(r.catalog, maxSteps)
+	b := build.NewBuilder(p.Factory(), stmt)
+	root, required, err := b.Build()
+	if err != nil {
+		return "", err
+	}
+
+	if r.mode == normalizeMode {
+		e := p.NormalizedExpr(root)
+		return e.String(), nil
+	}
+
+	e, err := p.Optimize(root, required)
+	if err != nil {
+		return "", err
+	}
+
+	// An EXPLAIN statement shows the properties that it asks for, regardless
+	// of the mode.
+	if flags, ok := b.Explain(); ok {
+		return e.Explain(flags), nil
+	}
+
+	switch r.mode {
+	case memoMode:
+		return p.MemoString(), nil
+
+	case explainMode:
+		return e.Explain(r.explainFlags), nil
+	}
+	return e.String(), nil
+}
+
+// help writes the list of REPL commands.
+func help(w io.Writer) {
+	fmt.Fprintf(w, "Statements end with a semicolon. Queries are shown according to the\n")
+	fmt.Fprintf(w, "current mode, which is opt to begin with. The commands are:\n\n")
+	fmt.Fprintf(w, "\t\\build              show the expression built for each query\n")
+	fmt.Fprintf(w, "\t\\normalize          show the normalized expression\n")
+	fmt.Fprintf(w, "\t\\memo               show the memo after optimization\n")
+	fmt.Fprintf(w, "\t\\opt                show the optimized expression\n")
+	fmt.Fprintf(w, "\t\\explain [flags]    show the optimized expression with properties;\n")
+	fmt.Fprintf(w, "\t                    the flags are rows, cost, ordering, keys, fd\n")
+	fmt.Fprintf(w, "\t                    and verbose\n")
+	fmt.Fprintf(w, "\t\\help               show this help\n")
+	fmt.Fprintf(w, "\t\\q                  quit\n")
+}
+
+// usage is a replacement usage function for the flags package.
+func usage() {
+	fmt.Fprintf(os.Stderr, "Opttoy is an interactive shell for the optimizer.\n\n")
+
+	fmt.Fprintf(os.Stderr, "Usage:\n")
+
+	fmt.Fprintf(os.Stderr, "\topttoy [file.sql ...]\n\n")
+
+	fmt.Fprintf(os.Stderr, "The files contain the statements that define the schema and statistics,\n")
+	fmt.Fprintf(os.Stderr, "such as CREATE TABLE and INSERT INTO histogram.table.column. Statements\n")
+	fmt.Fprintf(os.Stderr, "are then read from standard input.\n\n")
+
+	help(os.Stderr)
+
+	fmt.Fprintf(os.Stderr, "\n")
+}
+
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+	os.Exit(2)
+}
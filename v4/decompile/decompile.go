@@ -0,0 +1,720 @@
+// Package decompile converts optimizer expressions back into SQL, which is
+// useful for seeing the effect of normalization and exploration rules as a
+// query that can be run, or rebuilt by the builder.
+package decompile
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/cat"
+	"github.com/petermattis/opttoy/v4/opt"
+)
+
+// ToSQL returns a SELECT statement that computes the given relational
+// expression. Columns are named after their labels in the metadata, such as
+// "a.x", and labels that are used by more than one column are made unique by
+// appending the column index. The physical properties that are required of
+// the expression are honored: the result columns are named and ordered by
+// the required projection, and the rows are sorted by the required ordering.
+//
+// Each relational expression becomes a SELECT, which is nested as a derived
+// table in the FROM clause of its parent, except that filters, projections and
+// aggregations are merged into the SELECT of their input where possible.
+// Semi-joins and anti-joins become EXISTS and NOT EXISTS subqueries. Apply
+// joins become LATERAL joins if their right input refers to columns of their
+// left input, or an error is returned for apply joins that can't be written
+// that way, such as a correlated full join.
+func ToSQL(md *opt.Metadata, e opt.Expr) (sql string, err error) {
+	d := decompiler{md: md, names: make(map[opt.ColumnIndex]string), used: make(map[string]bool)}
+
+	defer func() {
+		if r := recover(); r != nil {
+			derr, ok := r.(decompileError)
+			if !ok {
+				panic(r)
+			}
+			err = derr.err
+		}
+	}()
+
+	return d.root(e), nil
+}
+
+// decompileError is used to unwind the decompiler when it encounters an
+// expression that can't be written as SQL.
+type decompileError struct {
+	err error
+}
+
+type decompiler struct {
+	md *opt.Metadata
+
+	// names maps from each column that has been referenced so far to its name
+	// in the SQL, and used is the set of those names.
+	names map[opt.ColumnIndex]string
+	used  map[string]bool
+
+	// tables is the number of derived tables and table aliases so far, which
+	// is used to give each of them a unique alias.
+	tables int
+}
+
+// selectClause is a SELECT that is built up by the decompiler. Filters,
+// projections and aggregations are merged into the SELECT of their input if
+// its SELECT list passes through the output columns of its FROM clause.
+type selectClause struct {
+	exprs   []string
+	from    string
+	where   []string
+	groupBy []string
+	orderBy []string
+
+	// computed maps from each output column that's computed by the SELECT
+	// list to the expression that computes it.
+	computed map[opt.ColumnIndex]string
+}
+
+func (s *selectClause) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("SELECT ")
+	if len(s.exprs) == 0 {
+		// A relation without columns still has rows, so select a constant
+		// that's never referenced.
+		buf.WriteString("1")
+	} else {
+		buf.WriteString(strings.Join(s.exprs, ", "))
+	}
+	if s.from != "" {
+		buf.WriteString(" FROM ")
+		buf.WriteString(s.from)
+	}
+	if len(s.where) != 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(s.where, " AND "))
+	}
+	if len(s.groupBy) != 0 {
+		buf.WriteString(" GROUP BY ")
+		buf.WriteString(strings.Join(s.groupBy, ", "))
+	}
+	if len(s.orderBy) != 0 {
+		buf.WriteString(" ORDER BY ")
+		buf.WriteString(strings.Join(s.orderBy, ", "))
+	}
+	return buf.String()
+}
+
+func (d *decompiler) errorf(format string, args ...interface{}) {
+	panic(decompileError{err: fmt.Errorf(format, args...)})
+}
+
+// root returns the SQL for the root expression, whose result columns are
+// named and ordered by its required physical properties.
+func (d *decompiler) root(e opt.Expr) string {
+	physical := e.Physical()
+	e = skipEnforcers(e)
+
+	if !physical.Projection.Defined() && !physical.Ordering.Defined() {
+		return d.query(e)
+	}
+
+	// Name the columns that are computed by the SELECT list directly, unless
+	// the rows are ordered by one of them, since ORDER BY can't refer to the
+	// columns of the SELECT list by their original names.
+	var s *selectClause
+	switch e.Operator() {
+	case opt.ProjectOp, opt.GroupByOp:
+		input := e.Child(0)
+		computed := e.Logical().Relational.OutputCols.Difference(input.Logical().Relational.OutputCols)
+		for _, col := range physical.Ordering {
+			if col < 0 {
+				col = -col
+			}
+			if computed.Contains(int(col)) {
+				s = &selectClause{from: d.derivedTable(e)}
+				break
+			}
+		}
+		if s == nil {
+			s = d.clause(e)
+		}
+
+	default:
+		s = d.input(e)
+	}
+
+	var exprs []string
+	if physical.Projection.Defined() {
+		for _, col := range physical.Projection.Columns {
+			expr, ok := s.computed[col.Index]
+			if !ok {
+				expr = d.colName(col.Index)
+			}
+			if col.Label != "" && quoteName(col.Label) != expr {
+				expr += " AS " + quoteName(col.Label)
+			}
+			exprs = append(exprs, expr)
+		}
+	} else {
+		exprs = d.colNames(e.Logical().Relational.OutputCols)
+	}
+	s.exprs = exprs
+
+	for _, col := range physical.Ordering {
+		if col < 0 {
+			s.orderBy = append(s.orderBy, d.colName(-col)+" DESC")
+		} else {
+			s.orderBy = append(s.orderBy, d.colName(col))
+		}
+	}
+	return s.String()
+}
+
+// query returns a SELECT, or a set operation, whose result columns are the
+// output columns of the expression in increasing order.
+func (d *decompiler) query(e opt.Expr) string {
+	switch e.Operator() {
+	case opt.UnionOp, opt.IntersectOp, opt.ExceptOp:
+		return d.setOp(e)
+	}
+	return d.clause(e).String()
+}
+
+// input returns a clause whose FROM clause produces the output columns of
+// the expression, and whose SELECT list is empty, so that the caller can fill
+// it in.
+func (d *decompiler) input(e opt.Expr) *selectClause {
+	e = skipEnforcers(e)
+	switch e.Operator() {
+	case opt.ProjectOp, opt.GroupByOp, opt.UnionOp, opt.IntersectOp, opt.ExceptOp:
+		return &selectClause{from: d.derivedTable(e)}
+	}
+
+	s := d.clause(e)
+	s.exprs = nil
+	return s
+}
+
+func (d *decompiler) clause(e opt.Expr) *selectClause {
+	e = skipEnforcers(e)
+	outputCols := e.Logical().Relational.OutputCols
+
+	switch e.Operator() {
+	case opt.ScanOp:
+		return &selectClause{exprs: d.colNames(outputCols), from: d.scan(e)}
+
+	case opt.ValuesOp:
+		if outputCols.Empty() && e.ChildCount() == 1 {
+			// A SELECT without a FROM clause returns a single row.
+			return &selectClause{}
+		}
+		return &selectClause{exprs: d.colNames(outputCols), from: d.values(e)}
+
+	case opt.SelectOp:
+		s := d.input(e.Child(0))
+		s.exprs = d.colNames(outputCols)
+		filter := e.Child(1)
+		s.where = append(s.where, d.conditions(&filter)...)
+		return s
+
+	case opt.ProjectOp:
+		s := d.input(e.Child(0))
+		projections := e.Child(1)
+		s.computed = make(map[opt.ColumnIndex]string)
+		s.exprs = d.projections(&projections, s.computed)
+		return s
+
+	case opt.GroupByOp:
+		s := d.input(e.Child(0))
+		groupings := e.Child(1)
+		aggregations := e.Child(2)
+		if groupings.ChildCount() == 0 && aggregations.ChildCount() == 0 {
+			d.errorf("%s without groupings or aggregations can't be written as SQL", e.Operator())
+		}
+		s.computed = make(map[opt.ColumnIndex]string)
+		s.exprs = append(d.projections(&groupings, s.computed), d.projections(&aggregations, s.computed)...)
+		for i := 0; i < groupings.ChildCount(); i++ {
+			grouping := groupings.Child(i)
+			s.groupBy = append(s.groupBy, d.scalar(&grouping))
+		}
+		return s
+
+	case opt.InnerJoinOp, opt.LeftJoinOp, opt.RightJoinOp, opt.FullJoinOp,
+		opt.InnerJoinApplyOp, opt.LeftJoinApplyOp, opt.RightJoinApplyOp, opt.FullJoinApplyOp:
+		return &selectClause{exprs: d.colNames(outputCols), from: d.join(e)}
+
+	case opt.SemiJoinOp, opt.AntiJoinOp, opt.SemiJoinApplyOp, opt.AntiJoinApplyOp:
+		s := d.input(e.Child(0))
+		s.exprs = d.colNames(outputCols)
+		s.where = append(s.where, d.exists(e))
+		return s
+	}
+
+	d.errorf("unsupported relational expression %s", e.Operator())
+	return nil
+}
+
+// scan returns a reference to the table, with an alias that renames its
+// columns. The table name is fully qualified, so that it refers to the same
+// table regardless of the current database and search path.
+func (d *decompiler) scan(e opt.Expr) string {
+	tblIndex := e.TablePrivate()
+	tbl := d.md.Table(tblIndex).Table
+
+	names := make([]string, tbl.ColumnCount())
+	for i := range names {
+		names[i] = d.colName(d.md.TableColumn(tblIndex, cat.ColumnOrdinal(i)))
+	}
+	name := tbl.TabName()
+	qualified := strings.Join([]string{
+		quoteName(name.Database), quoteName(name.Schema), quoteName(name.Table),
+	}, ".")
+	return fmt.Sprintf("%s AS %s (%s)", qualified, d.newAlias(), strings.Join(names, ", "))
+}
+
+// values returns a VALUES clause as a derived table, with an alias that names
+// its columns.
+func (d *decompiler) values(e opt.Expr) string {
+	rows := make([]string, e.ChildCount())
+	for i := range rows {
+		// Each row is a tuple, but VALUES doesn't allow a trailing comma in
+		// a row with a single column.
+		row := e.Child(i)
+		rows[i] = "(" + strings.Join(d.children(&row), ", ") + ")"
+	}
+
+	names := d.colNames(*e.ColIndexesPrivate())
+	return fmt.Sprintf("(VALUES %s) AS %s (%s)", strings.Join(rows, ", "), d.newAlias(), strings.Join(names, ", "))
+}
+
+// join returns a JOIN of the inputs of the join expression. The left input
+// is written without parentheses if it's also a join, so that a chain of
+// joins reads from left to right.
+func (d *decompiler) join(e opt.Expr) string {
+	left := skipEnforcers(e.Child(0))
+	right := skipEnforcers(e.Child(1))
+	on := e.Child(2)
+
+	var joinType string
+	switch e.Operator() {
+	case opt.InnerJoinOp, opt.InnerJoinApplyOp:
+		joinType = "INNER JOIN"
+	case opt.LeftJoinOp, opt.LeftJoinApplyOp:
+		joinType = "LEFT JOIN"
+	case opt.RightJoinOp, opt.RightJoinApplyOp:
+		joinType = "RIGHT JOIN"
+	case opt.FullJoinOp, opt.FullJoinApplyOp:
+		joinType = "FULL JOIN"
+	}
+
+	if e.IsJoinApply() && right.Logical().UnboundCols.Intersects(left.Logical().Relational.OutputCols) {
+		// The right input refers to columns of the left input, which is only
+		// possible in SQL with a LATERAL derived table. Apply joins whose
+		// right input isn't correlated are written as ordinary joins.
+		switch e.Operator() {
+		case opt.InnerJoinApplyOp, opt.LeftJoinApplyOp:
+			joinType += " LATERAL"
+		default:
+			d.errorf("correlated %s can't be written as SQL", e.Operator())
+		}
+	}
+
+	var leftSQL string
+	if isJoin(left) {
+		leftSQL = d.join(left)
+	} else {
+		leftSQL = d.fromItem(left)
+	}
+
+	var rightSQL string
+	if isJoin(right) && !strings.HasSuffix(joinType, "LATERAL") {
+		rightSQL = "(" + d.join(right) + ")"
+	} else {
+		rightSQL = d.fromItem(right)
+	}
+
+	return fmt.Sprintf("%s %s %s ON %s", leftSQL, joinType, rightSQL, strings.Join(d.conditions(&on), " AND "))
+}
+
+// exists returns the EXISTS or NOT EXISTS subquery that filters the left
+// input of a semi-join or anti-join.
+func (d *decompiler) exists(e opt.Expr) string {
+	s := d.input(e.Child(1))
+	on := e.Child(2)
+	s.where = append(s.where, d.conditions(&on)...)
+
+	switch e.Operator() {
+	case opt.AntiJoinOp, opt.AntiJoinApplyOp:
+		return fmt.Sprintf("NOT EXISTS (%s)", s)
+	}
+	return fmt.Sprintf("EXISTS (%s)", s)
+}
+
+// setOp returns a set operation. The right input's columns are listed in the
+// order of the left input's columns that they correspond to.
+func (d *decompiler) setOp(e opt.Expr) string {
+	left := e.Child(0)
+	right := e.Child(1)
+
+	var opName string
+	var leftCols, rightCols []opt.ColumnIndex
+	left.Logical().Relational.OutputCols.ForEach(func(i int) {
+		leftCols = append(leftCols, opt.ColumnIndex(i))
+	})
+	switch e.Operator() {
+	case opt.UnionOp:
+		opName = "UNION"
+		colMap := *e.ColMapPrivate()
+		for _, col := range leftCols {
+			rightCols = append(rightCols, colMap[col])
+		}
+
+	case opt.IntersectOp, opt.ExceptOp:
+		if e.Operator() == opt.IntersectOp {
+			opName = "INTERSECT"
+		} else {
+			opName = "EXCEPT"
+		}
+		right.Logical().Relational.OutputCols.ForEach(func(i int) {
+			rightCols = append(rightCols, opt.ColumnIndex(i))
+		})
+	}
+
+	return fmt.Sprintf("%s %s %s", d.setOpInput(left, leftCols), opName, d.setOpInput(right, rightCols))
+}
+
+// setOpInput returns a SELECT whose result columns are the given output
+// columns of the expression, in the given order.
+func (d *decompiler) setOpInput(e opt.Expr, cols []opt.ColumnIndex) *selectClause {
+	e = skipEnforcers(e)
+
+	var s *selectClause
+	switch e.Operator() {
+	case opt.UnionOp, opt.IntersectOp, opt.ExceptOp:
+		s = &selectClause{from: d.derivedTable(e)}
+	default:
+		s = d.clause(e)
+	}
+
+	s.exprs = nil
+	for _, col := range cols {
+		if expr, ok := s.computed[col]; ok {
+			s.exprs = append(s.exprs, expr+" AS "+d.colName(col))
+		} else {
+			s.exprs = append(s.exprs, d.colName(col))
+		}
+	}
+	return s
+}
+
+// fromItem returns a FROM clause item that produces the output columns of the
+// expression.
+func (d *decompiler) fromItem(e opt.Expr) string {
+	e = skipEnforcers(e)
+	if e.Operator() == opt.ScanOp {
+		return d.scan(e)
+	}
+	return d.derivedTable(e)
+}
+
+func (d *decompiler) derivedTable(e opt.Expr) string {
+	return fmt.Sprintf("(%s) AS %s", d.query(e), d.newAlias())
+}
+
+// projections returns the SELECT list items for a Projections expression, in
+// increasing order of the columns they produce, and adds the expressions that
+// compute new columns to computed. Variables of existing columns pass the
+// column through, and a ConstAgg of an existing column computes that column,
+// as constructed by appendPrunedGroupings. The remaining items compute the
+// remaining columns, in increasing order.
+func (d *decompiler) projections(e *opt.Expr, computed map[opt.ColumnIndex]string) []string {
+	cols := *e.ColIndexesPrivate()
+
+	// itemCol returns the existing column that the item passes through or
+	// computes, if any.
+	itemCol := func(item *opt.Expr) (opt.ColumnIndex, bool) {
+		if item.Operator() == opt.ConstAggOp {
+			input := item.Child(0)
+			item = &input
+		}
+		if item.Operator() == opt.VariableOp && cols.Contains(int(item.ColIndexPrivate())) {
+			return item.ColIndexPrivate(), true
+		}
+		return 0, false
+	}
+
+	var bound opt.ColSet
+	for i := 0; i < e.ChildCount(); i++ {
+		item := e.Child(i)
+		if col, ok := itemCol(&item); ok {
+			bound.Add(int(col))
+		}
+	}
+
+	newCols := cols.Difference(bound).Ordered()
+	for i := 0; i < e.ChildCount(); i++ {
+		item := e.Child(i)
+		col, ok := itemCol(&item)
+		if !ok {
+			col = opt.ColumnIndex(newCols[0])
+			newCols = newCols[1:]
+		} else if item.Operator() == opt.VariableOp {
+			continue
+		}
+		computed[col] = d.scalar(&item)
+	}
+
+	var exprs []string
+	cols.ForEach(func(i int) {
+		col := opt.ColumnIndex(i)
+		if expr, ok := computed[col]; ok {
+			exprs = append(exprs, expr+" AS "+d.colName(col))
+		} else {
+			exprs = append(exprs, d.colName(col))
+		}
+	})
+	return exprs
+}
+
+// conditions returns the conjuncts of a filter, which is either a Filters
+// expression or a single condition.
+func (d *decompiler) conditions(e *opt.Expr) []string {
+	switch e.Operator() {
+	case opt.TrueOp:
+		return []string{"true"}
+
+	case opt.FiltersOp:
+		if e.ChildCount() == 0 {
+			return []string{"true"}
+		}
+		conds := make([]string, e.ChildCount())
+		for i := range conds {
+			cond := e.Child(i)
+			conds[i] = d.operand(&cond)
+		}
+		return conds
+	}
+	return []string{d.operand(e)}
+}
+
+func (d *decompiler) scalar(e *opt.Expr) string {
+	switch e.Operator() {
+	case opt.VariableOp:
+		return d.colName(e.ColIndexPrivate())
+
+	case opt.ConstOp:
+		return e.DatumPrivate().String()
+
+	case opt.PlaceholderOp:
+		return e.TypedExprPrivate().String()
+
+	case opt.TrueOp:
+		return "true"
+
+	case opt.FalseOp:
+		return "false"
+
+	case opt.ListOp, opt.OrderedListOp, opt.TupleOp:
+		elems := d.children(e)
+		if len(elems) == 1 {
+			return "ROW(" + elems[0] + ")"
+		}
+		return "(" + strings.Join(elems, ", ") + ")"
+
+	case opt.FiltersOp:
+		return strings.Join(d.conditions(e), " AND ")
+
+	case opt.AndOp:
+		return d.binary(e, "AND")
+
+	case opt.OrOp:
+		return d.binary(e, "OR")
+
+	case opt.NotOp:
+		input := e.Child(0)
+		return "NOT " + d.operand(&input)
+
+	case opt.InOp, opt.NotInOp:
+		// The right side of IN is written as a parenthesized list, even if
+		// it's a single scalar, such as a column that's computed by a hoisted
+		// subquery.
+		left := e.Child(0)
+		right := e.Child(1)
+		var rightSQL string
+		switch right.Operator() {
+		case opt.ListOp, opt.OrderedListOp, opt.TupleOp:
+			rightSQL = "(" + strings.Join(d.children(&right), ", ") + ")"
+		case opt.SubqueryOp:
+			rightSQL = d.scalar(&right)
+		default:
+			rightSQL = "(" + d.scalar(&right) + ")"
+		}
+		return fmt.Sprintf("%s %s %s", d.operand(&left), comparisonOpMap[e.Operator()], rightSQL)
+
+	case opt.IsOp:
+		return d.binary(e, tree.IsNotDistinctFrom.String())
+
+	case opt.IsNotOp:
+		return d.binary(e, tree.IsDistinctFrom.String())
+
+	case opt.ExistsOp:
+		return fmt.Sprintf("EXISTS (%s)", d.query(e.Child(0)))
+
+	case opt.SubqueryOp:
+		s := d.input(e.Child(0))
+		projection := e.Child(1)
+		s.exprs = []string{d.scalar(&projection)}
+		return fmt.Sprintf("(%s)", s)
+
+	case opt.FunctionOp:
+		return fmt.Sprintf("%s(%s)", e.FuncDefPrivate().Name, strings.Join(d.children(e), ", "))
+
+	case opt.ConstAggOp:
+		// ConstAgg is only used when every row in the group has the same
+		// value, so any aggregate that returns one of them will do.
+		input := e.Child(0)
+		return fmt.Sprintf("max(%s)", d.scalar(&input))
+	}
+
+	if op, ok := comparisonOpMap[e.Operator()]; ok {
+		return d.binary(e, op.String())
+	}
+	if op, ok := binaryOpMap[e.Operator()]; ok {
+		return d.binary(e, op.String())
+	}
+	if op, ok := unaryOpMap[e.Operator()]; ok {
+		input := e.Child(0)
+		return op.String() + d.operand(&input)
+	}
+
+	d.errorf("unsupported scalar expression %s", e.Operator())
+	return ""
+}
+
+func (d *decompiler) binary(e *opt.Expr, op string) string {
+	left := e.Child(0)
+	right := e.Child(1)
+	return fmt.Sprintf("%s %s %s", d.operand(&left), op, d.operand(&right))
+}
+
+// operand returns the SQL for a scalar expression that's the operand of an
+// operator, which is parenthesized unless it's atomic.
+func (d *decompiler) operand(e *opt.Expr) string {
+	switch e.Operator() {
+	case opt.VariableOp, opt.ConstOp, opt.PlaceholderOp, opt.TrueOp, opt.FalseOp,
+		opt.ListOp, opt.OrderedListOp, opt.TupleOp, opt.SubqueryOp, opt.ExistsOp,
+		opt.FunctionOp, opt.ConstAggOp:
+		return d.scalar(e)
+	}
+	return "(" + d.scalar(e) + ")"
+}
+
+func (d *decompiler) children(e *opt.Expr) []string {
+	children := make([]string, e.ChildCount())
+	for i := range children {
+		child := e.Child(i)
+		children[i] = d.scalar(&child)
+	}
+	return children
+}
+
+// colName returns the quoted name of the column in the SQL, which is its
+// label, unless another column already has that name.
+func (d *decompiler) colName(col opt.ColumnIndex) string {
+	if name, ok := d.names[col]; ok {
+		return name
+	}
+
+	label := d.md.ColumnLabel(col)
+	name := quoteName(label)
+	if d.used[name] {
+		name = quoteName(fmt.Sprintf("%s_%d", label, col))
+	}
+	d.names[col] = name
+	d.used[name] = true
+	return name
+}
+
+func (d *decompiler) colNames(cols opt.ColSet) []string {
+	var names []string
+	cols.ForEach(func(i int) {
+		names = append(names, d.colName(opt.ColumnIndex(i)))
+	})
+	return names
+}
+
+func (d *decompiler) newAlias() string {
+	d.tables++
+	return fmt.Sprintf("t%d", d.tables)
+}
+
+// skipEnforcers returns the input of any enforcers at the root of the
+// expression. Enforcers only provide physical properties, which the SQL for
+// the root expression provides instead.
+func skipEnforcers(e opt.Expr) opt.Expr {
+	for e.IsEnforcer() {
+		e = e.Child(0)
+	}
+	return e
+}
+
+func isJoin(e opt.Expr) bool {
+	switch e.Operator() {
+	case opt.InnerJoinOp, opt.LeftJoinOp, opt.RightJoinOp, opt.FullJoinOp,
+		opt.InnerJoinApplyOp, opt.LeftJoinApplyOp, opt.RightJoinApplyOp, opt.FullJoinApplyOp:
+		return true
+	}
+	return false
+}
+
+func quoteName(name string) string {
+	n := tree.Name(name)
+	return tree.AsString(&n)
+}
+
+var comparisonOpMap = map[opt.Operator]tree.ComparisonOperator{
+	opt.EqOp:                tree.EQ,
+	opt.LtOp:                tree.LT,
+	opt.GtOp:                tree.GT,
+	opt.LeOp:                tree.LE,
+	opt.GeOp:                tree.GE,
+	opt.NeOp:                tree.NE,
+	opt.InOp:                tree.In,
+	opt.NotInOp:             tree.NotIn,
+	opt.LikeOp:              tree.Like,
+	opt.NotLikeOp:           tree.NotLike,
+	opt.ILikeOp:             tree.ILike,
+	opt.NotILikeOp:          tree.NotILike,
+	opt.SimilarToOp:         tree.SimilarTo,
+	opt.NotSimilarToOp:      tree.NotSimilarTo,
+	opt.RegMatchOp:          tree.RegMatch,
+	opt.NotRegMatchOp:       tree.NotRegMatch,
+	opt.RegIMatchOp:         tree.RegIMatch,
+	opt.NotRegIMatchOp:      tree.NotRegIMatch,
+	opt.IsDistinctFromOp:    tree.IsDistinctFrom,
+	opt.IsNotDistinctFromOp: tree.IsNotDistinctFrom,
+}
+
+var binaryOpMap = map[opt.Operator]tree.BinaryOperator{
+	opt.BitandOp:   tree.Bitand,
+	opt.BitorOp:    tree.Bitor,
+	opt.BitxorOp:   tree.Bitxor,
+	opt.PlusOp:     tree.Plus,
+	opt.MinusOp:    tree.Minus,
+	opt.MultOp:     tree.Mult,
+	opt.DivOp:      tree.Div,
+	opt.FloorDivOp: tree.FloorDiv,
+	opt.ModOp:      tree.Mod,
+	opt.PowOp:      tree.Pow,
+	opt.ConcatOp:   tree.Concat,
+	opt.LShiftOp:   tree.LShift,
+	opt.RShiftOp:   tree.RShift,
+}
+
+var unaryOpMap = map[opt.Operator]tree.UnaryOperator{
+	opt.UnaryPlusOp:       tree.UnaryPlus,
+	opt.UnaryMinusOp:      tree.UnaryMinus,
+	opt.UnaryComplementOp: tree.UnaryComplement,
+}
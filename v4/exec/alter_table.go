@@ -0,0 +1,144 @@
+package exec
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+// alterTable reuses the methods of createTable that add columns and
+// constraints to ct.tbl, which is an existing table of the catalog.
+type alterTable struct {
+	createTable
+
+	// views are the views that were dropped by the commands, which are added
+	// back if a command fails.
+	views []*cat.View
+}
+
+// Apply the commands of an ALTER TABLE statement to the table, and return the
+// table. Either all of the commands are applied, or none of them are.
+func (at *alterTable) execute(stmt *tree.AlterTable) *cat.MemTable {
+	tn, err := stmt.Table.Normalize()
+	if err != nil {
+		raise(pgerror.CodeSyntaxError, err)
+	}
+
+	tbl, err := at.catalog.MemTable(cat.NewTableName(tn))
+	if err != nil {
+		if stmt.IfExists {
+			return nil
+		}
+		raise(pgerror.CodeUndefinedTableError, err)
+	}
+	at.tbl = tbl
+
+	// The commands change the tables in place, so they're restored if a
+	// command fails. Dropping a column can change the foreign keys of other
+	// tables, so every table is saved.
+	saved := make(map[*cat.MemTable]*cat.MemTable)
+	for _, t := range at.catalog.MemTables() {
+		saved[t] = t.Copy()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			for t, cp := range saved {
+				*t = *cp
+			}
+			for _, view := range at.views {
+				if err := at.catalog.AddView(view); err != nil {
+					fatalf("%v", err)
+				}
+			}
+			panic(r)
+		}
+	}()
+
+	for _, cmd := range stmt.Cmds {
+		switch cmd := cmd.(type) {
+		case *tree.AlterTableAddColumn:
+			if _, err := tbl.ColumnOrdinal(cat.ColumnName(cmd.ColumnDef.Name)); err == nil && cmd.IfNotExists {
+				continue
+			}
+			at.addColumn(cmd.ColumnDef)
+
+		case *tree.AlterTableAddConstraint:
+			switch def := cmd.ConstraintDef.(type) {
+			case *tree.UniqueConstraintTableDef:
+				at.addUniqueConstraintKey(def)
+
+			case *tree.ForeignKeyConstraintTableDef:
+				at.addTableForeignKey(def)
+
+			case *tree.CheckConstraintTableDef:
+				at.addCheck(def.Name, def.Expr)
+
+			default:
+				unimplemented("%T", def)
+			}
+
+		case *tree.AlterTableDropColumn:
+			at.dropColumn(cmd)
+
+		default:
+			unimplemented("%T", cmd)
+		}
+	}
+
+	return tbl
+}
+
+// dropColumn drops a column that isn't part of the primary key. The other
+// keys and check constraints that refer to the column, and the foreign keys
+// that reference it, are dropped along with it if the command uses CASCADE, as
+// are the views that depend on the table. Otherwise they prevent the column
+// from being dropped.
+func (at *alterTable) dropColumn(cmd *tree.AlterTableDropColumn) {
+	name := cat.ColumnName(cmd.Column)
+	ord, err := at.tbl.ColumnOrdinal(name)
+	if err != nil {
+		if cmd.IfExists {
+			return
+		}
+		raise(pgerror.CodeUndefinedColumnError, err)
+	}
+
+	if cat.PrimaryKey(at.tbl).HasColumn(ord) {
+		errorf(pgerror.CodeInvalidColumnReferenceError, "column '%s' is referenced by the primary key", name)
+	}
+
+	if cmd.DropBehavior == tree.DropCascade {
+		views := at.catalog.DependentViews(at.tbl.Name)
+		dropViews(at.catalog, views)
+		at.views = append(at.views, views...)
+
+		for _, ref := range at.catalog.ReferencingKeys(at.tbl) {
+			for _, c := range ref.Key.Fkey.Columns {
+				if c == ord {
+					ref.Key.Fkey = nil
+					break
+				}
+			}
+		}
+
+		keys := at.tbl.Keys[:0]
+		for _, key := range at.tbl.Keys {
+			if !key.HasColumn(ord) {
+				keys = append(keys, key)
+			}
+		}
+		at.tbl.Keys = keys
+
+		checks := at.tbl.Checks[:0]
+		for _, check := range at.tbl.Checks {
+			if !check.HasColumn(ord) {
+				checks = append(checks, check)
+			}
+		}
+		at.tbl.Checks = checks
+	}
+
+	if err := at.catalog.DropColumn(at.tbl, name); err != nil {
+		raise(pgerror.CodeDependentObjectsStillExistError, err)
+	}
+}
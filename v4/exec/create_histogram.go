@@ -3,13 +3,14 @@ package exec
 import (
 	"sort"
 
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
 	"github.com/petermattis/opttoy/v4/cat"
 )
 
 type createHistogram struct {
-	catalog *cat.Catalog
+	catalog *cat.MemCatalog
 }
 
 // Create a histogram from an INSERT clause. The rows are expected to be a
@@ -27,39 +28,32 @@ type createHistogram struct {
 func (ch *createHistogram) execute(tblName cat.TableName, colName cat.ColumnName, rows *tree.Select) *cat.Histogram {
 	values, ok := rows.Select.(*tree.ValuesClause)
 	if !ok {
-		fatalf("unsupported rows: %s", rows)
+		unimplemented("rows: %s", rows)
 	}
 
-	tbl := ch.catalog.Table(tblName)
-	col := tbl.Column(colName)
+	col := histogramColumn(ch.catalog, tblName, colName)
 	hist := &cat.Histogram{}
 
 	for _, v := range values.Tuples {
 		if len(v.Exprs) != 2 && len(v.Exprs) != 3 {
-			fatalf("malformed histogram bucket: %s", v)
+			errorf(pgerror.CodeInvalidParameterValueError, "malformed histogram bucket: %s", v)
 		}
 
-		val, err := v.Exprs[1].(*tree.NumVal).AsInt64()
-		if err != nil {
-			fatalf("malformed histogram bucket: %s: %v", v, err)
-		}
+		val := bucketValue(v, 1)
 
 		switch t := v.Exprs[0].(type) {
 		case *tree.NumVal:
 			upperBound, err := t.ResolveAsType(nil, types.Int)
 			if err != nil {
-				fatalf("malformed histogram bucket: %s: %v", v, err)
+				errorf(pgerror.CodeInvalidParameterValueError, "malformed histogram bucket: %s: %v", v, err)
 			}
 
 			// Buckets have 3 values.
 			if len(v.Exprs) != 3 {
-				fatalf("malformed histogram bucket: %s", v)
+				errorf(pgerror.CodeInvalidParameterValueError, "malformed histogram bucket: %s", v)
 			}
 
-			numEq, err := v.Exprs[2].(*tree.NumVal).AsInt64()
-			if err != nil {
-				fatalf("malformed histogram bucket: %s: %v", v, err)
-			}
+			numEq := bucketValue(v, 2)
 
 			hist.Buckets = append(hist.Buckets, cat.Bucket{NumEq: numEq, NumRange: val, UpperBound: upperBound})
 
@@ -85,10 +79,27 @@ func (ch *createHistogram) execute(tblName cat.TableName, colName cat.ColumnName
 		return bi.UpperBound.Compare(nil, bj.UpperBound) < 0
 	})
 
-	hist.Validate()
+	if err := hist.Validate(); err != nil {
+		raise(pgerror.CodeInvalidParameterValueError, err)
+	}
 
 	// Update the table column's stats with the new histogram.
 	col.Stats = hist
 
 	return hist
 }
+
+// bucketValue returns the integer value at the given position in a histogram
+// bucket.
+func bucketValue(v *tree.Tuple, i int) int64 {
+	num, ok := v.Exprs[i].(*tree.NumVal)
+	if !ok {
+		errorf(pgerror.CodeInvalidParameterValueError, "malformed histogram bucket: %s", v)
+	}
+
+	val, err := num.AsInt64()
+	if err != nil {
+		errorf(pgerror.CodeInvalidParameterValueError, "malformed histogram bucket: %s: %v", v, err)
+	}
+	return val
+}
@@ -0,0 +1,53 @@
+package exec
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+// createIndex reuses the methods of createTable that add keys to ct.tbl,
+// which is an existing table of the catalog.
+type createIndex struct {
+	createTable
+}
+
+// Add a key for the index to the table, and return the table. As with the
+// indexes of CREATE TABLE, an unnamed index on the same columns as an existing
+// key is merged into that key. A named index on the same columns is an error,
+// since the merged key would keep its own name, so the index couldn't be
+// dropped by its name.
+func (ci *createIndex) execute(stmt *tree.CreateIndex) *cat.MemTable {
+	tn, err := stmt.Table.Normalize()
+	if err != nil {
+		raise(pgerror.CodeSyntaxError, err)
+	}
+	ci.tbl = ci.table(cat.NewTableName(tn))
+
+	names := make(tree.NameList, len(stmt.Columns))
+	for i, col := range stmt.Columns {
+		names[i] = col.Column
+	}
+	cols := extractNames(ci.tbl, names)
+
+	name := string(stmt.Name)
+	if name == "" {
+		name = ci.columnNames(cols) + "_idx"
+	}
+
+	if _, err := ci.tbl.KeyByName(name); err == nil {
+		if stmt.IfNotExists {
+			return ci.tbl
+		}
+		errorf(pgerror.CodeDuplicateObjectError, "table '%s' already has key '%s'", ci.tbl.Name, name)
+	}
+
+	key := &cat.TableKey{Name: name, Unique: stmt.Unique, Columns: cols}
+	if existing := ci.getKey(key); existing != nil && stmt.Name != "" {
+		errorf(pgerror.CodeDuplicateObjectError, "index '%s' has the same columns as key '%s' of table '%s'",
+			name, existing.Name, ci.tbl.Name)
+	}
+
+	ci.addKey(key)
+	return ci.tbl
+}
@@ -1,23 +1,32 @@
 package exec
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/cockroachdb/cockroach/pkg/sql/coltypes"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/petermattis/opttoy/v4/cat"
 )
 
 type createTable struct {
-	catalog *cat.Catalog
-	tbl     *cat.Table
+	catalog *cat.MemCatalog
+	tbl     *cat.MemTable
 }
 
-func (ct *createTable) execute(stmt *tree.CreateTable) *cat.Table {
+func (ct *createTable) execute(stmt *tree.CreateTable) *cat.MemTable {
 	tn, err := stmt.Table.Normalize()
 	if err != nil {
-		fatalf("%s", err)
+		raise(pgerror.CodeSyntaxError, err)
 	}
 
-	ct.tbl = &cat.Table{Name: cat.TableName(tn.Table())}
+	name, err := ct.catalog.QualifyName(cat.NewTableName(tn))
+	if err != nil {
+		raise(pgerror.CodeInvalidSchemaNameError, err)
+	}
+
+	ct.tbl = &cat.MemTable{Name: name}
 
 	for _, def := range stmt.Defs {
 		switch def := def.(type) {
@@ -33,13 +42,18 @@ func (ct *createTable) execute(stmt *tree.CreateTable) *cat.Table {
 		case *tree.ForeignKeyConstraintTableDef:
 			ct.addTableForeignKey(def)
 
+		case *tree.CheckConstraintTableDef:
+			ct.addCheck(def.Name, def.Expr)
+
 		default:
 			unimplemented("%T", def)
 		}
 	}
 
 	// Add the new table to the catalog.
-	ct.catalog.AddTable(ct.tbl)
+	if err := ct.catalog.AddTable(ct.tbl); err != nil {
+		raise(pgerror.CodeDuplicateRelationError, err)
+	}
 
 	return ct.tbl
 }
@@ -49,35 +63,36 @@ func (ct *createTable) addColumn(def *tree.ColumnTableDef) {
 	typ := coltypes.CastTargetToDatumType(def.Type)
 	col := cat.Column{Name: cat.ColumnName(def.Name), NotNull: notNull, Type: typ}
 
-	ord := ct.tbl.AddColumn(&col)
+	ord, err := ct.tbl.AddColumn(&col)
+	if err != nil {
+		raise(pgerror.CodeDuplicateColumnError, err)
+	}
 
 	if def.Unique || def.PrimaryKey {
-		key := ct.addKey(&cat.TableKey{
+		name := "primary"
+		if !def.PrimaryKey {
+			name = ct.columnNames([]cat.ColumnOrdinal{ord}) + "_idx"
+		}
+
+		ct.addKey(&cat.TableKey{
+			Name:    name,
 			Primary: def.PrimaryKey,
 			Unique:  true,
 			Columns: []cat.ColumnOrdinal{ord},
 		})
-
-		if key.Name == "" {
-			if def.PrimaryKey {
-				key.Name = "primary"
-			} else {
-				key.Name = string(def.Name) + "_idx"
-			}
-		}
 	}
 
 	if def.HasFKConstraint() {
 		refTable, err := def.References.Table.Normalize()
 		if err != nil {
-			fatalf("%s", err)
+			raise(pgerror.CodeSyntaxError, err)
 		}
 
-		ref := ct.catalog.Table(cat.TableName(refTable.Table()))
+		ref := ct.table(cat.NewTableName(refTable))
 
 		var refCols []cat.ColumnOrdinal
 		if def.References.Col != "" {
-			refCols = []cat.ColumnOrdinal{ref.ColumnOrdinal(cat.ColumnName(def.References.Col))}
+			refCols = extractNames(ref, tree.NameList{def.References.Col})
 		} else {
 			for _, key := range ref.Keys {
 				if key.Primary {
@@ -87,12 +102,16 @@ func (ct *createTable) addColumn(def *tree.ColumnTableDef) {
 			}
 
 			if refCols == nil {
-				fatalf("%s does not contain a primary key", ref.Name)
+				errorf(pgerror.CodeInvalidForeignKeyError, "%s does not contain a primary key", ref.Name)
 			}
 		}
 
 		ct.addForeignKey(ref, []cat.ColumnOrdinal{ord}, refCols)
 	}
+
+	for _, check := range def.CheckExprs {
+		ct.addCheck(check.ConstraintName, check.Expr)
+	}
 }
 
 func (ct *createTable) addUniqueConstraintKey(def *tree.UniqueConstraintTableDef) {
@@ -103,38 +122,44 @@ func (ct *createTable) addUniqueConstraintKey(def *tree.UniqueConstraintTableDef
 		}
 	}
 
-	key := ct.addKey(&cat.TableKey{
+	name := string(def.Name)
+	if name == "" {
+		if def.PrimaryKey {
+			name = "primary"
+		} else {
+			name = ct.columnNames(cols) + "_idx"
+		}
+	}
+
+	ct.addKey(&cat.TableKey{
+		Name:    name,
 		Primary: def.PrimaryKey,
 		Unique:  true,
 		Columns: cols,
 	})
-
-	if key.Name == "" {
-		key.Name = string(def.Name)
-		if key.Name == "" {
-			key.Name = "primary"
-		}
-	}
 }
 
 func (ct *createTable) addIndexKey(def *tree.IndexTableDef) {
-	key := ct.addKey(&cat.TableKey{
+	cols := ct.extractColumns(def)
+	name := string(def.Name)
+	if name == "" {
+		name = ct.columnNames(cols) + "_idx"
+	}
+
+	ct.addKey(&cat.TableKey{
+		Name:    name,
 		Unique:  true,
-		Columns: ct.extractColumns(def),
+		Columns: cols,
 	})
-
-	if key.Name == "" {
-		key.Name = string(def.Name)
-	}
 }
 
 func (ct *createTable) addTableForeignKey(def *tree.ForeignKeyConstraintTableDef) {
 	refTable, err := def.Table.Normalize()
 	if err != nil {
-		fatalf("%s", err)
+		raise(pgerror.CodeSyntaxError, err)
 	}
 
-	ref := ct.catalog.Table(cat.TableName(refTable.Table()))
+	ref := ct.table(cat.NewTableName(refTable))
 
 	var toCols []cat.ColumnOrdinal
 	if len(def.ToCols) == 0 {
@@ -146,21 +171,33 @@ func (ct *createTable) addTableForeignKey(def *tree.ForeignKeyConstraintTableDef
 		}
 
 		if toCols == nil {
-			fatalf("%s does not contain a primary key", ref.Name)
+			errorf(pgerror.CodeInvalidForeignKeyError, "%s does not contain a primary key", ref.Name)
 		}
 	} else {
 		toCols = extractNames(ref, def.ToCols)
 	}
 
 	if len(def.FromCols) != len(toCols) {
-		fatalf("invalid foreign key specification: %s(%s) -> %s(%s)",
+		errorf(pgerror.CodeInvalidForeignKeyError, "invalid foreign key specification: %s(%s) -> %s(%s)",
 			ct.tbl.Name, def.FromCols, ref.Name, def.ToCols)
 	}
 
 	ct.addForeignKey(ref, extractNames(ct.tbl, def.FromCols), toCols)
 }
 
+// addKey adds the key to the table, unless the table already has a key with the
+// same columns. In that case the existing key keeps its name, and becomes
+// primary or unique if the new key is.
 func (ct *createTable) addKey(key *cat.TableKey) *cat.TableKey {
+	if key.Primary {
+		for i := range ct.tbl.Keys {
+			if primary := &ct.tbl.Keys[i]; primary.Primary && !primary.EqualColumns(key) {
+				errorf(pgerror.CodeInvalidTableDefinitionError,
+					"multiple primary keys for table '%s' are not allowed", ct.tbl.Name)
+			}
+		}
+	}
+
 	existing := ct.getKey(key)
 	if existing != nil {
 		existing.Primary = existing.Primary || key.Primary
@@ -174,7 +211,11 @@ func (ct *createTable) addKey(key *cat.TableKey) *cat.TableKey {
 		key.NotNull = key.NotNull && ct.tbl.Columns[i].NotNull
 	}
 
-	return ct.tbl.AddKey(key)
+	key, err := ct.tbl.AddKey(key)
+	if err != nil {
+		raise(pgerror.CodeDuplicateObjectError, err)
+	}
+	return key
 }
 
 func (ct *createTable) getKey(key *cat.TableKey) *cat.TableKey {
@@ -188,11 +229,12 @@ func (ct *createTable) getKey(key *cat.TableKey) *cat.TableKey {
 	return nil
 }
 
-func (ct *createTable) addForeignKey(dst *cat.Table, srcColumns, dstColumns []cat.ColumnOrdinal) {
-	srcKey := ct.addKey(&cat.TableKey{Columns: srcColumns})
+func (ct *createTable) addForeignKey(dst *cat.MemTable, srcColumns, dstColumns []cat.ColumnOrdinal) {
+	name := fmt.Sprintf("fk_%s_ref_%s", ct.columnNames(srcColumns), dst.Name.Table)
+	srcKey := ct.addKey(&cat.TableKey{Name: name, Columns: srcColumns})
 
 	if srcKey.Fkey != nil {
-		fatalf("foreign key already defined for %d", srcColumns)
+		errorf(pgerror.CodeDuplicateObjectError, "foreign key already defined for %d", srcColumns)
 	}
 
 	srcKey.Fkey = &cat.ForeignKey{
@@ -201,18 +243,111 @@ func (ct *createTable) addForeignKey(dst *cat.Table, srcColumns, dstColumns []ca
 	}
 }
 
+// columnNames returns the names of the columns at the given ordinal positions,
+// separated by underscores. Keys and check constraints that aren't named by
+// the statement are named after their columns.
+func (ct *createTable) columnNames(cols []cat.ColumnOrdinal) string {
+	names := make([]string, len(cols))
+	for i, ord := range cols {
+		names[i] = string(ct.tbl.Columns[ord].Name)
+	}
+	return strings.Join(names, "_")
+}
+
+// addCheck adds a check constraint with the given expression. If the
+// constraint isn't named, it is named after the columns that it refers to.
+func (ct *createTable) addCheck(name tree.Name, expr tree.Expr) {
+	check := cat.CheckConstraint{
+		Name:    string(name),
+		Expr:    expr,
+		Columns: checkColumns(ct.tbl, expr),
+	}
+
+	if check.Name == "" {
+		check.Name = "check_" + ct.columnNames(check.Columns)
+	}
+
+	if err := ct.tbl.AddCheck(&check); err != nil {
+		raise(pgerror.CodeDuplicateObjectError, err)
+	}
+}
+
 func (ct *createTable) extractColumns(def *tree.IndexTableDef) []cat.ColumnOrdinal {
-	res := make([]cat.ColumnOrdinal, len(def.Columns))
+	names := make(tree.NameList, len(def.Columns))
 	for i, col := range def.Columns {
-		res[i] = ct.tbl.ColumnOrdinal(cat.ColumnName(col.Column))
+		names[i] = col.Column
 	}
-	return res
+	return extractNames(ct.tbl, names)
 }
 
-func extractNames(tbl *cat.Table, names tree.NameList) []cat.ColumnOrdinal {
+// table returns the table with the given name from the catalog.
+func (ct *createTable) table(name cat.TableName) *cat.MemTable {
+	tbl, err := ct.catalog.MemTable(name)
+	if err != nil {
+		raise(pgerror.CodeUndefinedTableError, err)
+	}
+	return tbl
+}
+
+func extractNames(tbl *cat.MemTable, names tree.NameList) []cat.ColumnOrdinal {
 	res := make([]cat.ColumnOrdinal, len(names))
 	for i, name := range names {
-		res[i] = tbl.ColumnOrdinal(cat.ColumnName(name))
+		ord, err := tbl.ColumnOrdinal(cat.ColumnName(name))
+		if err != nil {
+			raise(pgerror.CodeUndefinedColumnError, err)
+		}
+		res[i] = ord
 	}
 	return res
 }
+
+// checkColumns returns the columns of the table that the expression of a check
+// constraint refers to, in the order in which they first appear.
+func checkColumns(tbl *cat.MemTable, expr tree.Expr) []cat.ColumnOrdinal {
+	v := checkVisitor{tbl: tbl}
+	tree.WalkExprConst(&v, expr)
+	return v.cols
+}
+
+// checkVisitor collects the columns that are referenced by a check constraint.
+type checkVisitor struct {
+	tbl  *cat.MemTable
+	cols []cat.ColumnOrdinal
+}
+
+var _ tree.Visitor = &checkVisitor{}
+
+// VisitPre is part of the tree.Visitor interface.
+func (v *checkVisitor) VisitPre(expr tree.Expr) (recurse bool, newExpr tree.Expr) {
+	switch t := expr.(type) {
+	case *tree.UnresolvedName:
+		vn, err := t.NormalizeVarName()
+		if err != nil {
+			raise(pgerror.CodeSyntaxError, err)
+		}
+		return v.VisitPre(vn)
+
+	case *tree.ColumnItem:
+		ord, err := v.tbl.ColumnOrdinal(cat.ColumnName(t.ColumnName))
+		if err != nil {
+			raise(pgerror.CodeUndefinedColumnError, err)
+		}
+		for _, c := range v.cols {
+			if c == ord {
+				return false, expr
+			}
+		}
+		v.cols = append(v.cols, ord)
+		return false, expr
+
+	case *tree.Subquery:
+		errorf(pgerror.CodeFeatureNotSupportedError, "subqueries are not allowed in check constraints")
+	}
+
+	return true, expr
+}
+
+// VisitPost is part of the tree.Visitor interface.
+func (v *checkVisitor) VisitPost(expr tree.Expr) tree.Expr {
+	return expr
+}
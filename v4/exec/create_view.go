@@ -0,0 +1,67 @@
+package exec
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/build"
+	"github.com/petermattis/opttoy/v4/cat"
+	"github.com/petermattis/opttoy/v4/opt"
+)
+
+type createView struct {
+	catalog *cat.MemCatalog
+}
+
+// Add the view to the catalog, and return it. The query is built in order to
+// check that it's valid, to find the names of its columns if the statement
+// doesn't name them, and to resolve its table names using the current database
+// and search path, so that they refer to the same tables whenever the view is
+// used.
+func (cv *createView) execute(stmt *tree.CreateView) *cat.View {
+	tn, err := stmt.Name.Normalize()
+	if err != nil {
+		raise(pgerror.CodeSyntaxError, err)
+	}
+
+	name, err := cv.catalog.QualifyName(cat.NewTableName(tn))
+	if err != nil {
+		raise(pgerror.CodeInvalidSchemaNameError, err)
+	}
+
+	p := opt.NewPlanner(cv.catalog, 0)
+	b := build.NewBuilder(p.Factory(), stmt.AsSource)
+	_, required, err := b.Build()
+	if err != nil {
+		raise(pgerror.CodeInvalidObjectDefinitionError, err)
+	}
+
+	cols := required.Projection.Columns
+	if len(stmt.ColumnNames) != 0 && len(stmt.ColumnNames) != len(cols) {
+		errorf(pgerror.CodeSyntaxError, "CREATE VIEW specifies %d column names, but the query returns %d columns",
+			len(stmt.ColumnNames), len(cols))
+	}
+
+	view := &cat.View{Name: name, Query: tree.AsString(stmt.AsSource), Names: b.TableNames()}
+	seen := make(map[cat.ColumnName]bool)
+	for i, col := range cols {
+		// An unnamed expression gets the name the builder gave its column.
+		colName := cat.ColumnName(col.Label)
+		if colName == "" {
+			colName = cat.ColumnName(p.Factory().Metadata().ColumnLabel(col.Index))
+		}
+		if len(stmt.ColumnNames) != 0 {
+			colName = cat.ColumnName(stmt.ColumnNames[i])
+		}
+
+		if seen[colName] {
+			errorf(pgerror.CodeDuplicateColumnError, "view '%s' already has column '%s'", name, colName)
+		}
+		seen[colName] = true
+		view.Columns = append(view.Columns, colName)
+	}
+
+	if err := cv.catalog.AddView(view); err != nil {
+		raise(pgerror.CodeDuplicateRelationError, err)
+	}
+	return view
+}
@@ -0,0 +1,134 @@
+package exec
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+type dropIndex struct {
+	catalog *cat.MemCatalog
+}
+
+// indexRef identifies the key of a table that an index name refers to.
+type indexRef struct {
+	tbl  *cat.MemTable
+	name string
+}
+
+// Drop the keys of the indexes, and return the tables that they belonged to.
+// An index that is a foreign key, or that is a unique key whose columns are
+// referenced by a foreign key, is only dropped if the statement uses CASCADE,
+// which drops the foreign key as well. The primary key can't be dropped.
+func (di *dropIndex) execute(stmt *tree.DropIndex) []*cat.MemTable {
+	var indexes []indexRef
+	for _, index := range stmt.IndexList {
+		ref, ok := di.resolve(index)
+		if !ok {
+			if stmt.IfExists {
+				continue
+			}
+			errorf(pgerror.CodeUndefinedObjectError, "index not found: %s", tree.AsString(index))
+		}
+
+		key, _ := ref.tbl.KeyByName(ref.name)
+		if key.Primary {
+			errorf(pgerror.CodeInvalidTableDefinitionError, "the primary key of table '%s' can't be dropped", ref.tbl.Name)
+		}
+		if stmt.DropBehavior != tree.DropCascade {
+			if key.Fkey != nil {
+				errorf(pgerror.CodeDependentObjectsStillExistError, "index '%s' is in use as a foreign key", ref.name)
+			}
+			if len(di.referencingKeys(ref.tbl, key)) != 0 {
+				errorf(pgerror.CodeDependentObjectsStillExistError,
+					"index '%s' is referenced by a foreign key", ref.name)
+			}
+		}
+		indexes = append(indexes, ref)
+	}
+
+	var tables []*cat.MemTable
+	seen := make(map[*cat.MemTable]bool)
+	for _, ref := range indexes {
+		key, err := ref.tbl.KeyByName(ref.name)
+		if err != nil {
+			// The same index was named twice.
+			continue
+		}
+		for _, fkey := range di.referencingKeys(ref.tbl, key) {
+			fkey.Key.Fkey = nil
+		}
+		if err := ref.tbl.DropKey(ref.name); err != nil {
+			raise(pgerror.CodeUndefinedObjectError, err)
+		}
+
+		if !seen[ref.tbl] {
+			seen[ref.tbl] = true
+			tables = append(tables, ref.tbl)
+		}
+	}
+	return tables
+}
+
+// resolve returns the table and key name of the index, and false if there is
+// no such index. An index that isn't qualified by its table name is searched
+// for in the tables that unqualified table names can refer to.
+func (di *dropIndex) resolve(index *tree.TableNameWithIndex) (indexRef, bool) {
+	tn, err := index.Table.Normalize()
+	if err != nil {
+		raise(pgerror.CodeSyntaxError, err)
+	}
+
+	if !index.SearchTable {
+		tbl, err := di.catalog.MemTable(cat.NewTableName(tn))
+		if err != nil {
+			raise(pgerror.CodeUndefinedTableError, err)
+		}
+		if _, err := tbl.KeyByName(string(index.Index)); err != nil {
+			return indexRef{}, false
+		}
+		return indexRef{tbl: tbl, name: string(index.Index)}, true
+	}
+
+	// The parser puts the name of the index in place of the table name.
+	name := string(tn.TableName)
+	var found []indexRef
+	for _, schema := range di.catalog.SearchPath() {
+		for _, tbl := range di.catalog.MemTables() {
+			if tbl.Name.Database != di.catalog.Database() || tbl.Name.Schema != schema {
+				continue
+			}
+			if _, err := tbl.KeyByName(name); err == nil {
+				found = append(found, indexRef{tbl: tbl, name: name})
+			}
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return indexRef{}, false
+
+	case 1:
+		return found[0], true
+	}
+
+	errorf(pgerror.CodeAmbiguousParameterError, "index name '%s' is ambiguous: found in %s and %s",
+		name, found[0].tbl.Name, found[1].tbl.Name)
+	return indexRef{}, false
+}
+
+// referencingKeys returns the keys whose foreign keys reference the columns of
+// the given unique key.
+func (di *dropIndex) referencingKeys(tbl *cat.MemTable, key *cat.TableKey) []cat.KeyRef {
+	if !key.Unique {
+		return nil
+	}
+
+	var refs []cat.KeyRef
+	for _, ref := range di.catalog.ReferencingKeys(tbl) {
+		if key.EqualColumns(&cat.TableKey{Columns: ref.Key.Fkey.Columns}) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
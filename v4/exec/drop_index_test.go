@@ -0,0 +1,90 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+// TestDropIndexSearchPath tests that an index that isn't qualified by its table
+// name is found in the schemas of the search path, which have to be created by
+// MemCatalog.CreateSchema, since the parser doesn't support CREATE SCHEMA.
+func TestDropIndexSearchPath(t *testing.T) {
+	catalog := cat.NewCatalog()
+	for _, schema := range []string{"s1", "s2"} {
+		if err := catalog.CreateSchema(cat.DefaultDatabase, schema); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := NewEngine(catalog)
+	execute := func(sql string) error {
+		stmt, err := parser.ParseOne(sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = e.Execute(stmt)
+		return err
+	}
+	for _, sql := range []string{
+		"CREATE TABLE s1.a (x INT PRIMARY KEY, y INT, INDEX idx (y))",
+		"CREATE TABLE s2.b (x INT PRIMARY KEY, y INT, INDEX idx (y))",
+		"CREATE TABLE s2.c (x INT PRIMARY KEY, y INT, INDEX c_idx (y))",
+	} {
+		if err := execute(sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	hasIndex := func(table, schema, index string) bool {
+		tbl, err := catalog.MemTable(cat.TableName{Database: cat.DefaultDatabase, Schema: schema, Table: table})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tbl.KeyByName(index)
+		return err == nil
+	}
+
+	testCases := []struct {
+		searchPath string
+		index      string
+		code       string
+
+		// dropped is the schema and table of the index that's dropped.
+		schema string
+		table  string
+	}{
+		{searchPath: "public", index: "idx", code: pgerror.CodeUndefinedObjectError},
+		{searchPath: "s1, s2", index: "idx", code: pgerror.CodeAmbiguousParameterError},
+		{searchPath: "s2, s1", index: "idx", code: pgerror.CodeAmbiguousParameterError},
+		{searchPath: "s1", index: "c_idx", code: pgerror.CodeUndefinedObjectError},
+		{searchPath: "s1, s2", index: "c_idx", schema: "s2", table: "c"},
+		{searchPath: "missing, s1", index: "idx", schema: "s1", table: "a"},
+		// Once the index of s1.a is dropped, the index of s2.b is no longer
+		// ambiguous.
+		{searchPath: "s1, s2", index: "idx", schema: "s2", table: "b"},
+	}
+	for _, tc := range testCases {
+		if err := execute("SET search_path = " + tc.searchPath); err != nil {
+			t.Fatal(err)
+		}
+
+		err := execute("DROP INDEX " + tc.index)
+		if tc.code != "" {
+			pgErr, ok := pgerror.GetPGCause(err)
+			if !ok || pgErr.Code != tc.code {
+				t.Errorf("%s (%s): expected error code %s, but found %v", tc.index, tc.searchPath, tc.code, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s (%s): %v", tc.index, tc.searchPath, err)
+			continue
+		}
+		if hasIndex(tc.table, tc.schema, tc.index) {
+			t.Errorf("%s (%s): index wasn't dropped from %s.%s", tc.index, tc.searchPath, tc.schema, tc.table)
+		}
+	}
+}
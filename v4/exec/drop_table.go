@@ -0,0 +1,66 @@
+package exec
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+type dropTable struct {
+	catalog *cat.MemCatalog
+}
+
+// Drop the tables from the catalog. A table that is referenced by a foreign
+// key of a table that isn't dropped along with it is only dropped if the
+// statement uses CASCADE, which drops the foreign key. Likewise, a table that a
+// view depends on is only dropped if the statement uses CASCADE, which drops
+// the view.
+func (dt *dropTable) execute(stmt *tree.DropTable) {
+	dropped := make(map[*cat.MemTable]bool)
+	var tables []*cat.MemTable
+	for i := range stmt.Names {
+		tn, err := stmt.Names[i].Normalize()
+		if err != nil {
+			raise(pgerror.CodeSyntaxError, err)
+		}
+
+		tbl, err := dt.catalog.MemTable(cat.NewTableName(tn))
+		if err != nil {
+			if stmt.IfExists {
+				continue
+			}
+			raise(pgerror.CodeUndefinedTableError, err)
+		}
+		if !dropped[tbl] {
+			dropped[tbl] = true
+			tables = append(tables, tbl)
+		}
+	}
+
+	names := make([]cat.TableName, len(tables))
+	for i, tbl := range tables {
+		names[i] = tbl.Name
+	}
+	views := dependentViews(dt.catalog, "table", names, nil /* dropped */, stmt.DropBehavior == tree.DropCascade)
+
+	refs := make(map[*cat.MemTable][]cat.KeyRef)
+	for _, tbl := range tables {
+		refs[tbl] = dt.catalog.ReferencingKeys(tbl)
+		for _, ref := range refs[tbl] {
+			if !dropped[ref.Table] && stmt.DropBehavior != tree.DropCascade {
+				errorf(pgerror.CodeDependentObjectsStillExistError,
+					"table '%s' is referenced by a foreign key of table '%s'", tbl.Name, ref.Table.Name)
+			}
+		}
+	}
+
+	dropViews(dt.catalog, views)
+	for _, tbl := range tables {
+		for _, ref := range refs[tbl] {
+			ref.Key.Fkey = nil
+		}
+		if err := dt.catalog.DropTable(tbl); err != nil {
+			raise(pgerror.CodeDependentObjectsStillExistError, err)
+		}
+	}
+}
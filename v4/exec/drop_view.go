@@ -0,0 +1,79 @@
+package exec
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+type dropView struct {
+	catalog *cat.MemCatalog
+}
+
+// Drop the views from the catalog. A view that another view depends on is only
+// dropped if the statement uses CASCADE, which drops the other view as well.
+func (dv *dropView) execute(stmt *tree.DropView) {
+	var names []cat.TableName
+	views := make(map[cat.TableName]*cat.View)
+	for i := range stmt.Names {
+		tn, err := stmt.Names[i].Normalize()
+		if err != nil {
+			raise(pgerror.CodeSyntaxError, err)
+		}
+
+		view, err := dv.catalog.View(cat.NewTableName(tn))
+		if err != nil {
+			if stmt.IfExists {
+				continue
+			}
+			raise(pgerror.CodeUndefinedTableError, err)
+		}
+		if views[view.Name] == nil {
+			views[view.Name] = view
+			names = append(names, view.Name)
+		}
+	}
+
+	// Drop the views that depend on the dropped views first, including the
+	// dropped views that depend on each other.
+	dependents := dependentViews(dv.catalog, "view", names, views, stmt.DropBehavior == tree.DropCascade)
+	for _, view := range dependents {
+		delete(views, view.Name)
+	}
+	for _, name := range names {
+		if view, ok := views[name]; ok {
+			dependents = append(dependents, view)
+		}
+	}
+	dropViews(dv.catalog, dependents)
+}
+
+// dependentViews returns the views that depend on the tables or views with the
+// given names, in the order in which they can be dropped. Unless cascade is
+// true, a dependent view is an error if it isn't one of the dropped views. kind
+// is "table" or "view", and describes the names in the error.
+func dependentViews(
+	catalog *cat.MemCatalog, kind string, names []cat.TableName, dropped map[cat.TableName]*cat.View, cascade bool,
+) []*cat.View {
+	views := catalog.DependentViews(names...)
+	if !cascade {
+		for _, name := range names {
+			for _, view := range views {
+				if dropped[view.Name] == nil && view.DependsOn(name) {
+					errorf(pgerror.CodeDependentObjectsStillExistError, "%s '%s' is used by view '%s'", kind, name, view.Name)
+				}
+			}
+		}
+	}
+	return views
+}
+
+// dropViews drops the views in order. Each view must come before the views that
+// it depends on.
+func dropViews(catalog *cat.MemCatalog, views []*cat.View) {
+	for _, view := range views {
+		if err := catalog.DropView(view); err != nil {
+			raise(pgerror.CodeDependentObjectsStillExistError, err)
+		}
+	}
+}
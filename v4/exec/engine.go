@@ -1,86 +1,191 @@
 package exec
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/petermattis/opttoy/v4/cat"
 )
 
+// histogramDatabase is the database name that identifies the statements that
+// create and filter histograms.
+const histogramDatabase = "histogram"
+
 type Engine struct {
-	catalog *cat.Catalog
+	catalog *cat.MemCatalog
 }
 
-func NewEngine(catalog *cat.Catalog) *Engine {
+func NewEngine(catalog *cat.MemCatalog) *Engine {
 	return &Engine{catalog: catalog}
 }
 
-func (e *Engine) Catalog() *cat.Catalog {
+func (e *Engine) Catalog() *cat.MemCatalog {
 	return e.catalog
 }
 
-func (e *Engine) Execute(stmt tree.Statement) string {
-	if stmt.StatementType() != tree.DDL {
-		fatalf("statement type is not DDL: %v", stmt.StatementType())
+// CanExecute returns true if the statement is executed by the engine rather
+// than optimized. These are DDL statements, the SET statements that change the
+// current database and search path, and the INSERT and SELECT statements that
+// create and filter histograms.
+func (e *Engine) CanExecute(stmt tree.Statement) bool {
+	if stmt.StatementType() == tree.DDL {
+		return true
+	}
+	if stmt, ok := stmt.(*tree.SetVar); ok {
+		_, ok := setVarNames[strings.ToLower(stmt.Name)]
+		return ok
+	}
+	_, ok := histogramName(stmt)
+	return ok
+}
+
+// Execute executes the statement, and returns a description of the database,
+// table, view or histogram that it created or changed, or of the setting that it
+// changed. DROP TABLE and DROP VIEW return an empty description. If the statement can't be executed, Execute
+// returns an error with a code such as pgerror.CodeUndefinedTableError.
+func (e *Engine) Execute(stmt tree.Statement) (res string, err error) {
+	defer catchError(&err)
+
+	if !e.CanExecute(stmt) {
+		errorf(pgerror.CodeFeatureNotSupportedError, "statement cannot be executed: %s", stmt)
 	}
 
 	switch stmt := stmt.(type) {
+	case *tree.CreateDatabase:
+		// The histogram database is reserved for the statements that create
+		// and filter histograms.
+		if stmt.Name == histogramDatabase {
+			errorf(pgerror.CodeReservedNameError, "database name is reserved: %s", stmt.Name)
+		}
+		if err := e.catalog.CreateDatabase(string(stmt.Name)); err != nil {
+			if stmt.IfNotExists {
+				return "", nil
+			}
+			raise(pgerror.CodeDuplicateDatabaseError, err)
+		}
+		return fmt.Sprintf("database %s\n", stmt.Name), nil
+
+	case *tree.SetVar:
+		sv := setVar{catalog: e.catalog}
+		return sv.execute(stmt), nil
+
 	case *tree.CreateTable:
 		ct := createTable{catalog: e.catalog}
 		tbl := ct.execute(stmt)
-		return tbl.String()
+		return tbl.String(), nil
 
-	case *tree.Insert:
-		name, ok := stmt.Table.(*tree.NormalizableTableName)
-		if !ok {
-			unimplemented("%T", stmt)
-		}
+	case *tree.CreateView:
+		cv := createView{catalog: e.catalog}
+		view := cv.execute(stmt)
+		return view.String(), nil
 
-		tname, err := name.Normalize()
-		if err != nil {
-			fatalf("unable to normalize: %v", err)
+	case *tree.AlterTable:
+		at := alterTable{createTable: createTable{catalog: e.catalog}}
+		if tbl := at.execute(stmt); tbl != nil {
+			return tbl.String(), nil
 		}
-
-		if tname.PrefixName != "histogram" {
-			unimplemented("%s", stmt)
+		return "", nil
+
+	case *tree.DropTable:
+		dt := dropTable{catalog: e.catalog}
+		dt.execute(stmt)
+		return "", nil
+
+	case *tree.DropView:
+		dv := dropView{catalog: e.catalog}
+		dv.execute(stmt)
+		return "", nil
+
+	case *tree.CreateIndex:
+		ci := createIndex{createTable{catalog: e.catalog}}
+		tbl := ci.execute(stmt)
+		return tbl.String(), nil
+
+	case *tree.DropIndex:
+		di := dropIndex{catalog: e.catalog}
+		var buf bytes.Buffer
+		for _, tbl := range di.execute(stmt) {
+			buf.WriteString(tbl.String())
 		}
+		return buf.String(), nil
+
+	case *tree.Insert:
+		tname, _ := histogramName(stmt)
 
 		// This is a statement of the form
 		//   INSERT INTO histogram.table.column VALUES ...
 		//
 		// The histogram.table.column tokens map to
 		// PrefixName.DatabaseName.TableName. So we get the table name from
-		// DatabaseName and the column name from TableName.
+		// DatabaseName and the column name from TableName. The table name is
+		// unqualified, so it is resolved using the search path.
 		ch := createHistogram{catalog: e.catalog}
-		h := ch.execute(cat.TableName(tname.DatabaseName), cat.ColumnName(tname.TableName), stmt.Rows)
-		return h.String()
+		h := ch.execute(histogramTable(tname), cat.ColumnName(tname.TableName), stmt.Rows)
+		return h.String(), nil
 
 	case *tree.Select:
-		sel, ok := stmt.Select.(*tree.SelectClause)
-		if !ok {
-			unimplemented("%s", stmt)
-		}
+		tname, _ := histogramName(stmt)
+		sel := stmt.Select.(*tree.SelectClause)
+		fh := filterHistogram{catalog: e.catalog}
+		h := fh.execute(histogramTable(tname), cat.ColumnName(tname.TableName), sel)
+		return h.String(), nil
 
-		// Get the histogram name.
-		name, ok := sel.From.Tables[0].(*tree.AliasedTableExpr).Expr.(*tree.NormalizableTableName)
-		if !ok {
-			unimplemented("%s", stmt)
-		}
+	default:
+		unimplemented("%T", stmt)
+	}
 
-		tname, err := name.Normalize()
-		if err != nil {
-			fatalf("unable to normalize: %v", err)
-		}
+	return "", nil
+}
+
+// histogramName returns the name of the histogram that the statement inserts
+// into or selects from, and false if the statement doesn't refer to a
+// histogram.
+func histogramName(stmt tree.Statement) (*tree.TableName, bool) {
+	var name *tree.NormalizableTableName
+	switch stmt := stmt.(type) {
+	case *tree.Insert:
+		name, _ = stmt.Table.(*tree.NormalizableTableName)
 
-		if tname.PrefixName != "histogram" {
-			unimplemented("%s", stmt)
+	case *tree.Select:
+		sel, ok := stmt.Select.(*tree.SelectClause)
+		if !ok || len(sel.From.Tables) != 1 {
+			return nil, false
+		}
+		if table, ok := sel.From.Tables[0].(*tree.AliasedTableExpr); ok {
+			name, _ = table.Expr.(*tree.NormalizableTableName)
 		}
+	}
+	if name == nil {
+		return nil, false
+	}
 
-		fh := filterHistogram{catalog: e.catalog}
-		h := fh.execute(cat.TableName(tname.DatabaseName), cat.ColumnName(tname.TableName), sel)
-		return h.String()
+	tname, err := name.Normalize()
+	if err != nil || tname.PrefixName != histogramDatabase {
+		return nil, false
+	}
+	return tname, true
+}
 
-	default:
-		unimplemented("%T", stmt)
+// histogramTable returns the name of the table whose histogram is created or
+// filtered by a statement, given the name that histogramName returned.
+func histogramTable(tname *tree.TableName) cat.TableName {
+	return cat.MakeTableName(string(tname.DatabaseName))
+}
+
+// histogramColumn returns the column of the given table whose histogram is
+// created or filtered.
+func histogramColumn(catalog *cat.MemCatalog, tblName cat.TableName, colName cat.ColumnName) *cat.Column {
+	tbl, err := catalog.MemTable(tblName)
+	if err != nil {
+		raise(pgerror.CodeUndefinedTableError, err)
 	}
 
-	return ""
+	col, err := tbl.ColumnByName(colName)
+	if err != nil {
+		raise(pgerror.CodeUndefinedColumnError, err)
+	}
+	return col
 }
@@ -1,12 +1,13 @@
 package exec
 
 import (
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/petermattis/opttoy/v4/cat"
 )
 
 type filterHistogram struct {
-	catalog *cat.Catalog
+	catalog *cat.MemCatalog
 }
 
 // FilterHistogram filters a histogram based on the WHERE clause in the given select
@@ -19,9 +20,11 @@ func (fh *filterHistogram) execute(
 	sel *tree.SelectClause,
 ) *cat.Histogram {
 	// Get the histogram from the catalog.
-	tbl := fh.catalog.Table(tblName)
-	col := tbl.Column(colName)
+	col := histogramColumn(fh.catalog, tblName, colName)
 	hist := col.Stats
+	if hist == nil {
+		errorf(pgerror.CodeUndefinedObjectError, "no histogram for column %s.%s", tblName, colName)
+	}
 
 	// Filter the histogram.
 	expr, ok := sel.Where.Expr.(*tree.ComparisonExpr)
@@ -37,7 +40,7 @@ func (fh *filterHistogram) execute(
 	case *tree.NumVal:
 		val, err = v.AsInt64()
 		if err != nil {
-			fatalf("unable to cast datum to int64: %v", err)
+			errorf(pgerror.CodeInvalidParameterValueError, "unable to cast datum to int64: %v", err)
 		}
 
 		vals = []int64{val}
@@ -51,7 +54,7 @@ func (fh *filterHistogram) execute(
 
 			val, err = numVal.AsInt64()
 			if err != nil {
-				fatalf("unable to cast datum to int64: %v", err)
+				errorf(pgerror.CodeInvalidParameterValueError, "unable to cast datum to int64: %v", err)
 			}
 
 			vals = append(vals, val)
@@ -0,0 +1,49 @@
+package exec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+// setVarNames are the session variables that can be changed by a SET
+// statement. USE d is the same as SET database = d.
+var setVarNames = map[string]struct{}{
+	"database":    {},
+	"search_path": {},
+}
+
+type setVar struct {
+	catalog *cat.MemCatalog
+}
+
+// Change the current database or search path of the catalog, and return the
+// new value of the variable.
+func (sv *setVar) execute(stmt *tree.SetVar) string {
+	name := strings.ToLower(stmt.Name)
+	values := make([]string, len(stmt.Values))
+	for i, v := range stmt.Values {
+		values[i] = tree.AsStringWithFlags(v, tree.FmtBareStrings|tree.FmtBareIdentifiers)
+	}
+
+	switch name {
+	case "database":
+		if len(values) != 1 {
+			errorf(pgerror.CodeInvalidParameterValueError, "SET %s takes only one argument", name)
+		}
+		if err := sv.catalog.SetDatabase(values[0]); err != nil {
+			raise(pgerror.CodeInvalidCatalogNameError, err)
+		}
+
+	case "search_path":
+		sv.catalog.SetSearchPath(values)
+
+	default:
+		unimplemented("SET %s", name)
+	}
+
+	return fmt.Sprintf("%s %s\n", name, strings.Join(values, ", "))
+}
@@ -14,9 +14,11 @@ import (
 	"testing"
 
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/petermattis/opttoy/v4/build"
 	"github.com/petermattis/opttoy/v4/cat"
+	"github.com/petermattis/opttoy/v4/decompile"
 	"github.com/petermattis/opttoy/v4/exec"
 	"github.com/petermattis/opttoy/v4/opt"
 )
@@ -24,6 +26,8 @@ import (
 var (
 	logicTestData    = flag.String("d", "testdata/[^.]*", "test data glob")
 	rewriteTestFiles = flag.Bool("rewrite", false, "")
+	ruleCoverageFile = flag.String("rule-coverage", "",
+		"write a report of the normalization rules applied by the tests to this file")
 )
 
 type lineScanner struct {
@@ -49,6 +53,7 @@ func (l *lineScanner) Scan() bool {
 type testdata struct {
 	pos      string // file and line number
 	cmd      string // exec, query, ...
+	args     []string
 	sql      string
 	stmt     tree.Statement
 	expected string
@@ -104,6 +109,7 @@ func (r *testdataReader) Next(t *testing.T) bool {
 		}
 		r.data.pos = fmt.Sprintf("%s:%d", r.path, r.scanner.line)
 		r.data.cmd = cmd
+		r.data.args = fields[1:]
 
 		var buf bytes.Buffer
 		var separator bool
@@ -122,11 +128,17 @@ func (r *testdataReader) Next(t *testing.T) bool {
 		}
 
 		r.data.sql = strings.TrimSpace(buf.String())
-		stmt, err := parser.ParseOne(r.data.sql)
-		if err != nil {
-			t.Fatal(err)
+		switch cmd {
+		case "rule", "memo-load":
+			// Rule tests are written as expressions, and memo-load tests are
+			// written as JSON, rather than SQL.
+		default:
+			stmt, err := parser.ParseOne(r.data.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.data.stmt = stmt
 		}
-		r.data.stmt = stmt
 
 		if separator {
 			buf.Reset()
@@ -188,6 +200,20 @@ func TestLogic(t *testing.T) {
 		t.Fatalf("no testfiles found matching: %s", *logicTestData)
 	}
 
+	var coverage *ruleCoverage
+	if *ruleCoverageFile != "" {
+		coverage = &ruleCoverage{}
+		defer func() {
+			report := coverage.String()
+			if err := ioutil.WriteFile(*ruleCoverageFile, []byte(report), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if testing.Verbose() {
+				fmt.Print(report)
+			}
+		}()
+	}
+
 	for _, path := range paths {
 		t.Run(filepath.Base(path), func(t *testing.T) {
 			catalog := cat.NewCatalog()
@@ -196,27 +222,333 @@ func TestLogic(t *testing.T) {
 				switch d.cmd {
 				case "exec":
 					e := exec.NewEngine(catalog)
-					return e.Execute(d.stmt)
+					res, err := e.Execute(d.stmt)
+					if err != nil {
+						return formatError(err)
+					}
+					return res
+
+				case "rule":
+					return runRule(t, catalog, d, coverage)
+
+				case "memo-load":
+					p, err := opt.LoadPlanner(catalog, 0 /* maxSteps */, strings.NewReader(d.sql))
+					if err != nil {
+						return formatError(err)
+					}
+					return p.MemoString()
 				}
 
 				var maxSteps int
 				switch d.cmd {
-				case "normalize", "memo":
+				case "normalize", "memo", "memo-json", "memo-dot", "trace", "rules", "explain", "sql":
 					// Complete all normalization steps.
 					maxSteps = int(math.MaxInt32)
 				}
 
 				p := opt.NewPlanner(catalog, maxSteps)
+				for _, arg := range d.args {
+					if strings.HasPrefix(arg, "disable=") {
+						names := strings.Split(strings.TrimPrefix(arg, "disable="), ",")
+						if err := p.DisableRules(names...); err != nil {
+							t.Fatalf("%s: %v", d.pos, err)
+						}
+					}
+				}
+
+				var applied bytes.Buffer
+				p.Factory().SetAppliedRule(func(rule opt.RuleName, source opt.Operator, target opt.GroupID) {
+					coverage.record(rule)
+					if d.cmd == "rules" {
+						fmt.Fprintf(&applied, "%s: %s => [%d]\n", rule, source, target)
+					}
+				})
+
 				b := build.NewBuilder(p.Factory(), d.stmt)
-				root, required := b.Build()
-				e := p.Optimize(root, required)
+				root, required, err := b.Build()
+				if err != nil {
+					return formatError(err)
+				}
 
-				if d.cmd == "memo" {
+				if d.cmd == "rules" {
+					return applied.String()
+				}
+
+				var trace opt.OptimizeTrace
+				if d.cmd == "trace" {
+					p.SetOptimizeTrace(&trace)
+				}
+
+				e, err := p.Optimize(root, required)
+				if err != nil {
+					t.Fatalf("%s: %v", d.pos, err)
+				}
+
+				switch d.cmd {
+				case "explain":
+					var names []string
+					for _, arg := range d.args {
+						if !strings.Contains(arg, "=") {
+							names = append(names, arg)
+						}
+					}
+					flags, err := opt.ParseExplainFlags(names)
+					if err != nil {
+						t.Fatalf("%s: %v", d.pos, err)
+					}
+					return e.Explain(flags)
+
+				case "memo":
+					checkMemoRoundTrip(t, catalog, d, p)
 					return p.MemoString()
+
+				case "memo-dot":
+					return p.MemoDOT()
+
+				case "trace":
+					// Make sure that the trace can be replayed as HTML.
+					if err := p.WriteMemoHTML(ioutil.Discard, &trace); err != nil {
+						t.Fatalf("%s: %v", d.pos, err)
+					}
+					return trace.String()
+
+				case "sql":
+					return runDecompile(t, catalog, d, p, e)
+
+				case "memo-json":
+					var buf bytes.Buffer
+					if err := p.SaveMemo(&buf); err != nil {
+						t.Fatalf("%s: %v", d.pos, err)
+					}
+					return buf.String()
 				}
 
+				if flags, ok := b.Explain(); ok {
+					return e.Explain(flags)
+				}
 				return e.String()
 			})
 		})
 	}
 }
+
+// checkMemoRoundTrip saves the planner's memo, loads it into a new planner, and
+// verifies that the loaded memo is the same as the original.
+func checkMemoRoundTrip(t *testing.T, catalog cat.Catalog, d *testdata, p *opt.Planner) {
+	t.Helper()
+
+	var saved bytes.Buffer
+	if err := p.SaveMemo(&saved); err != nil {
+		t.Fatalf("%s: %v", d.pos, err)
+	}
+
+	loaded, err := opt.LoadPlanner(catalog, math.MaxInt32, bytes.NewReader(saved.Bytes()))
+	if err != nil {
+		t.Fatalf("%s: %v", d.pos, err)
+	}
+	if expected, actual := p.MemoString(), loaded.MemoString(); expected != actual {
+		t.Fatalf("%s: loaded memo differs\nexpected:\n%s\nfound:\n%s", d.pos, expected, actual)
+	}
+
+	var resaved bytes.Buffer
+	if err := loaded.SaveMemo(&resaved); err != nil {
+		t.Fatalf("%s: %v", d.pos, err)
+	}
+	if saved.String() != resaved.String() {
+		t.Fatalf("%s: saved memo differs after loading\nexpected:\n%s\nfound:\n%s",
+			d.pos, saved.String(), resaved.String())
+	}
+}
+
+// runDecompile converts the optimized expression to SQL, and verifies that the
+// SQL can be parsed, built and optimized, and that it returns the same
+// columns in the same order. The parser doesn't support LATERAL joins, so SQL that uses them
+// isn't checked.
+func runDecompile(t *testing.T, catalog cat.Catalog, d *testdata, p *opt.Planner, e opt.Expr) string {
+	t.Helper()
+
+	sql, err := decompile.ToSQL(p.Metadata(), e)
+	if err != nil {
+		return fmt.Sprintf("error: %v\n", err)
+	}
+	if strings.Contains(sql, " LATERAL ") {
+		return sql + "\n"
+	}
+
+	stmt, err := parser.ParseOne(sql)
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", d.pos, err, sql)
+	}
+
+	rebuilt := opt.NewPlanner(catalog, math.MaxInt32)
+	root, required, err := build.NewBuilder(rebuilt.Factory(), stmt).Build()
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", d.pos, err, sql)
+	}
+	if !sameColumnLabels(e.Physical().Projection, required.Projection) {
+		t.Fatalf("%s: rebuilt SQL returns columns %s, expected %s\n%s",
+			d.pos, required.Projection, e.Physical().Projection, sql)
+	}
+	reoptimized, err := rebuilt.Optimize(root, required)
+	if err != nil {
+		t.Fatalf("%s: %v\n%s", d.pos, err, sql)
+	}
+	if _, err := decompile.ToSQL(rebuilt.Metadata(), reoptimized); err != nil {
+		t.Fatalf("%s: %v\n%s", d.pos, err, sql)
+	}
+	return sql + "\n"
+}
+
+// sameColumnLabels returns true if the rebuilt projection has the same
+// columns, in the same order and with the same labels, as the original. A
+// column without a label, such as the result of COUNT(*), can have any label
+// in the rebuilt projection, since SQL can't leave a column unnamed.
+func sameColumnLabels(original, rebuilt opt.Projection) bool {
+	if len(original.Columns) != len(rebuilt.Columns) {
+		return false
+	}
+	for i, col := range original.Columns {
+		if col.Label != "" && col.Label != rebuilt.Columns[i].Label {
+			return false
+		}
+	}
+	return true
+}
+
+// formatError formats an error returned by the engine or the builder as the
+// output of a test, including its code.
+func formatError(err error) string {
+	if pgErr, ok := pgerror.GetPGCause(err); ok {
+		return fmt.Sprintf("error (%s): %s\n", pgErr.Code, pgErr.Message)
+	}
+	return fmt.Sprintf("error: %v\n", err)
+}
+
+// runRule constructs the expression in the test with only the rule named by
+// the test enabled, and returns the normalized expression. If the rule did not
+// change the expression, runRule returns "no match" instead.
+func runRule(t *testing.T, catalog cat.Catalog, d *testdata, coverage *ruleCoverage) string {
+	t.Helper()
+
+	if len(d.args) != 1 {
+		t.Fatalf("%s: expected rule name", d.pos)
+	}
+
+	rule := opt.LookupRuleName(d.args[0])
+	if rule == opt.UnknownRule {
+		t.Fatalf("%s: unknown rule %s", d.pos, d.args[0])
+	}
+
+	construct := func(enabled opt.RuleName) string {
+		p := opt.NewPlanner(catalog, math.MaxInt32)
+		for r := opt.RuleName(1); r < opt.NumRules; r++ {
+			if r != enabled {
+				p.Factory().DisableRule(r)
+			}
+		}
+		p.Factory().SetAppliedRule(func(rule opt.RuleName, source opt.Operator, target opt.GroupID) {
+			coverage.record(rule)
+		})
+		root, err := p.Factory().ParseExpr(d.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", d.pos, err)
+		}
+		e := p.NormalizedExpr(root)
+		return e.String()
+	}
+
+	before := construct(opt.UnknownRule)
+	after := construct(rule)
+	if before == after {
+		return "no match\n"
+	}
+	return after
+}
+
+// ruleCoverage counts the number of times that each normalization rule is
+// applied across all of the logic tests. A nil ruleCoverage ignores rules.
+type ruleCoverage struct {
+	counts [opt.NumRules]int
+}
+
+func (c *ruleCoverage) record(rule opt.RuleName) {
+	if c != nil {
+		c.counts[rule]++
+	}
+}
+
+// String returns a report that lists the number of times that each rule was
+// applied, followed by the rules that were never applied.
+func (c *ruleCoverage) String() string {
+	var applied, unapplied bytes.Buffer
+	var count int
+	for r := opt.RuleName(1); r < opt.NumRules; r++ {
+		if c.counts[r] == 0 {
+			fmt.Fprintf(&unapplied, "  %s\n", r)
+		} else {
+			fmt.Fprintf(&applied, "  %-32s %d\n", r, c.counts[r])
+			count++
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "rule coverage: %d of %d rules applied\n", count, opt.NumRules-1)
+	if applied.Len() != 0 {
+		fmt.Fprintf(&buf, "\napplied:\n%s", applied.String())
+	}
+	if unapplied.Len() != 0 {
+		fmt.Fprintf(&buf, "\nnever applied:\n%s", unapplied.String())
+	}
+	return buf.String()
+}
+
+// BenchmarkNormalize builds and normalizes a set of queries that exercise the
+// normalization rules, and reports the rules whose custom match functions are
+// the most expensive.
+func BenchmarkNormalize(b *testing.B) {
+	catalog := cat.NewCatalog()
+	for _, sql := range []string{
+		"CREATE TABLE a (x INT PRIMARY KEY, y INT)",
+		"CREATE TABLE b (x INT, z INT NOT NULL REFERENCES a (x))",
+	} {
+		stmt, err := parser.ParseOne(sql)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := exec.NewEngine(catalog).Execute(stmt); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	var stmts []tree.Statement
+	for _, sql := range []string{
+		"SELECT * FROM a WHERE 5 > x AND x > 1",
+		"SELECT * FROM a, b WHERE a.x = b.x AND a.y > 5 AND b.z < 10",
+		"SELECT * FROM a WHERE EXISTS (SELECT * FROM b WHERE a.x = b.x)",
+		"SELECT * FROM a WHERE NOT EXISTS (SELECT * FROM b WHERE a.x = b.x)",
+		"SELECT * FROM a WHERE (SELECT MAX(b.z) FROM b WHERE a.x = b.x) > 5",
+		"SELECT b.x FROM a JOIN b ON a.x = b.z",
+		"SELECT x, y, COUNT(*) FROM a GROUP BY x, y",
+	} {
+		stmt, err := parser.ParseOne(sql)
+		if err != nil {
+			b.Fatal(err)
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	var profile opt.RuleProfile
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, stmt := range stmts {
+			p := opt.NewPlanner(catalog, math.MaxInt32)
+			p.SetRuleProfile(&profile)
+			if _, _, err := build.NewBuilder(p.Factory(), stmt).Build(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.StopTimer()
+
+	b.Logf("\n%s", profile.Report(10))
+}
@@ -13,7 +13,15 @@ func (c *coster) computeCost(e *Expr) physicalCost {
 		switch e.Operator() {
 		case SortOp:
 			return c.computeSortCost(e)
+
+		case ArrangeOp:
+			return c.computeArrangeCost(e)
+
+		case ScanOp:
+			return c.computeScanCost(e)
 		}
+
+		return c.computeRelationalCost(e)
 	}
 
 	// By default, cost of parent is sum of child costs.
@@ -28,6 +36,26 @@ func (c *coster) computeArrangeCost(e *Expr) physicalCost {
 	return c.computeChildrenCost(e)
 }
 
+// computeScanCost assumes that the cost of a scan is proportional to the
+// number of rows in the table.
+func (c *coster) computeScanCost(e *Expr) physicalCost {
+	return physicalCost(e.Logical().Relational.Rows)
+}
+
+// computeRelationalCost assumes that the cost of a relational operator is
+// proportional to the number of rows that it reads from its relational
+// inputs, plus the cost of computing those inputs.
+func (c *coster) computeRelationalCost(e *Expr) physicalCost {
+	cost := c.computeChildrenCost(e)
+
+	for i := 0; i < e.ChildCount(); i++ {
+		logical := c.mem.lookupGroup(e.ChildGroup(i)).logical
+		cost += physicalCost(logical.Relational.Rows)
+	}
+
+	return cost
+}
+
 func (c *coster) computeChildrenCost(e *Expr) physicalCost {
 	var cost physicalCost
 
@@ -0,0 +1,149 @@
+package opt
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/util/treeprinter"
+)
+
+// ExplainFlags controls which properties Expr.Explain shows for each
+// relational expression. The output columns are always shown.
+type ExplainFlags int
+
+const (
+	// ExplainRows shows the estimated number of rows returned by each
+	// expression.
+	ExplainRows ExplainFlags = 1 << iota
+
+	// ExplainCost shows the estimated cost of each expression, including the
+	// cost of its inputs. The cost is only known once the expression has been
+	// optimized.
+	ExplainCost
+
+	// ExplainOrdering shows the ordering that is required of each expression,
+	// and the ordering that it provides.
+	ExplainOrdering
+
+	// ExplainKeys shows the keys and foreign keys of each expression.
+	ExplainKeys
+
+	// ExplainFuncDeps shows the equivalent columns and functional
+	// dependencies of each expression.
+	ExplainFuncDeps
+
+	// ExplainDefault is used when no flags are given.
+	ExplainDefault = ExplainRows | ExplainCost
+
+	// ExplainVerbose shows all properties.
+	ExplainVerbose = ExplainRows | ExplainCost | ExplainOrdering | ExplainKeys | ExplainFuncDeps
+)
+
+var explainFlagNames = map[string]ExplainFlags{
+	"rows":     ExplainRows,
+	"cost":     ExplainCost,
+	"ordering": ExplainOrdering,
+	"keys":     ExplainKeys,
+	"fd":       ExplainFuncDeps,
+	"verbose":  ExplainVerbose,
+}
+
+// ParseExplainFlags returns the flags with the given names, such as "rows" or
+// "verbose", in any case. If there are no names, it returns ExplainDefault.
+func ParseExplainFlags(names []string) (ExplainFlags, error) {
+	if len(names) == 0 {
+		return ExplainDefault, nil
+	}
+
+	var flags ExplainFlags
+	for _, name := range names {
+		flag, ok := explainFlagNames[strings.ToLower(name)]
+		if !ok {
+			return 0, pgerror.NewErrorf(pgerror.CodeSyntaxError, "unknown EXPLAIN option %s", name)
+		}
+		flags |= flag
+	}
+	return flags, nil
+}
+
+// Explain returns a tree of the expression like String, but with the
+// properties of each relational expression that are selected by flags.
+func (e *Expr) Explain(flags ExplainFlags) string {
+	tp := treeprinter.New()
+	e.explain(tp, flags)
+	return tp.String()
+}
+
+func (e *Expr) explain(tp treeprinter.Node, flags ExplainFlags) {
+	if e.IsScalar() {
+		// Scalar expressions are formatted the same way as in String, since
+		// their properties are mostly redundant. But their children can be
+		// relational, as in the case of a subquery.
+		tp = tp.Child(e.scalarString())
+		for i := 0; i < e.ChildCount(); i++ {
+			child := e.Child(i)
+			child.explain(tp, flags)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%v", e.op)
+
+	logicalProps := e.Logical()
+	var annotations []string
+	if flags&ExplainRows != 0 {
+		annotations = append(annotations, "rows="+formatEstimate(logicalProps.Relational.Rows))
+	}
+	if flags&ExplainCost != 0 {
+		best := e.mem.lookupGroup(e.loc.group).lookupBestExpr(e.required)
+		if best != nil && best.op != UnknownOp {
+			annotations = append(annotations, "cost="+formatEstimate(float64(best.cost)))
+		}
+	}
+	if !logicalProps.UnboundCols.Empty() {
+		annotations = append(annotations, fmt.Sprintf("unbound=%s", logicalProps.UnboundCols))
+	}
+	if len(annotations) != 0 {
+		fmt.Fprintf(&buf, " [%s]", strings.Join(annotations, ", "))
+	}
+
+	tp = tp.Child(buf.String())
+
+	e.formatColumns(tp)
+	if flags&ExplainKeys != 0 {
+		e.formatKeys(tp)
+	}
+	if flags&ExplainFuncDeps != 0 {
+		e.formatFuncDeps(tp)
+	}
+	if flags&ExplainOrdering != 0 {
+		required := e.mem.lookupPhysicalProps(e.required).Ordering
+		provided := e.mem.physPropsFactory.providedOrdering(e)
+		if required.Defined() || provided.Defined() {
+			tp.Childf("ordering: required=%s provided=%s",
+				formatOrdering(required), formatOrdering(provided))
+		}
+	}
+
+	for i := 0; i < e.ChildCount(); i++ {
+		child := e.Child(i)
+		child.explain(tp, flags)
+	}
+}
+
+// formatEstimate formats an estimated row count or cost with at most one
+// digit after the decimal point.
+func formatEstimate(f float64) string {
+	return strings.TrimSuffix(strconv.FormatFloat(f, 'f', 1, 64), ".0")
+}
+
+func formatOrdering(o Ordering) string {
+	if !o.Defined() {
+		return "-"
+	}
+	return o.String()
+}
@@ -1,9 +1,15 @@
 package opt
 
 import (
+	"fmt"
 	"math"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 )
 
+//go:generate optgen -out explorer.og.go -pkg opt -funcs explorer explorer ops/private.opt ops/scalar.opt ops/relational.opt ops/enforcer.opt explore/group_by.opt explore/semi_join.opt
+
 var fullyExploredPass = optimizePass{major: math.MaxInt16, minor: math.MaxInt16}
 
 // explorer ...
@@ -14,11 +20,17 @@ var fullyExploredPass = optimizePass{major: math.MaxInt16, minor: math.MaxInt16}
 type explorer struct {
 	mem     *memo
 	factory *Factory
+
+	// partialAggs caches the partial aggregations constructed for each
+	// aggregations group by partialAggregations, so that the synthesized
+	// partial aggregate columns are only allocated once.
+	partialAggs map[GroupID]GroupID
 }
 
 func (e *explorer) init(factory *Factory) {
 	e.mem = factory.mem
 	e.factory = factory
+	e.partialAggs = make(map[GroupID]GroupID)
 }
 
 func (e *explorer) exploreGroup(mgrp *memoGroup, pass optimizePass) (fullyExplored bool) {
@@ -31,31 +43,30 @@ func (e *explorer) exploreGroup(mgrp *memoGroup, pass optimizePass) (fullyExplor
 	mgrp.exploreCtx.start = mgrp.exploreCtx.end
 	mgrp.exploreCtx.end = exprID(len(mgrp.exprs))
 
-	// If this group has already been explored during this optimization pass,
-	// then it's not necessary to re-explore expressions that have already been
-	// explored this pass, because child groups will not have changed after the
-	// first iteration.
-	exprs := mgrp.exprs
-	if pass.minor > 1 {
-		exprs = exprs[mgrp.exploreCtx.start:]
-	}
-
+	// Expressions that were explored during a previous iteration only need to
+	// be matched against child expressions that have been added since then.
 	fullyExplored = true
-	for i := range exprs {
+	for i := 0; i < int(mgrp.exploreCtx.end); i++ {
 		if e.isExprFullyExplored(mgrp, i) {
 			continue
 		}
 
-		mexpr := &exprs[i]
+		loc := memoLoc{group: mgrp.id, expr: exprID(i)}
 		partlyExplored := i < int(mgrp.exploreCtx.start)
 
-		if e.exploreExpr(mexpr, pass, partlyExplored) {
+		if e.exploreExpr(loc, pass, partlyExplored) {
 			e.markExprAsFullyExplored(mgrp, i)
 		} else {
 			fullyExplored = false
 		}
 	}
 
+	// Expressions that were added to the group during exploration have not
+	// been explored yet.
+	if len(mgrp.exprs) != int(mgrp.exploreCtx.end) {
+		fullyExplored = false
+	}
+
 	if fullyExplored {
 		mgrp.exploreCtx.pass = fullyExploredPass
 		return true
@@ -65,72 +76,6 @@ func (e *explorer) exploreGroup(mgrp *memoGroup, pass optimizePass) (fullyExplor
 	return false
 }
 
-func (_e *explorer) exploreExpr(base *memoExpr, pass optimizePass, partlyExplored bool) (fullyExplored bool) {
-	switch base.op {
-	case InnerJoinOp:
-		return _e.exploreInnerJoin(base.asInnerJoin(), pass, partlyExplored)
-
-	default:
-		fatalf("unhandled op type: %s", base.op)
-		return false
-	}
-}
-
-func (_e *explorer) exploreInnerJoin(_root *innerJoinExpr, pass optimizePass, partlyExplored bool) (fullyExplored bool) {
-	fullyExplored = true
-
-	//_leftGroup := _e.mem.lookupGroup(_root.left)
-
-	//	[AssociateJoin]
-	//	(InnerJoin
-	//	    (InnerJoin $r:* $s:* $lowerFilter:*)
-	//	    $t:*
-	//	    $upperOn:* && (CanBeSplitByColUsage $upperFilter $s)
-	//  )
-	//	=>
-	//	($newLowerFilter, $newUpperFilter):(SplitByColUsage $upperFilter $s)
-	//	($newLowerFilter2, $newUpperFilter2):(SplitByColUsage $lowerFilter $s)
-	//	(InnerJoin
-	//	    (InnerJoin
-	//	        $r
-	//      	$t
-	//	        (Filters [$newLowerFilter $newLowerFilter2])
-	//      )
-	//	    $s
-	//	    (Filters [$newUpperFilter $newUpperFilter2])
-	//  )
-	/*	{
-		t := _root.right
-		upperFilter := _root.filter
-
-		if !_e.exploreGroup(_leftGroup, pass) {
-			fullyExplored = false
-		}
-
-		for _, _leftOffset := range _e.lookupExploreExprs(_leftGroup, partlyExplored) {
-			_innerJoinExpr2 := _e.mem.lookupExpr(_leftOffset).asInnerJoin()
-			if _innerJoinExpr2 != nil {
-				r := _innerJoinExpr2.left
-				s := _innerJoinExpr2.right
-				lowerFilter := _innerJoinExpr2.filter
-
-				if _e.canBeSplitByColUsage(upperFilter, s) {
-					newLowerFilter, newUpperFilter := _e.splitByColUsage(upperFilter, s)
-					newLowerFilter2, newUpperFilter2 := _e.splitByColUsage(lowerFilter, s)
-
-					_filter := _e.factory.ConstructFilters(newLowerFilter, newLowerFilter2)
-					_innerJoin := _e.factory.ConstructInnerJoin(r, t, _and)
-					_filter2 := _e.factory.ConstructFilters(newUpperFilter, newUpperFilter2)
-					_innerJoinExpr2 := innerJoinExpr{memoExpr: memoExpr{op: InnerJoinOp, group: _root.group}, left: _innerJoin, right: s, filter: _and2}
-					_e.mem.memoizeDenormExpr(_root.group, &_innerJoinExpr2)
-				}
-			}
-		}
-	}*/
-
-	return fullyExplored
-}
-
 func (e *explorer) isGroupExploredThisPass(mgrp *memoGroup, pass optimizePass) bool {
 	return !mgrp.exploreCtx.pass.Less(pass)
 }
@@ -162,3 +107,327 @@ func (e *explorer) canBeSplitByColUsage(filter GroupID, group GroupID) bool {
 func (e *explorer) splitByColUsage(filter GroupID, group GroupID) (GroupID, GroupID) {
 	return 0, 0
 }
+
+// variableCols returns the columns projected by the given projections
+// operator, and whether every projection is a simple variable reference.
+func (e *explorer) variableCols(projections GroupID) (ColSet, bool) {
+	projectionsExpr := e.mem.lookupNormExpr(projections).asProjections()
+	for _, item := range e.mem.lookupList(projectionsExpr.items()) {
+		if e.mem.lookupNormExpr(item).op != VariableOp {
+			return ColSet{}, false
+		}
+	}
+	return *e.mem.lookupColIndexes(projectionsExpr.cols()), true
+}
+
+// colProjections returns a projections operator that projects each of the
+// given columns as a simple variable reference.
+func (e *explorer) colProjections(cols ColSet) GroupID {
+	items := make([]GroupID, 0, cols.Len())
+	cols.ForEach(func(i int) {
+		items = append(items, e.factory.ConstructVariable(ColumnIndex(i)))
+	})
+
+	return e.factory.ConstructProjections(e.mem.storeList(items), &cols)
+}
+
+// pushedGroupingCols returns the grouping columns of a GroupBy operator that
+// has been pushed into the input side of a join. They are the grouping
+// columns that come from the input, plus the input columns that are
+// referenced by the join filter, since the join needs those columns.
+func (e *explorer) pushedGroupingCols(input, on, groupings GroupID) ColSet {
+	groupingCols, _ := e.variableCols(groupings)
+	inputCols := e.mem.lookupGroup(input).logical.Relational.OutputCols
+	onCols := e.mem.lookupGroup(on).logical.UnboundCols
+	return groupingCols.Union(onCols).Intersection(inputCols)
+}
+
+// pushedGroupings constructs the groupings of a GroupBy operator that has been
+// pushed into the input side of a join. See pushedGroupingCols.
+func (e *explorer) pushedGroupings(input, on, groupings GroupID) GroupID {
+	return e.colProjections(e.pushedGroupingCols(input, on, groupings))
+}
+
+// canPushGroupByCommon returns true if a GroupBy operator over the given join
+// can be pushed into the input side of the join, either completely or
+// partially. This is possible when:
+//
+//   1. The grouping columns are simple variable references, and there is at
+//      least one of them. A scalar GroupBy returns a row even if its input is
+//      empty, so it cannot be pushed below a join.
+//   2. The aggregates only reference columns from the input side.
+//   3. The pushed grouping columns determine a key of the other side, so that
+//      each input row matches at most one row from the other side. Otherwise,
+//      the aggregates would be computed over too few rows.
+//   4. The input is not already unique on the pushed grouping columns, since
+//      pushing the GroupBy would not reduce the number of rows.
+func (e *explorer) canPushGroupByCommon(join, input, other, on, groupings, aggregations GroupID) bool {
+	groupingCols, ok := e.variableCols(groupings)
+	if !ok || groupingCols.Empty() {
+		return false
+	}
+
+	inputProps := e.mem.lookupGroup(input).logical
+	inputCols := inputProps.Relational.OutputCols
+	if !e.mem.lookupGroup(aggregations).logical.UnboundCols.SubsetOf(inputCols) {
+		return false
+	}
+
+	pushedCols := e.pushedGroupingCols(input, on, groupings)
+	joinFDs := e.mem.lookupGroup(join).logical.Relational.FuncDeps
+	otherProps := e.mem.lookupGroup(other).logical
+	otherCols := joinFDs.closure(pushedCols).Intersection(otherProps.Relational.OutputCols)
+	if !otherProps.hasStrongKey(otherCols) {
+		return false
+	}
+
+	inputClosure := inputProps.Relational.FuncDeps.closure(pushedCols)
+	for _, key := range inputProps.Relational.WeakKeys {
+		if key.SubsetOf(inputClosure) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isCompletePushDown returns true if the grouping columns of a GroupBy over
+// the given join and the pushed grouping columns determine one another. In
+// that case, each group of the pushed GroupBy corresponds to exactly one
+// group of the original GroupBy, and no aggregation is needed above the join.
+func (e *explorer) isCompletePushDown(join, input, on, groupings GroupID) bool {
+	groupingCols, _ := e.variableCols(groupings)
+	pushedCols := e.pushedGroupingCols(input, on, groupings)
+	joinFDs := e.mem.lookupGroup(join).logical.Relational.FuncDeps
+	return pushedCols.SubsetOf(joinFDs.closure(groupingCols)) &&
+		groupingCols.SubsetOf(joinFDs.closure(pushedCols))
+}
+
+// canPushGroupBy returns true if a GroupBy operator over the given join can be
+// completely pushed into the input side of the join. See canPushGroupByCommon
+// and isCompletePushDown.
+func (e *explorer) canPushGroupBy(join, input, other, on, groupings, aggregations GroupID) bool {
+	return e.canPushGroupByCommon(join, input, other, on, groupings, aggregations) &&
+		e.isCompletePushDown(join, input, on, groupings)
+}
+
+// canPushPartialGroupBy returns true if a partial aggregation can be pushed
+// into the input side of the given join, with a final aggregation above the
+// join combining the partial results. This requires all of the aggregates to
+// be decomposable. Partial aggregation is not used when a complete push down
+// is possible, or when the grouping columns contain a key of the other side.
+// In the latter case, each final group would combine at most the partial
+// groups of a single row from the other side, and skipping it prevents the
+// rule from firing again on the GroupBy generated by PullGroupByAboveJoin.
+func (e *explorer) canPushPartialGroupBy(join, input, other, on, groupings, aggregations GroupID) bool {
+	if !e.canPushGroupByCommon(join, input, other, on, groupings, aggregations) {
+		return false
+	}
+
+	if e.isCompletePushDown(join, input, on, groupings) {
+		return false
+	}
+
+	groupingCols, _ := e.variableCols(groupings)
+	otherProps := e.mem.lookupGroup(other).logical
+	if otherProps.hasStrongKey(groupingCols.Intersection(otherProps.Relational.OutputCols)) {
+		return false
+	}
+
+	aggregationsExpr := e.mem.lookupNormExpr(aggregations).asProjections()
+	for _, item := range e.mem.lookupList(aggregationsExpr.items()) {
+		if _, _, ok := e.decomposeAggregate(item); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// groupByColProjections constructs a projections operator that projects the
+// output columns of a GroupBy operator with the given groupings and
+// aggregations.
+func (e *explorer) groupByColProjections(groupings, aggregations GroupID) GroupID {
+	groupingsExpr := e.mem.lookupNormExpr(groupings).asProjections()
+	groupingCols := *e.mem.lookupColIndexes(groupingsExpr.cols())
+	aggregationsExpr := e.mem.lookupNormExpr(aggregations).asProjections()
+	aggregationCols := *e.mem.lookupColIndexes(aggregationsExpr.cols())
+	return e.colProjections(groupingCols.Union(aggregationCols))
+}
+
+// decomposeAggregate returns the names of the partial and final aggregate
+// functions that together compute the given aggregate. The partial aggregate
+// is computed over subsets of a group's rows, and the final aggregate combines
+// the partial results. For example, MIN is computed as the MIN of partial
+// MINs. The last return value is false if the aggregate is not decomposable,
+// as is the case for AVG. The ConstAgg aggregate is decomposed into two
+// ConstAgg aggregates, which is indicated by empty names.
+//
+// TODO(andy): COUNT is the SUM of partial COUNTs, but that SUM is a decimal
+// rather than an integer. Decompose COUNT once there is a cast operator to
+// convert the SUM back to an integer.
+func (e *explorer) decomposeAggregate(agg GroupID) (partial, final string, ok bool) {
+	aggExpr := e.mem.lookupNormExpr(agg)
+	switch aggExpr.op {
+	case ConstAggOp:
+		return "", "", true
+
+	case FunctionOp:
+		functionExpr := aggExpr.asFunction()
+		def := e.mem.lookupFuncDef(functionExpr.def())
+		name := strings.ToLower(def.Name)
+		switch name {
+		case "min", "max", "sum":
+			return name, name, true
+		}
+	}
+
+	return "", "", false
+}
+
+// partialAggregations constructs the aggregations of a partial GroupBy that
+// has been pushed below a join. Each aggregate is replaced by its partial
+// aggregate, which produces a new column. The partial aggregations are cached,
+// since finalAggregations refers to their columns.
+func (e *explorer) partialAggregations(aggregations GroupID) GroupID {
+	if partial, ok := e.partialAggs[aggregations]; ok {
+		return partial
+	}
+
+	aggregationsExpr := e.mem.lookupNormExpr(aggregations).asProjections()
+	aggregationsItems := e.mem.lookupList(aggregationsExpr.items())
+
+	var outputCols ColSet
+	items := make([]GroupID, len(aggregationsItems))
+	for i, item := range aggregationsItems {
+		name, _, _ := e.decomposeAggregate(item)
+		itemExpr := e.mem.lookupNormExpr(item)
+		if itemExpr.op == ConstAggOp {
+			items[i] = item
+			name = "const_agg"
+		} else {
+			args := itemExpr.asFunction().args()
+			items[i] = e.factory.ConstructFunction(args, tree.FunDefs[name])
+		}
+
+		col := e.mem.metadata.AddColumn(fmt.Sprintf("%s_partial", name))
+		outputCols.Add(int(col))
+	}
+
+	partial := e.factory.ConstructProjections(e.mem.storeList(items), &outputCols)
+	e.partialAggs[aggregations] = partial
+	return partial
+}
+
+// finalAggregations constructs the aggregations of the GroupBy that combines
+// the results of the partial GroupBy constructed by partialAggregations. Each
+// final aggregate is applied to the column of the corresponding partial
+// aggregate, and produces the same column as the original aggregate.
+func (e *explorer) finalAggregations(aggregations GroupID) GroupID {
+	aggregationsExpr := e.mem.lookupNormExpr(aggregations).asProjections()
+	aggregationsItems := e.mem.lookupList(aggregationsExpr.items())
+
+	partialExpr := e.mem.lookupNormExpr(e.partialAggregations(aggregations)).asProjections()
+	partialCols := e.mem.lookupColIndexes(partialExpr.cols()).Ordered()
+
+	items := make([]GroupID, len(aggregationsItems))
+	for i, item := range aggregationsItems {
+		variable := e.factory.ConstructVariable(ColumnIndex(partialCols[i]))
+
+		_, name, _ := e.decomposeAggregate(item)
+		if name == "" {
+			items[i] = e.factory.ConstructConstAgg(variable)
+		} else {
+			args := e.mem.storeList([]GroupID{variable})
+			items[i] = e.factory.ConstructFunction(args, tree.FunDefs[name])
+		}
+	}
+
+	return e.factory.constructProjections(e.mem.storeList(items), aggregationsExpr.cols())
+}
+
+// canPullGroupBy returns true if a GroupBy operator with the given groupings
+// can be pulled above a join with the other side. This is possible when:
+//
+//   1. The grouping columns are simple variable references, and there is at
+//      least one of them.
+//   2. The other side has a key, so that grouping on the original grouping
+//      columns plus all columns of the other side does not combine the groups
+//      of distinct rows from the other side.
+//   3. The join filter does not reference any aggregate columns, so that it
+//      can be evaluated before the aggregation.
+func (e *explorer) canPullGroupBy(groupings, other, on GroupID) bool {
+	groupingCols, ok := e.variableCols(groupings)
+	if !ok || groupingCols.Empty() {
+		return false
+	}
+
+	otherProps := e.mem.lookupGroup(other).logical
+	otherCols := otherProps.Relational.OutputCols
+	if !otherProps.hasStrongKey(otherCols) {
+		return false
+	}
+
+	onCols := e.mem.lookupGroup(on).logical.UnboundCols
+	return onCols.SubsetOf(groupingCols.Union(otherCols))
+}
+
+// pulledGroupings constructs the groupings of a GroupBy operator that has been
+// pulled above a join. The groupings include all columns of the other side of
+// the join, so that they are passed through the GroupBy.
+func (e *explorer) pulledGroupings(groupings, other GroupID) GroupID {
+	groupingCols, _ := e.variableCols(groupings)
+	otherCols := e.mem.lookupGroup(other).logical.Relational.OutputCols
+	return e.colProjections(groupingCols.Union(otherCols))
+}
+
+// columnProjections returns a projections operator that projects each output
+// column of the given group as a simple variable reference.
+func (e *explorer) columnProjections(group GroupID) GroupID {
+	return e.colProjections(e.mem.lookupGroup(group).logical.Relational.OutputCols)
+}
+
+// emptyProjections returns a projections operator without any projections,
+// such as the aggregations of a DISTINCT operator.
+func (e *explorer) emptyProjections() GroupID {
+	return e.colProjections(ColSet{})
+}
+
+// hasStrongKey returns true if the given group has a key, so that no two of
+// its rows are equal.
+func (e *explorer) hasStrongKey(group GroupID) bool {
+	props := e.mem.lookupGroup(group).logical
+	return props.hasStrongKey(props.Relational.OutputCols)
+}
+
+// isKeyedOnJoinCols returns true if the join filter equates the columns of a
+// key of the right input with columns of the left input, so that each left
+// row matches at most one right row.
+func (e *explorer) isKeyedOnJoinCols(left, right, on GroupID) bool {
+	return e.mem.logPropsFactory.isKeyedOnJoinCols(left, right, on)
+}
+
+// notNullJoinCols returns the columns of the right input of a join that are
+// referenced by the join filter and are never NULL in the right input.
+func (e *explorer) notNullJoinCols(right, on GroupID) ColSet {
+	rightProps := e.mem.lookupGroup(right).logical
+	onCols := e.mem.lookupGroup(on).logical.UnboundCols
+	return onCols.Intersection(rightProps.Relational.OutputCols).Intersection(rightProps.Relational.NotNullCols)
+}
+
+// hasNotNullJoinCol returns true if the join filter references at least one
+// column of the right input that is never NULL in the right input.
+func (e *explorer) hasNotNullJoinCol(right, on GroupID) bool {
+	return !e.notNullJoinCols(right, on).Empty()
+}
+
+// isNullFilter constructs a filter that tests whether the lowest NOT NULL
+// column of the right input that is referenced by the join filter is NULL.
+// After a left join, the column is only NULL in rows for which the left row
+// had no match.
+func (e *explorer) isNullFilter(right, on GroupID) GroupID {
+	col, _ := e.notNullJoinCols(right, on).Next(0)
+	variable := e.factory.ConstructVariable(ColumnIndex(col))
+	null := e.factory.ConstructConst(tree.DNull)
+	isNull := e.factory.ConstructIs(variable, null)
+	return e.factory.ConstructFilters(e.mem.storeList([]GroupID{isNull}))
+}
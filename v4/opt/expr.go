@@ -1,6 +1,6 @@
 package opt
 
-//go:generate optgen -out expr.og.go -pkg opt exprs ops/scalar.opt ops/relational.opt ops/enforcer.opt
+//go:generate optgen -out expr.og.go -pkg opt exprs ops/private.opt ops/scalar.opt ops/relational.opt ops/enforcer.opt
 
 import (
 	"bytes"
@@ -56,7 +56,15 @@ func (e *Expr) Child(nth int) Expr {
 		return makeExpr(e.mem, group, defaultPhysPropsID)
 	}
 
+	// The children of an optimized expression are the lowest cost expressions
+	// in their groups, even if no physical properties are required of them.
+	// For example, the lowest cost expression in the input group of an
+	// Arrange enforcer may not be the normalized expression.
 	required := e.mem.physPropsFactory.constructChildProps(e, nth)
+	best := e.mem.lookupGroup(group).lookupBestExpr(required)
+	if best != nil && best.op != UnknownOp {
+		return Expr{mem: e.mem, loc: best.loc, op: best.op, required: required}
+	}
 	return makeExpr(e.mem, group, required)
 }
 
@@ -64,6 +72,9 @@ func (e *Expr) ChildGroup(nth int) GroupID {
 	return childGroupLookup[e.op](e, nth)
 }
 
+// Private returns the private field of the expression, or nil if it has none.
+// Callers that know the operator should use the strongly-typed accessor for
+// its private field instead, such as TablePrivate.
 func (e *Expr) Private() interface{} {
 	return e.mem.lookupPrivate(privateLookup[e.op](e))
 }
@@ -95,6 +106,16 @@ func (e *Expr) format(tp treeprinter.Node) {
 }
 
 func (e *Expr) formatScalar(tp treeprinter.Node) {
+	tp = tp.Child(e.scalarString())
+	for i := 0; i < e.ChildCount(); i++ {
+		child := e.Child(i)
+		child.format(tp)
+	}
+}
+
+// scalarString returns the operator and private field of a scalar expression,
+// along with its unbound columns.
+func (e *Expr) scalarString() string {
 	var buf bytes.Buffer
 
 	fmt.Fprintf(&buf, "%v", e.op)
@@ -111,11 +132,7 @@ func (e *Expr) formatScalar(tp treeprinter.Node) {
 		buf.WriteString("]")
 	}
 
-	tp = tp.Child(buf.String())
-	for i := 0; i < e.ChildCount(); i++ {
-		child := e.Child(i)
-		child.format(tp)
-	}
+	return buf.String()
 }
 
 func (e *Expr) formatPrivate(buf *bytes.Buffer, private interface{}) {
@@ -147,7 +164,28 @@ func (e *Expr) formatRelational(tp treeprinter.Node) {
 
 	tp = tp.Child(buf.String())
 
-	buf.Reset()
+	e.formatColumns(tp)
+	e.formatKeys(tp)
+	e.formatFuncDeps(tp)
+
+	if requiredProps.Ordering.Defined() {
+		tp.Childf("ordering: %s", requiredProps.Ordering.String())
+	}
+
+	for i := 0; i < e.ChildCount(); i++ {
+		child := e.Child(i)
+		child.format(tp)
+	}
+}
+
+// formatColumns writes the output columns of a relational expression, in the
+// required order and with the required names if there is a required
+// projection.
+func (e *Expr) formatColumns(tp treeprinter.Node) {
+	var buf bytes.Buffer
+
+	logicalProps := e.Logical()
+	requiredProps := e.mem.lookupPhysicalProps(e.required)
 
 	// Write the output columns.
 	if requiredProps.Projection.Defined() {
@@ -170,8 +208,12 @@ func (e *Expr) formatRelational(tp treeprinter.Node) {
 			tp.Child(buf.String())
 		}
 	}
+}
+
+// formatKeys writes the keys and foreign keys of a relational expression.
+func (e *Expr) formatKeys(tp treeprinter.Node) {
+	logicalProps := e.Logical()
 
-	// Write keys.
 	for _, key := range logicalProps.Relational.WeakKeys {
 		var prefix string
 		if !key.SubsetOf(logicalProps.Relational.NotNullCols) {
@@ -183,10 +225,15 @@ func (e *Expr) formatRelational(tp treeprinter.Node) {
 	for _, fkey := range logicalProps.Relational.ForeignKeys {
 		tp.Childf("foreign key: %s -> %s", fkey.src, fkey.dest)
 	}
+}
+
+// formatFuncDeps writes the equivalent columns and functional dependencies of
+// a relational expression.
+func (e *Expr) formatFuncDeps(tp treeprinter.Node) {
+	logicalProps := e.Logical()
 
-	// Write equivalent columns.
 	if len(logicalProps.Relational.EquivCols) > 0 {
-		buf.Reset()
+		var buf bytes.Buffer
 		buf.WriteString("equiv:")
 		for _, equiv := range logicalProps.Relational.EquivCols {
 			fmt.Fprintf(&buf, " %s", equiv)
@@ -194,13 +241,8 @@ func (e *Expr) formatRelational(tp treeprinter.Node) {
 		tp.Child(buf.String())
 	}
 
-	if requiredProps.Ordering.Defined() {
-		tp.Childf("ordering: %s", requiredProps.Ordering.String())
-	}
-
-	for i := 0; i < e.ChildCount(); i++ {
-		child := e.Child(i)
-		child.format(tp)
+	if len(logicalProps.Relational.FuncDeps) > 0 {
+		tp.Childf("fd: %s", logicalProps.Relational.FuncDeps)
 	}
 }
 
@@ -2,6 +2,10 @@
 
 package opt
 
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+)
+
 type childCountLookupFunc func(e *Expr) int
 
 var childCountLookup = []childCountLookupFunc{
@@ -291,6 +295,11 @@ var childCountLookup = []childCountLookupFunc{
 		return 0 + int(functionExpr.args().len)
 	},
 
+	// ConstAggOp
+	func(e *Expr) int {
+		return 1
+	},
+
 	// TrueOp
 	func(e *Expr) int {
 		return 0
@@ -1136,6 +1145,18 @@ var childGroupLookup = []childGroupLookupFunc{
 		}
 	},
 
+	// ConstAggOp
+	func(e *Expr, n int) GroupID {
+		constAggExpr := (*constAggExpr)(e.mem.lookupExpr(e.loc))
+
+		switch n {
+		case 0:
+			return constAggExpr.input()
+		default:
+			panic("child index out of range")
+		}
+	},
+
 	// TrueOp
 	func(e *Expr, n int) GroupID {
 		panic("child index out of range")
@@ -1747,6 +1768,11 @@ var privateLookup = []privateLookupFunc{
 		return functionExpr.def()
 	},
 
+	// ConstAggOp
+	func(e *Expr) PrivateID {
+		return 0
+	},
+
 	// TrueOp
 	func(e *Expr) PrivateID {
 		return 0
@@ -1871,6 +1897,126 @@ var privateLookup = []privateLookupFunc{
 	},
 }
 
+// ColIndexPrivate returns the private field of a Variable expression.
+func (e *Expr) ColIndexPrivate() ColumnIndex {
+	return e.mem.lookupColIndex(e.privateID())
+}
+
+// DatumPrivate returns the private field of a Const expression.
+func (e *Expr) DatumPrivate() tree.Datum {
+	return e.mem.lookupDatum(e.privateID())
+}
+
+// TypedExprPrivate returns the private field of a Placeholder expression.
+func (e *Expr) TypedExprPrivate() tree.TypedExpr {
+	return e.mem.lookupTypedExpr(e.privateID())
+}
+
+// ColIndexesPrivate returns the private field of a Projections or Values expression.
+func (e *Expr) ColIndexesPrivate() *ColSet {
+	return e.mem.lookupColIndexes(e.privateID())
+}
+
+// FuncDefPrivate returns the private field of a Function expression.
+func (e *Expr) FuncDefPrivate() *tree.FunctionDefinition {
+	return e.mem.lookupFuncDef(e.privateID())
+}
+
+// TablePrivate returns the private field of a Scan expression.
+func (e *Expr) TablePrivate() TableIndex {
+	return e.mem.lookupTable(e.privateID())
+}
+
+// ColMapPrivate returns the private field of a Union expression.
+func (e *Expr) ColMapPrivate() *ColMap {
+	return e.mem.lookupColMap(e.privateID())
+}
+
+var stateLayoutLookup = []stateLayout{
+	{}, // UnknownOp
+
+	{exprs: 2, relational: []bool{true, false}}, // SubqueryOp
+	{private: "ColIndex"},                       // VariableOp
+	{private: "Datum"},                          // ConstOp
+	{private: "TypedExpr"},                      // PlaceholderOp
+	{list: true},                                // ListOp
+	{list: true},                                // OrderedListOp
+	{list: true},                                // TupleOp
+	{list: true},                                // FiltersOp
+	{list: true, private: "ColIndexes"},         // ProjectionsOp
+	{exprs: 1, relational: []bool{true}},        // ExistsOp
+	{exprs: 2},                                  // AndOp
+	{exprs: 2},                                  // OrOp
+	{exprs: 1},                                  // NotOp
+	{exprs: 2},                                  // EqOp
+	{exprs: 2},                                  // LtOp
+	{exprs: 2},                                  // GtOp
+	{exprs: 2},                                  // LeOp
+	{exprs: 2},                                  // GeOp
+	{exprs: 2},                                  // NeOp
+	{exprs: 2},                                  // InOp
+	{exprs: 2},                                  // NotInOp
+	{exprs: 2},                                  // LikeOp
+	{exprs: 2},                                  // NotLikeOp
+	{exprs: 2},                                  // ILikeOp
+	{exprs: 2},                                  // NotILikeOp
+	{exprs: 2},                                  // SimilarToOp
+	{exprs: 2},                                  // NotSimilarToOp
+	{exprs: 2},                                  // RegMatchOp
+	{exprs: 2},                                  // NotRegMatchOp
+	{exprs: 2},                                  // RegIMatchOp
+	{exprs: 2},                                  // NotRegIMatchOp
+	{exprs: 2},                                  // IsDistinctFromOp
+	{exprs: 2},                                  // IsNotDistinctFromOp
+	{exprs: 2},                                  // IsOp
+	{exprs: 2},                                  // IsNotOp
+	{exprs: 2},                                  // AnyOp
+	{exprs: 2},                                  // SomeOp
+	{exprs: 2},                                  // AllOp
+	{exprs: 2},                                  // BitandOp
+	{exprs: 2},                                  // BitorOp
+	{exprs: 2},                                  // BitxorOp
+	{exprs: 2},                                  // PlusOp
+	{exprs: 2},                                  // MinusOp
+	{exprs: 2},                                  // MultOp
+	{exprs: 2},                                  // DivOp
+	{exprs: 2},                                  // FloorDivOp
+	{exprs: 2},                                  // ModOp
+	{exprs: 2},                                  // PowOp
+	{exprs: 2},                                  // ConcatOp
+	{exprs: 2},                                  // LShiftOp
+	{exprs: 2},                                  // RShiftOp
+	{exprs: 1},                                  // UnaryPlusOp
+	{exprs: 1},                                  // UnaryMinusOp
+	{exprs: 1},                                  // UnaryComplementOp
+	{list: true, private: "FuncDef"},            // FunctionOp
+	{exprs: 1},                                  // ConstAggOp
+	{},                                          // TrueOp
+	{},                                          // FalseOp
+	{private: "Table"},                          // ScanOp
+	{list: true, private: "ColIndexes"},         // ValuesOp
+	{exprs: 2, relational: []bool{true, false}},                   // SelectOp
+	{exprs: 2, relational: []bool{true, false}},                   // ProjectOp
+	{exprs: 3, relational: []bool{true, true, false}},             // InnerJoinOp
+	{exprs: 3, relational: []bool{true, true, false}},             // LeftJoinOp
+	{exprs: 3, relational: []bool{true, true, false}},             // RightJoinOp
+	{exprs: 3, relational: []bool{true, true, false}},             // FullJoinOp
+	{exprs: 3, relational: []bool{true, true, false}},             // SemiJoinOp
+	{exprs: 3, relational: []bool{true, true, false}},             // AntiJoinOp
+	{exprs: 3, relational: []bool{true, true, false}},             // InnerJoinApplyOp
+	{exprs: 3, relational: []bool{true, true, false}},             // LeftJoinApplyOp
+	{exprs: 3, relational: []bool{true, true, false}},             // RightJoinApplyOp
+	{exprs: 3, relational: []bool{true, true, false}},             // FullJoinApplyOp
+	{exprs: 3, relational: []bool{true, true, false}},             // SemiJoinApplyOp
+	{exprs: 3, relational: []bool{true, true, false}},             // AntiJoinApplyOp
+	{exprs: 3, relational: []bool{true, false, false}},            // GroupByOp
+	{exprs: 2, relational: []bool{true, true}, private: "ColMap"}, // UnionOp
+	{exprs: 2, relational: []bool{true, true}},                    // IntersectOp
+	{exprs: 2, relational: []bool{true, true}},                    // ExceptOp
+	{exprs: 1, relational: []bool{true}},                          // SortOp
+	{exprs: 1, relational: []bool{true}},                          // ArrangeOp
+}
+
 var isScalarLookup = []bool{
 	false, // UnknownOp
 
@@ -1929,6 +2075,7 @@ var isScalarLookup = []bool{
 	true,  // UnaryMinusOp
 	true,  // UnaryComplementOp
 	true,  // FunctionOp
+	true,  // ConstAggOp
 	true,  // TrueOp
 	true,  // FalseOp
 	false, // ScanOp
@@ -2013,6 +2160,7 @@ var isRelationalLookup = []bool{
 	false, // UnaryMinusOp
 	false, // UnaryComplementOp
 	false, // FunctionOp
+	false, // ConstAggOp
 	false, // TrueOp
 	false, // FalseOp
 	true,  // ScanOp
@@ -2097,6 +2245,7 @@ var isJoinLookup = []bool{
 	false, // UnaryMinusOp
 	false, // UnaryComplementOp
 	false, // FunctionOp
+	false, // ConstAggOp
 	false, // TrueOp
 	false, // FalseOp
 	false, // ScanOp
@@ -2181,6 +2330,7 @@ var isJoinApplyLookup = []bool{
 	false, // UnaryMinusOp
 	false, // UnaryComplementOp
 	false, // FunctionOp
+	false, // ConstAggOp
 	false, // TrueOp
 	false, // FalseOp
 	false, // ScanOp
@@ -2265,6 +2415,7 @@ var isEnforcerLookup = []bool{
 	false, // UnaryMinusOp
 	false, // UnaryComplementOp
 	false, // FunctionOp
+	false, // ConstAggOp
 	false, // TrueOp
 	false, // FalseOp
 	false, // ScanOp
@@ -3638,6 +3789,27 @@ func (m *memoExpr) asFunction() *functionExpr {
 	return (*functionExpr)(m)
 }
 
+type constAggExpr memoExpr
+
+func makeConstAggExpr(input GroupID) constAggExpr {
+	return constAggExpr{op: ConstAggOp, state: exprState{uint32(input)}}
+}
+
+func (e *constAggExpr) input() GroupID {
+	return GroupID(e.state[0])
+}
+
+func (e *constAggExpr) fingerprint() fingerprint {
+	return fingerprint(*e)
+}
+
+func (m *memoExpr) asConstAgg() *constAggExpr {
+	if m.op != ConstAggOp {
+		return nil
+	}
+	return (*constAggExpr)(m)
+}
+
 type trueExpr memoExpr
 
 func makeTrueExpr() trueExpr {
@@ -0,0 +1,259 @@
+package opt
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+// exprParser parses expressions that are written in an s-expression syntax
+// that mirrors the optgen source, and constructs them using the factory. For
+// example:
+//
+//   (Select
+//     (Scan a)
+//     (Filters [(Eq (Variable a.x) (Const 1))])
+//   )
+//
+// Each expression names its operator, followed by its fields in the order in
+// which they're defined. List fields are written in brackets. Private fields
+// are written according to the operator:
+//
+//   Scan         the name of a catalog table, which is added to the metadata
+//   Variable     the label of a column, such as a.x
+//   Const        an integer, a float, a 'string', true, false, or null
+//   Projections  a set of column labels in braces, such as {a.x y}; labels
+//   Values       that don't match an existing column create a new column
+//
+// Column labels refer to the most recently added column with that label, so
+// if a table is scanned more than once, its columns refer to the last scan.
+type exprParser struct {
+	f   *Factory
+	s   string
+	pos int
+
+	// tok is the current token, or the empty string at the end of the input.
+	tok string
+}
+
+// parseError is used to unwind the parser when it encounters an error.
+type parseError struct {
+	err error
+}
+
+// ParseExpr parses an expression in the syntax that's described by exprParser,
+// and constructs it using the factory. Normalization rules are applied as each
+// expression is constructed, just as if it had been built from SQL.
+func (f *Factory) ParseExpr(input string) (group GroupID, err error) {
+	p := exprParser{f: f, s: input}
+
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			err = perr.err
+		}
+	}()
+
+	p.next()
+	group = p.parseExpr()
+	if p.tok != "" {
+		p.errorf("unexpected %q after expression", p.tok)
+	}
+	return group, nil
+}
+
+func (p *exprParser) parseExpr() GroupID {
+	p.expect("(")
+
+	op := p.lookupOperator(p.tok)
+	p.next()
+
+	var children []GroupID
+	var private PrivateID
+
+	for p.tok != ")" {
+		switch p.tok {
+		case "":
+			p.errorf("unexpected end of input in %s", op)
+
+		case "(":
+			children = append(children, p.parseExpr())
+
+		case "[":
+			p.next()
+			for p.tok != "]" {
+				children = append(children, p.parseExpr())
+			}
+			p.next()
+
+		default:
+			if private != 0 {
+				p.errorf("%s has more than one private field", op)
+			}
+			private = p.parsePrivate(op)
+		}
+	}
+	p.next()
+
+	return p.f.DynamicConstruct(op, children, private)
+}
+
+func (p *exprParser) parsePrivate(op Operator) PrivateID {
+	md := p.f.Metadata()
+
+	switch op {
+	case ScanOp:
+		name := p.tok
+		p.next()
+		tbl, err := md.Catalog().Table(cat.MakeTableName(name))
+		if err != nil {
+			panic(parseError{err: err})
+		}
+		return p.f.mem.internTable(md.AddTable(tbl))
+
+	case VariableOp:
+		label := p.tok
+		p.next()
+
+		col, ok := p.lookupColumn(label)
+		if !ok {
+			p.errorf("unknown column %s", label)
+		}
+		return p.f.mem.internColIndex(col)
+
+	case ConstOp:
+		return p.f.mem.internDatum(p.parseDatum())
+
+	case ProjectionsOp, ValuesOp:
+		var cols ColSet
+		p.expect("{")
+		for p.tok != "}" {
+			if p.tok == "" {
+				p.errorf("unexpected end of input in column set")
+			}
+
+			col, ok := p.lookupColumn(p.tok)
+			if !ok {
+				col = md.AddColumn(p.tok)
+			}
+			cols.Add(int(col))
+			p.next()
+		}
+		p.next()
+		return p.f.mem.internColIndexes(&cols)
+	}
+
+	p.errorf("cannot parse private field of %s", op)
+	return 0
+}
+
+func (p *exprParser) parseDatum() tree.Datum {
+	tok := p.tok
+	p.next()
+
+	switch {
+	case strings.HasPrefix(tok, "'"):
+		return tree.NewDString(tok[1 : len(tok)-1])
+
+	case tok == "true":
+		return tree.DBoolTrue
+
+	case tok == "false":
+		return tree.DBoolFalse
+
+	case tok == "null":
+		return tree.DNull
+	}
+
+	if i, err := strconv.ParseInt(tok, 10, 64); err == nil {
+		return tree.NewDInt(tree.DInt(i))
+	}
+	if f, err := strconv.ParseFloat(tok, 64); err == nil {
+		return tree.NewDFloat(tree.DFloat(f))
+	}
+
+	p.errorf("invalid constant %s", tok)
+	return nil
+}
+
+// lookupOperator returns the operator that has the given name, which is
+// written as it is in the optgen source, such as InnerJoin.
+func (p *exprParser) lookupOperator(name string) Operator {
+	// Operator strings are written in "dash case", such as inner-join.
+	var buf bytes.Buffer
+	for i, ch := range name {
+		if unicode.IsUpper(ch) {
+			if i != 0 {
+				buf.WriteByte('-')
+			}
+			ch = unicode.ToLower(ch)
+		}
+		buf.WriteRune(ch)
+	}
+
+	op := lookupOperatorName(buf.String())
+	if op == UnknownOp {
+		p.errorf("unknown operator %q", name)
+	}
+	return op
+}
+
+// lookupColumn returns the most recently added column with the given label.
+func (p *exprParser) lookupColumn(label string) (ColumnIndex, bool) {
+	md := p.f.Metadata()
+	for col := md.nextCol; col > 0; col-- {
+		if md.ColumnLabel(col) == label {
+			return col, true
+		}
+	}
+	return 0, false
+}
+
+func (p *exprParser) expect(tok string) {
+	if p.tok != tok {
+		p.errorf("expected %q, found %q", tok, p.tok)
+	}
+	p.next()
+}
+
+// next scans the next token, which is either a single delimiter character, a
+// quoted string, or a run of other non-whitespace characters.
+func (p *exprParser) next() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+
+	start := p.pos
+	if p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '(', ')', '[', ']', '{', '}':
+			p.pos++
+
+		case '\'':
+			end := strings.IndexByte(p.s[p.pos+1:], '\'')
+			if end == -1 {
+				p.errorf("unterminated string")
+			}
+			p.pos += end + 2
+
+		default:
+			for p.pos < len(p.s) && !unicode.IsSpace(rune(p.s[p.pos])) && !strings.ContainsRune("()[]{}'", rune(p.s[p.pos])) {
+				p.pos++
+			}
+		}
+	}
+
+	p.tok = p.s[start:p.pos]
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) {
+	panic(parseError{err: fmt.Errorf(format, args...)})
+}
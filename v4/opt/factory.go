@@ -2,13 +2,57 @@ package opt
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util"
 )
 
-//go:generate optgen -out factory.og.go -pkg opt factory ops/scalar.opt ops/relational.opt ops/enforcer.opt norm/norm.opt norm/filter.opt norm/push_down.opt norm/decorrelate.opt
+//go:generate optgen -out factory.og.go -pkg opt -funcs Factory factory ops/private.opt ops/scalar.opt ops/relational.opt ops/enforcer.opt norm/norm.opt norm/filter.opt norm/push_down.opt norm/decorrelate.opt norm/group_by.opt norm/join.opt
+
+// RuleName identifies a normalization rule by the name that it's given in the
+// optgen source. The constants are generated along with the factory.
+type RuleName uint16
+
+func (r RuleName) String() string {
+	if r >= RuleName(len(ruleIndexes)-1) {
+		return fmt.Sprintf("RuleName(%d)", r)
+	}
+
+	return ruleNames[ruleIndexes[r]:ruleIndexes[r+1]]
+}
+
+// LookupRuleName returns the normalization rule that has the given name, or
+// UnknownRule if there is no such rule.
+func LookupRuleName(name string) RuleName {
+	for r := RuleName(1); r < NumRules; r++ {
+		if r.String() == name {
+			return r
+		}
+	}
+	return UnknownRule
+}
+
+// AppliedRuleFunc is called each time that a normalization rule replaces an
+// expression. The replaced expression is never entered into the memo, so it's
+// identified by its operator. The target is the group of the expression that
+// replaces it.
+type AppliedRuleFunc func(rule RuleName, source Operator, target GroupID)
 
 type Factory struct {
 	mem      *memo
 	maxSteps int
+
+	// disabledRules is the set of normalization rules that the factory does
+	// not apply, even if they match.
+	disabledRules util.FastIntSet
+
+	// appliedRule, if set, is called each time that a rule is applied.
+	appliedRule AppliedRuleFunc
+
+	// profile, if set, records the cost of matching each rule.
+	profile *RuleProfile
 }
 
 func newFactory(mem *memo, maxSteps int) *Factory {
@@ -16,6 +60,62 @@ func newFactory(mem *memo, maxSteps int) *Factory {
 	return f
 }
 
+// DisableRule prevents the factory from applying the given normalization rule
+// to expressions that are constructed from now on. This allows rules to be
+// tested in isolation.
+func (f *Factory) DisableRule(rule RuleName) {
+	f.disabledRules.Add(int(rule))
+}
+
+// allowRule returns true if the given normalization rule can be applied. It's
+// called by the generated code before trying to match each rule.
+func (f *Factory) allowRule(rule RuleName) bool {
+	if f.disabledRules.Contains(int(rule)) {
+		return false
+	}
+	if f.profile != nil {
+		f.profile.Rules[rule].Attempts++
+	}
+	return true
+}
+
+// SetAppliedRule sets a function that's called each time that the factory
+// applies a normalization rule. Replacement expressions are constructed
+// recursively, so any rules that are applied while constructing a replacement
+// are reported before the rule that constructed it.
+func (f *Factory) SetAppliedRule(appliedRule AppliedRuleFunc) {
+	f.appliedRule = appliedRule
+}
+
+// reportAppliedRule is called by the generated code after each rule has been
+// applied.
+func (f *Factory) reportAppliedRule(rule RuleName, source Operator, target GroupID) {
+	if f.profile != nil {
+		f.profile.Rules[rule].Matches++
+	}
+	if f.appliedRule != nil {
+		f.appliedRule(rule, source, target)
+	}
+}
+
+// startMatchFunc is called by the generated code before each custom match
+// function is invoked. It returns the current time if rules are being
+// profiled.
+func (f *Factory) startMatchFunc() time.Time {
+	if f.profile == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// stopMatchFunc is called by the generated code after each custom match
+// function returns, with the time that was returned by startMatchFunc.
+func (f *Factory) stopMatchFunc(rule RuleName, start time.Time) {
+	if f.profile != nil {
+		f.profile.Rules[rule].MatchFuncTime += time.Since(start)
+	}
+}
+
 func (f *Factory) Metadata() *Metadata {
 	return f.mem.metadata
 }
@@ -24,10 +124,6 @@ func (f *Factory) StoreList(items []GroupID) ListID {
 	return f.mem.storeList(items)
 }
 
-func (f *Factory) InternPrivate(private interface{}) PrivateID {
-	return f.mem.internPrivate(private)
-}
-
 func (f *Factory) onConstruct(group GroupID) GroupID {
 	if f.maxSteps <= 0 {
 		return group
@@ -77,7 +173,7 @@ func (f *Factory) commuteInequalityExpr(op Operator, left, right GroupID) GroupI
 		return f.ConstructGt(right, left)
 	}
 
-	panic(fmt.Sprint("called commuteInequalityExpr with operator %s", op))
+	panic(fmt.Sprintf("called commuteInequalityExpr with operator %s", op))
 }
 
 func (f *Factory) concatFilterConditions(filterLeft, filterRight GroupID) GroupID {
@@ -207,7 +303,7 @@ func (f *Factory) hasUnboundCols(rel GroupID) bool {
 func (f *Factory) singleColIndex(rel GroupID) PrivateID {
 	cols := f.mem.lookupGroup(rel).logical.Relational.OutputCols
 	index, _ := cols.Next(0)
-	return f.mem.internPrivate(ColumnIndex(index))
+	return f.mem.internColIndex(ColumnIndex(index))
 }
 
 func (f *Factory) removeApply(op Operator, left, right, filter GroupID) GroupID {
@@ -234,16 +330,16 @@ func (f *Factory) columnProjections(group GroupID) GroupID {
 	outputCols := f.mem.lookupGroup(group).logical.Relational.OutputCols
 	items := make([]GroupID, 0, outputCols.Len())
 	outputCols.ForEach(func(i int) {
-		items = append(items, f.ConstructVariable(f.mem.internPrivate(ColumnIndex(i))))
+		items = append(items, f.ConstructVariable(ColumnIndex(i)))
 	})
 
-	return f.ConstructProjections(f.mem.storeList(items), f.mem.internPrivate(&outputCols))
+	return f.ConstructProjections(f.mem.storeList(items), &outputCols)
 }
 
 func (f *Factory) appendColumnProjections(projections, group GroupID) GroupID {
 	projectionsExpr := f.mem.lookupNormExpr(projections).asProjections()
 	projectionsItems := f.mem.lookupList(projectionsExpr.items())
-	projectionsCols := *f.mem.lookupPrivate(projectionsExpr.cols()).(*ColSet)
+	projectionsCols := *f.mem.lookupColIndexes(projectionsExpr.cols())
 
 	// The final output columns are the union of the columns in "projections"
 	// with the appended columns.
@@ -263,11 +359,11 @@ func (f *Factory) appendColumnProjections(projections, group GroupID) GroupID {
 	// expression.
 	appendCols.ForEach(func(i int) {
 		if !projectionsCols.Contains(i) {
-			items = append(items, f.ConstructVariable(f.mem.internPrivate(ColumnIndex(i))))
+			items = append(items, f.ConstructVariable(ColumnIndex(i)))
 		}
 	})
 
-	return f.ConstructProjections(f.mem.storeList(items), f.mem.internPrivate(&outputCols))
+	return f.ConstructProjections(f.mem.storeList(items), &outputCols)
 }
 
 // substitute recursively substitutes oldCol with newCol in the given filter
@@ -283,8 +379,8 @@ func (f *Factory) substitute(filter GroupID, oldCol, newCol ColumnIndex) (GroupI
 	// Base case: we have a variable expression.  If it matches oldCol, replace
 	// it with newCol.
 	if filterExpr.op == VariableOp {
-		if f.mem.lookupPrivate(filterExpr.asVariable().col()).(ColumnIndex) == oldCol {
-			return f.ConstructVariable(f.InternPrivate(newCol)), true
+		if f.mem.lookupColIndex(filterExpr.asVariable().col()) == oldCol {
+			return f.ConstructVariable(newCol), true
 		}
 		return filter, false
 	}
@@ -364,7 +460,230 @@ func (f *Factory) inferEquivFilters(input ListID, filters []GroupID) GroupID {
 
 func (f *Factory) projectsSameCols(projections, input GroupID) bool {
 	projectionsExpr := f.mem.lookupNormExpr(projections).asProjections()
-	projectionsCols := *f.mem.lookupPrivate(projectionsExpr.cols()).(*ColSet)
+	projectionsCols := *f.mem.lookupColIndexes(projectionsExpr.cols())
 	inputCols := f.mem.lookupGroup(input).logical.Relational.OutputCols
 	return projectionsCols.Equals(inputCols)
 }
+
+// isStrongKey returns true if the columns projected by the given projections
+// operator contain a key of the input, so that no two input rows have equal
+// values for those columns.
+func (f *Factory) isStrongKey(input, projections GroupID) bool {
+	projectionsExpr := f.mem.lookupNormExpr(projections).asProjections()
+	projectionsCols := *f.mem.lookupColIndexes(projectionsExpr.cols())
+	return f.mem.lookupGroup(input).logical.hasStrongKey(projectionsCols)
+}
+
+// prunableGroupingCols returns the grouping columns that can be removed from
+// the given groupings, because they are functionally determined by the
+// remaining grouping columns. Only grouping columns that are simple variable
+// references are returned.
+func (f *Factory) prunableGroupingCols(input, groupings GroupID) ColSet {
+	groupingsExpr := f.mem.lookupNormExpr(groupings).asProjections()
+	groupingCols := *f.mem.lookupColIndexes(groupingsExpr.cols())
+
+	var varCols ColSet
+	for _, item := range f.mem.lookupList(groupingsExpr.items()) {
+		itemExpr := f.mem.lookupNormExpr(item)
+		if itemExpr.op == VariableOp {
+			varCols.Add(int(f.mem.lookupColIndex(itemExpr.asVariable().col())))
+		}
+	}
+
+	fds := f.mem.lookupGroup(input).logical.Relational.FuncDeps
+	pruned := groupingCols.Difference(fds.reduce(groupingCols))
+	return pruned.Intersection(varCols)
+}
+
+func (f *Factory) canPruneGroupings(input, groupings GroupID) bool {
+	return !f.prunableGroupingCols(input, groupings).Empty()
+}
+
+// pruneGroupings constructs a new groupings list that does not contain the
+// grouping columns that are determined by the other grouping columns.
+func (f *Factory) pruneGroupings(input, groupings GroupID) GroupID {
+	pruned := f.prunableGroupingCols(input, groupings)
+
+	groupingsExpr := f.mem.lookupNormExpr(groupings).asProjections()
+	groupingsItems := f.mem.lookupList(groupingsExpr.items())
+	groupingCols := *f.mem.lookupColIndexes(groupingsExpr.cols())
+
+	items := make([]GroupID, 0, len(groupingsItems))
+	for _, item := range groupingsItems {
+		itemExpr := f.mem.lookupNormExpr(item)
+		if itemExpr.op == VariableOp {
+			col := f.mem.lookupColIndex(itemExpr.asVariable().col())
+			if pruned.Contains(int(col)) {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+
+	outputCols := groupingCols.Difference(pruned)
+	return f.ConstructProjections(f.mem.storeList(items), &outputCols)
+}
+
+// appendPrunedGroupings constructs a new aggregations list that computes the
+// grouping columns pruned by pruneGroupings, using the ConstAgg aggregate.
+func (f *Factory) appendPrunedGroupings(input, groupings, aggregations GroupID) GroupID {
+	pruned := f.prunableGroupingCols(input, groupings)
+
+	aggregationsExpr := f.mem.lookupNormExpr(aggregations).asProjections()
+	aggregationsItems := f.mem.lookupList(aggregationsExpr.items())
+	aggregationsCols := *f.mem.lookupColIndexes(aggregationsExpr.cols())
+
+	items := make([]GroupID, len(aggregationsItems), len(aggregationsItems)+pruned.Len())
+	copy(items, aggregationsItems)
+	pruned.ForEach(func(i int) {
+		variable := f.ConstructVariable(ColumnIndex(i))
+		items = append(items, f.ConstructConstAgg(variable))
+	})
+
+	outputCols := aggregationsCols.Union(pruned)
+	return f.ConstructProjections(f.mem.storeList(items), &outputCols)
+}
+
+// singleRowAggregate returns the expression that computes the given aggregate
+// when it is applied to a group containing a single row, or 0 if there is no
+// such expression. The min and max aggregates of a single row are equal to
+// their argument, as is the ConstAgg aggregate.
+//
+// TODO(andy): sum and avg of a single row are also equal to their argument,
+// but an integer argument needs to be cast to decimal, which requires a cast
+// operator.
+func (f *Factory) singleRowAggregate(agg GroupID) GroupID {
+	aggExpr := f.mem.lookupNormExpr(agg)
+	switch aggExpr.op {
+	case ConstAggOp:
+		return aggExpr.asConstAgg().input()
+
+	case FunctionOp:
+		functionExpr := aggExpr.asFunction()
+		args := f.mem.lookupList(functionExpr.args())
+		def := f.mem.lookupFuncDef(functionExpr.def())
+		if len(args) != 1 {
+			return 0
+		}
+
+		switch strings.ToLower(def.Name) {
+		case "min", "max":
+			return args[0]
+		}
+	}
+
+	return 0
+}
+
+// canEliminateAggregations returns true if every aggregate in the given
+// aggregations list can be replaced by a scalar expression when it is applied
+// to a group containing a single row.
+func (f *Factory) canEliminateAggregations(aggregations GroupID) bool {
+	aggregationsExpr := f.mem.lookupNormExpr(aggregations).asProjections()
+	for _, item := range f.mem.lookupList(aggregationsExpr.items()) {
+		if f.singleRowAggregate(item) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// eliminateAggregations constructs a projections list that computes the
+// output columns of a GroupBy operator, when each group contains a single row.
+// The grouping expressions are computed as-is, and each aggregate is replaced
+// by its single row equivalent.
+func (f *Factory) eliminateAggregations(groupings, aggregations GroupID) GroupID {
+	groupingsExpr := f.mem.lookupNormExpr(groupings).asProjections()
+	groupingsItems := f.mem.lookupList(groupingsExpr.items())
+	groupingsCols := *f.mem.lookupColIndexes(groupingsExpr.cols())
+
+	aggregationsExpr := f.mem.lookupNormExpr(aggregations).asProjections()
+	aggregationsItems := f.mem.lookupList(aggregationsExpr.items())
+	aggregationsCols := *f.mem.lookupColIndexes(aggregationsExpr.cols())
+
+	items := make([]GroupID, len(groupingsItems), len(groupingsItems)+len(aggregationsItems))
+	copy(items, groupingsItems)
+	for _, item := range aggregationsItems {
+		items = append(items, f.singleRowAggregate(item))
+	}
+
+	outputCols := groupingsCols.Union(aggregationsCols)
+	return f.ConstructProjections(f.mem.storeList(items), &outputCols)
+}
+
+// foreignKeyJoin returns the foreign key from the columns of the left input of
+// a join to the columns of the right input, if the join filter only consists
+// of equalities between the foreign key columns and the columns that they
+// reference, and the right input is an unfiltered scan of the referenced
+// table. Every left row with non-NULL foreign key values then matches exactly
+// one right row.
+func (f *Factory) foreignKeyJoin(left, right, on GroupID) (fkey ForeignKeyProps, ok bool) {
+	conditions := f.mem.logPropsFactory.filterConditions(on)
+	for _, fkey := range f.mem.logPropsFactory.joinForeignKeys(left, right, on) {
+		// Every condition must be one of the foreign key equalities, since any
+		// other condition could filter out the matching row.
+		ok := true
+		for _, condition := range conditions {
+			condLeft, condRight, isEq := f.mem.logPropsFactory.columnEquality(condition)
+			if !isEq || (fkey.cols[condLeft] != condRight && fkey.cols[condRight] != condLeft) {
+				ok = false
+				break
+			}
+		}
+
+		if ok {
+			return fkey, true
+		}
+	}
+
+	return ForeignKeyProps{}, false
+}
+
+// isRedundantSemiJoin returns true if every row of the left input of a
+// semi-join is guaranteed to match a row of the right input, because the join
+// is a foreign key join (see foreignKeyJoin), and the foreign key columns are
+// NOT NULL.
+func (f *Factory) isRedundantSemiJoin(left, right, on GroupID) bool {
+	fkey, ok := f.foreignKeyJoin(left, right, on)
+	if !ok {
+		return false
+	}
+
+	notNullCols := f.mem.lookupGroup(left).logical.Relational.NotNullCols
+	return fkey.src.SubsetOf(notNullCols)
+}
+
+// isForeignKeyJoin returns true if the join is a foreign key join from the
+// left input to the right input. See foreignKeyJoin.
+func (f *Factory) isForeignKeyJoin(left, right, on GroupID) bool {
+	_, ok := f.foreignKeyJoin(left, right, on)
+	return ok
+}
+
+// filterNullForeignKeys returns the left input of a foreign key join, filtered
+// to the rows that match a row of the right input. Those are the rows for
+// which none of the foreign key columns are NULL. If the foreign key columns
+// are NOT NULL, then the left input is returned as is.
+func (f *Factory) filterNullForeignKeys(left, right, on GroupID) GroupID {
+	fkey, _ := f.foreignKeyJoin(left, right, on)
+	nullableCols := fkey.src.Difference(f.mem.lookupGroup(left).logical.Relational.NotNullCols)
+	if nullableCols.Empty() {
+		return left
+	}
+
+	conditions := make([]GroupID, 0, nullableCols.Len())
+	nullableCols.ForEach(func(i int) {
+		variable := f.ConstructVariable(ColumnIndex(i))
+		null := f.ConstructConst(tree.DNull)
+		conditions = append(conditions, f.ConstructIsNot(variable, null))
+	})
+
+	return f.ConstructSelect(left, f.ConstructFilters(f.mem.storeList(conditions)))
+}
+
+// isKeyedOnJoinCols returns true if the join filter equates the columns of a
+// key of the right input with columns of the left input, so that each left
+// row matches at most one right row.
+func (f *Factory) isKeyedOnJoinCols(left, right, on GroupID) bool {
+	return f.mem.logPropsFactory.isKeyedOnJoinCols(left, right, on)
+}
@@ -2,6 +2,52 @@
 
 package opt
 
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+)
+
+const (
+	UnknownRule RuleName = iota
+
+	NormalizeVar
+	NormalizeVarOrder
+	NormalizeInequalityVar
+	NormalizeInequalityVarOrder
+	EliminateProject
+	EliminateSemiAntiJoinProject
+	MergeSelectSelect
+	EliminateSelect
+	EliminateFilters
+	EnsureSelectFilters
+	EnsureJoinFilters
+	PushDownSelectJoinLeft
+	PushDownSelectJoinRight
+	PushDownSelectJoin
+	PushDownJoinFilter
+	DecorrelateJoin
+	HoistSelectExists
+	HoistSelectNotExists
+	HoistSelectFilterSubquery
+	HoistJoinFilterSubquery
+	TryDecorrelateProject
+	TryDecorrelateSelect
+	TryDecorrelateScalarGroupBy
+	EliminateGroupBy
+	EliminateGroupByKey
+	PruneGroupByCols
+	EliminateSemiJoin
+	EliminateForeignKeyJoinRight
+	EliminateForeignKeyJoinLeft
+	EliminateLeftJoin
+
+	// NumRules is one greater than the largest rule name.
+	NumRules
+)
+
+const ruleNames = "UnknownRuleNormalizeVarNormalizeVarOrderNormalizeInequalityVarNormalizeInequalityVarOrderEliminateProjectEliminateSemiAntiJoinProjectMergeSelectSelectEliminateSelectEliminateFiltersEnsureSelectFiltersEnsureJoinFiltersPushDownSelectJoinLeftPushDownSelectJoinRightPushDownSelectJoinPushDownJoinFilterDecorrelateJoinHoistSelectExistsHoistSelectNotExistsHoistSelectFilterSubqueryHoistJoinFilterSubqueryTryDecorrelateProjectTryDecorrelateSelectTryDecorrelateScalarGroupByEliminateGroupByEliminateGroupByKeyPruneGroupByColsEliminateSemiJoinEliminateForeignKeyJoinRightEliminateForeignKeyJoinLeftEliminateLeftJoin"
+
+var ruleIndexes = [...]uint32{0, 11, 23, 40, 62, 89, 105, 133, 150, 165, 181, 200, 217, 239, 262, 280, 298, 313, 330, 350, 375, 398, 419, 439, 466, 482, 501, 517, 534, 562, 589, 606}
+
 func (_f *Factory) ConstructSubquery(
 	input GroupID,
 	projection GroupID,
@@ -16,6 +62,12 @@ func (_f *Factory) ConstructSubquery(
 }
 
 func (_f *Factory) ConstructVariable(
+	col ColumnIndex,
+) GroupID {
+	return _f.constructVariable(_f.mem.internColIndex(col))
+}
+
+func (_f *Factory) constructVariable(
 	col PrivateID,
 ) GroupID {
 	_variableExpr := makeVariableExpr(col)
@@ -28,6 +80,12 @@ func (_f *Factory) ConstructVariable(
 }
 
 func (_f *Factory) ConstructConst(
+	value tree.Datum,
+) GroupID {
+	return _f.constructConst(_f.mem.internDatum(value))
+}
+
+func (_f *Factory) constructConst(
 	value PrivateID,
 ) GroupID {
 	_constExpr := makeConstExpr(value)
@@ -40,6 +98,12 @@ func (_f *Factory) ConstructConst(
 }
 
 func (_f *Factory) ConstructPlaceholder(
+	value tree.TypedExpr,
+) GroupID {
+	return _f.constructPlaceholder(_f.mem.internTypedExpr(value))
+}
+
+func (_f *Factory) constructPlaceholder(
 	value PrivateID,
 ) GroupID {
 	_placeholderExpr := makePlaceholderExpr(value)
@@ -100,13 +164,17 @@ func (_f *Factory) ConstructFilters(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_filtersExpr))
 	}
 
-	// [EliminateFilters]
-	{
+	// [EliminateFilters] norm/filter.opt:21
+	if _f.allowRule(EliminateFilters) {
 		items := conditions
-		if _f.isEmptyList(items) {
+		_start := _f.startMatchFunc()
+		_result := _f.isEmptyList(items)
+		_f.stopMatchFunc(EliminateFilters, _start)
+		if _result {
 			_f.maxSteps--
 			_group = _f.ConstructTrue()
 			_f.mem.addAltFingerprint(_filtersExpr.fingerprint(), _group)
+			_f.reportAppliedRule(EliminateFilters, FiltersOp, _group)
 			return _group
 		}
 	}
@@ -115,6 +183,13 @@ func (_f *Factory) ConstructFilters(
 }
 
 func (_f *Factory) ConstructProjections(
+	items ListID,
+	cols *ColSet,
+) GroupID {
+	return _f.constructProjections(items, _f.mem.internColIndexes(cols))
+}
+
+func (_f *Factory) constructProjections(
 	items ListID,
 	cols PrivateID,
 ) GroupID {
@@ -191,8 +266,8 @@ func (_f *Factory) ConstructEq(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_eqExpr))
 	}
 
-	// [NormalizeVar]
-	{
+	// [NormalizeVar] norm/norm.opt:9
+	if _f.allowRule(NormalizeVar) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable == nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
@@ -200,21 +275,26 @@ func (_f *Factory) ConstructEq(
 				_f.maxSteps--
 				_group = _f.ConstructEq(right, left)
 				_f.mem.addAltFingerprint(_eqExpr.fingerprint(), _group)
+				_f.reportAppliedRule(NormalizeVar, EqOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [NormalizeVarOrder]
-	{
+	// [NormalizeVarOrder] norm/norm.opt:16
+	if _f.allowRule(NormalizeVarOrder) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable != nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
 			if _variable2 != nil {
-				if _f.isLowerExpr(right, left) {
+				_start := _f.startMatchFunc()
+				_result := _f.isLowerExpr(right, left)
+				_f.stopMatchFunc(NormalizeVarOrder, _start)
+				if _result {
 					_f.maxSteps--
 					_group = _f.ConstructEq(right, left)
 					_f.mem.addAltFingerprint(_eqExpr.fingerprint(), _group)
+					_f.reportAppliedRule(NormalizeVarOrder, EqOp, _group)
 					return _group
 				}
 			}
@@ -238,8 +318,8 @@ func (_f *Factory) ConstructLt(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_ltExpr))
 	}
 
-	// [NormalizeInequalityVar]
-	{
+	// [NormalizeInequalityVar] norm/norm.opt:26
+	if _f.allowRule(NormalizeInequalityVar) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable == nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
@@ -247,21 +327,26 @@ func (_f *Factory) ConstructLt(
 				_f.maxSteps--
 				_group = _f.commuteInequalityExpr(LtOp, left, right)
 				_f.mem.addAltFingerprint(_ltExpr.fingerprint(), _group)
+				_f.reportAppliedRule(NormalizeInequalityVar, LtOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [NormalizeInequalityVarOrder]
-	{
+	// [NormalizeInequalityVarOrder] norm/norm.opt:36
+	if _f.allowRule(NormalizeInequalityVarOrder) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable != nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
 			if _variable2 != nil {
-				if _f.isLowerExpr(right, left) {
+				_start := _f.startMatchFunc()
+				_result := _f.isLowerExpr(right, left)
+				_f.stopMatchFunc(NormalizeInequalityVarOrder, _start)
+				if _result {
 					_f.maxSteps--
 					_group = _f.commuteInequalityExpr(LtOp, left, right)
 					_f.mem.addAltFingerprint(_ltExpr.fingerprint(), _group)
+					_f.reportAppliedRule(NormalizeInequalityVarOrder, LtOp, _group)
 					return _group
 				}
 			}
@@ -285,8 +370,8 @@ func (_f *Factory) ConstructGt(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_gtExpr))
 	}
 
-	// [NormalizeInequalityVar]
-	{
+	// [NormalizeInequalityVar] norm/norm.opt:26
+	if _f.allowRule(NormalizeInequalityVar) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable == nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
@@ -294,21 +379,26 @@ func (_f *Factory) ConstructGt(
 				_f.maxSteps--
 				_group = _f.commuteInequalityExpr(GtOp, left, right)
 				_f.mem.addAltFingerprint(_gtExpr.fingerprint(), _group)
+				_f.reportAppliedRule(NormalizeInequalityVar, GtOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [NormalizeInequalityVarOrder]
-	{
+	// [NormalizeInequalityVarOrder] norm/norm.opt:36
+	if _f.allowRule(NormalizeInequalityVarOrder) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable != nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
 			if _variable2 != nil {
-				if _f.isLowerExpr(right, left) {
+				_start := _f.startMatchFunc()
+				_result := _f.isLowerExpr(right, left)
+				_f.stopMatchFunc(NormalizeInequalityVarOrder, _start)
+				if _result {
 					_f.maxSteps--
 					_group = _f.commuteInequalityExpr(GtOp, left, right)
 					_f.mem.addAltFingerprint(_gtExpr.fingerprint(), _group)
+					_f.reportAppliedRule(NormalizeInequalityVarOrder, GtOp, _group)
 					return _group
 				}
 			}
@@ -332,8 +422,8 @@ func (_f *Factory) ConstructLe(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_leExpr))
 	}
 
-	// [NormalizeInequalityVar]
-	{
+	// [NormalizeInequalityVar] norm/norm.opt:26
+	if _f.allowRule(NormalizeInequalityVar) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable == nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
@@ -341,21 +431,26 @@ func (_f *Factory) ConstructLe(
 				_f.maxSteps--
 				_group = _f.commuteInequalityExpr(LeOp, left, right)
 				_f.mem.addAltFingerprint(_leExpr.fingerprint(), _group)
+				_f.reportAppliedRule(NormalizeInequalityVar, LeOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [NormalizeInequalityVarOrder]
-	{
+	// [NormalizeInequalityVarOrder] norm/norm.opt:36
+	if _f.allowRule(NormalizeInequalityVarOrder) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable != nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
 			if _variable2 != nil {
-				if _f.isLowerExpr(right, left) {
+				_start := _f.startMatchFunc()
+				_result := _f.isLowerExpr(right, left)
+				_f.stopMatchFunc(NormalizeInequalityVarOrder, _start)
+				if _result {
 					_f.maxSteps--
 					_group = _f.commuteInequalityExpr(LeOp, left, right)
 					_f.mem.addAltFingerprint(_leExpr.fingerprint(), _group)
+					_f.reportAppliedRule(NormalizeInequalityVarOrder, LeOp, _group)
 					return _group
 				}
 			}
@@ -379,8 +474,8 @@ func (_f *Factory) ConstructGe(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_geExpr))
 	}
 
-	// [NormalizeInequalityVar]
-	{
+	// [NormalizeInequalityVar] norm/norm.opt:26
+	if _f.allowRule(NormalizeInequalityVar) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable == nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
@@ -388,21 +483,26 @@ func (_f *Factory) ConstructGe(
 				_f.maxSteps--
 				_group = _f.commuteInequalityExpr(GeOp, left, right)
 				_f.mem.addAltFingerprint(_geExpr.fingerprint(), _group)
+				_f.reportAppliedRule(NormalizeInequalityVar, GeOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [NormalizeInequalityVarOrder]
-	{
+	// [NormalizeInequalityVarOrder] norm/norm.opt:36
+	if _f.allowRule(NormalizeInequalityVarOrder) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable != nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
 			if _variable2 != nil {
-				if _f.isLowerExpr(right, left) {
+				_start := _f.startMatchFunc()
+				_result := _f.isLowerExpr(right, left)
+				_f.stopMatchFunc(NormalizeInequalityVarOrder, _start)
+				if _result {
 					_f.maxSteps--
 					_group = _f.commuteInequalityExpr(GeOp, left, right)
 					_f.mem.addAltFingerprint(_geExpr.fingerprint(), _group)
+					_f.reportAppliedRule(NormalizeInequalityVarOrder, GeOp, _group)
 					return _group
 				}
 			}
@@ -426,8 +526,8 @@ func (_f *Factory) ConstructNe(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_neExpr))
 	}
 
-	// [NormalizeVar]
-	{
+	// [NormalizeVar] norm/norm.opt:9
+	if _f.allowRule(NormalizeVar) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable == nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
@@ -435,21 +535,26 @@ func (_f *Factory) ConstructNe(
 				_f.maxSteps--
 				_group = _f.ConstructNe(right, left)
 				_f.mem.addAltFingerprint(_neExpr.fingerprint(), _group)
+				_f.reportAppliedRule(NormalizeVar, NeOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [NormalizeVarOrder]
-	{
+	// [NormalizeVarOrder] norm/norm.opt:16
+	if _f.allowRule(NormalizeVarOrder) {
 		_variable := _f.mem.lookupNormExpr(left).asVariable()
 		if _variable != nil {
 			_variable2 := _f.mem.lookupNormExpr(right).asVariable()
 			if _variable2 != nil {
-				if _f.isLowerExpr(right, left) {
+				_start := _f.startMatchFunc()
+				_result := _f.isLowerExpr(right, left)
+				_f.stopMatchFunc(NormalizeVarOrder, _start)
+				if _result {
 					_f.maxSteps--
 					_group = _f.ConstructNe(right, left)
 					_f.mem.addAltFingerprint(_neExpr.fingerprint(), _group)
+					_f.reportAppliedRule(NormalizeVarOrder, NeOp, _group)
 					return _group
 				}
 			}
@@ -912,6 +1017,13 @@ func (_f *Factory) ConstructUnaryComplement(
 }
 
 func (_f *Factory) ConstructFunction(
+	args ListID,
+	def *tree.FunctionDefinition,
+) GroupID {
+	return _f.constructFunction(args, _f.mem.internFuncDef(def))
+}
+
+func (_f *Factory) constructFunction(
 	args ListID,
 	def PrivateID,
 ) GroupID {
@@ -924,6 +1036,18 @@ func (_f *Factory) ConstructFunction(
 	return _f.onConstruct(_f.mem.memoizeNormExpr((*memoExpr)(&_functionExpr)))
 }
 
+func (_f *Factory) ConstructConstAgg(
+	input GroupID,
+) GroupID {
+	_constAggExpr := makeConstAggExpr(input)
+	_group := _f.mem.lookupGroupByFingerprint(_constAggExpr.fingerprint())
+	if _group != 0 {
+		return _group
+	}
+
+	return _f.onConstruct(_f.mem.memoizeNormExpr((*memoExpr)(&_constAggExpr)))
+}
+
 func (_f *Factory) ConstructTrue() GroupID {
 	_trueExpr := makeTrueExpr()
 	_group := _f.mem.lookupGroupByFingerprint(_trueExpr.fingerprint())
@@ -945,6 +1069,12 @@ func (_f *Factory) ConstructFalse() GroupID {
 }
 
 func (_f *Factory) ConstructScan(
+	table TableIndex,
+) GroupID {
+	return _f.constructScan(_f.mem.internTable(table))
+}
+
+func (_f *Factory) constructScan(
 	table PrivateID,
 ) GroupID {
 	_scanExpr := makeScanExpr(table)
@@ -957,6 +1087,13 @@ func (_f *Factory) ConstructScan(
 }
 
 func (_f *Factory) ConstructValues(
+	rows ListID,
+	cols *ColSet,
+) GroupID {
+	return _f.constructValues(rows, _f.mem.internColIndexes(cols))
+}
+
+func (_f *Factory) constructValues(
 	rows ListID,
 	cols PrivateID,
 ) GroupID {
@@ -983,8 +1120,8 @@ func (_f *Factory) ConstructSelect(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_selectExpr))
 	}
 
-	// [MergeSelectSelect]
-	{
+	// [MergeSelectSelect] norm/norm.opt:67
+	if _f.allowRule(MergeSelectSelect) {
 		_select := _f.mem.lookupNormExpr(input).asSelect()
 		if _select != nil {
 			input := _select.input()
@@ -997,38 +1134,44 @@ func (_f *Factory) ConstructSelect(
 					_f.maxSteps--
 					_group = _f.ConstructSelect(input, _f.concatFilterConditions(outerFilter, innerFilter))
 					_f.mem.addAltFingerprint(_selectExpr.fingerprint(), _group)
+					_f.reportAppliedRule(MergeSelectSelect, SelectOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [EliminateSelect]
-	{
+	// [EliminateSelect] norm/filter.opt:15
+	if _f.allowRule(EliminateSelect) {
 		_true := _f.mem.lookupNormExpr(filter).asTrue()
 		if _true != nil {
 			_f.maxSteps--
 			_group = input
 			_f.mem.addAltFingerprint(_selectExpr.fingerprint(), _group)
+			_f.reportAppliedRule(EliminateSelect, SelectOp, _group)
 			return _group
 		}
 	}
 
-	// [EnsureSelectFilters]
-	{
+	// [EnsureSelectFilters] norm/filter.opt:29
+	if _f.allowRule(EnsureSelectFilters) {
 		_filters := _f.mem.lookupNormExpr(filter).asFilters()
 		if _filters == nil {
-			if _f.useFilters(filter) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(filter)
+			_f.stopMatchFunc(EnsureSelectFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructSelect(input, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{input}), filter))
 				_f.mem.addAltFingerprint(_selectExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureSelectFilters, SelectOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownSelectJoinLeft]
-	{
+	// [PushDownSelectJoinLeft] norm/push_down.opt:5
+	if _f.allowRule(PushDownSelectJoinLeft) {
 		_norm := _f.mem.lookupNormExpr(input)
 		if isJoinLookup[_norm.op] {
 			_e := makeExpr(_f.mem, input, defaultPhysPropsID)
@@ -1040,10 +1183,14 @@ func (_f *Factory) ConstructSelect(
 				list := _filters.conditions()
 				for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 					condition := _item
-					if !_f.isCorrelated(condition, right) {
+					_start := _f.startMatchFunc()
+					_result := _f.isCorrelated(condition, right)
+					_f.stopMatchFunc(PushDownSelectJoinLeft, _start)
+					if !_result {
 						_f.maxSteps--
 						_group = _f.ConstructSelect(_f.DynamicConstruct(_f.mem.lookupNormExpr(input).op, []GroupID{_f.ConstructSelect(left, condition), right, on}, 0), _f.ConstructFilters(_f.removeListItem(list, condition)))
 						_f.mem.addAltFingerprint(_selectExpr.fingerprint(), _group)
+						_f.reportAppliedRule(PushDownSelectJoinLeft, SelectOp, _group)
 						return _group
 					}
 				}
@@ -1051,8 +1198,8 @@ func (_f *Factory) ConstructSelect(
 		}
 	}
 
-	// [PushDownSelectJoinRight]
-	{
+	// [PushDownSelectJoinRight] norm/push_down.opt:23
+	if _f.allowRule(PushDownSelectJoinRight) {
 		_norm := _f.mem.lookupNormExpr(input)
 		if _norm.op == InnerJoinOp || _norm.op == InnerJoinApplyOp {
 			_e := makeExpr(_f.mem, input, defaultPhysPropsID)
@@ -1064,10 +1211,14 @@ func (_f *Factory) ConstructSelect(
 				list := _filters.conditions()
 				for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 					condition := _item
-					if !_f.isCorrelated(condition, left) {
+					_start := _f.startMatchFunc()
+					_result := _f.isCorrelated(condition, left)
+					_f.stopMatchFunc(PushDownSelectJoinRight, _start)
+					if !_result {
 						_f.maxSteps--
 						_group = _f.ConstructSelect(_f.DynamicConstruct(_f.mem.lookupNormExpr(input).op, []GroupID{left, _f.ConstructSelect(right, condition), on}, 0), _f.ConstructFilters(_f.removeListItem(list, condition)))
 						_f.mem.addAltFingerprint(_selectExpr.fingerprint(), _group)
+						_f.reportAppliedRule(PushDownSelectJoinRight, SelectOp, _group)
 						return _group
 					}
 				}
@@ -1075,8 +1226,8 @@ func (_f *Factory) ConstructSelect(
 		}
 	}
 
-	// [PushDownSelectJoin]
-	{
+	// [PushDownSelectJoin] norm/push_down.opt:40
+	if _f.allowRule(PushDownSelectJoin) {
 		_norm := _f.mem.lookupNormExpr(input)
 		if _norm.op == InnerJoinOp || _norm.op == InnerJoinApplyOp {
 			_e := makeExpr(_f.mem, input, defaultPhysPropsID)
@@ -1086,12 +1237,13 @@ func (_f *Factory) ConstructSelect(
 			_f.maxSteps--
 			_group = _f.DynamicConstruct(_f.mem.lookupNormExpr(input).op, []GroupID{left, right, _f.concatFilterConditions(on, filter)}, 0)
 			_f.mem.addAltFingerprint(_selectExpr.fingerprint(), _group)
+			_f.reportAppliedRule(PushDownSelectJoin, SelectOp, _group)
 			return _group
 		}
 	}
 
-	// [HoistSelectExists]
-	{
+	// [HoistSelectExists] norm/decorrelate.opt:51
+	if _f.allowRule(HoistSelectExists) {
 		_filters := _f.mem.lookupNormExpr(filter).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1103,14 +1255,15 @@ func (_f *Factory) ConstructSelect(
 					_f.maxSteps--
 					_group = _f.ConstructSemiJoinApply(input, subquery, _f.ConstructFilters(_f.removeListItem(list, exists)))
 					_f.mem.addAltFingerprint(_selectExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistSelectExists, SelectOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [HoistSelectNotExists]
-	{
+	// [HoistSelectNotExists] norm/decorrelate.opt:66
+	if _f.allowRule(HoistSelectNotExists) {
 		_filters := _f.mem.lookupNormExpr(filter).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1124,6 +1277,7 @@ func (_f *Factory) ConstructSelect(
 						_f.maxSteps--
 						_group = _f.ConstructAntiJoinApply(input, subquery, _f.ConstructFilters(_f.removeListItem(list, exists)))
 						_f.mem.addAltFingerprint(_selectExpr.fingerprint(), _group)
+						_f.reportAppliedRule(HoistSelectNotExists, SelectOp, _group)
 						return _group
 					}
 				}
@@ -1131,8 +1285,8 @@ func (_f *Factory) ConstructSelect(
 		}
 	}
 
-	// [HoistSelectFilterSubquery]
-	{
+	// [HoistSelectFilterSubquery] norm/decorrelate.opt:81
+	if _f.allowRule(HoistSelectFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(filter).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1145,6 +1299,7 @@ func (_f *Factory) ConstructSelect(
 					_f.maxSteps--
 					_group = _f.ConstructInnerJoinApply(input, subqueryInput, _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_selectExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistSelectFilterSubquery, SelectOp, _group)
 					return _group
 				}
 			}
@@ -1168,16 +1323,95 @@ func (_f *Factory) ConstructProject(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_projectExpr))
 	}
 
-	// [EliminateProject]
-	{
-		if _f.projectsSameCols(projections, input) {
+	// [EliminateProject] norm/norm.opt:46
+	if _f.allowRule(EliminateProject) {
+		_start := _f.startMatchFunc()
+		_result := _f.projectsSameCols(projections, input)
+		_f.stopMatchFunc(EliminateProject, _start)
+		if _result {
 			_f.maxSteps--
 			_group = input
 			_f.mem.addAltFingerprint(_projectExpr.fingerprint(), _group)
+			_f.reportAppliedRule(EliminateProject, ProjectOp, _group)
 			return _group
 		}
 	}
 
+	// [EliminateForeignKeyJoinRight] norm/join.opt:37
+	if _f.allowRule(EliminateForeignKeyJoinRight) {
+		_innerJoin := _f.mem.lookupNormExpr(input).asInnerJoin()
+		if _innerJoin != nil {
+			left := _innerJoin.left()
+			right := _innerJoin.right()
+			on := _innerJoin.on()
+			_start := _f.startMatchFunc()
+			_result := _f.isForeignKeyJoin(left, right, on)
+			_f.stopMatchFunc(EliminateForeignKeyJoinRight, _start)
+			if _result {
+				_start2 := _f.startMatchFunc()
+				_result2 := _f.isCorrelated(projections, right)
+				_f.stopMatchFunc(EliminateForeignKeyJoinRight, _start2)
+				if !_result2 {
+					_f.maxSteps--
+					_group = _f.ConstructProject(_f.filterNullForeignKeys(left, right, on), projections)
+					_f.mem.addAltFingerprint(_projectExpr.fingerprint(), _group)
+					_f.reportAppliedRule(EliminateForeignKeyJoinRight, ProjectOp, _group)
+					return _group
+				}
+			}
+		}
+	}
+
+	// [EliminateForeignKeyJoinLeft] norm/join.opt:50
+	if _f.allowRule(EliminateForeignKeyJoinLeft) {
+		_innerJoin := _f.mem.lookupNormExpr(input).asInnerJoin()
+		if _innerJoin != nil {
+			left := _innerJoin.left()
+			right := _innerJoin.right()
+			on := _innerJoin.on()
+			_start := _f.startMatchFunc()
+			_result := _f.isForeignKeyJoin(right, left, on)
+			_f.stopMatchFunc(EliminateForeignKeyJoinLeft, _start)
+			if _result {
+				_start2 := _f.startMatchFunc()
+				_result2 := _f.isCorrelated(projections, left)
+				_f.stopMatchFunc(EliminateForeignKeyJoinLeft, _start2)
+				if !_result2 {
+					_f.maxSteps--
+					_group = _f.ConstructProject(_f.filterNullForeignKeys(right, left, on), projections)
+					_f.mem.addAltFingerprint(_projectExpr.fingerprint(), _group)
+					_f.reportAppliedRule(EliminateForeignKeyJoinLeft, ProjectOp, _group)
+					return _group
+				}
+			}
+		}
+	}
+
+	// [EliminateLeftJoin] norm/join.opt:66
+	if _f.allowRule(EliminateLeftJoin) {
+		_leftJoin := _f.mem.lookupNormExpr(input).asLeftJoin()
+		if _leftJoin != nil {
+			left := _leftJoin.left()
+			right := _leftJoin.right()
+			on := _leftJoin.on()
+			_start := _f.startMatchFunc()
+			_result := _f.isKeyedOnJoinCols(left, right, on)
+			_f.stopMatchFunc(EliminateLeftJoin, _start)
+			if _result {
+				_start2 := _f.startMatchFunc()
+				_result2 := _f.isCorrelated(projections, right)
+				_f.stopMatchFunc(EliminateLeftJoin, _start2)
+				if !_result2 {
+					_f.maxSteps--
+					_group = _f.ConstructProject(left, projections)
+					_f.mem.addAltFingerprint(_projectExpr.fingerprint(), _group)
+					_f.reportAppliedRule(EliminateLeftJoin, ProjectOp, _group)
+					return _group
+				}
+			}
+		}
+	}
+
 	return _f.onConstruct(_f.mem.memoizeNormExpr((*memoExpr)(&_projectExpr)))
 }
 
@@ -1196,38 +1430,46 @@ func (_f *Factory) ConstructInnerJoin(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_innerJoinExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructInnerJoin(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_innerJoinExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, InnerJoinOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructInnerJoin(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_innerJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, InnerJoinOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1240,6 +1482,7 @@ func (_f *Factory) ConstructInnerJoin(
 					_f.maxSteps--
 					_group = _f.ConstructInnerJoin(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_innerJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, InnerJoinOp, _group)
 					return _group
 				}
 			}
@@ -1264,38 +1507,46 @@ func (_f *Factory) ConstructLeftJoin(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_leftJoinExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructLeftJoin(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_leftJoinExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, LeftJoinOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructLeftJoin(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_leftJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, LeftJoinOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1308,6 +1559,7 @@ func (_f *Factory) ConstructLeftJoin(
 					_f.maxSteps--
 					_group = _f.ConstructLeftJoin(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_leftJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, LeftJoinOp, _group)
 					return _group
 				}
 			}
@@ -1332,38 +1584,46 @@ func (_f *Factory) ConstructRightJoin(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_rightJoinExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructRightJoin(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_rightJoinExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, RightJoinOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructRightJoin(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_rightJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, RightJoinOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1376,6 +1636,7 @@ func (_f *Factory) ConstructRightJoin(
 					_f.maxSteps--
 					_group = _f.ConstructRightJoin(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_rightJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, RightJoinOp, _group)
 					return _group
 				}
 			}
@@ -1400,38 +1661,46 @@ func (_f *Factory) ConstructFullJoin(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_fullJoinExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructFullJoin(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_fullJoinExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, FullJoinOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructFullJoin(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_fullJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, FullJoinOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1444,6 +1713,7 @@ func (_f *Factory) ConstructFullJoin(
 					_f.maxSteps--
 					_group = _f.ConstructFullJoin(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_fullJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, FullJoinOp, _group)
 					return _group
 				}
 			}
@@ -1468,38 +1738,46 @@ func (_f *Factory) ConstructSemiJoin(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_semiJoinExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructSemiJoin(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_semiJoinExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, SemiJoinOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructSemiJoin(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_semiJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, SemiJoinOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1512,12 +1790,27 @@ func (_f *Factory) ConstructSemiJoin(
 					_f.maxSteps--
 					_group = _f.ConstructSemiJoin(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_semiJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, SemiJoinOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
+	// [EliminateSemiJoin] norm/join.opt:22
+	if _f.allowRule(EliminateSemiJoin) {
+		_start := _f.startMatchFunc()
+		_result := _f.isRedundantSemiJoin(left, right, on)
+		_f.stopMatchFunc(EliminateSemiJoin, _start)
+		if _result {
+			_f.maxSteps--
+			_group = left
+			_f.mem.addAltFingerprint(_semiJoinExpr.fingerprint(), _group)
+			_f.reportAppliedRule(EliminateSemiJoin, SemiJoinOp, _group)
+			return _group
+		}
+	}
+
 	return _f.onConstruct(_f.mem.memoizeNormExpr((*memoExpr)(&_semiJoinExpr)))
 }
 
@@ -1536,38 +1829,46 @@ func (_f *Factory) ConstructAntiJoin(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_antiJoinExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructAntiJoin(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_antiJoinExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, AntiJoinOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructAntiJoin(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_antiJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, AntiJoinOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1580,6 +1881,7 @@ func (_f *Factory) ConstructAntiJoin(
 					_f.maxSteps--
 					_group = _f.ConstructAntiJoin(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_antiJoinExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, AntiJoinOp, _group)
 					return _group
 				}
 			}
@@ -1604,48 +1906,60 @@ func (_f *Factory) ConstructInnerJoinApply(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_innerJoinApplyExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructInnerJoinApply(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_innerJoinApplyExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, InnerJoinApplyOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructInnerJoinApply(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_innerJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, InnerJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [DecorrelateJoin]
-	{
-		if !_f.isCorrelated(right, left) {
+	// [DecorrelateJoin] norm/decorrelate.opt:39
+	if _f.allowRule(DecorrelateJoin) {
+		_start := _f.startMatchFunc()
+		_result := _f.isCorrelated(right, left)
+		_f.stopMatchFunc(DecorrelateJoin, _start)
+		if !_result {
 			_f.maxSteps--
 			_group = _f.removeApply(InnerJoinApplyOp, left, right, on)
 			_f.mem.addAltFingerprint(_innerJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(DecorrelateJoin, InnerJoinApplyOp, _group)
 			return _group
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1658,14 +1972,15 @@ func (_f *Factory) ConstructInnerJoinApply(
 					_f.maxSteps--
 					_group = _f.ConstructInnerJoinApply(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_innerJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, InnerJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [TryDecorrelateProject]
-	{
+	// [TryDecorrelateProject] norm/decorrelate.opt:120
+	if _f.allowRule(TryDecorrelateProject) {
 		_project := _f.mem.lookupNormExpr(right).asProject()
 		if _project != nil {
 			input := _project.input()
@@ -1673,12 +1988,13 @@ func (_f *Factory) ConstructInnerJoinApply(
 			_f.maxSteps--
 			_group = _f.ConstructSelect(_f.ConstructProject(_f.ConstructInnerJoinApply(left, input, _f.ConstructTrue()), _f.appendColumnProjections(projections, left)), on)
 			_f.mem.addAltFingerprint(_innerJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(TryDecorrelateProject, InnerJoinApplyOp, _group)
 			return _group
 		}
 	}
 
-	// [TryDecorrelateSelect]
-	{
+	// [TryDecorrelateSelect] norm/decorrelate.opt:141
+	if _f.allowRule(TryDecorrelateSelect) {
 		_select := _f.mem.lookupNormExpr(right).asSelect()
 		if _select != nil {
 			input := _select.input()
@@ -1686,23 +2002,28 @@ func (_f *Factory) ConstructInnerJoinApply(
 			_f.maxSteps--
 			_group = _f.ConstructInnerJoinApply(left, input, _f.concatFilterConditions(on, filter))
 			_f.mem.addAltFingerprint(_innerJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(TryDecorrelateSelect, InnerJoinApplyOp, _group)
 			return _group
 		}
 	}
 
-	// [TryDecorrelateScalarGroupBy]
-	{
+	// [TryDecorrelateScalarGroupBy] norm/decorrelate.opt:163
+	if _f.allowRule(TryDecorrelateScalarGroupBy) {
 		_groupBy := _f.mem.lookupNormExpr(right).asGroupBy()
 		if _groupBy != nil {
 			input := _groupBy.input()
 			_projections := _f.mem.lookupNormExpr(_groupBy.groupings()).asProjections()
 			if _projections != nil {
 				items := _projections.items()
-				if _f.isEmptyList(items) {
+				_start := _f.startMatchFunc()
+				_result := _f.isEmptyList(items)
+				_f.stopMatchFunc(TryDecorrelateScalarGroupBy, _start)
+				if _result {
 					aggregations := _groupBy.aggregations()
 					_f.maxSteps--
 					_group = _f.ConstructSelect(_f.ConstructGroupBy(_f.ConstructLeftJoinApply(left, input, _f.ConstructTrue()), _f.columnProjections(left), aggregations), on)
 					_f.mem.addAltFingerprint(_innerJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(TryDecorrelateScalarGroupBy, InnerJoinApplyOp, _group)
 					return _group
 				}
 			}
@@ -1727,48 +2048,60 @@ func (_f *Factory) ConstructLeftJoinApply(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_leftJoinApplyExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructLeftJoinApply(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_leftJoinApplyExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, LeftJoinApplyOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructLeftJoinApply(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_leftJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, LeftJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [DecorrelateJoin]
-	{
-		if !_f.isCorrelated(right, left) {
+	// [DecorrelateJoin] norm/decorrelate.opt:39
+	if _f.allowRule(DecorrelateJoin) {
+		_start := _f.startMatchFunc()
+		_result := _f.isCorrelated(right, left)
+		_f.stopMatchFunc(DecorrelateJoin, _start)
+		if !_result {
 			_f.maxSteps--
 			_group = _f.removeApply(LeftJoinApplyOp, left, right, on)
 			_f.mem.addAltFingerprint(_leftJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(DecorrelateJoin, LeftJoinApplyOp, _group)
 			return _group
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1781,14 +2114,15 @@ func (_f *Factory) ConstructLeftJoinApply(
 					_f.maxSteps--
 					_group = _f.ConstructLeftJoinApply(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_leftJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, LeftJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [TryDecorrelateSelect]
-	{
+	// [TryDecorrelateSelect] norm/decorrelate.opt:141
+	if _f.allowRule(TryDecorrelateSelect) {
 		_select := _f.mem.lookupNormExpr(right).asSelect()
 		if _select != nil {
 			input := _select.input()
@@ -1796,6 +2130,7 @@ func (_f *Factory) ConstructLeftJoinApply(
 			_f.maxSteps--
 			_group = _f.ConstructLeftJoinApply(left, input, _f.concatFilterConditions(on, filter))
 			_f.mem.addAltFingerprint(_leftJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(TryDecorrelateSelect, LeftJoinApplyOp, _group)
 			return _group
 		}
 	}
@@ -1818,48 +2153,60 @@ func (_f *Factory) ConstructRightJoinApply(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_rightJoinApplyExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructRightJoinApply(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_rightJoinApplyExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, RightJoinApplyOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructRightJoinApply(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_rightJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, RightJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [DecorrelateJoin]
-	{
-		if !_f.isCorrelated(right, left) {
+	// [DecorrelateJoin] norm/decorrelate.opt:39
+	if _f.allowRule(DecorrelateJoin) {
+		_start := _f.startMatchFunc()
+		_result := _f.isCorrelated(right, left)
+		_f.stopMatchFunc(DecorrelateJoin, _start)
+		if !_result {
 			_f.maxSteps--
 			_group = _f.removeApply(RightJoinApplyOp, left, right, on)
 			_f.mem.addAltFingerprint(_rightJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(DecorrelateJoin, RightJoinApplyOp, _group)
 			return _group
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1872,14 +2219,15 @@ func (_f *Factory) ConstructRightJoinApply(
 					_f.maxSteps--
 					_group = _f.ConstructRightJoinApply(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_rightJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, RightJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [TryDecorrelateSelect]
-	{
+	// [TryDecorrelateSelect] norm/decorrelate.opt:141
+	if _f.allowRule(TryDecorrelateSelect) {
 		_select := _f.mem.lookupNormExpr(right).asSelect()
 		if _select != nil {
 			input := _select.input()
@@ -1887,6 +2235,7 @@ func (_f *Factory) ConstructRightJoinApply(
 			_f.maxSteps--
 			_group = _f.ConstructRightJoinApply(left, input, _f.concatFilterConditions(on, filter))
 			_f.mem.addAltFingerprint(_rightJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(TryDecorrelateSelect, RightJoinApplyOp, _group)
 			return _group
 		}
 	}
@@ -1909,48 +2258,60 @@ func (_f *Factory) ConstructFullJoinApply(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_fullJoinApplyExpr))
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructFullJoinApply(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_fullJoinApplyExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, FullJoinApplyOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructFullJoinApply(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_fullJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, FullJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [DecorrelateJoin]
-	{
-		if !_f.isCorrelated(right, left) {
+	// [DecorrelateJoin] norm/decorrelate.opt:39
+	if _f.allowRule(DecorrelateJoin) {
+		_start := _f.startMatchFunc()
+		_result := _f.isCorrelated(right, left)
+		_f.stopMatchFunc(DecorrelateJoin, _start)
+		if !_result {
 			_f.maxSteps--
 			_group = _f.removeApply(FullJoinApplyOp, left, right, on)
 			_f.mem.addAltFingerprint(_fullJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(DecorrelateJoin, FullJoinApplyOp, _group)
 			return _group
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -1963,14 +2324,15 @@ func (_f *Factory) ConstructFullJoinApply(
 					_f.maxSteps--
 					_group = _f.ConstructFullJoinApply(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_fullJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, FullJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [TryDecorrelateSelect]
-	{
+	// [TryDecorrelateSelect] norm/decorrelate.opt:141
+	if _f.allowRule(TryDecorrelateSelect) {
 		_select := _f.mem.lookupNormExpr(right).asSelect()
 		if _select != nil {
 			input := _select.input()
@@ -1978,6 +2340,7 @@ func (_f *Factory) ConstructFullJoinApply(
 			_f.maxSteps--
 			_group = _f.ConstructFullJoinApply(left, input, _f.concatFilterConditions(on, filter))
 			_f.mem.addAltFingerprint(_fullJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(TryDecorrelateSelect, FullJoinApplyOp, _group)
 			return _group
 		}
 	}
@@ -2000,8 +2363,8 @@ func (_f *Factory) ConstructSemiJoinApply(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_semiJoinApplyExpr))
 	}
 
-	// [EliminateSemiAntiJoinProject]
-	{
+	// [EliminateSemiAntiJoinProject] norm/norm.opt:57
+	if _f.allowRule(EliminateSemiAntiJoinProject) {
 		_project := _f.mem.lookupNormExpr(right).asProject()
 		if _project != nil {
 			input := _project.input()
@@ -2010,53 +2373,66 @@ func (_f *Factory) ConstructSemiJoinApply(
 				_f.maxSteps--
 				_group = _f.ConstructSemiJoinApply(left, input, _f.ConstructTrue())
 				_f.mem.addAltFingerprint(_semiJoinApplyExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EliminateSemiAntiJoinProject, SemiJoinApplyOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructSemiJoinApply(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_semiJoinApplyExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, SemiJoinApplyOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructSemiJoinApply(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_semiJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, SemiJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [DecorrelateJoin]
-	{
-		if !_f.isCorrelated(right, left) {
+	// [DecorrelateJoin] norm/decorrelate.opt:39
+	if _f.allowRule(DecorrelateJoin) {
+		_start := _f.startMatchFunc()
+		_result := _f.isCorrelated(right, left)
+		_f.stopMatchFunc(DecorrelateJoin, _start)
+		if !_result {
 			_f.maxSteps--
 			_group = _f.removeApply(SemiJoinApplyOp, left, right, on)
 			_f.mem.addAltFingerprint(_semiJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(DecorrelateJoin, SemiJoinApplyOp, _group)
 			return _group
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -2069,14 +2445,15 @@ func (_f *Factory) ConstructSemiJoinApply(
 					_f.maxSteps--
 					_group = _f.ConstructSemiJoinApply(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_semiJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, SemiJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [TryDecorrelateSelect]
-	{
+	// [TryDecorrelateSelect] norm/decorrelate.opt:141
+	if _f.allowRule(TryDecorrelateSelect) {
 		_select := _f.mem.lookupNormExpr(right).asSelect()
 		if _select != nil {
 			input := _select.input()
@@ -2084,23 +2461,28 @@ func (_f *Factory) ConstructSemiJoinApply(
 			_f.maxSteps--
 			_group = _f.ConstructSemiJoinApply(left, input, _f.concatFilterConditions(on, filter))
 			_f.mem.addAltFingerprint(_semiJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(TryDecorrelateSelect, SemiJoinApplyOp, _group)
 			return _group
 		}
 	}
 
-	// [TryDecorrelateScalarGroupBy]
-	{
+	// [TryDecorrelateScalarGroupBy] norm/decorrelate.opt:163
+	if _f.allowRule(TryDecorrelateScalarGroupBy) {
 		_groupBy := _f.mem.lookupNormExpr(right).asGroupBy()
 		if _groupBy != nil {
 			input := _groupBy.input()
 			_projections := _f.mem.lookupNormExpr(_groupBy.groupings()).asProjections()
 			if _projections != nil {
 				items := _projections.items()
-				if _f.isEmptyList(items) {
+				_start := _f.startMatchFunc()
+				_result := _f.isEmptyList(items)
+				_f.stopMatchFunc(TryDecorrelateScalarGroupBy, _start)
+				if _result {
 					aggregations := _groupBy.aggregations()
 					_f.maxSteps--
 					_group = _f.ConstructSelect(_f.ConstructGroupBy(_f.ConstructLeftJoinApply(left, input, _f.ConstructTrue()), _f.columnProjections(left), aggregations), on)
 					_f.mem.addAltFingerprint(_semiJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(TryDecorrelateScalarGroupBy, SemiJoinApplyOp, _group)
 					return _group
 				}
 			}
@@ -2125,8 +2507,8 @@ func (_f *Factory) ConstructAntiJoinApply(
 		return _f.mem.memoizeNormExpr((*memoExpr)(&_antiJoinApplyExpr))
 	}
 
-	// [EliminateSemiAntiJoinProject]
-	{
+	// [EliminateSemiAntiJoinProject] norm/norm.opt:57
+	if _f.allowRule(EliminateSemiAntiJoinProject) {
 		_project := _f.mem.lookupNormExpr(right).asProject()
 		if _project != nil {
 			input := _project.input()
@@ -2135,53 +2517,66 @@ func (_f *Factory) ConstructAntiJoinApply(
 				_f.maxSteps--
 				_group = _f.ConstructAntiJoinApply(left, input, _f.ConstructTrue())
 				_f.mem.addAltFingerprint(_antiJoinApplyExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EliminateSemiAntiJoinProject, AntiJoinApplyOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [EnsureJoinFilters]
-	{
+	// [EnsureJoinFilters] norm/filter.opt:43
+	if _f.allowRule(EnsureJoinFilters) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters == nil {
-			if _f.useFilters(on) {
+			_start := _f.startMatchFunc()
+			_result := _f.useFilters(on)
+			_f.stopMatchFunc(EnsureJoinFilters, _start)
+			if _result {
 				_f.maxSteps--
 				_group = _f.ConstructAntiJoinApply(left, right, _f.flattenFilterCondition(_f.mem.storeList([]GroupID{left, right}), on))
 				_f.mem.addAltFingerprint(_antiJoinApplyExpr.fingerprint(), _group)
+				_f.reportAppliedRule(EnsureJoinFilters, AntiJoinApplyOp, _group)
 				return _group
 			}
 		}
 	}
 
-	// [PushDownJoinFilter]
-	{
+	// [PushDownJoinFilter] norm/push_down.opt:54
+	if _f.allowRule(PushDownJoinFilter) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
 			for _, _item := range _f.mem.lookupList(_filters.conditions()) {
 				condition := _item
-				if !_f.isCorrelated(condition, right) {
+				_start := _f.startMatchFunc()
+				_result := _f.isCorrelated(condition, right)
+				_f.stopMatchFunc(PushDownJoinFilter, _start)
+				if !_result {
 					_f.maxSteps--
 					_group = _f.ConstructAntiJoinApply(_f.ConstructSelect(left, condition), right, _f.ConstructFilters(_f.removeListItem(list, condition)))
 					_f.mem.addAltFingerprint(_antiJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(PushDownJoinFilter, AntiJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [DecorrelateJoin]
-	{
-		if !_f.isCorrelated(right, left) {
+	// [DecorrelateJoin] norm/decorrelate.opt:39
+	if _f.allowRule(DecorrelateJoin) {
+		_start := _f.startMatchFunc()
+		_result := _f.isCorrelated(right, left)
+		_f.stopMatchFunc(DecorrelateJoin, _start)
+		if !_result {
 			_f.maxSteps--
 			_group = _f.removeApply(AntiJoinApplyOp, left, right, on)
 			_f.mem.addAltFingerprint(_antiJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(DecorrelateJoin, AntiJoinApplyOp, _group)
 			return _group
 		}
 	}
 
-	// [HoistJoinFilterSubquery]
-	{
+	// [HoistJoinFilterSubquery] norm/decorrelate.opt:98
+	if _f.allowRule(HoistJoinFilterSubquery) {
 		_filters := _f.mem.lookupNormExpr(on).asFilters()
 		if _filters != nil {
 			list := _filters.conditions()
@@ -2194,14 +2589,15 @@ func (_f *Factory) ConstructAntiJoinApply(
 					_f.maxSteps--
 					_group = _f.ConstructAntiJoinApply(left, _f.ConstructInnerJoinApply(right, subqueryInput, _f.ConstructTrue()), _f.ConstructFilters(_f.replaceListItem(list, subquery, projection)))
 					_f.mem.addAltFingerprint(_antiJoinApplyExpr.fingerprint(), _group)
+					_f.reportAppliedRule(HoistJoinFilterSubquery, AntiJoinApplyOp, _group)
 					return _group
 				}
 			}
 		}
 	}
 
-	// [TryDecorrelateSelect]
-	{
+	// [TryDecorrelateSelect] norm/decorrelate.opt:141
+	if _f.allowRule(TryDecorrelateSelect) {
 		_select := _f.mem.lookupNormExpr(right).asSelect()
 		if _select != nil {
 			input := _select.input()
@@ -2209,6 +2605,7 @@ func (_f *Factory) ConstructAntiJoinApply(
 			_f.maxSteps--
 			_group = _f.ConstructAntiJoinApply(left, input, _f.concatFilterConditions(on, filter))
 			_f.mem.addAltFingerprint(_antiJoinApplyExpr.fingerprint(), _group)
+			_f.reportAppliedRule(TryDecorrelateSelect, AntiJoinApplyOp, _group)
 			return _group
 		}
 	}
@@ -2227,10 +2624,92 @@ func (_f *Factory) ConstructGroupBy(
 		return _group
 	}
 
+	if _f.maxSteps <= 0 {
+		return _f.mem.memoizeNormExpr((*memoExpr)(&_groupByExpr))
+	}
+
+	// [EliminateGroupBy] norm/group_by.opt:17
+	if _f.allowRule(EliminateGroupBy) {
+		_start := _f.startMatchFunc()
+		_result := _f.projectsSameCols(groupings, input)
+		_f.stopMatchFunc(EliminateGroupBy, _start)
+		if _result {
+			_start2 := _f.startMatchFunc()
+			_result2 := _f.isStrongKey(input, groupings)
+			_f.stopMatchFunc(EliminateGroupBy, _start2)
+			if _result2 {
+				_projections := _f.mem.lookupNormExpr(aggregations).asProjections()
+				if _projections != nil {
+					items := _projections.items()
+					_start3 := _f.startMatchFunc()
+					_result3 := _f.isEmptyList(items)
+					_f.stopMatchFunc(EliminateGroupBy, _start3)
+					if _result3 {
+						_f.maxSteps--
+						_group = input
+						_f.mem.addAltFingerprint(_groupByExpr.fingerprint(), _group)
+						_f.reportAppliedRule(EliminateGroupBy, GroupByOp, _group)
+						return _group
+					}
+				}
+			}
+		}
+	}
+
+	// [EliminateGroupByKey] norm/group_by.opt:37
+	if _f.allowRule(EliminateGroupByKey) {
+		_projections := _f.mem.lookupNormExpr(groupings).asProjections()
+		if _projections != nil {
+			groupingItems := _projections.items()
+			_start := _f.startMatchFunc()
+			_result := _f.isEmptyList(groupingItems)
+			_f.stopMatchFunc(EliminateGroupByKey, _start)
+			if !_result {
+				_start2 := _f.startMatchFunc()
+				_result2 := _f.isStrongKey(input, groupings)
+				_f.stopMatchFunc(EliminateGroupByKey, _start2)
+				if _result2 {
+					_start3 := _f.startMatchFunc()
+					_result3 := _f.canEliminateAggregations(aggregations)
+					_f.stopMatchFunc(EliminateGroupByKey, _start3)
+					if _result3 {
+						_f.maxSteps--
+						_group = _f.ConstructProject(input, _f.eliminateAggregations(groupings, aggregations))
+						_f.mem.addAltFingerprint(_groupByExpr.fingerprint(), _group)
+						_f.reportAppliedRule(EliminateGroupByKey, GroupByOp, _group)
+						return _group
+					}
+				}
+			}
+		}
+	}
+
+	// [PruneGroupByCols] norm/group_by.opt:55
+	if _f.allowRule(PruneGroupByCols) {
+		_start := _f.startMatchFunc()
+		_result := _f.canPruneGroupings(input, groupings)
+		_f.stopMatchFunc(PruneGroupByCols, _start)
+		if _result {
+			_f.maxSteps--
+			_group = _f.ConstructGroupBy(input, _f.pruneGroupings(input, groupings), _f.appendPrunedGroupings(input, groupings, aggregations))
+			_f.mem.addAltFingerprint(_groupByExpr.fingerprint(), _group)
+			_f.reportAppliedRule(PruneGroupByCols, GroupByOp, _group)
+			return _group
+		}
+	}
+
 	return _f.onConstruct(_f.mem.memoizeNormExpr((*memoExpr)(&_groupByExpr)))
 }
 
 func (_f *Factory) ConstructUnion(
+	left GroupID,
+	right GroupID,
+	colMap *ColMap,
+) GroupID {
+	return _f.constructUnion(left, right, _f.mem.internColMap(colMap))
+}
+
+func (_f *Factory) constructUnion(
 	left GroupID,
 	right GroupID,
 	colMap PrivateID,
@@ -2272,7 +2751,7 @@ func (_f *Factory) ConstructExcept(
 
 type dynConstructLookupFunc func(f *Factory, children []GroupID, private PrivateID) GroupID
 
-var dynConstructLookup [78]dynConstructLookupFunc
+var dynConstructLookup [79]dynConstructLookupFunc
 
 func init() {
 	// UnknownOp
@@ -2287,17 +2766,17 @@ func init() {
 
 	// VariableOp
 	dynConstructLookup[VariableOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {
-		return f.ConstructVariable(private)
+		return f.constructVariable(private)
 	}
 
 	// ConstOp
 	dynConstructLookup[ConstOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {
-		return f.ConstructConst(private)
+		return f.constructConst(private)
 	}
 
 	// PlaceholderOp
 	dynConstructLookup[PlaceholderOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {
-		return f.ConstructPlaceholder(private)
+		return f.constructPlaceholder(private)
 	}
 
 	// ListOp
@@ -2322,7 +2801,7 @@ func init() {
 
 	// ProjectionsOp
 	dynConstructLookup[ProjectionsOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {
-		return f.ConstructProjections(f.StoreList(children), private)
+		return f.constructProjections(f.StoreList(children), private)
 	}
 
 	// ExistsOp
@@ -2552,7 +3031,12 @@ func init() {
 
 	// FunctionOp
 	dynConstructLookup[FunctionOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {
-		return f.ConstructFunction(f.StoreList(children), private)
+		return f.constructFunction(f.StoreList(children), private)
+	}
+
+	// ConstAggOp
+	dynConstructLookup[ConstAggOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {
+		return f.ConstructConstAgg(children[0])
 	}
 
 	// TrueOp
@@ -2567,12 +3051,12 @@ func init() {
 
 	// ScanOp
 	dynConstructLookup[ScanOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {
-		return f.ConstructScan(private)
+		return f.constructScan(private)
 	}
 
 	// ValuesOp
 	dynConstructLookup[ValuesOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {
-		return f.ConstructValues(f.StoreList(children), private)
+		return f.constructValues(f.StoreList(children), private)
 	}
 
 	// SelectOp
@@ -2652,7 +3136,7 @@ func init() {
 
 	// UnionOp
 	dynConstructLookup[UnionOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {
-		return f.ConstructUnion(children[0], children[1], private)
+		return f.constructUnion(children[0], children[1], private)
 	}
 
 	// IntersectOp
@@ -0,0 +1,230 @@
+package opt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// FuncDep is a functional dependency between two sets of columns in a
+// relation. The dependency "From --> To" states that any two rows which have
+// equal values for the From columns must also have equal values for the To
+// columns. NULL values are considered equal to one another for the purpose of
+// the dependency, which matches the way that GROUP BY and DISTINCT treat NULL
+// values.
+//
+// A dependency with an empty From set is a constant dependency: the To columns
+// have the same value in every row of the relation. Constant dependencies are
+// typically derived from filters such as "a = 5".
+type FuncDep struct {
+	From ColSet
+	To   ColSet
+}
+
+// FuncDeps is a set of functional dependencies that hold for a relation.
+// Consider the schema and query:
+//
+//   CREATE TABLE t (k INT PRIMARY KEY, a INT, b INT)
+//
+//   SELECT * FROM t WHERE a = b AND b = 5
+//
+// The primary key determines all other columns, the equality between a and b
+// means that each determines the other, and the comparison with a constant
+// means that b (and therefore a) has the same value in every row:
+//
+//   (k)-->(a,b) (a)-->(b) (b)-->(a) ()-->(a,b)
+//
+// FuncDeps values are treated as immutable once they have been attached to
+// logical properties, so they can be safely shared between expressions. The
+// add methods never modify the column sets of existing dependencies in place.
+type FuncDeps []FuncDep
+
+// closure returns the set of all columns that are functionally determined by
+// the given columns, including the columns themselves. This is computed using
+// the standard attribute closure algorithm: keep adding the To columns of any
+// dependency whose From columns are already in the closure until nothing
+// changes.
+func (fds FuncDeps) closure(cols ColSet) ColSet {
+	closure := cols.Copy()
+	for {
+		changed := false
+		for i := range fds {
+			fd := &fds[i]
+			if fd.From.SubsetOf(closure) && !fd.To.SubsetOf(closure) {
+				closure.UnionWith(fd.To)
+				changed = true
+			}
+		}
+
+		if !changed {
+			return closure
+		}
+	}
+}
+
+// constantCols returns the set of columns that have the same value in every
+// row of the relation.
+func (fds FuncDeps) constantCols() ColSet {
+	return fds.closure(ColSet{})
+}
+
+// add returns a new set of dependencies that includes the "from --> to"
+// dependency. Trivial dependencies (where to is a subset of from) are
+// discarded, and dependencies with the same determinant are merged.
+func (fds FuncDeps) add(from, to ColSet) FuncDeps {
+	to = to.Difference(from)
+	if to.Empty() {
+		return fds
+	}
+
+	for i := range fds {
+		if fds[i].From.Equals(from) {
+			if to.SubsetOf(fds[i].To) {
+				return fds
+			}
+
+			// Don't modify the existing slice, since it may be shared with
+			// the logical properties of another expression.
+			res := make(FuncDeps, len(fds))
+			copy(res, fds)
+			res[i].To = fds[i].To.Union(to)
+			return res
+		}
+	}
+
+	return append(fds[:len(fds):len(fds)], FuncDep{From: from.Copy(), To: to})
+}
+
+// addConstant returns a new set of dependencies that includes the given
+// columns as constant columns.
+func (fds FuncDeps) addConstant(cols ColSet) FuncDeps {
+	return fds.add(ColSet{}, cols)
+}
+
+// addEquiv returns a new set of dependencies in which each of the given
+// columns determines all of the others.
+func (fds FuncDeps) addEquiv(cols ColSet) FuncDeps {
+	cols.ForEach(func(i int) {
+		var from ColSet
+		from.Add(i)
+		fds = fds.add(from, cols)
+	})
+	return fds
+}
+
+// union returns a new set of dependencies that includes the dependencies from
+// both sets.
+func (fds FuncDeps) union(other FuncDeps) FuncDeps {
+	for i := range other {
+		fds = fds.add(other[i].From, other[i].To)
+	}
+	return fds
+}
+
+// nullExtended returns the subset of the dependencies that continue to hold
+// when NULL-extended rows are added to the relation, as happens to the inputs
+// of an outer join. A NULL-extended row has NULL values for all of the
+// relation's columns. Constant dependencies do not survive, since the
+// NULL-extended rows do not have the constant value. Other dependencies
+// survive only if their determinant columns cannot be NULL in the original
+// rows, since otherwise an original row and a NULL-extended row could have
+// equal (NULL) determinants, but different dependent values.
+func (fds FuncDeps) nullExtended(notNullCols ColSet) FuncDeps {
+	var res FuncDeps
+	for i := range fds {
+		if !fds[i].From.Empty() && fds[i].From.SubsetOf(notNullCols) {
+			res = append(res, fds[i])
+		}
+	}
+	return res
+}
+
+// project returns the dependencies that hold when the relation is projected
+// onto the given columns. Transitive dependencies that pass through columns
+// that are not projected are preserved. For example, if "a --> b" and
+// "b --> c", and b is not projected, then "a --> c" is part of the result.
+func (fds FuncDeps) project(cols ColSet) FuncDeps {
+	var res FuncDeps
+
+	constCols := fds.constantCols().Intersection(cols)
+	if !constCols.Empty() {
+		res = res.addConstant(constCols)
+	}
+
+	for i := range fds {
+		from := fds[i].From
+		if from.Empty() || !from.SubsetOf(cols) {
+			continue
+		}
+
+		res = res.add(from, fds.closure(from).Intersection(cols))
+	}
+
+	return res
+}
+
+// reduce returns the smallest subset of the given columns that functionally
+// determines all of the given columns. Columns are removed in order of
+// decreasing column index, so that earlier columns (which are typically table
+// columns rather than synthesized columns) are preferred. At least one column
+// is always kept if the set is non-empty, since callers like GROUP BY treat
+// an empty set of columns differently than a set of constant columns.
+func (fds FuncDeps) reduce(cols ColSet) ColSet {
+	res := cols.Copy()
+	ordered := cols.Ordered()
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if res.Len() == 1 {
+			break
+		}
+
+		col := ordered[i]
+		res.Remove(col)
+		if !fds.closure(res).Contains(col) {
+			res.Add(col)
+		}
+	}
+	return res
+}
+
+// reduceOrdering returns the given ordering with any columns removed that are
+// functionally determined by the columns which precede them in the ordering.
+// Those columns cannot change the order of rows, so sorting on them is wasted
+// work. For example, if "a --> b", then the ordering "+a,+b,+c" can be
+// reduced to "+a,+c". Constant columns are always removed.
+func (fds FuncDeps) reduceOrdering(ordering Ordering) Ordering {
+	if len(fds) == 0 {
+		return ordering
+	}
+
+	var prefix ColSet
+	var res Ordering
+	for _, col := range ordering {
+		colIndex := col
+		if colIndex < 0 {
+			colIndex = -colIndex
+		}
+
+		if fds.closure(prefix).Contains(int(colIndex)) {
+			continue
+		}
+
+		prefix.Add(int(colIndex))
+		res = append(res, col)
+	}
+
+	return res
+}
+
+func (fds FuncDeps) String() string {
+	var buf bytes.Buffer
+	fds.format(&buf)
+	return buf.String()
+}
+
+func (fds FuncDeps) format(buf *bytes.Buffer) {
+	for i := range fds {
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		fmt.Fprintf(buf, "%s-->%s", fds[i].From, fds[i].To)
+	}
+}
@@ -89,6 +89,23 @@ type LogicalProps struct {
 		// in the result set. No column may appear in more than one entry.
 		// EquivCols returns the empty slice for non-relational expressions.
 		EquivCols ColSets
+
+		// FuncDeps are the functional dependencies that hold between the
+		// expression's output columns. Keys, equivalent columns and constant
+		// columns (e.g. from a filter such as "a = 5") are all represented as
+		// functional dependencies. The dependencies are used to reduce sets of
+		// columns, such as the grouping columns of a GroupBy or the columns of
+		// a required ordering, to a smaller equivalent set. FuncDeps returns
+		// the empty slice for non-relational expressions.
+		FuncDeps FuncDeps
+
+		// Rows is the estimated number of rows returned by the expression. It
+		// is derived from table statistics when they are available, and
+		// otherwise from simple heuristics, such as assuming that a filter
+		// passes a third of its input rows. The coster uses the estimate to
+		// choose between logically equivalent expressions. Rows is zero for
+		// non-relational expressions.
+		Rows float64
 	}
 }
 
@@ -109,7 +126,25 @@ func (p *LogicalProps) addEquivColumnSets(colsets []ColSet) {
 	}
 }
 
+// hasStrongKey returns true if the given columns contain a key of the
+// expression, either directly or by functionally determining the columns of
+// one of its keys. In that case, no two rows of the expression can have equal
+// values for the columns.
+func (p *LogicalProps) hasStrongKey(cols ColSet) bool {
+	closure := p.Relational.FuncDeps.closure(cols)
+	for _, key := range p.Relational.WeakKeys {
+		if key.SubsetOf(p.Relational.NotNullCols) && key.SubsetOf(closure) {
+			return true
+		}
+	}
+	return false
+}
+
 type ForeignKeyProps struct {
 	src  ColSet
 	dest ColSet
+
+	// cols maps each foreign key column in src to the column in dest that it
+	// references.
+	cols ColMap
 }
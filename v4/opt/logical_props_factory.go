@@ -1,5 +1,25 @@
 package opt
 
+import (
+	"math"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+// defaultTableRows is the estimated number of rows in a table that does not
+// have any statistics.
+const defaultTableRows = 1000
+
+// defaultSelectivity is the estimated fraction of input rows that pass a
+// filter, when nothing more is known about the filter.
+const defaultSelectivity = 1.0 / 3
+
+// defaultGroupSize is the estimated number of input rows in each group of a
+// GroupBy operator, when nothing more is known about the grouping columns.
+const defaultGroupSize = 10
+
 type logicalPropsFactory struct {
 	mem *memo
 }
@@ -40,6 +60,9 @@ func (f *logicalPropsFactory) constructRelationalProps(e *Expr) *LogicalProps {
 
 	case GroupByOp:
 		return f.constructGroupByProps(e)
+
+	case IntersectOp, ExceptOp:
+		unimplemented("%v", e.op)
 	}
 
 	fatalf("unrecognized relational expression type: %v", e.op)
@@ -49,14 +72,15 @@ func (f *logicalPropsFactory) constructRelationalProps(e *Expr) *LogicalProps {
 func (f *logicalPropsFactory) constructScanProps(e *Expr) *LogicalProps {
 	var props LogicalProps
 
-	tblIndex := e.Private().(TableIndex)
+	tblIndex := e.TablePrivate()
 	tbl := f.mem.metadata.Table(tblIndex).Table
 
 	// A table's output column indexes are contiguous.
-	props.Relational.OutputCols.AddRange(int(tblIndex), int(tblIndex)+len(tbl.Columns)-1)
+	props.Relational.OutputCols.AddRange(int(tblIndex), int(tblIndex)+tbl.ColumnCount()-1)
 
 	// Initialize keys from the table schema.
-	for _, k := range tbl.Keys {
+	for i := 0; i < tbl.KeyCount(); i++ {
+		k := tbl.Key(i)
 		if k.Fkey == nil && (k.Primary || k.Unique) {
 			var key ColSet
 			for _, i := range k.Columns {
@@ -68,15 +92,43 @@ func (f *logicalPropsFactory) constructScanProps(e *Expr) *LogicalProps {
 	}
 
 	// Initialize not-NULL columns from the table schema.
-	for i, col := range tbl.Columns {
-		if col.NotNull {
+	for i := 0; i < tbl.ColumnCount(); i++ {
+		if tbl.Column(cat.ColumnOrdinal(i)).NotNull {
 			props.Relational.NotNullCols.Add(int(tblIndex) + i)
 		}
 	}
 
+	// Every key that has no NULL-able columns functionally determines all of
+	// the table's columns. Weak keys do not, since multiple rows can have
+	// NULL values for the key columns.
+	for _, key := range props.Relational.WeakKeys {
+		if key.SubsetOf(props.Relational.NotNullCols) {
+			props.Relational.FuncDeps = props.Relational.FuncDeps.add(key, props.Relational.OutputCols)
+		}
+	}
+
+	props.Relational.Rows = tableRows(tbl)
+
 	return &props
 }
 
+// tableRows returns the number of rows in the table according to the
+// statistics of its columns, or defaultTableRows if there are no statistics.
+func tableRows(tbl cat.Table) float64 {
+	rows := int64(-1)
+	for i := 0; i < tbl.ColumnCount(); i++ {
+		stats := tbl.Column(cat.ColumnOrdinal(i)).Stats
+		if stats != nil && stats.RowCount > rows {
+			rows = stats.RowCount
+		}
+	}
+
+	if rows < 0 {
+		return defaultTableRows
+	}
+	return float64(rows)
+}
+
 func (f *logicalPropsFactory) constructSelectProps(e *Expr) *LogicalProps {
 	var props LogicalProps
 
@@ -97,9 +149,26 @@ func (f *logicalPropsFactory) constructSelectProps(e *Expr) *LogicalProps {
 	// Inherit equivalent columns from input.
 	props.Relational.EquivCols = inputProps.Relational.EquivCols
 
+	// Inherit keys and functional dependencies from input. Filtering rows
+	// cannot invalidate either.
+	props.Relational.WeakKeys = inputProps.Relational.WeakKeys
+	props.Relational.FuncDeps = inputProps.Relational.FuncDeps
+
+	// Inherit foreign keys from input.
+	props.Relational.ForeignKeys = inputProps.Relational.ForeignKeys
+
 	// Set additional properties according to the join filter.
 	filter := e.Child(1)
 	f.addPropsFromFilter(&props, &filter, true)
+	props.Relational.FuncDeps = f.addFuncDepsFromFilter(props.Relational.FuncDeps, &filter)
+
+	// If the filter fixes the value of a key, then at most one row passes it.
+	// Otherwise, assume the default selectivity.
+	if props.hasStrongKey(props.Relational.FuncDeps.constantCols()) {
+		props.Relational.Rows = math.Min(inputProps.Relational.Rows, 1)
+	} else {
+		props.Relational.Rows = inputProps.Relational.Rows * defaultSelectivity
+	}
 
 	return &props
 }
@@ -112,7 +181,7 @@ func (f *logicalPropsFactory) constructProjectProps(e *Expr) *LogicalProps {
 
 	// Use output columns from projection list.
 	projections := e.Child(1)
-	props.Relational.OutputCols = *projections.Private().(*ColSet)
+	props.Relational.OutputCols = *projections.ColIndexesPrivate()
 
 	// Inherit not null columns from input. This may contain non-output
 	// columns.
@@ -128,6 +197,25 @@ func (f *logicalPropsFactory) constructProjectProps(e *Expr) *LogicalProps {
 	// columns.
 	props.Relational.EquivCols = inputProps.Relational.EquivCols
 
+	// Inherit any keys from input that are still fully projected.
+	for _, key := range inputProps.Relational.WeakKeys {
+		if key.SubsetOf(props.Relational.OutputCols) {
+			props.Relational.WeakKeys = append(props.Relational.WeakKeys, key)
+		}
+	}
+
+	// Inherit any foreign keys from input that are still fully projected.
+	for _, fkey := range inputProps.Relational.ForeignKeys {
+		if fkey.src.Union(fkey.dest).SubsetOf(props.Relational.OutputCols) {
+			props.Relational.ForeignKeys = append(props.Relational.ForeignKeys, fkey)
+		}
+	}
+
+	// Project the input's functional dependencies onto the output columns.
+	props.Relational.FuncDeps = inputProps.Relational.FuncDeps.project(props.Relational.OutputCols)
+
+	props.Relational.Rows = inputProps.Relational.Rows
+
 	return &props
 }
 
@@ -182,13 +270,267 @@ func (f *logicalPropsFactory) constructJoinProps(e *Expr) *LogicalProps {
 		props.Relational.EquivCols = append(props.Relational.EquivCols, rightProps.Relational.EquivCols...)
 	}
 
-	// Set additional properties according to the join filter.
+	// Set additional properties according to the join filter. Outer and anti
+	// joins output rows for which the filter is not true, so the filter does
+	// not constrain their output columns.
 	filter := e.Child(2)
-	f.addPropsFromFilter(&props, &filter, false)
+	switch e.Operator() {
+	case InnerJoinOp, SemiJoinOp, InnerJoinApplyOp, SemiJoinApplyOp:
+		f.addPropsFromFilter(&props, &filter, false)
+	}
+
+	// Combine functional dependencies from the inputs. The dependencies of an
+	// input that can be NULL-extended by an outer join are weakened.
+	leftFDs := leftProps.Relational.FuncDeps
+	rightFDs := rightProps.Relational.FuncDeps
+	switch e.Operator() {
+	case InnerJoinOp, InnerJoinApplyOp:
+		props.Relational.FuncDeps = leftFDs.union(rightFDs)
+		props.Relational.FuncDeps = f.addFuncDepsFromFilter(props.Relational.FuncDeps, &filter)
+
+	case LeftJoinOp, LeftJoinApplyOp:
+		rightFDs = rightFDs.nullExtended(rightProps.Relational.NotNullCols)
+		props.Relational.FuncDeps = leftFDs.union(rightFDs)
+
+	case RightJoinOp, RightJoinApplyOp:
+		leftFDs = leftFDs.nullExtended(leftProps.Relational.NotNullCols)
+		props.Relational.FuncDeps = leftFDs.union(rightFDs)
+
+	case FullJoinOp, FullJoinApplyOp:
+		leftFDs = leftFDs.nullExtended(leftProps.Relational.NotNullCols)
+		rightFDs = rightFDs.nullExtended(rightProps.Relational.NotNullCols)
+		props.Relational.FuncDeps = leftFDs.union(rightFDs)
+
+	case SemiJoinOp, AntiJoinOp, SemiJoinApplyOp, AntiJoinApplyOp:
+		props.Relational.FuncDeps = leftFDs
+	}
+
+	// Derive keys from the inputs.
+	switch e.Operator() {
+	case SemiJoinOp, AntiJoinOp, SemiJoinApplyOp, AntiJoinApplyOp:
+		// Semi and anti joins return each left row at most once.
+		props.Relational.WeakKeys = leftProps.Relational.WeakKeys
+
+	default:
+		// The union of a key from each side is a key of the join.
+		for _, leftKey := range leftProps.Relational.WeakKeys {
+			for _, rightKey := range rightProps.Relational.WeakKeys {
+				props.Relational.WeakKeys = append(props.Relational.WeakKeys, leftKey.Union(rightKey))
+			}
+		}
+
+		// If the columns of one side of an inner join determine a key of the
+		// join (e.g. because the filter equates them to a key of the other
+		// side), then each row of that side joins with at most one row, and
+		// its keys remain keys of the join.
+		switch e.Operator() {
+		case InnerJoinOp, InnerJoinApplyOp:
+			if props.hasStrongKey(leftProps.Relational.OutputCols) {
+				props.Relational.WeakKeys = append(props.Relational.WeakKeys, leftProps.Relational.WeakKeys...)
+			}
+			if props.hasStrongKey(rightProps.Relational.OutputCols) {
+				props.Relational.WeakKeys = append(props.Relational.WeakKeys, rightProps.Relational.WeakKeys...)
+			}
+		}
+	}
+
+	// Derive foreign keys from the inputs. A foreign key from one input to the
+	// other is added when the join filter equates the foreign key columns with
+	// the columns they reference, and the rows of that input are preserved by
+	// the join.
+	left := e.ChildGroup(0)
+	right := e.ChildGroup(1)
+	on := e.ChildGroup(2)
+	switch e.Operator() {
+	case InnerJoinOp, InnerJoinApplyOp:
+		props.Relational.ForeignKeys = append(props.Relational.ForeignKeys, leftProps.Relational.ForeignKeys...)
+		props.Relational.ForeignKeys = append(props.Relational.ForeignKeys, rightProps.Relational.ForeignKeys...)
+		props.Relational.ForeignKeys = append(props.Relational.ForeignKeys, f.joinForeignKeys(left, right, on)...)
+		props.Relational.ForeignKeys = append(props.Relational.ForeignKeys, f.joinForeignKeys(right, left, on)...)
+
+	case LeftJoinOp, LeftJoinApplyOp:
+		props.Relational.ForeignKeys = append(props.Relational.ForeignKeys, leftProps.Relational.ForeignKeys...)
+		props.Relational.ForeignKeys = append(props.Relational.ForeignKeys, f.joinForeignKeys(left, right, on)...)
+
+	case RightJoinOp, RightJoinApplyOp:
+		props.Relational.ForeignKeys = append(props.Relational.ForeignKeys, rightProps.Relational.ForeignKeys...)
+		props.Relational.ForeignKeys = append(props.Relational.ForeignKeys, f.joinForeignKeys(right, left, on)...)
+
+	case SemiJoinOp, AntiJoinOp, SemiJoinApplyOp, AntiJoinApplyOp:
+		props.Relational.ForeignKeys = leftProps.Relational.ForeignKeys
+	}
+
+	// Estimate the number of rows. A join with a filter is assumed to match
+	// each row of its smaller input with a single row of its larger input, as
+	// happens with an equality join on a key. A join without a filter is a
+	// cross product. Outer joins return at least the rows of their preserved
+	// inputs.
+	leftRows := leftProps.Relational.Rows
+	rightRows := rightProps.Relational.Rows
+	innerRows := leftRows * rightRows
+	if !filterProps.UnboundCols.Empty() {
+		innerRows = math.Min(leftRows, rightRows)
+	}
+
+	switch e.Operator() {
+	case LeftJoinOp, LeftJoinApplyOp:
+		props.Relational.Rows = math.Max(innerRows, leftRows)
+
+	case RightJoinOp, RightJoinApplyOp:
+		props.Relational.Rows = math.Max(innerRows, rightRows)
+
+	case FullJoinOp, FullJoinApplyOp:
+		props.Relational.Rows = math.Max(innerRows, math.Max(leftRows, rightRows))
+
+	case SemiJoinOp, AntiJoinOp, SemiJoinApplyOp, AntiJoinApplyOp:
+		props.Relational.Rows = leftRows / 2
+
+	default:
+		props.Relational.Rows = innerRows
+	}
 
 	return &props
 }
 
+// joinForeignKeys returns the foreign keys from columns of the source input of
+// a join to columns of the destination input, for which the join filter
+// equates each foreign key column with the column that it references. The
+// destination input must be an unfiltered scan of the referenced table, so
+// that it contains every row that a foreign key value can reference.
+func (f *logicalPropsFactory) joinForeignKeys(src, dest, on GroupID) []ForeignKeyProps {
+	destExpr := f.mem.lookupNormExpr(dest)
+	if destExpr.op != ScanOp {
+		return nil
+	}
+
+	md := f.mem.metadata
+	destIndex := f.mem.lookupTable(destExpr.asScan().table())
+	destTbl := md.Table(destIndex).Table
+	srcCols := f.mem.lookupGroup(src).logical.Relational.OutputCols
+
+	// Visit tables in index order, so that the foreign keys are derived in a
+	// deterministic order.
+	tblIndexes := make([]TableIndex, 0, len(md.tables))
+	for tblIndex := range md.tables {
+		tblIndexes = append(tblIndexes, tblIndex)
+	}
+	sort.Slice(tblIndexes, func(i, j int) bool { return tblIndexes[i] < tblIndexes[j] })
+
+	var fkeys []ForeignKeyProps
+	for _, tblIndex := range tblIndexes {
+		tbl := md.Table(tblIndex).Table
+		for i := 0; i < tbl.KeyCount(); i++ {
+			// Tables are compared by name, since a catalog can return a new
+			// Table each time that it's asked for one.
+			k := tbl.Key(i)
+			if k.Fkey == nil || k.Fkey.Referenced.TabName() != destTbl.TabName() {
+				continue
+			}
+
+			fkey := ForeignKeyProps{cols: make(ColMap)}
+			for i, ord := range k.Columns {
+				srcCol := md.TableColumn(tblIndex, ord)
+				destCol := md.TableColumn(destIndex, k.Fkey.Columns[i])
+				fkey.src.Add(int(srcCol))
+				fkey.dest.Add(int(destCol))
+				fkey.cols[srcCol] = destCol
+			}
+
+			if fkey.src.SubsetOf(srcCols) && f.equatesColumns(on, fkey.cols) {
+				fkeys = append(fkeys, fkey)
+			}
+		}
+	}
+
+	return fkeys
+}
+
+// isKeyedOnJoinCols returns true if the join filter equates the columns of a
+// key of the right input with columns of the left input, so that each left
+// row matches at most one right row. Weak keys of the right input qualify as
+// well, since rows with NULL key values never satisfy the equalities.
+func (f *logicalPropsFactory) isKeyedOnJoinCols(left, right, on GroupID) bool {
+	leftProps := f.mem.lookupGroup(left).logical
+	rightProps := f.mem.lookupGroup(right).logical
+
+	fds := leftProps.Relational.FuncDeps.union(rightProps.Relational.FuncDeps)
+	onExpr := makeExpr(f.mem, on, defaultPhysPropsID)
+	fds = f.addFuncDepsFromFilter(fds, &onExpr)
+
+	closure := fds.closure(leftProps.Relational.OutputCols)
+	for _, key := range rightProps.Relational.WeakKeys {
+		if key.SubsetOf(closure) {
+			return true
+		}
+	}
+	return false
+}
+
+// equatesColumns returns true if the filter contains an equality condition
+// between each pair of columns in the given map.
+func (f *logicalPropsFactory) equatesColumns(filter GroupID, cols ColMap) bool {
+	for left, right := range cols {
+		found := false
+		for _, condition := range f.filterConditions(filter) {
+			if condLeft, condRight, ok := f.columnEquality(condition); ok {
+				if (condLeft == left && condRight == right) || (condLeft == right && condRight == left) {
+					found = true
+					break
+				}
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterConditions returns the conjuncts of the given filter, which may be a
+// Filters or And operator, or a single condition. The True operator has no
+// conjuncts.
+func (f *logicalPropsFactory) filterConditions(filter GroupID) []GroupID {
+	filterExpr := f.mem.lookupNormExpr(filter)
+	switch filterExpr.op {
+	case TrueOp:
+		return nil
+
+	case FiltersOp:
+		var conditions []GroupID
+		for _, condition := range f.mem.lookupList(filterExpr.asFilters().conditions()) {
+			conditions = append(conditions, f.filterConditions(condition)...)
+		}
+		return conditions
+
+	case AndOp:
+		andExpr := filterExpr.asAnd()
+		return append(f.filterConditions(andExpr.left()), f.filterConditions(andExpr.right())...)
+	}
+
+	return []GroupID{filter}
+}
+
+// columnEquality returns the columns of the given condition if it is an
+// equality between two columns.
+func (f *logicalPropsFactory) columnEquality(condition GroupID) (left, right ColumnIndex, ok bool) {
+	eqExpr := f.mem.lookupNormExpr(condition).asEq()
+	if eqExpr == nil {
+		return 0, 0, false
+	}
+
+	leftExpr := f.mem.lookupNormExpr(eqExpr.left()).asVariable()
+	rightExpr := f.mem.lookupNormExpr(eqExpr.right()).asVariable()
+	if leftExpr == nil || rightExpr == nil {
+		return 0, 0, false
+	}
+
+	left = f.mem.lookupColIndex(leftExpr.col())
+	right = f.mem.lookupColIndex(rightExpr.col())
+	return left, right, true
+}
+
 func (f *logicalPropsFactory) constructGroupByProps(e *Expr) *LogicalProps {
 	var props LogicalProps
 
@@ -199,9 +541,9 @@ func (f *logicalPropsFactory) constructGroupByProps(e *Expr) *LogicalProps {
 	// Output columns are union of columns from grouping and aggregate
 	// projection lists.
 	groupings := e.Child(1)
-	props.Relational.OutputCols = groupings.Private().(*ColSet).Copy()
+	props.Relational.OutputCols = groupings.ColIndexesPrivate().Copy()
 	agg := e.Child(2)
-	props.Relational.OutputCols.UnionWith(*agg.Private().(*ColSet))
+	props.Relational.OutputCols.UnionWith(*agg.ColIndexesPrivate())
 
 	// Find all unbound columns from the groupings or aggregation expressions
 	// that are not bound by the input columns, and union those with unbound
@@ -211,6 +553,32 @@ func (f *logicalPropsFactory) constructGroupByProps(e *Expr) *LogicalProps {
 	props.UnboundCols.DifferenceWith(inputProps.Relational.OutputCols)
 	props.UnboundCols.UnionWith(inputProps.UnboundCols)
 
+	// Grouping columns that are not NULL in the input remain not NULL.
+	groupingCols := *groupings.ColIndexesPrivate()
+	props.Relational.NotNullCols = inputProps.Relational.NotNullCols.Intersection(groupingCols)
+
+	// The grouping columns form a key, since each group is output as a single
+	// row, and they determine the values of the aggregate columns. Any
+	// dependencies between the grouping columns in the input still hold.
+	props.Relational.WeakKeys = ColSets{groupingCols}
+	props.Relational.FuncDeps = inputProps.Relational.FuncDeps.project(groupingCols)
+	props.Relational.FuncDeps = props.Relational.FuncDeps.add(groupingCols, props.Relational.OutputCols)
+
+	// A scalar GroupBy always returns one row. If the grouping columns contain
+	// a key of the input, then each group has a single row. Otherwise, assume
+	// the default group size.
+	inputRows := inputProps.Relational.Rows
+	switch {
+	case groupingCols.Empty():
+		props.Relational.Rows = 1
+
+	case inputProps.hasStrongKey(groupingCols):
+		props.Relational.Rows = inputRows
+
+	default:
+		props.Relational.Rows = math.Max(inputRows/defaultGroupSize, math.Min(inputRows, 1))
+	}
+
 	return &props
 }
 
@@ -219,7 +587,7 @@ func (f *logicalPropsFactory) constructSetProps(e *Expr) *LogicalProps {
 
 	leftProps := f.mem.lookupGroup(e.ChildGroup(0)).logical
 	rightProps := f.mem.lookupGroup(e.ChildGroup(1)).logical
-	colMap := *e.Private().(*ColMap)
+	colMap := *e.ColMapPrivate()
 
 	// Use left input's output columns.
 	props.Relational.OutputCols = leftProps.Relational.OutputCols
@@ -238,6 +606,8 @@ func (f *logicalPropsFactory) constructSetProps(e *Expr) *LogicalProps {
 	// Unbound columns from either side are unbound in result.
 	props.UnboundCols = leftProps.UnboundCols.Union(rightProps.UnboundCols)
 
+	props.Relational.Rows = leftProps.Relational.Rows + rightProps.Relational.Rows
+
 	return &props
 }
 
@@ -247,7 +617,7 @@ func (f *logicalPropsFactory) constructValuesProps(e *Expr) *LogicalProps {
 	rowsProps := f.mem.lookupGroup(e.ChildGroup(0)).logical
 
 	// Use output columns that are attached to the values op.
-	props.Relational.OutputCols = *e.Private().(*ColSet)
+	props.Relational.OutputCols = *e.ColIndexesPrivate()
 
 	// Inherit unbound columns from rows expression.
 	props.UnboundCols = rowsProps.UnboundCols
@@ -258,16 +628,85 @@ func (f *logicalPropsFactory) constructValuesProps(e *Expr) *LogicalProps {
 // Add additional not-NULL columns based on the filtering expression.
 func (f *logicalPropsFactory) addPropsFromFilter(props *LogicalProps, filter *Expr, copyOnWrite bool) {
 	// Expand the set of non-NULL columns based on the filter.
-	//
-	// TODO(peter): Need to make sure the filter is not null-tolerant.
 	if copyOnWrite {
 		props.Relational.NotNullCols = props.Relational.NotNullCols.Copy()
 	}
-	props.Relational.NotNullCols.UnionWith(filter.Logical().UnboundCols)
+	props.Relational.NotNullCols.UnionWith(f.nullRejectedCols(filter))
 
 	f.addEquivProperties(props, filter, copyOnWrite)
 }
 
+// nullRejectedCols returns the columns referenced by the filtering expression
+// that cannot be NULL in any row that passes the filter. A comparison such as
+// "x < y + 1" is NULL if x or y is NULL, so it rejects rows where they are.
+// Conditions such as "x IS NULL" or "coalesce(x, 0) = 0" pass rows where
+// their columns are NULL, so their columns are not included, with the
+// exception of "x IS NOT NULL". An OR condition only rejects the columns that
+// both of its sides reject. Any other condition is assumed not to reject
+// NULL columns.
+func (f *logicalPropsFactory) nullRejectedCols(filter *Expr) ColSet {
+	switch filter.Operator() {
+	case FiltersOp, AndOp:
+		var cols ColSet
+		for i := 0; i < filter.ChildCount(); i++ {
+			child := filter.Child(i)
+			cols.UnionWith(f.nullRejectedCols(&child))
+		}
+		return cols
+
+	case OrOp:
+		left := filter.Child(0)
+		right := filter.Child(1)
+		return f.nullRejectedCols(&left).Intersection(f.nullRejectedCols(&right))
+
+	case IsNotOp:
+		left := filter.Child(0)
+		right := filter.Child(1)
+		if right.Operator() == ConstOp && right.DatumPrivate() == tree.DNull {
+			return f.nullPropagatingCols(&left)
+		}
+		return ColSet{}
+
+	case EqOp, LtOp, GtOp, LeOp, GeOp, NeOp,
+		LikeOp, NotLikeOp, ILikeOp, NotILikeOp, SimilarToOp, NotSimilarToOp,
+		RegMatchOp, NotRegMatchOp, RegIMatchOp, NotRegIMatchOp:
+		left := filter.Child(0)
+		right := filter.Child(1)
+		return f.nullPropagatingCols(&left).Union(f.nullPropagatingCols(&right))
+
+	case InOp, NotInOp:
+		// "1 IN (x, 1)" is true even if x is NULL, so only the columns of the
+		// left side are rejected.
+		left := filter.Child(0)
+		return f.nullPropagatingCols(&left)
+	}
+
+	return ColSet{}
+}
+
+// nullPropagatingCols returns the columns referenced by the scalar expression
+// that make the expression NULL if any of them is NULL. Variables and the
+// arithmetic, bitwise and concatenation operators propagate NULL, while
+// functions and all other operators are assumed not to.
+func (f *logicalPropsFactory) nullPropagatingCols(scalar *Expr) ColSet {
+	switch scalar.Operator() {
+	case VariableOp:
+		return scalar.Logical().UnboundCols
+
+	case PlusOp, MinusOp, MultOp, DivOp, FloorDivOp, ModOp, PowOp, ConcatOp,
+		BitandOp, BitorOp, BitxorOp, LShiftOp, RShiftOp,
+		UnaryPlusOp, UnaryMinusOp, UnaryComplementOp:
+		var cols ColSet
+		for i := 0; i < scalar.ChildCount(); i++ {
+			child := scalar.Child(i)
+			cols.UnionWith(f.nullPropagatingCols(&child))
+		}
+		return cols
+	}
+
+	return ColSet{}
+}
+
 func (f *logicalPropsFactory) addEquivProperties(props *LogicalProps, filter *Expr, copyOnWrite bool) bool {
 	// Find equivalent columns.
 	switch filter.Operator() {
@@ -296,6 +735,44 @@ func (f *logicalPropsFactory) addEquivProperties(props *LogicalProps, filter *Ex
 	return copyOnWrite
 }
 
+// addFuncDepsFromFilter returns the given functional dependencies, augmented
+// with dependencies derived from the filtering expression. Equalities between
+// two columns make each column determine the other, and equalities between a
+// column and a constant value make the column constant. Both kinds of
+// equality are NULL-intolerant, so the dependencies hold for all rows that
+// pass the filter.
+func (f *logicalPropsFactory) addFuncDepsFromFilter(fds FuncDeps, filter *Expr) FuncDeps {
+	switch filter.Operator() {
+	case EqOp:
+		left := filter.Child(0)
+		right := filter.Child(1)
+
+		// Filters are only normalized to put variables on the left side when
+		// normalization is enabled.
+		if left.op != VariableOp {
+			left, right = right, left
+		}
+
+		if left.op == VariableOp {
+			switch right.op {
+			case VariableOp:
+				fds = fds.addEquiv(left.Logical().UnboundCols.Union(right.Logical().UnboundCols))
+
+			case ConstOp, PlaceholderOp:
+				fds = fds.addConstant(left.Logical().UnboundCols)
+			}
+		}
+
+	case AndOp, FiltersOp:
+		for i := 0; i < filter.ChildCount(); i++ {
+			child := filter.Child(i)
+			fds = f.addFuncDepsFromFilter(fds, &child)
+		}
+	}
+
+	return fds
+}
+
 func (f *logicalPropsFactory) constructScalarProps(e *Expr) *LogicalProps {
 	switch e.Operator() {
 	case VariableOp:
@@ -315,7 +792,7 @@ func (f *logicalPropsFactory) constructScalarProps(e *Expr) *LogicalProps {
 
 func (f *logicalPropsFactory) constructVariableProps(e *Expr) *LogicalProps {
 	var props LogicalProps
-	props.UnboundCols.Add(int(e.Private().(ColumnIndex)))
+	props.UnboundCols.Add(int(e.ColIndexPrivate()))
 	return &props
 }
 
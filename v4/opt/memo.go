@@ -2,6 +2,7 @@ package opt
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/petermattis/opttoy/v4/cat"
@@ -48,8 +49,10 @@ type memo struct {
 
 	// groups is the set of all groups in the memo, indexed by group ID. Note
 	// the group ID 0 is invalid in order to allow zero initialization of an
-	// expression to indicate that it did not originate from the memo.
-	groups []memoGroup
+	// expression to indicate that it did not originate from the memo. Groups
+	// are stored by pointer so that callers can hold on to a group while new
+	// groups are added to the memo, as happens during exploration.
+	groups []*memoGroup
 
 	// logPropsFactory is used to derive logical properties for an expression,
 	// based on the logical properties of its children.
@@ -72,14 +75,15 @@ type memo struct {
 	// list.
 	lists []GroupID
 
-	// Intern the set of unique privates used by expressions in the memo, since
-	// there are so many duplicates. Note that PrivateID 0 is invalid in order
-	// to indicate an unknown private.
-	privatesMap map[interface{}]PrivateID
-	privates    []interface{}
+	// Intern the set of unique lists, so that expressions with lists that
+	// contain the same items have the same fingerprint.
+	listsMap map[string]ListID
+
+	// Intern the set of unique privates used by expressions in the memo.
+	privateStorage
 }
 
-func newMemo(catalog *cat.Catalog) *memo {
+func newMemo(catalog cat.Catalog) *memo {
 	// NB: group 0 is reserved and intentionally nil so that the 0 group index
 	// can indicate that we don't know the group for an expression. Similarly,
 	// index 0 for private data, index 0 for physical properties, and index 0
@@ -87,14 +91,15 @@ func newMemo(catalog *cat.Catalog) *memo {
 	m := &memo{
 		metadata:     newMetadata(catalog),
 		exprMap:      make(map[fingerprint]GroupID),
-		groups:       make([]memoGroup, 1),
+		groups:       make([]*memoGroup, 1),
 		physPropsMap: make(map[string]physicalPropsID),
 		physProps:    make([]PhysicalProps, 1, 2),
 		lists:        make([]GroupID, 1),
-		privatesMap:  make(map[interface{}]PrivateID),
-		privates:     make([]interface{}, 1),
+		listsMap:     make(map[string]ListID),
 	}
 
+	m.privateStorage.init()
+
 	m.logPropsFactory.init(m)
 	m.physPropsFactory.init(m)
 
@@ -110,12 +115,13 @@ func newMemo(catalog *cat.Catalog) *memo {
 func (m *memo) newGroup(norm *memoExpr) *memoGroup {
 	id := GroupID(len(m.groups))
 	exprs := []memoExpr{*norm}
-	m.groups = append(m.groups, memoGroup{
+	mgrp := &memoGroup{
 		id:           id,
 		exprs:        exprs,
 		bestExprsMap: make(map[physicalPropsID]int),
-	})
-	return &m.groups[len(m.groups)-1]
+	}
+	m.groups = append(m.groups, mgrp)
+	return mgrp
 }
 
 // addAltFingerprint checks whether the given fingerprint already references
@@ -170,7 +176,7 @@ func (m *memo) memoizeDenormExpr(group GroupID, denorm *memoExpr) {
 }
 
 func (m *memo) lookupGroup(group GroupID) *memoGroup {
-	return &m.groups[group]
+	return m.groups[group]
 }
 
 func (m *memo) lookupGroupByFingerprint(f fingerprint) GroupID {
@@ -186,28 +192,23 @@ func (m *memo) lookupNormExpr(group GroupID) *memoExpr {
 }
 
 func (m *memo) storeList(items []GroupID) ListID {
-	id := ListID{offset: uint32(len(m.lists)), len: uint32(len(items))}
-	m.lists = append(m.lists, items...)
-	return id
-}
-
-func (m *memo) lookupList(id ListID) []GroupID {
-	return m.lists[id.offset : id.offset+id.len : id.offset+id.len]
-}
+	var buf bytes.Buffer
+	for _, item := range items {
+		binary.Write(&buf, binary.LittleEndian, item)
+	}
 
-func (m *memo) internPrivate(private interface{}) PrivateID {
-	id, ok := m.privatesMap[private]
+	key := buf.String()
+	id, ok := m.listsMap[key]
 	if !ok {
-		id = PrivateID(len(m.privates))
-		m.privates = append(m.privates, private)
-		m.privatesMap[private] = id
+		id = ListID{offset: uint32(len(m.lists)), len: uint32(len(items))}
+		m.lists = append(m.lists, items...)
+		m.listsMap[key] = id
 	}
-
 	return id
 }
 
-func (m *memo) lookupPrivate(id PrivateID) interface{} {
-	return m.privates[id]
+func (m *memo) lookupList(id ListID) []GroupID {
+	return m.lists[id.offset : id.offset+id.len : id.offset+id.len]
 }
 
 func (m *memo) internPhysicalProps(props *PhysicalProps) physicalPropsID {
@@ -230,11 +231,11 @@ func (m *memo) lookupPhysicalProps(id physicalPropsID) *PhysicalProps {
 func (m *memo) String() string {
 	var buf bytes.Buffer
 	for i := len(m.groups) - 1; i > 0; i-- {
-		mgrp := &m.groups[i]
+		mgrp := m.groups[i]
 		fmt.Fprintf(&buf, "%d:", i)
 		for i := range mgrp.exprs {
 			mexpr := &mgrp.exprs[i]
-			fmt.Fprintf(&buf, " %s", mexpr.memoString(m, mgrp))
+			fmt.Fprintf(&buf, " %s", mexpr.memoString(m, memoLoc{group: mgrp.id, expr: exprID(i)}))
 		}
 		fmt.Fprintf(&buf, "\n")
 	}
@@ -0,0 +1,100 @@
+package opt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// formatDOT writes the memo to the buffer as a Graphviz DOT graph. Each group
+// is drawn as a cluster that contains its expressions, and each expression
+// has an edge to each of its child groups. The best expression for each set
+// of required properties is highlighted and labeled with its cost. Enforcers
+// are not stored in the memo, so they're only drawn if they're the best
+// expression for some set of required properties.
+func (m *memo) formatDOT(buf *bytes.Buffer) {
+	buf.WriteString("digraph memo {\n")
+	buf.WriteString("  compound=true;\n")
+	buf.WriteString("  node [shape=box];\n")
+
+	for _, mgrp := range m.groups[1:] {
+		required := make([]physicalPropsID, len(mgrp.bestExprs))
+		for id, index := range mgrp.bestExprsMap {
+			required[index] = id
+		}
+
+		// Collect the labels of the best expressions for each expression in
+		// the group.
+		bests := make([][]string, len(mgrp.exprs))
+		var enforcers []string
+		for i := range mgrp.bestExprs {
+			best := &mgrp.bestExprs[i]
+			if best.op == UnknownOp {
+				// No expression in the group could provide the properties
+				// within the cost limit.
+				continue
+			}
+
+			props := m.lookupPhysicalProps(required[i]).fingerprint()
+			label := fmt.Sprintf("best (%s): %g", formatRequired(props), best.cost)
+			if isEnforcerLookup[best.op] {
+				enforcers = append(enforcers, fmt.Sprintf(
+					"    g%dp%d [label=%q, style=\"filled,dashed\", fillcolor=lightblue];\n",
+					mgrp.id, required[i], best.op.String()+"\n"+label))
+			} else {
+				bests[best.loc.expr] = append(bests[best.loc.expr], label)
+			}
+		}
+
+		fmt.Fprintf(buf, "  subgraph cluster_%d {\n", mgrp.id)
+		fmt.Fprintf(buf, "    label=\"[%d]\";\n", mgrp.id)
+		for i := range mgrp.exprs {
+			loc := memoLoc{group: mgrp.id, expr: exprID(i)}
+			label := m.exprLabel(loc)
+			attrs := ""
+			for _, best := range bests[i] {
+				label += "\n" + best
+				attrs = ", style=filled, fillcolor=lightblue"
+			}
+			fmt.Fprintf(buf, "    %s [label=%q%s];\n", dotNode(loc), label, attrs)
+		}
+		for _, enforcer := range enforcers {
+			buf.WriteString(enforcer)
+		}
+		buf.WriteString("  }\n")
+	}
+
+	for _, mgrp := range m.groups[1:] {
+		for i := range mgrp.exprs {
+			loc := memoLoc{group: mgrp.id, expr: exprID(i)}
+			e := Expr{mem: m, loc: loc, op: mgrp.exprs[i].op, required: defaultPhysPropsID}
+			for child := 0; child < e.ChildCount(); child++ {
+				group := e.ChildGroup(child)
+				if group == 0 {
+					continue
+				}
+				fmt.Fprintf(buf, "  %s -> %s [lhead=cluster_%d];\n",
+					dotNode(loc), dotNode(memoLoc{group: group, expr: normExprID}), group)
+			}
+		}
+	}
+
+	buf.WriteString("}\n")
+}
+
+// exprLabel returns the operator of the expression at the given location,
+// followed by a short description of its private value.
+func (m *memo) exprLabel(loc memoLoc) string {
+	mexpr := m.lookupExpr(loc)
+	e := Expr{mem: m, loc: loc, op: mexpr.op, required: defaultPhysPropsID}
+
+	var buf bytes.Buffer
+	buf.WriteString(mexpr.op.String())
+	formatPrivate(&buf, m, e.Private())
+	return buf.String()
+}
+
+// dotNode returns the name of the DOT node for the expression at the given
+// location.
+func dotNode(loc memoLoc) string {
+	return fmt.Sprintf("g%de%d", loc.group, loc.expr)
+}
@@ -9,6 +9,33 @@ import (
 // fields in the memo expression.
 type exprState [3]uint32
 
+// stateLayout describes how the fields of an operator are stored in the state
+// of its memo expressions (see stateLayoutLookup in expr.og.go). The state
+// starts with the group of each child expression field, followed by the offset
+// and length of the list field, if there is one, and then the id of the
+// private field, if there is one.
+type stateLayout struct {
+	// exprs is the number of child expression fields, not counting the list.
+	exprs int
+
+	// relational is true for each child expression field that's relational,
+	// which is declared by the RelExpr type. It's nil if all of the child
+	// expression fields are scalar. The items of a list are always scalar.
+	relational []bool
+
+	// list is true if the operator has a list field.
+	list bool
+
+	// private is the name of the type of the private field in the optgen
+	// source, such as ColIndexes, or "" if there's no private field.
+	private string
+}
+
+// isRelational returns true if the nth child expression field is relational.
+func (l *stateLayout) isRelational(nth int) bool {
+	return nth < len(l.relational) && l.relational[nth]
+}
+
 // memoExpr is a memoized representation of an expression. Strongly-typed
 // specializations of memoExpr are generated by optgen for each operator (see
 // Expr.og.go). Each memoExpr belongs to a memo group, which contain logically
@@ -44,27 +71,13 @@ func (me memoExpr) fingerprint() fingerprint {
 	return fingerprint(me)
 }
 
-func (me memoExpr) memoString(mem *memo, mgrp *memoGroup) string {
+func (me memoExpr) memoString(mem *memo, loc memoLoc) string {
 	var buf bytes.Buffer
 
-	e := makeExpr(mem, mgrp.id, defaultPhysPropsID)
+	e := Expr{mem: mem, loc: loc, op: me.op, required: defaultPhysPropsID}
 
 	fmt.Fprintf(&buf, "[%s", e.Operator())
-
-	private := e.Private()
-	if private != nil {
-		switch t := private.(type) {
-		case nil:
-		case TableIndex:
-			fmt.Fprintf(&buf, " %s", mem.metadata.Table(t).Table.Name)
-		case ColumnIndex:
-			fmt.Fprintf(&buf, " %s", mem.metadata.ColumnLabel(t))
-		case *ColSet, *ColMap:
-			// Don't show anything, because it's mostly redundant.
-		default:
-			fmt.Fprintf(&buf, " %s", private)
-		}
-	}
+	formatPrivate(&buf, mem, e.Private())
 
 	if e.ChildCount() > 0 {
 		fmt.Fprintf(&buf, " [")
@@ -85,3 +98,20 @@ func (me memoExpr) memoString(mem *memo, mgrp *memoGroup) string {
 	buf.WriteString("]")
 	return buf.String()
 }
+
+// formatPrivate writes a short description of the given private value to the
+// buffer, preceded by a space. Nothing is written for privates that are mostly
+// redundant with the expression's children.
+func formatPrivate(buf *bytes.Buffer, mem *memo, private interface{}) {
+	switch t := private.(type) {
+	case nil:
+	case TableIndex:
+		fmt.Fprintf(buf, " %s", mem.metadata.Table(t).Table.TabName().Table)
+	case ColumnIndex:
+		fmt.Fprintf(buf, " %s", mem.metadata.ColumnLabel(t))
+	case *ColSet, *ColMap:
+		// Don't show anything, because it's mostly redundant.
+	default:
+		fmt.Fprintf(buf, " %s", private)
+	}
+}
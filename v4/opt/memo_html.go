@@ -0,0 +1,238 @@
+package opt
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// writeHTML writes an HTML page that replays the optimization of the memo,
+// one step of the given trace at a time. The page shows every group and
+// expression in the memo, and each step adds, costs or prunes an expression.
+// Expressions that were added by exploration are hidden until the step that
+// added them, and the best expression found so far for each set of required
+// properties is highlighted.
+func (m *memo) writeHTML(w io.Writer, trace *OptimizeTrace) error {
+	var data htmlMemo
+	for _, mgrp := range m.groups[1:] {
+		grp := htmlGroup{ID: mgrp.id}
+		for i := range mgrp.exprs {
+			loc := memoLoc{group: mgrp.id, expr: exprID(i)}
+			e := Expr{mem: m, loc: loc, op: mgrp.exprs[i].op, required: defaultPhysPropsID}
+
+			var children []string
+			for child := 0; child < e.ChildCount(); child++ {
+				if group := e.ChildGroup(child); group != 0 {
+					children = append(children, fmt.Sprintf("%d", group))
+				}
+			}
+
+			expr := htmlExpr{Node: dotNode(loc), Label: m.exprLabel(loc)}
+			if len(children) != 0 {
+				expr.Label += " [" + strings.Join(children, " ") + "]"
+			}
+			grp.Exprs = append(grp.Exprs, expr)
+		}
+		data.Groups = append(data.Groups, grp)
+	}
+
+	for i := range trace.Events {
+		ev := &trace.Events[i]
+		data.Events = append(data.Events, htmlEvent{
+			Kind:      ev.Kind.String(),
+			Group:     ev.Group,
+			Expr:      ev.Expr,
+			Op:        ev.Op.String(),
+			Required:  formatRequired(ev.Required),
+			Cost:      ev.Cost,
+			Improved:  ev.Improved,
+			Permanent: ev.Permanent,
+			Text:      ev.String(),
+		})
+	}
+
+	return memoHTMLTemplate.Execute(w, &data)
+}
+
+type htmlMemo struct {
+	Groups []htmlGroup
+	Events []htmlEvent
+}
+
+type htmlGroup struct {
+	ID    GroupID
+	Exprs []htmlExpr
+}
+
+type htmlExpr struct {
+	Node  string
+	Label string
+}
+
+type htmlEvent struct {
+	Kind      string  `json:"kind"`
+	Group     GroupID `json:"group"`
+	Expr      int     `json:"expr"`
+	Op        string  `json:"op"`
+	Required  string  `json:"required"`
+	Cost      float64 `json:"cost"`
+	Improved  bool    `json:"improved"`
+	Permanent bool    `json:"permanent"`
+	Text      string  `json:"text"`
+}
+
+var memoHTMLTemplate = template.Must(template.New("memo").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>memo</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; }
+table { border-collapse: collapse; margin: 8px 0; }
+td { border: 1px solid #ccc; padding: 2px 6px; vertical-align: top; }
+td.expr div.info { font-size: 11px; color: #555; }
+.hidden { visibility: hidden; }
+.added { background: #dfd; }
+.pruned { text-decoration: line-through; color: #999; }
+.best { background: #cdf; }
+.current { outline: 2px solid #d00; }
+tr.optimizing td:first-child { background: #ffd; }
+#log { height: 240px; overflow: auto; font-family: monospace; border: 1px solid #ccc; }
+#log div { cursor: pointer; }
+#log div.current { background: #fdd; }
+</style>
+</head>
+<body>
+<div>
+<button id="prev-pass">&laquo; pass</button>
+<button id="prev">&lsaquo; step</button>
+<input id="step" type="range" min="0" max="{{len .Events}}" value="0">
+<button id="next">step &rsaquo;</button>
+<button id="next-pass">pass &raquo;</button>
+<span id="status"></span>
+</div>
+<table>
+{{range .Groups}}<tr id="g{{.ID}}"><td>[{{.ID}}]</td>{{range .Exprs}}<td class="expr" id="{{.Node}}" data-label="{{.Label}}"></td>{{end}}<td class="best-exprs"></td></tr>
+{{end}}</table>
+<div id="log"></div>
+<script>
+var events = {{.Events}} || [];
+var step = 0;
+
+// addedAt maps from the node of each expression that was added by exploration
+// to the step that added it.
+var addedAt = {};
+events.forEach(function(ev, i) {
+  if (ev.kind === "add") {
+    addedAt["g" + ev.group + "e" + ev.expr] = i + 1;
+  }
+});
+
+var log = document.getElementById("log");
+events.forEach(function(ev, i) {
+  var div = document.createElement("div");
+  div.textContent = (i + 1) + ": " + ev.text;
+  div.onclick = function() { show(i + 1); };
+  log.appendChild(div);
+});
+
+function show(n) {
+  step = Math.max(0, Math.min(events.length, n));
+  var costs = {}, pruned = {}, best = {};
+  var current = null, optimizing = null;
+  for (var i = 0; i < step; i++) {
+    var ev = events[i];
+    var node = "g" + ev.group + "e" + ev.expr;
+    if (ev.kind === "optimize") {
+      optimizing = ev.group;
+    } else if (ev.kind === "cost") {
+      var desc = ev.expr < 0 ? ev.op : node;
+      costs[desc] = costs[desc] || [];
+      costs[desc].push(ev.required + ": " + ev.cost);
+      if (ev.improved) {
+        best[ev.group] = best[ev.group] || {};
+        best[ev.group][ev.required] = {node: ev.expr < 0 ? null : node, text: ev.required + ": " +
+          (ev.expr < 0 ? ev.op : ev.op + " (" + ev.expr + ")") + " " + ev.cost};
+      }
+    } else if (ev.kind === "prune") {
+      pruned[node] = true;
+    }
+    if (i === step - 1) {
+      current = node;
+    }
+  }
+
+  var bestNodes = {};
+  document.querySelectorAll("tr").forEach(function(row) {
+    var group = row.id.substr(1);
+    row.className = group == optimizing ? "optimizing" : "";
+    var lines = [];
+    for (var required in best[group] || {}) {
+      var b = best[group][required];
+      if (b.node) {
+        bestNodes[b.node] = true;
+      }
+      lines.push(b.text);
+    }
+    row.querySelector(".best-exprs").textContent = lines.join("; ");
+  });
+
+  document.querySelectorAll("td.expr").forEach(function(cell) {
+    var classes = ["expr"];
+    if (addedAt[cell.id] > step) {
+      classes.push("hidden");
+    } else if (addedAt[cell.id] === step) {
+      classes.push("added");
+    }
+    if (pruned[cell.id]) {
+      classes.push("pruned");
+    }
+    if (bestNodes[cell.id]) {
+      classes.push("best");
+    }
+    if (cell.id === current) {
+      classes.push("current");
+    }
+    cell.className = classes.join(" ");
+    cell.textContent = cell.dataset.label;
+    if (costs[cell.id]) {
+      var info = document.createElement("div");
+      info.className = "info";
+      info.textContent = costs[cell.id].join(", ");
+      cell.appendChild(info);
+    }
+  });
+
+  log.childNodes.forEach(function(div, i) {
+    div.className = i === step - 1 ? "current" : "";
+  });
+  if (step > 0) {
+    log.childNodes[step - 1].scrollIntoView({block: "nearest"});
+  }
+  document.getElementById("step").value = step;
+  document.getElementById("status").textContent =
+    "step " + step + " of " + events.length + (step > 0 ? ": " + events[step - 1].text : "");
+}
+
+// findPass returns the step of the next (dir > 0) or previous (dir < 0)
+// optimize event, which starts an iteration of the optimizer over a group.
+function findPass(dir) {
+  for (var n = step + dir; n > 0 && n <= events.length; n += dir) {
+    if (events[n - 1].kind === "optimize") {
+      return n;
+    }
+  }
+  return dir > 0 ? events.length : 0;
+}
+
+document.getElementById("prev").onclick = function() { show(step - 1); };
+document.getElementById("next").onclick = function() { show(step + 1); };
+document.getElementById("prev-pass").onclick = function() { show(findPass(-1)); };
+document.getElementById("next-pass").onclick = function() { show(findPass(1)); };
+document.getElementById("step").oninput = function() { show(parseInt(this.value, 10)); };
+show(0);
+</script>
+</body>
+</html>
+`))
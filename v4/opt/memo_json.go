@@ -0,0 +1,600 @@
+package opt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/types"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+// memoJSON is the serialized form of a memo. Memo expressions are stored in
+// their raw form, as an operator plus its opaque state, so the privates, lists
+// and physical properties that they reference are stored in the order of their
+// ids. When the memo is loaded, they're interned again in that same order,
+// which reproduces the same ids. Logical properties are not stored, since they
+// are derived again from each group's normalized expression as it's loaded.
+// The explorer's per-group state is not stored either, so exploration of a
+// loaded memo starts over from the first expression in each group.
+type memoJSON struct {
+	Columns   []string            `json:"columns"`
+	Tables    []tableJSON         `json:"tables"`
+	Privates  []privateJSON       `json:"privates"`
+	Lists     [][]GroupID         `json:"lists"`
+	PhysProps []physicalPropsJSON `json:"physProps"`
+	Groups    []groupJSON         `json:"groups"`
+	AltExprs  []altExprJSON       `json:"altExprs"`
+}
+
+type tableJSON struct {
+	Index    TableIndex `json:"index"`
+	Database string     `json:"database"`
+	Schema   string     `json:"schema"`
+	Name     string     `json:"name"`
+	Ordering Ordering   `json:"ordering"`
+}
+
+// privateJSON stores a private value, tagged by the name of its type in the
+// optgen source, such as ColIndexes.
+type privateJSON struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type physicalPropsJSON struct {
+	Ordering   Ordering        `json:"ordering,omitempty"`
+	Projection []LabeledColumn `json:"projection,omitempty"`
+}
+
+type groupJSON struct {
+	Exprs []memoExprJSON `json:"exprs"`
+	Best  []bestExprJSON `json:"best,omitempty"`
+}
+
+type memoExprJSON struct {
+	Op    string    `json:"op"`
+	State exprState `json:"state"`
+}
+
+// altExprJSON stores an expression whose fingerprint maps to a group, but
+// which isn't one of the group's expressions (see memo.addAltFingerprint).
+type altExprJSON struct {
+	memoExprJSON
+	Group GroupID `json:"group"`
+}
+
+type bestExprJSON struct {
+	Required       physicalPropsID `json:"required"`
+	Op             string          `json:"op"`
+	Group          GroupID         `json:"group"`
+	Expr           exprID          `json:"expr"`
+	CostedID       exprID          `json:"costedID"`
+	LastOptimized  [2]uint16       `json:"lastOptimized"`
+	LastImproved   [2]uint16       `json:"lastImproved"`
+	FullyOptimized []int           `json:"fullyOptimized,omitempty"`
+	Cost           physicalCost    `json:"cost"`
+}
+
+// saveJSON writes the memo to w as indented JSON. The result can be loaded
+// into an equivalent memo by loadMemoJSON.
+func (m *memo) saveJSON(w io.Writer) error {
+	var out memoJSON
+
+	md := m.metadata
+	out.Columns = md.cols[1:]
+	for index, tbl := range md.tables {
+		name := tbl.Table.TabName()
+		out.Tables = append(out.Tables, tableJSON{
+			Index:    index,
+			Database: name.Database,
+			Schema:   name.Schema,
+			Name:     name.Table,
+			Ordering: tbl.Ordering,
+		})
+	}
+	sort.Slice(out.Tables, func(i, j int) bool {
+		return out.Tables[i].Index < out.Tables[j].Index
+	})
+
+	for _, private := range m.privates[1:] {
+		p, err := makePrivateJSON(private)
+		if err != nil {
+			return err
+		}
+		out.Privates = append(out.Privates, p)
+	}
+
+	// Store the lists in the order that they were interned, which is the
+	// order of their offsets. An empty list has the same offset as the list
+	// that was interned after it, so it sorts first.
+	lists := make([]ListID, 0, len(m.listsMap))
+	for _, id := range m.listsMap {
+		lists = append(lists, id)
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		if lists[i].offset != lists[j].offset {
+			return lists[i].offset < lists[j].offset
+		}
+		return lists[i].len < lists[j].len
+	})
+	for _, id := range lists {
+		out.Lists = append(out.Lists, m.lookupList(id))
+	}
+
+	// Skip the default physical properties, which every memo interns.
+	for _, props := range m.physProps[defaultPhysPropsID+1:] {
+		out.PhysProps = append(out.PhysProps, physicalPropsJSON{
+			Ordering:   props.Ordering,
+			Projection: props.Projection.Columns,
+		})
+	}
+
+	for _, mgrp := range m.groups[1:] {
+		var grp groupJSON
+		for _, mexpr := range mgrp.exprs {
+			grp.Exprs = append(grp.Exprs, makeMemoExprJSON(&mexpr))
+		}
+
+		required := make([]physicalPropsID, len(mgrp.bestExprs))
+		for id, index := range mgrp.bestExprsMap {
+			required[index] = id
+		}
+		for i := range mgrp.bestExprs {
+			best := &mgrp.bestExprs[i]
+			grp.Best = append(grp.Best, bestExprJSON{
+				Required:       required[i],
+				Op:             best.op.String(),
+				Group:          best.loc.group,
+				Expr:           best.loc.expr,
+				CostedID:       best.costedID,
+				LastOptimized:  [2]uint16{best.lastOptimized.major, best.lastOptimized.minor},
+				LastImproved:   [2]uint16{best.lastImproved.major, best.lastImproved.minor},
+				FullyOptimized: best.fullyOptimized.Ordered(),
+				Cost:           best.cost,
+			})
+		}
+		out.Groups = append(out.Groups, grp)
+	}
+
+	for f, group := range m.exprMap {
+		if m.isGroupExpr(f, group) {
+			continue
+		}
+		mexpr := memoExpr(f)
+		out.AltExprs = append(out.AltExprs, altExprJSON{
+			memoExprJSON: makeMemoExprJSON(&mexpr),
+			Group:        group,
+		})
+	}
+	sort.Slice(out.AltExprs, func(i, j int) bool {
+		left, right := &out.AltExprs[i], &out.AltExprs[j]
+		if left.Group != right.Group {
+			return left.Group < right.Group
+		}
+		if left.Op != right.Op {
+			return left.Op < right.Op
+		}
+		for k := range left.State {
+			if left.State[k] != right.State[k] {
+				return left.State[k] < right.State[k]
+			}
+		}
+		return false
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&out)
+}
+
+// isGroupExpr returns true if the expression with the given fingerprint is one
+// of the expressions in the given group.
+func (m *memo) isGroupExpr(f fingerprint, group GroupID) bool {
+	for _, mexpr := range m.lookupGroup(group).exprs {
+		if mexpr.fingerprint() == f {
+			return true
+		}
+	}
+	return false
+}
+
+// loadMemoJSON reads a memo that was written by saveJSON. Tables are looked up
+// by name in the given catalog, which must contain the same tables as the
+// catalog of the saved memo.
+func loadMemoJSON(catalog cat.Catalog, r io.Reader) (*memo, error) {
+	var in memoJSON
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, err
+	}
+
+	m := newMemo(catalog)
+
+	md := m.metadata
+	for _, label := range in.Columns {
+		md.AddColumn(label)
+	}
+	for _, t := range in.Tables {
+		tbl, err := catalog.Table(cat.TableName{Database: t.Database, Schema: t.Schema, Table: t.Name})
+		if err != nil {
+			return nil, err
+		}
+		first := md.TableColumn(t.Index, 0)
+		last := md.TableColumn(t.Index, cat.ColumnOrdinal(tbl.ColumnCount()-1))
+		if err := checkColumns(md, first, last); err != nil {
+			return nil, err
+		}
+		if err := checkColumns(md, t.Ordering...); err != nil {
+			return nil, err
+		}
+		md.tables[t.Index] = &TableMetadata{Table: tbl, Ordering: t.Ordering}
+	}
+
+	// privateTypes is the type of each loaded private, indexed by its id, so
+	// that the privates referenced by expressions can be checked.
+	privateTypes := make([]string, 1, len(in.Privates)+1)
+	for i := range in.Privates {
+		id, err := m.loadPrivate(&in.Privates[i])
+		if err != nil {
+			return nil, err
+		}
+		if id != PrivateID(i+1) {
+			return nil, fmt.Errorf("duplicate private %s: %s", in.Privates[i].Type, in.Privates[i].Value)
+		}
+		privateTypes = append(privateTypes, in.Privates[i].Type)
+	}
+
+	for _, items := range in.Lists {
+		m.storeList(items)
+	}
+	if len(m.listsMap) != len(in.Lists) {
+		return nil, fmt.Errorf("duplicate lists")
+	}
+
+	for i := range in.PhysProps {
+		props := PhysicalProps{
+			Ordering:   in.PhysProps[i].Ordering,
+			Projection: Projection{Columns: in.PhysProps[i].Projection},
+		}
+		if err := checkColumns(md, props.Ordering...); err != nil {
+			return nil, err
+		}
+		for _, col := range props.Projection.Columns {
+			if err := checkColumns(md, col.Index); err != nil {
+				return nil, err
+			}
+		}
+		if m.internPhysicalProps(&props) != defaultPhysPropsID+physicalPropsID(i+1) {
+			return nil, fmt.Errorf("duplicate physical properties: %s", props.fingerprint())
+		}
+	}
+
+	// Create all of the groups before adding their other expressions, since
+	// exploration can add expressions to a group that reference groups that
+	// were created after it. A normalized expression can only reference the
+	// groups that were created before it, since its logical properties are
+	// derived from theirs.
+	numGroups := GroupID(len(in.Groups))
+	for i := range in.Groups {
+		if len(in.Groups[i].Exprs) == 0 {
+			return nil, fmt.Errorf("group %d has no expressions", i+1)
+		}
+		norm, err := m.loadMemoExpr(&in.Groups[i].Exprs[0], GroupID(i), privateTypes)
+		if err != nil {
+			return nil, err
+		}
+		if m.lookupGroupByFingerprint(norm.fingerprint()) != 0 {
+			return nil, fmt.Errorf("duplicate %s expression in group %d", norm.op, i+1)
+		}
+		m.memoizeNormExpr(&norm)
+	}
+
+	for i := range in.Groups {
+		grp := &in.Groups[i]
+		mgrp := m.groups[i+1]
+		for j := range grp.Exprs[1:] {
+			denorm, err := m.loadMemoExpr(&grp.Exprs[j+1], numGroups, privateTypes)
+			if err != nil {
+				return nil, err
+			}
+			if m.lookupGroupByFingerprint(denorm.fingerprint()) != 0 {
+				return nil, fmt.Errorf("duplicate %s expression in group %d", denorm.op, i+1)
+			}
+			if relational := isRelationalLookup[mgrp.exprs[0].op]; isRelationalLookup[denorm.op] != relational {
+				return nil, fmt.Errorf("%s expression in %s group %d", denorm.op, groupKind(relational), i+1)
+			}
+			m.memoizeDenormExpr(mgrp.id, &denorm)
+		}
+	}
+
+	// Load the best expressions once all of the expressions are loaded, since
+	// they can refer to any expression in their group.
+	for i := range in.Groups {
+		mgrp := m.groups[i+1]
+		for j := range in.Groups[i].Best {
+			best, err := m.loadBestExpr(mgrp, &in.Groups[i].Best[j])
+			if err != nil {
+				return nil, err
+			}
+			mgrp.bestExprsMap[in.Groups[i].Best[j].Required] = len(mgrp.bestExprs)
+			mgrp.bestExprs = append(mgrp.bestExprs, best)
+		}
+	}
+
+	for i := range in.AltExprs {
+		group := in.AltExprs[i].Group
+		if group == 0 || group > numGroups {
+			return nil, fmt.Errorf("alternate expression references unknown group %d", group)
+		}
+		alt, err := m.loadMemoExpr(&in.AltExprs[i].memoExprJSON, numGroups, privateTypes)
+		if err != nil {
+			return nil, err
+		}
+		if existing := m.lookupGroupByFingerprint(alt.fingerprint()); existing != 0 && existing != group {
+			return nil, fmt.Errorf("alternate %s expression is in groups %d and %d", alt.op, existing, group)
+		}
+		m.addAltFingerprint(alt.fingerprint(), group)
+	}
+
+	return m, nil
+}
+
+// loadMemoExpr returns the memo expression stored in e. It returns an error if
+// the expression can't be memoized, or if its state references a group greater
+// than maxGroup, or a list or private that hasn't been loaded. privateTypes is
+// the type of each loaded private, indexed by its id.
+func (m *memo) loadMemoExpr(e *memoExprJSON, maxGroup GroupID, privateTypes []string) (memoExpr, error) {
+	op := lookupOperatorName(e.Op)
+	if op == UnknownOp {
+		return memoExpr{}, fmt.Errorf("unknown operator %s", e.Op)
+	}
+	if isEnforcerLookup[op] {
+		return memoExpr{}, fmt.Errorf("enforcer %s cannot be memoized", op)
+	}
+
+	checkGroup := func(group GroupID, relational bool) error {
+		if group == 0 || group > maxGroup {
+			return fmt.Errorf("%s expression references unknown group %d", op, group)
+		}
+		if isRelationalLookup[m.lookupNormExpr(group).op] != relational {
+			return fmt.Errorf("%s expression references %s group %d, expected %s",
+				op, groupKind(!relational), group, groupKind(relational))
+		}
+		return nil
+	}
+
+	layout := &stateLayoutLookup[op]
+	for i := 0; i < layout.exprs; i++ {
+		if err := checkGroup(GroupID(e.State[i]), layout.isRelational(i)); err != nil {
+			return memoExpr{}, err
+		}
+	}
+
+	slot := layout.exprs
+	if layout.list {
+		offset, length := e.State[slot], e.State[slot+1]
+		if uint64(offset)+uint64(length) > uint64(len(m.lists)) {
+			return memoExpr{}, fmt.Errorf("%s expression references unknown list %d:%d", op, offset, length)
+		}
+		for _, item := range m.lists[offset : offset+length] {
+			if err := checkGroup(item, false /* relational */); err != nil {
+				return memoExpr{}, err
+			}
+		}
+		slot += 2
+	}
+
+	if layout.private != "" {
+		id := e.State[slot]
+		if id == 0 || int(id) >= len(privateTypes) {
+			return memoExpr{}, fmt.Errorf("%s expression references unknown private %d", op, id)
+		}
+		if privateTypes[id] != layout.private {
+			return memoExpr{}, fmt.Errorf("%s expression references %s private %d, expected %s",
+				op, privateTypes[id], id, layout.private)
+		}
+	}
+
+	return memoExpr{op: op, state: e.State}, nil
+}
+
+// groupKind describes a group as relational or scalar in errors.
+func groupKind(relational bool) string {
+	if relational {
+		return "relational"
+	}
+	return "scalar"
+}
+
+// loadBestExpr returns the best expression of the given group that's stored in
+// b. It returns an error if b references physical properties that haven't been
+// loaded, or an expression that isn't in the group.
+func (m *memo) loadBestExpr(mgrp *memoGroup, b *bestExprJSON) (bestExpr, error) {
+	op := lookupOperatorName(b.Op)
+	if op == UnknownOp {
+		return bestExpr{}, fmt.Errorf("unknown operator %s", b.Op)
+	}
+	if int(b.Required) >= len(m.physProps) {
+		return bestExpr{}, fmt.Errorf("best expression of group %d requires unknown physical properties %d",
+			mgrp.id, b.Required)
+	}
+	if b.Group != mgrp.id {
+		return bestExpr{}, fmt.Errorf("best expression of group %d is in group %d", mgrp.id, b.Group)
+	}
+	if !isEnforcerLookup[op] {
+		if int(b.Expr) >= len(mgrp.exprs) {
+			return bestExpr{}, fmt.Errorf("best expression of group %d is unknown expression %d", mgrp.id, b.Expr)
+		}
+		if mgrp.exprs[b.Expr].op != op {
+			return bestExpr{}, fmt.Errorf("best expression %d of group %d is %s, not %s",
+				b.Expr, mgrp.id, mgrp.exprs[b.Expr].op, op)
+		}
+	}
+	if int(b.CostedID) > len(mgrp.exprs) {
+		return bestExpr{}, fmt.Errorf("best expression of group %d costed unknown expression %d", mgrp.id, b.CostedID)
+	}
+	for _, eid := range b.FullyOptimized {
+		if eid < 0 || eid >= len(mgrp.exprs) {
+			return bestExpr{}, fmt.Errorf("best expression of group %d optimized unknown expression %d", mgrp.id, eid)
+		}
+	}
+
+	return bestExpr{
+		op:             op,
+		loc:            memoLoc{group: b.Group, expr: b.Expr},
+		costedID:       b.CostedID,
+		lastOptimized:  optimizePass{major: b.LastOptimized[0], minor: b.LastOptimized[1]},
+		lastImproved:   optimizePass{major: b.LastImproved[0], minor: b.LastImproved[1]},
+		fullyOptimized: util.MakeFastIntSet(b.FullyOptimized...),
+		cost:           b.Cost,
+	}, nil
+}
+
+func makeMemoExprJSON(mexpr *memoExpr) memoExprJSON {
+	return memoExprJSON{Op: mexpr.op.String(), State: mexpr.state}
+}
+
+func makePrivateJSON(private interface{}) (privateJSON, error) {
+	var typ string
+	var value interface{}
+
+	// NB: *tree.Placeholder implements tree.Datum, so it needs to be checked
+	// before the tree.Datum case.
+	switch t := private.(type) {
+	case TableIndex:
+		typ, value = "Table", t
+	case ColumnIndex:
+		typ, value = "ColIndex", t
+	case *ColSet:
+		typ, value = "ColIndexes", t.Ordered()
+	case *ColMap:
+		typ, value = "ColMap", t
+	case *tree.FunctionDefinition:
+		typ, value = "FuncDef", t.Name
+	case *tree.Placeholder:
+		// Annotate the placeholder with its type so that it can be type
+		// checked again when it's loaded.
+		typ, value = "TypedExpr", fmt.Sprintf("%s:::%s", t, t.ResolvedType())
+	case tree.Datum:
+		typ, value = "Datum", tree.Serialize(t)
+	default:
+		return privateJSON{}, fmt.Errorf("unsupported private type %T", private)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return privateJSON{}, err
+	}
+	return privateJSON{Type: typ, Value: raw}, nil
+}
+
+// loadPrivate interns the private value stored in p, and returns its id.
+func (m *memo) loadPrivate(p *privateJSON) (PrivateID, error) {
+	switch p.Type {
+	case "Table":
+		var table TableIndex
+		if err := json.Unmarshal(p.Value, &table); err != nil {
+			return 0, err
+		}
+		if m.metadata.Table(table) == nil {
+			return 0, fmt.Errorf("unknown table %d", table)
+		}
+		return m.internTable(table), nil
+
+	case "ColIndex":
+		var col ColumnIndex
+		if err := json.Unmarshal(p.Value, &col); err != nil {
+			return 0, err
+		}
+		if err := checkColumns(m.metadata, col); err != nil {
+			return 0, err
+		}
+		return m.internColIndex(col), nil
+
+	case "ColIndexes":
+		var cols []int
+		if err := json.Unmarshal(p.Value, &cols); err != nil {
+			return 0, err
+		}
+		for _, col := range cols {
+			if err := checkColumns(m.metadata, ColumnIndex(col)); err != nil {
+				return 0, err
+			}
+		}
+		colSet := util.MakeFastIntSet(cols...)
+		return m.internColIndexes(&colSet), nil
+
+	case "ColMap":
+		colMap := make(ColMap)
+		if err := json.Unmarshal(p.Value, &colMap); err != nil {
+			return 0, err
+		}
+		for from, to := range colMap {
+			if err := checkColumns(m.metadata, from, to); err != nil {
+				return 0, err
+			}
+		}
+		return m.internColMap(&colMap), nil
+
+	case "FuncDef":
+		var name string
+		if err := json.Unmarshal(p.Value, &name); err != nil {
+			return 0, err
+		}
+		def, ok := tree.FunDefs[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown function %s", name)
+		}
+		return m.internFuncDef(def), nil
+
+	case "Datum", "TypedExpr":
+		var sql string
+		if err := json.Unmarshal(p.Value, &sql); err != nil {
+			return 0, err
+		}
+		expr, err := parseTypedExpr(sql)
+		if err != nil {
+			return 0, err
+		}
+		if p.Type == "TypedExpr" {
+			return m.internTypedExpr(expr), nil
+		}
+		datum, err := expr.Eval(&tree.EvalContext{})
+		if err != nil {
+			return 0, err
+		}
+		return m.internDatum(datum), nil
+	}
+
+	return 0, fmt.Errorf("unknown private type %s", p.Type)
+}
+
+// checkColumns returns an error if any of the given columns haven't been added
+// to the metadata. Negative columns are allowed, since they indicate descending
+// order in an Ordering.
+func checkColumns(md *Metadata, cols ...ColumnIndex) error {
+	for _, col := range cols {
+		if col < 0 {
+			col = -col
+		}
+		if col == 0 || int(col) >= len(md.cols) {
+			return fmt.Errorf("unknown column %d", col)
+		}
+	}
+	return nil
+}
+
+// parseTypedExpr parses and type checks a scalar expression that was
+// formatted by makePrivateJSON.
+func parseTypedExpr(sql string) (tree.TypedExpr, error) {
+	expr, err := parser.ParseExpr(sql)
+	if err != nil {
+		return nil, err
+	}
+	semaCtx := tree.MakeSemaContext(false /* privileged */)
+	return tree.TypeCheck(expr, &semaCtx, types.Any)
+}
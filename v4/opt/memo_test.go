@@ -0,0 +1,57 @@
+package opt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/petermattis/opttoy/v4/cat"
+)
+
+// TestMemoGroupPointers tests that a group that's been looked up stays valid
+// while new groups are added to the memo, as happens during exploration.
+func TestMemoGroupPointers(t *testing.T) {
+	mem := newMemo(cat.NewCatalog())
+	f := newFactory(mem, 0 /* maxSteps */)
+
+	group := f.ConstructTrue()
+	mgrp := mem.lookupGroup(group)
+	for i := 0; i < 100; i++ {
+		f.ConstructVariable(mem.metadata.AddColumn(fmt.Sprintf("c%d", i)))
+	}
+
+	if mem.lookupGroup(group) != mgrp {
+		t.Fatalf("group %d moved when new groups were added", group)
+	}
+	mgrp.addExpr(&memoExpr{op: FalseOp})
+	if n := len(mem.lookupGroup(group).exprs); n != 2 {
+		t.Fatalf("expected 2 expressions in group %d, but found %d", group, n)
+	}
+}
+
+// TestMemoStoreList tests that lists with the same items are interned, so that
+// expressions with the same list items have the same fingerprint.
+func TestMemoStoreList(t *testing.T) {
+	mem := newMemo(cat.NewCatalog())
+
+	a := mem.storeList([]GroupID{1, 2})
+	b := mem.storeList([]GroupID{2, 1})
+	c := mem.storeList([]GroupID{1})
+	empty := mem.storeList(nil)
+	if a == b || a == c || b == c || a == empty {
+		t.Fatalf("lists with different items have the same id: %v %v %v %v", a, b, c, empty)
+	}
+
+	if id := mem.storeList([]GroupID{1, 2}); id != a {
+		t.Errorf("expected list id %v, but found %v", a, id)
+	}
+	if id := mem.storeList([]GroupID{}); id != empty {
+		t.Errorf("expected list id %v, but found %v", empty, id)
+	}
+
+	f := newFactory(mem, 0 /* maxSteps */)
+	left := f.ConstructFilters(mem.storeList([]GroupID{f.ConstructTrue()}))
+	right := f.ConstructFilters(mem.storeList([]GroupID{f.ConstructTrue()}))
+	if left != right {
+		t.Errorf("filters with the same conditions are in groups %d and %d", left, right)
+	}
+}
@@ -17,12 +17,12 @@ type ColumnIndex int32
 type TableIndex int32
 
 type TableMetadata struct {
-	Table    *cat.Table
+	Table    cat.Table
 	Ordering Ordering
 }
 
 type Metadata struct {
-	catalog *cat.Catalog
+	catalog cat.Catalog
 
 	cols []string
 
@@ -35,11 +35,11 @@ type Metadata struct {
 	tables map[TableIndex]*TableMetadata
 }
 
-func newMetadata(catalog *cat.Catalog) *Metadata {
+func newMetadata(catalog cat.Catalog) *Metadata {
 	return &Metadata{catalog: catalog, cols: make([]string, 1), tables: make(map[TableIndex]*TableMetadata)}
 }
 
-func (md *Metadata) Catalog() *cat.Catalog {
+func (md *Metadata) Catalog() cat.Catalog {
 	return md.catalog
 }
 
@@ -66,20 +66,20 @@ func (md *Metadata) ColumnLabel(index ColumnIndex) string {
 // In this query, `l.x` is not equivalent to `r.x` and `l.y` is not
 // equivalent to `r.y`. In order to achieve this, we need to give these
 // columns different indexes.
-func (md *Metadata) AddTable(tbl *cat.Table) TableIndex {
+func (md *Metadata) AddTable(tbl cat.Table) TableIndex {
 	tblIndex := TableIndex(md.nextCol + 1)
 
-	for i := range tbl.Columns {
-		col := &tbl.Columns[i]
-		if tbl.Name == "" {
+	for i := 0; i < tbl.ColumnCount(); i++ {
+		col := tbl.Column(cat.ColumnOrdinal(i))
+		if tbl.TabName().Table == "" {
 			md.AddColumn(string(col.Name))
 		} else {
-			md.AddColumn(fmt.Sprintf("%s.%s", tbl.Name, col.Name))
+			md.AddColumn(fmt.Sprintf("%s.%s", tbl.TabName().Table, col.Name))
 		}
 	}
 
 	// Tables are naturally ordered by primary key.
-	primary := tbl.PrimaryKey()
+	primary := cat.PrimaryKey(tbl)
 
 	ordering := make(Ordering, len(primary.Columns))
 	for i, ord := range primary.Columns {
@@ -2,7 +2,7 @@ package opt
 
 import "fmt"
 
-//go:generate optgen -out operator.og.go -pkg opt ops ops/scalar.opt ops/relational.opt ops/enforcer.opt
+//go:generate optgen -out operator.og.go -pkg opt ops ops/private.opt ops/scalar.opt ops/relational.opt ops/enforcer.opt
 type Operator uint16
 
 func (i Operator) String() string {
@@ -12,3 +12,14 @@ func (i Operator) String() string {
 
 	return opNames[opIndexes[i]:opIndexes[i+1]]
 }
+
+// lookupOperatorName returns the operator whose String() is the given name,
+// such as inner-join, or UnknownOp if there is no such operator.
+func lookupOperatorName(name string) Operator {
+	for op := Operator(1); op < Operator(len(opIndexes)-1); op++ {
+		if op.String() == name {
+			return op
+		}
+	}
+	return UnknownOp
+}
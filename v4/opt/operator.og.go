@@ -60,6 +60,7 @@ const (
 	UnaryMinusOp
 	UnaryComplementOp
 	FunctionOp
+	ConstAggOp
 	TrueOp
 	FalseOp
 	ScanOp
@@ -86,6 +87,6 @@ const (
 	ArrangeOp
 )
 
-const opNames = "unknownsubqueryvariableconstplaceholderlistordered-listtuplefiltersprojectionsexistsandornoteqltgtlegeneinnot-inlikenot-likei-likenot-i-likesimilar-tonot-similar-toreg-matchnot-reg-matchreg-i-matchnot-reg-i-matchis-distinct-fromis-not-distinct-fromisis-notanysomeallbitandbitorbitxorplusminusmultdivfloor-divmodpowconcatl-shiftr-shiftunary-plusunary-minusunary-complementfunctiontruefalsescanvaluesselectprojectinner-joinleft-joinright-joinfull-joinsemi-joinanti-joininner-join-applyleft-join-applyright-join-applyfull-join-applysemi-join-applyanti-join-applygroup-byunionintersectexceptsortarrange"
+const opNames = "unknownsubqueryvariableconstplaceholderlistordered-listtuplefiltersprojectionsexistsandornoteqltgtlegeneinnot-inlikenot-likei-likenot-i-likesimilar-tonot-similar-toreg-matchnot-reg-matchreg-i-matchnot-reg-i-matchis-distinct-fromis-not-distinct-fromisis-notanysomeallbitandbitorbitxorplusminusmultdivfloor-divmodpowconcatl-shiftr-shiftunary-plusunary-minusunary-complementfunctionconst-aggtruefalsescanvaluesselectprojectinner-joinleft-joinright-joinfull-joinsemi-joinanti-joininner-join-applyleft-join-applyright-join-applyfull-join-applysemi-join-applyanti-join-applygroup-byunionintersectexceptsortarrange"
 
-var opIndexes = [...]uint32{0, 7, 15, 23, 28, 39, 43, 55, 60, 67, 78, 84, 87, 89, 92, 94, 96, 98, 100, 102, 104, 106, 112, 116, 124, 130, 140, 150, 164, 173, 186, 197, 212, 228, 248, 250, 256, 259, 263, 266, 272, 277, 283, 287, 292, 296, 299, 308, 311, 314, 320, 327, 334, 344, 355, 371, 379, 383, 388, 392, 398, 404, 411, 421, 430, 440, 449, 458, 467, 483, 498, 514, 529, 544, 559, 567, 572, 581, 587, 591, 598}
+var opIndexes = [...]uint32{0, 7, 15, 23, 28, 39, 43, 55, 60, 67, 78, 84, 87, 89, 92, 94, 96, 98, 100, 102, 104, 106, 112, 116, 124, 130, 140, 150, 164, 173, 186, 197, 212, 228, 248, 250, 256, 259, 263, 266, 272, 277, 283, 287, 292, 296, 299, 308, 311, 314, 320, 327, 334, 344, 355, 371, 379, 388, 392, 397, 401, 407, 413, 420, 430, 439, 449, 458, 467, 476, 492, 507, 523, 538, 553, 568, 576, 581, 590, 596, 600, 607}
@@ -0,0 +1,129 @@
+package opt
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// OptimizeEventKind identifies the kind of step that the optimizer took.
+type OptimizeEventKind int
+
+const (
+	// OptimizeGroupEvent is recorded at the start of each iteration of the
+	// optimizer over a group, for a particular set of required properties.
+	OptimizeGroupEvent OptimizeEventKind = iota
+
+	// AddExprEvent is recorded for each expression that exploration adds to
+	// the memo, including the normalized expressions of new groups.
+	AddExprEvent
+
+	// CostExprEvent is recorded each time the optimizer computes the cost of
+	// an expression, or of an enforcer that provides the required properties
+	// for a group.
+	CostExprEvent
+
+	// PruneExprEvent is recorded when the optimizer abandons an expression,
+	// because the cost of one of its children exceeds the cost limit.
+	PruneExprEvent
+)
+
+var optimizeEventKindNames = [...]string{
+	OptimizeGroupEvent: "optimize",
+	AddExprEvent:       "add",
+	CostExprEvent:      "cost",
+	PruneExprEvent:     "prune",
+}
+
+func (k OptimizeEventKind) String() string {
+	return optimizeEventKindNames[k]
+}
+
+// OptimizeEvent describes a single step taken by the optimizer.
+type OptimizeEvent struct {
+	Kind OptimizeEventKind
+
+	// Group and Expr locate the expression in the memo. Enforcers are not
+	// stored in the memo, so they have an Expr of -1.
+	Group GroupID
+	Expr  int
+
+	// Op is the operator of the expression, or of the enforcer.
+	Op Operator
+
+	// Required describes the physical properties that the group is being
+	// optimized for, or "" for the default properties. It's not set for
+	// AddExprEvent.
+	Required string
+
+	// Pass is the optimization pass, which is set for OptimizeGroupEvent and
+	// AddExprEvent.
+	Pass string
+
+	// Cost is the cost that was computed for a CostExprEvent, and Improved is
+	// true if that cost made the expression the best expression in its group
+	// for the required properties.
+	Cost     float64
+	Improved bool
+
+	// Permanent is true if a PruneExprEvent means that the expression will
+	// never be optimized again for the required properties.
+	Permanent bool
+}
+
+func (ev *OptimizeEvent) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s [%d]", ev.Kind, ev.Group)
+	if ev.Kind != OptimizeGroupEvent {
+		if ev.Expr < 0 {
+			fmt.Fprintf(&buf, " %s", ev.Op)
+		} else {
+			fmt.Fprintf(&buf, ".%d %s", ev.Expr, ev.Op)
+		}
+	}
+	if ev.Kind != AddExprEvent {
+		fmt.Fprintf(&buf, " (%s)", formatRequired(ev.Required))
+	}
+	switch ev.Kind {
+	case OptimizeGroupEvent, AddExprEvent:
+		fmt.Fprintf(&buf, " pass %s", ev.Pass)
+
+	case CostExprEvent:
+		fmt.Fprintf(&buf, ": %g", ev.Cost)
+		if ev.Improved {
+			buf.WriteString(" best")
+		}
+
+	case PruneExprEvent:
+		if ev.Permanent {
+			buf.WriteString(" permanently")
+		}
+	}
+	return buf.String()
+}
+
+// OptimizeTrace records the steps taken by the optimizer, in the order that
+// they were taken.
+type OptimizeTrace struct {
+	Events []OptimizeEvent
+}
+
+func (t *OptimizeTrace) String() string {
+	var buf bytes.Buffer
+	for i := range t.Events {
+		fmt.Fprintf(&buf, "%s\n", t.Events[i].String())
+	}
+	return buf.String()
+}
+
+// formatRequired returns a description of the physical properties that have
+// the given fingerprint.
+func formatRequired(fingerprint string) string {
+	if fingerprint == "" {
+		return "default"
+	}
+	return fingerprint
+}
+
+func (p optimizePass) String() string {
+	return fmt.Sprintf("%d.%d", p.major, p.minor)
+}
@@ -64,6 +64,9 @@ type optimizer struct {
 	coster   coster
 	explorer explorer
 	pass     optimizePass
+
+	// trace, if not nil, records each step taken by the optimizer.
+	trace *OptimizeTrace
 }
 
 func newOptimizer(factory *Factory) *optimizer {
@@ -96,10 +99,14 @@ func (o *optimizer) optimizeGroup(mgrp *memoGroup, required physicalPropsID, cos
 	pass := o.pass
 	start := exprID(0)
 	for {
+		if o.trace != nil {
+			o.traceGroup(mgrp, required, pass)
+		}
+
 		groupFullyOptimized := true
 
 		for i := range mgrp.exprs[start:] {
-			eid := exprID(i)
+			eid := start + exprID(i)
 
 			// If the group is already fully optimized for the given required
 			// properties, then skip it, since it won't get better.
@@ -133,10 +140,15 @@ func (o *optimizer) optimizeGroup(mgrp *memoGroup, required physicalPropsID, cos
 
 		// Now generate new expressions that are logically equivalent to other
 		// expressions in this group.
-		if false { //o.factory.maxSteps > 0 {
+		if o.factory.maxSteps > 0 {
+			numGroups := len(o.mem.groups)
 			if !o.explorer.exploreGroup(mgrp, pass) {
 				groupFullyOptimized = false
 			}
+
+			if o.trace != nil {
+				o.traceAddedExprs(mgrp, start, GroupID(numGroups), pass)
+			}
 		}
 
 		if groupFullyOptimized {
@@ -151,9 +163,10 @@ func (o *optimizer) optimizeGroup(mgrp *memoGroup, required physicalPropsID, cos
 		// required properties, but there may be further iterations.
 		best.lastOptimized = pass
 
-		if best.lastImproved.Less(pass) {
-			// The best expression did not improve, so iterations are complete
-			// during this pass
+		if best.lastImproved.Less(pass) && start == exprID(len(mgrp.exprs)) {
+			// The best expression did not improve, and exploration did not
+			// add any expressions that still need to be costed, so iterations
+			// are complete during this pass.
 			break
 		}
 	}
@@ -190,6 +203,7 @@ func (o *optimizer) optimizeExpr(
 
 	remainingCost := costLimit
 	fullyOptimized := true
+	pruned := false
 
 	for child := 0; child < e.ChildCount(); child++ {
 		childGroup := o.mem.lookupGroup(e.ChildGroup(child))
@@ -234,6 +248,7 @@ func (o *optimizer) optimizeExpr(
 			// No need to recompute the expression's cost, since it's going to
 			// be higher than the budget anyway.
 			recomputeCost = false
+			pruned = true
 			break
 		}
 
@@ -254,6 +269,17 @@ func (o *optimizer) optimizeExpr(
 		best.markExprAsFullyOptimized(eid)
 	}
 
+	if pruned && o.trace != nil {
+		o.trace.Events = append(o.trace.Events, OptimizeEvent{
+			Kind:      PruneExprEvent,
+			Group:     mgrp.id,
+			Expr:      int(eid),
+			Op:        op,
+			Required:  o.mem.lookupPhysicalProps(required).fingerprint(),
+			Permanent: best.isExprFullyOptimized(eid),
+		})
+	}
+
 	return
 }
 
@@ -318,9 +344,61 @@ func (o *optimizer) enforceProps(e *Expr, recomputeCost bool) (fullyOptimized bo
 
 func (o *optimizer) ratchetCost(best *bestExpr, e *Expr) {
 	cost := o.coster.computeCost(e)
+
+	if o.trace != nil {
+		ev := OptimizeEvent{
+			Kind:     CostExprEvent,
+			Group:    e.loc.group,
+			Expr:     int(e.loc.expr),
+			Op:       e.op,
+			Required: o.mem.lookupPhysicalProps(e.required).fingerprint(),
+			Cost:     float64(cost),
+			Improved: cost.Less(best.cost),
+		}
+		if e.IsEnforcer() {
+			ev.Expr = -1
+		}
+		o.trace.Events = append(o.trace.Events, ev)
+	}
+
 	best.ratchetCost(e, cost, o.pass)
 }
 
+// traceGroup records the start of an iteration of the optimizer over the
+// given group.
+func (o *optimizer) traceGroup(mgrp *memoGroup, required physicalPropsID, pass optimizePass) {
+	o.trace.Events = append(o.trace.Events, OptimizeEvent{
+		Kind:     OptimizeGroupEvent,
+		Group:    mgrp.id,
+		Required: o.mem.lookupPhysicalProps(required).fingerprint(),
+		Pass:     pass.String(),
+	})
+}
+
+// traceAddedExprs records the expressions that exploration of the given group
+// added to the memo. Exploration adds expressions to the group starting at the
+// start index, and can also add new groups starting at firstGroup, whose
+// normalized expressions are recorded first since the other expressions can
+// reference them.
+func (o *optimizer) traceAddedExprs(mgrp *memoGroup, start exprID, firstGroup GroupID, pass optimizePass) {
+	add := func(group GroupID, eid exprID) {
+		o.trace.Events = append(o.trace.Events, OptimizeEvent{
+			Kind:  AddExprEvent,
+			Group: group,
+			Expr:  int(eid),
+			Op:    o.mem.lookupExpr(memoLoc{group: group, expr: eid}).op,
+			Pass:  pass.String(),
+		})
+	}
+
+	for group := firstGroup; int(group) < len(o.mem.groups); group++ {
+		add(group, normExprID)
+	}
+	for eid := start; int(eid) < len(mgrp.exprs); eid++ {
+		add(mgrp.id, eid)
+	}
+}
+
 type optimizePass struct {
 	major uint16
 	minor uint16
@@ -80,8 +80,8 @@ func (p Projection) format(buf *bytes.Buffer) {
 }
 
 type LabeledColumn struct {
-	Label string
-	Index ColumnIndex
+	Label string      `json:"label"`
+	Index ColumnIndex `json:"index"`
 }
 
 // Ordering defines the order of columns provided or required by a relation.
@@ -35,7 +35,7 @@ func (c *physicalPropsFactory) canProvideOrdering(e *Expr) bool {
 		switch e.Operator() {
 		case ScanOp:
 			// Table scans provide primary key ordering.
-			tblIndex := e.Private().(TableIndex)
+			tblIndex := e.TablePrivate()
 			ordering := c.mem.metadata.Table(tblIndex).Ordering
 			return ordering.Provides(requiredProps.Ordering)
 
@@ -73,6 +73,45 @@ func (c *physicalPropsFactory) canProvideOrdering(e *Expr) bool {
 	return false
 }
 
+// providedOrdering returns the ordering of the rows that are returned by the
+// expression, which can be longer than the ordering required of it. For
+// example, a scan provides the primary key ordering of its table, even if no
+// ordering is required.
+func (c *physicalPropsFactory) providedOrdering(e *Expr) Ordering {
+	if !e.IsRelational() {
+		return nil
+	}
+
+	switch e.Operator() {
+	case ScanOp:
+		return c.mem.metadata.Table(e.TablePrivate()).Ordering
+
+	case SortOp:
+		return c.mem.lookupPhysicalProps(e.required).Ordering
+
+	case SelectOp, ProjectOp, ArrangeOp,
+		InnerJoinOp, LeftJoinOp, RightJoinOp, FullJoinOp,
+		SemiJoinOp, AntiJoinOp, InnerJoinApplyOp, LeftJoinApplyOp,
+		RightJoinApplyOp, FullJoinApplyOp, SemiJoinApplyOp, AntiJoinApplyOp:
+		// These operators preserve the ordering of their first input, at
+		// least up to the first column that they don't output.
+		input := e.Child(0)
+		ordering := c.providedOrdering(&input)
+		outputCols := e.Logical().Relational.OutputCols
+		for i, colIndex := range ordering {
+			if colIndex < 0 {
+				colIndex = -colIndex
+			}
+			if !outputCols.Contains(int(colIndex)) {
+				return ordering[:i]
+			}
+		}
+		return ordering
+	}
+
+	return nil
+}
+
 func (c *physicalPropsFactory) constructChildProps(e *Expr, nth int) (required physicalPropsID) {
 	if e.IsRelational() {
 		switch e.Operator() {
@@ -1,20 +1,39 @@
 package opt
 
 import (
+	"bytes"
+	"io"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/petermattis/opttoy/v4/cat"
 )
 
 type Planner struct {
 	mem     *memo
 	factory *Factory
+	trace   *OptimizeTrace
 }
 
-func NewPlanner(catalog *cat.Catalog, maxSteps int) *Planner {
+func NewPlanner(catalog cat.Catalog, maxSteps int) *Planner {
 	mem := newMemo(catalog)
 	factory := newFactory(mem, maxSteps)
 	return &Planner{mem: mem, factory: factory}
 }
 
+// LoadPlanner creates a planner whose memo is read from r, which must contain
+// a memo written by SaveMemo. The catalog must contain the tables that were
+// referenced by the saved memo.
+func LoadPlanner(catalog cat.Catalog, maxSteps int, r io.Reader) (p *Planner, err error) {
+	defer catchError(&err)
+
+	mem, err := loadMemoJSON(catalog, r)
+	if err != nil {
+		return nil, err
+	}
+	factory := newFactory(mem, maxSteps)
+	return &Planner{mem: mem, factory: factory}, nil
+}
+
 func (p *Planner) Metadata() *Metadata {
 	return p.mem.metadata
 }
@@ -23,12 +42,88 @@ func (p *Planner) Factory() *Factory {
 	return p.factory
 }
 
-func (p *Planner) Optimize(root GroupID, required *PhysicalProps) Expr {
+// DisableRules prevents the planner from applying the named normalization
+// rules. It must be called before any expressions are constructed.
+func (p *Planner) DisableRules(names ...string) error {
+	for _, name := range names {
+		rule := LookupRuleName(name)
+		if rule == UnknownRule {
+			return pgerror.NewErrorf(pgerror.CodeUndefinedObjectError, "unknown rule %s", name)
+		}
+		p.factory.DisableRule(rule)
+	}
+	return nil
+}
+
+// SetRuleProfile records the cost of matching each normalization rule in the
+// given profile. Passing nil stops profiling.
+func (p *Planner) SetRuleProfile(profile *RuleProfile) {
+	p.factory.profile = profile
+}
+
+// SetOptimizeTrace records each step taken by Optimize in the given trace.
+// Passing nil stops tracing.
+func (p *Planner) SetOptimizeTrace(trace *OptimizeTrace) {
+	p.trace = trace
+}
+
+// Optimize finds the lowest cost expression in the root group that provides
+// the required physical properties. Optimize doesn't fail on valid input, so
+// an error is only returned if an internal invariant has been violated, in
+// which case it has the pgerror.CodeInternalError code.
+func (p *Planner) Optimize(root GroupID, required *PhysicalProps) (e Expr, err error) {
+	defer catchError(&err)
+
 	o := newOptimizer(p.factory)
-	requiredID := p.mem.internPhysicalProps(required)
-	return o.optimize(root, requiredID)
+	o.trace = p.trace
+	requiredID := p.mem.internPhysicalProps(p.simplifyRequiredProps(root, required))
+	return o.optimize(root, requiredID), nil
+}
+
+// simplifyRequiredProps removes any columns from the required ordering that
+// are functionally determined by the columns that precede them, since those
+// columns cannot affect the order of rows. For example:
+//
+//   SELECT * FROM a ORDER BY a.x, a.y
+//
+// If a.x is the primary key of a, then ordering by a.x alone is sufficient.
+func (p *Planner) simplifyRequiredProps(root GroupID, required *PhysicalProps) *PhysicalProps {
+	if p.factory.maxSteps <= 0 || !required.Ordering.Defined() {
+		return required
+	}
+
+	fds := p.mem.lookupGroup(root).logical.Relational.FuncDeps
+	simplified := *required
+	simplified.Ordering = fds.reduceOrdering(required.Ordering)
+	return &simplified
+}
+
+// NormalizedExpr returns the normalized expression in the given group. Unlike
+// Optimize, it does not explore or cost alternative expressions.
+func (p *Planner) NormalizedExpr(group GroupID) Expr {
+	return makeExpr(p.mem, group, defaultPhysPropsID)
+}
+
+// SaveMemo writes the planner's memo to w as JSON, including all of its groups
+// and expressions and the best expressions found by Optimize. The memo can be
+// loaded again by LoadPlanner, for example in order to replay optimization.
+func (p *Planner) SaveMemo(w io.Writer) error {
+	return p.mem.saveJSON(w)
 }
 
 func (p *Planner) MemoString() string {
 	return p.mem.String()
 }
+
+// MemoDOT returns the memo as a Graphviz DOT graph.
+func (p *Planner) MemoDOT() string {
+	var buf bytes.Buffer
+	p.mem.formatDOT(&buf)
+	return buf.String()
+}
+
+// WriteMemoHTML writes an HTML page to w that replays the optimization steps
+// recorded in the given trace (see SetOptimizeTrace) against the memo.
+func (p *Planner) WriteMemoHTML(w io.Writer, trace *OptimizeTrace) error {
+	return p.mem.writeHTML(w, trace)
+}
@@ -0,0 +1,165 @@
+package opt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+)
+
+// privateStorage interns the private fields of the expressions in the memo,
+// since there are so many duplicates. Each type of private has its own intern
+// and lookup methods, which are called by the generated code. The intern
+// methods are keyed by type-specific equality, so two privates are the same if
+// they have the same value, even if they're stored by pointer. Note that
+// PrivateID 0 is invalid in order to indicate an unknown private.
+type privateStorage struct {
+	privates []interface{}
+
+	tables     map[TableIndex]PrivateID
+	cols       map[ColumnIndex]PrivateID
+	colSets    map[string]PrivateID
+	colMaps    map[string]PrivateID
+	datums     map[string]PrivateID
+	typedExprs map[string]PrivateID
+	funcDefs   map[*tree.FunctionDefinition]PrivateID
+}
+
+func (ps *privateStorage) init() {
+	ps.privates = make([]interface{}, 1)
+	ps.tables = make(map[TableIndex]PrivateID)
+	ps.cols = make(map[ColumnIndex]PrivateID)
+	ps.colSets = make(map[string]PrivateID)
+	ps.colMaps = make(map[string]PrivateID)
+	ps.datums = make(map[string]PrivateID)
+	ps.typedExprs = make(map[string]PrivateID)
+	ps.funcDefs = make(map[*tree.FunctionDefinition]PrivateID)
+}
+
+// addPrivate adds a new private value to the storage and returns its id.
+func (ps *privateStorage) addPrivate(private interface{}) PrivateID {
+	id := PrivateID(len(ps.privates))
+	ps.privates = append(ps.privates, private)
+	return id
+}
+
+// lookupPrivate returns the private value with the given id, whatever its
+// type.
+func (ps *privateStorage) lookupPrivate(id PrivateID) interface{} {
+	return ps.privates[id]
+}
+
+func (ps *privateStorage) internTable(table TableIndex) PrivateID {
+	id, ok := ps.tables[table]
+	if !ok {
+		id = ps.addPrivate(table)
+		ps.tables[table] = id
+	}
+	return id
+}
+
+func (ps *privateStorage) lookupTable(id PrivateID) TableIndex {
+	return ps.privates[id].(TableIndex)
+}
+
+func (ps *privateStorage) internColIndex(col ColumnIndex) PrivateID {
+	id, ok := ps.cols[col]
+	if !ok {
+		id = ps.addPrivate(col)
+		ps.cols[col] = id
+	}
+	return id
+}
+
+func (ps *privateStorage) lookupColIndex(id PrivateID) ColumnIndex {
+	return ps.privates[id].(ColumnIndex)
+}
+
+func (ps *privateStorage) internColIndexes(cols *ColSet) PrivateID {
+	// Column sets are stored by pointer, but two column sets with the same
+	// columns are the same private.
+	key := cols.String()
+	id, ok := ps.colSets[key]
+	if !ok {
+		id = ps.addPrivate(cols)
+		ps.colSets[key] = id
+	}
+	return id
+}
+
+func (ps *privateStorage) lookupColIndexes(id PrivateID) *ColSet {
+	return ps.privates[id].(*ColSet)
+}
+
+func (ps *privateStorage) internColMap(colMap *ColMap) PrivateID {
+	// Column maps are keyed by their entries, in order of the columns that
+	// they map from.
+	from := make([]int, 0, len(*colMap))
+	for col := range *colMap {
+		from = append(from, int(col))
+	}
+	sort.Ints(from)
+
+	var buf bytes.Buffer
+	for _, col := range from {
+		binary.Write(&buf, binary.LittleEndian, int32(col))
+		binary.Write(&buf, binary.LittleEndian, int32((*colMap)[ColumnIndex(col)]))
+	}
+
+	key := buf.String()
+	id, ok := ps.colMaps[key]
+	if !ok {
+		id = ps.addPrivate(colMap)
+		ps.colMaps[key] = id
+	}
+	return id
+}
+
+func (ps *privateStorage) lookupColMap(id PrivateID) *ColMap {
+	return ps.privates[id].(*ColMap)
+}
+
+func (ps *privateStorage) internDatum(datum tree.Datum) PrivateID {
+	// Datums are keyed by their type as well as their value, since values of
+	// different types can be formatted the same way (e.g. 1 and 1.0).
+	key := fmt.Sprintf("%s:%s", datum.ResolvedType(), datum)
+	id, ok := ps.datums[key]
+	if !ok {
+		id = ps.addPrivate(datum)
+		ps.datums[key] = id
+	}
+	return id
+}
+
+func (ps *privateStorage) lookupDatum(id PrivateID) tree.Datum {
+	return ps.privates[id].(tree.Datum)
+}
+
+func (ps *privateStorage) internTypedExpr(expr tree.TypedExpr) PrivateID {
+	key := expr.String()
+	id, ok := ps.typedExprs[key]
+	if !ok {
+		id = ps.addPrivate(expr)
+		ps.typedExprs[key] = id
+	}
+	return id
+}
+
+func (ps *privateStorage) lookupTypedExpr(id PrivateID) tree.TypedExpr {
+	return ps.privates[id].(tree.TypedExpr)
+}
+
+func (ps *privateStorage) internFuncDef(def *tree.FunctionDefinition) PrivateID {
+	id, ok := ps.funcDefs[def]
+	if !ok {
+		id = ps.addPrivate(def)
+		ps.funcDefs[def] = id
+	}
+	return id
+}
+
+func (ps *privateStorage) lookupFuncDef(id PrivateID) *tree.FunctionDefinition {
+	return ps.privates[id].(*tree.FunctionDefinition)
+}
@@ -0,0 +1,25 @@
+package opt
+
+import "testing"
+
+// TestInternColIndexes tests that column sets are interned by value rather
+// than by pointer.
+func TestInternColIndexes(t *testing.T) {
+	var ps privateStorage
+	ps.init()
+
+	var a, b, c ColSet
+	a.Add(1)
+	a.Add(2)
+	b.Add(2)
+	b.Add(1)
+	c.Add(1)
+
+	id := ps.internColIndexes(&a)
+	if other := ps.internColIndexes(&b); other != id {
+		t.Errorf("column sets %s and %s have ids %d and %d", a, b, id, other)
+	}
+	if other := ps.internColIndexes(&c); other == id {
+		t.Errorf("column sets %s and %s have the same id %d", a, c, id)
+	}
+}
@@ -0,0 +1,61 @@
+package opt
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RuleStats records the cost of matching a single normalization rule.
+type RuleStats struct {
+	// Attempts is the number of times that the factory tried to match the
+	// rule.
+	Attempts int
+
+	// Matches is the number of times that the rule matched and was applied.
+	Matches int
+
+	// MatchFuncTime is the total time spent in the custom match functions
+	// that the rule invokes, such as IsCorrelated.
+	MatchFuncTime time.Duration
+}
+
+// RuleProfile records the cost of matching each normalization rule. The same
+// profile can be shared by several planners in order to aggregate their
+// statistics, as long as they aren't used concurrently.
+type RuleProfile struct {
+	Rules [NumRules]RuleStats
+}
+
+// Report returns a table of the limit rules that spent the most time in custom
+// match functions, ordered by that time and then by the number of match
+// attempts. If limit is zero, all rules that were attempted are included.
+func (p *RuleProfile) Report(limit int) string {
+	var rules []RuleName
+	for r := RuleName(1); r < NumRules; r++ {
+		if p.Rules[r].Attempts != 0 {
+			rules = append(rules, r)
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		left, right := &p.Rules[rules[i]], &p.Rules[rules[j]]
+		if left.MatchFuncTime != right.MatchFuncTime {
+			return left.MatchFuncTime > right.MatchFuncTime
+		}
+		return left.Attempts > right.Attempts
+	})
+
+	if limit != 0 && len(rules) > limit {
+		rules = rules[:limit]
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%-32s %10s %10s %14s\n", "rule", "attempts", "matches", "match funcs")
+	for _, r := range rules {
+		stats := &p.Rules[r]
+		fmt.Fprintf(&buf, "%-32s %10d %10d %14s\n", r, stats.Attempts, stats.Matches, stats.MatchFuncTime)
+	}
+	return buf.String()
+}
@@ -2,12 +2,32 @@ package opt
 
 import (
 	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 )
 
+// unimplemented raises an error with the CodeFeatureNotSupportedError code for
+// an expression that uses a feature which isn't supported yet.
 func unimplemented(format string, args ...interface{}) {
-	panic("unimplemented: " + fmt.Sprintf(format, args...))
+	panic(pgerror.NewError(pgerror.CodeFeatureNotSupportedError, "unimplemented: "+fmt.Sprintf(format, args...)))
 }
 
+// fatalf panics because an internal invariant has been violated.
 func fatalf(format string, args ...interface{}) {
 	panic(fmt.Sprintf(format, args...))
 }
+
+// catchError recovers from a panic in a public API function, and stores the
+// error in err. It must be deferred directly by the function. Errors raised by
+// unimplemented are returned as is, and any other panic is due to a violated
+// internal invariant, so it is returned as an error with the CodeInternalError
+// code.
+func catchError(err *error) {
+	if r := recover(); r != nil {
+		if pgErr, ok := r.(*pgerror.Error); ok {
+			*err = pgErr
+			return
+		}
+		*err = pgerror.NewErrorf(pgerror.CodeInternalError, "internal error: %v", r)
+	}
+}
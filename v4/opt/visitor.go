@@ -0,0 +1,29 @@
+package opt
+
+//go:generate optgen -out visitor.og.go -pkg opt visitor ops/private.opt ops/scalar.opt ops/relational.opt ops/enforcer.opt
+
+// WalkExpr walks the tree rooted at the given expression in depth-first
+// order, calling the method of the visitor that corresponds to the operator
+// of each expression before walking its children.
+func WalkExpr(v ExprVisitor, e Expr) {
+	if !visitExpr(v, &e) {
+		return
+	}
+
+	for i := 0; i < e.ChildCount(); i++ {
+		WalkExpr(v, e.Child(i))
+	}
+}
+
+// RewriteExpr rewrites the tree rooted at the given expression bottom-up,
+// calling the method of the rewriter that corresponds to the operator of each
+// expression once its children have been rewritten. It returns the group of
+// the rewritten tree.
+func RewriteExpr(r ExprRewriter, e Expr) GroupID {
+	children := make([]GroupID, e.ChildCount())
+	for i := range children {
+		children[i] = RewriteExpr(r, e.Child(i))
+	}
+
+	return rewriteExpr(r, &e, children)
+}
@@ -0,0 +1,1323 @@
+// Code generated by optgen; DO NOT EDIT.
+
+package opt
+
+// ExprVisitor has a Visit method for each operator, which is called by
+// WalkExpr for each expression with that operator. If the method returns
+// false, then the children of the expression are not walked.
+type ExprVisitor interface {
+	VisitSubquery(e *Expr) bool
+	VisitVariable(e *Expr) bool
+	VisitConst(e *Expr) bool
+	VisitPlaceholder(e *Expr) bool
+	VisitList(e *Expr) bool
+	VisitOrderedList(e *Expr) bool
+	VisitTuple(e *Expr) bool
+	VisitFilters(e *Expr) bool
+	VisitProjections(e *Expr) bool
+	VisitExists(e *Expr) bool
+	VisitAnd(e *Expr) bool
+	VisitOr(e *Expr) bool
+	VisitNot(e *Expr) bool
+	VisitEq(e *Expr) bool
+	VisitLt(e *Expr) bool
+	VisitGt(e *Expr) bool
+	VisitLe(e *Expr) bool
+	VisitGe(e *Expr) bool
+	VisitNe(e *Expr) bool
+	VisitIn(e *Expr) bool
+	VisitNotIn(e *Expr) bool
+	VisitLike(e *Expr) bool
+	VisitNotLike(e *Expr) bool
+	VisitILike(e *Expr) bool
+	VisitNotILike(e *Expr) bool
+	VisitSimilarTo(e *Expr) bool
+	VisitNotSimilarTo(e *Expr) bool
+	VisitRegMatch(e *Expr) bool
+	VisitNotRegMatch(e *Expr) bool
+	VisitRegIMatch(e *Expr) bool
+	VisitNotRegIMatch(e *Expr) bool
+	VisitIsDistinctFrom(e *Expr) bool
+	VisitIsNotDistinctFrom(e *Expr) bool
+	VisitIs(e *Expr) bool
+	VisitIsNot(e *Expr) bool
+	VisitAny(e *Expr) bool
+	VisitSome(e *Expr) bool
+	VisitAll(e *Expr) bool
+	VisitBitand(e *Expr) bool
+	VisitBitor(e *Expr) bool
+	VisitBitxor(e *Expr) bool
+	VisitPlus(e *Expr) bool
+	VisitMinus(e *Expr) bool
+	VisitMult(e *Expr) bool
+	VisitDiv(e *Expr) bool
+	VisitFloorDiv(e *Expr) bool
+	VisitMod(e *Expr) bool
+	VisitPow(e *Expr) bool
+	VisitConcat(e *Expr) bool
+	VisitLShift(e *Expr) bool
+	VisitRShift(e *Expr) bool
+	VisitUnaryPlus(e *Expr) bool
+	VisitUnaryMinus(e *Expr) bool
+	VisitUnaryComplement(e *Expr) bool
+	VisitFunction(e *Expr) bool
+	VisitConstAgg(e *Expr) bool
+	VisitTrue(e *Expr) bool
+	VisitFalse(e *Expr) bool
+	VisitScan(e *Expr) bool
+	VisitValues(e *Expr) bool
+	VisitSelect(e *Expr) bool
+	VisitProject(e *Expr) bool
+	VisitInnerJoin(e *Expr) bool
+	VisitLeftJoin(e *Expr) bool
+	VisitRightJoin(e *Expr) bool
+	VisitFullJoin(e *Expr) bool
+	VisitSemiJoin(e *Expr) bool
+	VisitAntiJoin(e *Expr) bool
+	VisitInnerJoinApply(e *Expr) bool
+	VisitLeftJoinApply(e *Expr) bool
+	VisitRightJoinApply(e *Expr) bool
+	VisitFullJoinApply(e *Expr) bool
+	VisitSemiJoinApply(e *Expr) bool
+	VisitAntiJoinApply(e *Expr) bool
+	VisitGroupBy(e *Expr) bool
+	VisitUnion(e *Expr) bool
+	VisitIntersect(e *Expr) bool
+	VisitExcept(e *Expr) bool
+	VisitSort(e *Expr) bool
+	VisitArrange(e *Expr) bool
+}
+
+// ExprVisitorBase implements ExprVisitor by walking every expression.
+// Visitors can embed it and override only the methods for the operators
+// that they analyze.
+type ExprVisitorBase struct{}
+
+func (ExprVisitorBase) VisitSubquery(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitVariable(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitConst(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitPlaceholder(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitList(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitOrderedList(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitTuple(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitFilters(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitProjections(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitExists(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitAnd(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitOr(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitNot(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitEq(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitLt(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitGt(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitLe(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitGe(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitNe(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitIn(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitNotIn(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitLike(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitNotLike(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitILike(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitNotILike(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitSimilarTo(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitNotSimilarTo(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitRegMatch(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitNotRegMatch(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitRegIMatch(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitNotRegIMatch(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitIsDistinctFrom(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitIsNotDistinctFrom(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitIs(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitIsNot(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitAny(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitSome(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitAll(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitBitand(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitBitor(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitBitxor(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitPlus(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitMinus(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitMult(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitDiv(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitFloorDiv(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitMod(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitPow(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitConcat(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitLShift(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitRShift(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitUnaryPlus(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitUnaryMinus(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitUnaryComplement(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitFunction(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitConstAgg(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitTrue(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitFalse(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitScan(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitValues(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitSelect(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitProject(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitInnerJoin(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitLeftJoin(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitRightJoin(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitFullJoin(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitSemiJoin(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitAntiJoin(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitInnerJoinApply(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitLeftJoinApply(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitRightJoinApply(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitFullJoinApply(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitSemiJoinApply(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitAntiJoinApply(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitGroupBy(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitUnion(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitIntersect(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitExcept(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitSort(e *Expr) bool {
+	return true
+}
+
+func (ExprVisitorBase) VisitArrange(e *Expr) bool {
+	return true
+}
+
+func visitExpr(v ExprVisitor, e *Expr) bool {
+	switch e.op {
+	case SubqueryOp:
+		return v.VisitSubquery(e)
+
+	case VariableOp:
+		return v.VisitVariable(e)
+
+	case ConstOp:
+		return v.VisitConst(e)
+
+	case PlaceholderOp:
+		return v.VisitPlaceholder(e)
+
+	case ListOp:
+		return v.VisitList(e)
+
+	case OrderedListOp:
+		return v.VisitOrderedList(e)
+
+	case TupleOp:
+		return v.VisitTuple(e)
+
+	case FiltersOp:
+		return v.VisitFilters(e)
+
+	case ProjectionsOp:
+		return v.VisitProjections(e)
+
+	case ExistsOp:
+		return v.VisitExists(e)
+
+	case AndOp:
+		return v.VisitAnd(e)
+
+	case OrOp:
+		return v.VisitOr(e)
+
+	case NotOp:
+		return v.VisitNot(e)
+
+	case EqOp:
+		return v.VisitEq(e)
+
+	case LtOp:
+		return v.VisitLt(e)
+
+	case GtOp:
+		return v.VisitGt(e)
+
+	case LeOp:
+		return v.VisitLe(e)
+
+	case GeOp:
+		return v.VisitGe(e)
+
+	case NeOp:
+		return v.VisitNe(e)
+
+	case InOp:
+		return v.VisitIn(e)
+
+	case NotInOp:
+		return v.VisitNotIn(e)
+
+	case LikeOp:
+		return v.VisitLike(e)
+
+	case NotLikeOp:
+		return v.VisitNotLike(e)
+
+	case ILikeOp:
+		return v.VisitILike(e)
+
+	case NotILikeOp:
+		return v.VisitNotILike(e)
+
+	case SimilarToOp:
+		return v.VisitSimilarTo(e)
+
+	case NotSimilarToOp:
+		return v.VisitNotSimilarTo(e)
+
+	case RegMatchOp:
+		return v.VisitRegMatch(e)
+
+	case NotRegMatchOp:
+		return v.VisitNotRegMatch(e)
+
+	case RegIMatchOp:
+		return v.VisitRegIMatch(e)
+
+	case NotRegIMatchOp:
+		return v.VisitNotRegIMatch(e)
+
+	case IsDistinctFromOp:
+		return v.VisitIsDistinctFrom(e)
+
+	case IsNotDistinctFromOp:
+		return v.VisitIsNotDistinctFrom(e)
+
+	case IsOp:
+		return v.VisitIs(e)
+
+	case IsNotOp:
+		return v.VisitIsNot(e)
+
+	case AnyOp:
+		return v.VisitAny(e)
+
+	case SomeOp:
+		return v.VisitSome(e)
+
+	case AllOp:
+		return v.VisitAll(e)
+
+	case BitandOp:
+		return v.VisitBitand(e)
+
+	case BitorOp:
+		return v.VisitBitor(e)
+
+	case BitxorOp:
+		return v.VisitBitxor(e)
+
+	case PlusOp:
+		return v.VisitPlus(e)
+
+	case MinusOp:
+		return v.VisitMinus(e)
+
+	case MultOp:
+		return v.VisitMult(e)
+
+	case DivOp:
+		return v.VisitDiv(e)
+
+	case FloorDivOp:
+		return v.VisitFloorDiv(e)
+
+	case ModOp:
+		return v.VisitMod(e)
+
+	case PowOp:
+		return v.VisitPow(e)
+
+	case ConcatOp:
+		return v.VisitConcat(e)
+
+	case LShiftOp:
+		return v.VisitLShift(e)
+
+	case RShiftOp:
+		return v.VisitRShift(e)
+
+	case UnaryPlusOp:
+		return v.VisitUnaryPlus(e)
+
+	case UnaryMinusOp:
+		return v.VisitUnaryMinus(e)
+
+	case UnaryComplementOp:
+		return v.VisitUnaryComplement(e)
+
+	case FunctionOp:
+		return v.VisitFunction(e)
+
+	case ConstAggOp:
+		return v.VisitConstAgg(e)
+
+	case TrueOp:
+		return v.VisitTrue(e)
+
+	case FalseOp:
+		return v.VisitFalse(e)
+
+	case ScanOp:
+		return v.VisitScan(e)
+
+	case ValuesOp:
+		return v.VisitValues(e)
+
+	case SelectOp:
+		return v.VisitSelect(e)
+
+	case ProjectOp:
+		return v.VisitProject(e)
+
+	case InnerJoinOp:
+		return v.VisitInnerJoin(e)
+
+	case LeftJoinOp:
+		return v.VisitLeftJoin(e)
+
+	case RightJoinOp:
+		return v.VisitRightJoin(e)
+
+	case FullJoinOp:
+		return v.VisitFullJoin(e)
+
+	case SemiJoinOp:
+		return v.VisitSemiJoin(e)
+
+	case AntiJoinOp:
+		return v.VisitAntiJoin(e)
+
+	case InnerJoinApplyOp:
+		return v.VisitInnerJoinApply(e)
+
+	case LeftJoinApplyOp:
+		return v.VisitLeftJoinApply(e)
+
+	case RightJoinApplyOp:
+		return v.VisitRightJoinApply(e)
+
+	case FullJoinApplyOp:
+		return v.VisitFullJoinApply(e)
+
+	case SemiJoinApplyOp:
+		return v.VisitSemiJoinApply(e)
+
+	case AntiJoinApplyOp:
+		return v.VisitAntiJoinApply(e)
+
+	case GroupByOp:
+		return v.VisitGroupBy(e)
+
+	case UnionOp:
+		return v.VisitUnion(e)
+
+	case IntersectOp:
+		return v.VisitIntersect(e)
+
+	case ExceptOp:
+		return v.VisitExcept(e)
+
+	case SortOp:
+		return v.VisitSort(e)
+
+	case ArrangeOp:
+		return v.VisitArrange(e)
+
+	}
+
+	panic("unhandled op " + e.op.String())
+}
+
+// ExprRewriter has a Rewrite method for each operator, which is called by
+// RewriteExpr for each expression with that operator. The method is passed
+// the groups of the rewritten children of the expression, and returns the
+// group of the rewritten expression.
+type ExprRewriter interface {
+	RewriteSubquery(e *Expr, input GroupID, projection GroupID) GroupID
+	RewriteVariable(e *Expr) GroupID
+	RewriteConst(e *Expr) GroupID
+	RewritePlaceholder(e *Expr) GroupID
+	RewriteList(e *Expr, items []GroupID) GroupID
+	RewriteOrderedList(e *Expr, items []GroupID) GroupID
+	RewriteTuple(e *Expr, elems []GroupID) GroupID
+	RewriteFilters(e *Expr, conditions []GroupID) GroupID
+	RewriteProjections(e *Expr, items []GroupID) GroupID
+	RewriteExists(e *Expr, input GroupID) GroupID
+	RewriteAnd(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteOr(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteNot(e *Expr, input GroupID) GroupID
+	RewriteEq(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteLt(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteGt(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteLe(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteGe(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteNe(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteIn(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteNotIn(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteLike(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteNotLike(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteILike(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteNotILike(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteSimilarTo(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteNotSimilarTo(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteRegMatch(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteNotRegMatch(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteRegIMatch(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteNotRegIMatch(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteIsDistinctFrom(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteIsNotDistinctFrom(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteIs(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteIsNot(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteAny(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteSome(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteAll(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteBitand(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteBitor(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteBitxor(e *Expr, left GroupID, right GroupID) GroupID
+	RewritePlus(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteMinus(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteMult(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteDiv(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteFloorDiv(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteMod(e *Expr, left GroupID, right GroupID) GroupID
+	RewritePow(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteConcat(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteLShift(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteRShift(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteUnaryPlus(e *Expr, input GroupID) GroupID
+	RewriteUnaryMinus(e *Expr, input GroupID) GroupID
+	RewriteUnaryComplement(e *Expr, input GroupID) GroupID
+	RewriteFunction(e *Expr, args []GroupID) GroupID
+	RewriteConstAgg(e *Expr, input GroupID) GroupID
+	RewriteTrue(e *Expr) GroupID
+	RewriteFalse(e *Expr) GroupID
+	RewriteScan(e *Expr) GroupID
+	RewriteValues(e *Expr, rows []GroupID) GroupID
+	RewriteSelect(e *Expr, input GroupID, filter GroupID) GroupID
+	RewriteProject(e *Expr, input GroupID, projections GroupID) GroupID
+	RewriteInnerJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteLeftJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteRightJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteFullJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteSemiJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteAntiJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteInnerJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteLeftJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteRightJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteFullJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteSemiJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteAntiJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID
+	RewriteGroupBy(e *Expr, input GroupID, groupings GroupID, aggregations GroupID) GroupID
+	RewriteUnion(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteIntersect(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteExcept(e *Expr, left GroupID, right GroupID) GroupID
+	RewriteSort(e *Expr, input GroupID) GroupID
+	RewriteArrange(e *Expr, input GroupID) GroupID
+}
+
+// ExprRewriterBase implements ExprRewriter by constructing each expression
+// from its rewritten children, and by removing enforcers, since they are
+// not part of the logical expression tree. Rewriters can embed it and
+// override only the methods for the operators that they transform.
+type ExprRewriterBase struct {
+	Factory *Factory
+}
+
+func (r ExprRewriterBase) RewriteSubquery(e *Expr, input GroupID, projection GroupID) GroupID {
+	return r.Factory.ConstructSubquery(input, projection)
+}
+
+func (r ExprRewriterBase) RewriteVariable(e *Expr) GroupID {
+	return r.Factory.constructVariable(e.privateID())
+}
+
+func (r ExprRewriterBase) RewriteConst(e *Expr) GroupID {
+	return r.Factory.constructConst(e.privateID())
+}
+
+func (r ExprRewriterBase) RewritePlaceholder(e *Expr) GroupID {
+	return r.Factory.constructPlaceholder(e.privateID())
+}
+
+func (r ExprRewriterBase) RewriteList(e *Expr, items []GroupID) GroupID {
+	return r.Factory.ConstructList(r.Factory.StoreList(items))
+}
+
+func (r ExprRewriterBase) RewriteOrderedList(e *Expr, items []GroupID) GroupID {
+	return r.Factory.ConstructOrderedList(r.Factory.StoreList(items))
+}
+
+func (r ExprRewriterBase) RewriteTuple(e *Expr, elems []GroupID) GroupID {
+	return r.Factory.ConstructTuple(r.Factory.StoreList(elems))
+}
+
+func (r ExprRewriterBase) RewriteFilters(e *Expr, conditions []GroupID) GroupID {
+	return r.Factory.ConstructFilters(r.Factory.StoreList(conditions))
+}
+
+func (r ExprRewriterBase) RewriteProjections(e *Expr, items []GroupID) GroupID {
+	return r.Factory.constructProjections(r.Factory.StoreList(items), e.privateID())
+}
+
+func (r ExprRewriterBase) RewriteExists(e *Expr, input GroupID) GroupID {
+	return r.Factory.ConstructExists(input)
+}
+
+func (r ExprRewriterBase) RewriteAnd(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructAnd(left, right)
+}
+
+func (r ExprRewriterBase) RewriteOr(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructOr(left, right)
+}
+
+func (r ExprRewriterBase) RewriteNot(e *Expr, input GroupID) GroupID {
+	return r.Factory.ConstructNot(input)
+}
+
+func (r ExprRewriterBase) RewriteEq(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructEq(left, right)
+}
+
+func (r ExprRewriterBase) RewriteLt(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructLt(left, right)
+}
+
+func (r ExprRewriterBase) RewriteGt(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructGt(left, right)
+}
+
+func (r ExprRewriterBase) RewriteLe(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructLe(left, right)
+}
+
+func (r ExprRewriterBase) RewriteGe(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructGe(left, right)
+}
+
+func (r ExprRewriterBase) RewriteNe(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructNe(left, right)
+}
+
+func (r ExprRewriterBase) RewriteIn(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructIn(left, right)
+}
+
+func (r ExprRewriterBase) RewriteNotIn(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructNotIn(left, right)
+}
+
+func (r ExprRewriterBase) RewriteLike(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructLike(left, right)
+}
+
+func (r ExprRewriterBase) RewriteNotLike(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructNotLike(left, right)
+}
+
+func (r ExprRewriterBase) RewriteILike(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructILike(left, right)
+}
+
+func (r ExprRewriterBase) RewriteNotILike(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructNotILike(left, right)
+}
+
+func (r ExprRewriterBase) RewriteSimilarTo(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructSimilarTo(left, right)
+}
+
+func (r ExprRewriterBase) RewriteNotSimilarTo(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructNotSimilarTo(left, right)
+}
+
+func (r ExprRewriterBase) RewriteRegMatch(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructRegMatch(left, right)
+}
+
+func (r ExprRewriterBase) RewriteNotRegMatch(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructNotRegMatch(left, right)
+}
+
+func (r ExprRewriterBase) RewriteRegIMatch(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructRegIMatch(left, right)
+}
+
+func (r ExprRewriterBase) RewriteNotRegIMatch(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructNotRegIMatch(left, right)
+}
+
+func (r ExprRewriterBase) RewriteIsDistinctFrom(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructIsDistinctFrom(left, right)
+}
+
+func (r ExprRewriterBase) RewriteIsNotDistinctFrom(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructIsNotDistinctFrom(left, right)
+}
+
+func (r ExprRewriterBase) RewriteIs(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructIs(left, right)
+}
+
+func (r ExprRewriterBase) RewriteIsNot(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructIsNot(left, right)
+}
+
+func (r ExprRewriterBase) RewriteAny(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructAny(left, right)
+}
+
+func (r ExprRewriterBase) RewriteSome(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructSome(left, right)
+}
+
+func (r ExprRewriterBase) RewriteAll(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructAll(left, right)
+}
+
+func (r ExprRewriterBase) RewriteBitand(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructBitand(left, right)
+}
+
+func (r ExprRewriterBase) RewriteBitor(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructBitor(left, right)
+}
+
+func (r ExprRewriterBase) RewriteBitxor(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructBitxor(left, right)
+}
+
+func (r ExprRewriterBase) RewritePlus(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructPlus(left, right)
+}
+
+func (r ExprRewriterBase) RewriteMinus(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructMinus(left, right)
+}
+
+func (r ExprRewriterBase) RewriteMult(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructMult(left, right)
+}
+
+func (r ExprRewriterBase) RewriteDiv(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructDiv(left, right)
+}
+
+func (r ExprRewriterBase) RewriteFloorDiv(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructFloorDiv(left, right)
+}
+
+func (r ExprRewriterBase) RewriteMod(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructMod(left, right)
+}
+
+func (r ExprRewriterBase) RewritePow(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructPow(left, right)
+}
+
+func (r ExprRewriterBase) RewriteConcat(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructConcat(left, right)
+}
+
+func (r ExprRewriterBase) RewriteLShift(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructLShift(left, right)
+}
+
+func (r ExprRewriterBase) RewriteRShift(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructRShift(left, right)
+}
+
+func (r ExprRewriterBase) RewriteUnaryPlus(e *Expr, input GroupID) GroupID {
+	return r.Factory.ConstructUnaryPlus(input)
+}
+
+func (r ExprRewriterBase) RewriteUnaryMinus(e *Expr, input GroupID) GroupID {
+	return r.Factory.ConstructUnaryMinus(input)
+}
+
+func (r ExprRewriterBase) RewriteUnaryComplement(e *Expr, input GroupID) GroupID {
+	return r.Factory.ConstructUnaryComplement(input)
+}
+
+func (r ExprRewriterBase) RewriteFunction(e *Expr, args []GroupID) GroupID {
+	return r.Factory.constructFunction(r.Factory.StoreList(args), e.privateID())
+}
+
+func (r ExprRewriterBase) RewriteConstAgg(e *Expr, input GroupID) GroupID {
+	return r.Factory.ConstructConstAgg(input)
+}
+
+func (r ExprRewriterBase) RewriteTrue(e *Expr) GroupID {
+	return r.Factory.ConstructTrue()
+}
+
+func (r ExprRewriterBase) RewriteFalse(e *Expr) GroupID {
+	return r.Factory.ConstructFalse()
+}
+
+func (r ExprRewriterBase) RewriteScan(e *Expr) GroupID {
+	return r.Factory.constructScan(e.privateID())
+}
+
+func (r ExprRewriterBase) RewriteValues(e *Expr, rows []GroupID) GroupID {
+	return r.Factory.constructValues(r.Factory.StoreList(rows), e.privateID())
+}
+
+func (r ExprRewriterBase) RewriteSelect(e *Expr, input GroupID, filter GroupID) GroupID {
+	return r.Factory.ConstructSelect(input, filter)
+}
+
+func (r ExprRewriterBase) RewriteProject(e *Expr, input GroupID, projections GroupID) GroupID {
+	return r.Factory.ConstructProject(input, projections)
+}
+
+func (r ExprRewriterBase) RewriteInnerJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructInnerJoin(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteLeftJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructLeftJoin(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteRightJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructRightJoin(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteFullJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructFullJoin(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteSemiJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructSemiJoin(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteAntiJoin(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructAntiJoin(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteInnerJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructInnerJoinApply(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteLeftJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructLeftJoinApply(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteRightJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructRightJoinApply(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteFullJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructFullJoinApply(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteSemiJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructSemiJoinApply(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteAntiJoinApply(e *Expr, left GroupID, right GroupID, on GroupID) GroupID {
+	return r.Factory.ConstructAntiJoinApply(left, right, on)
+}
+
+func (r ExprRewriterBase) RewriteGroupBy(e *Expr, input GroupID, groupings GroupID, aggregations GroupID) GroupID {
+	return r.Factory.ConstructGroupBy(input, groupings, aggregations)
+}
+
+func (r ExprRewriterBase) RewriteUnion(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.constructUnion(left, right, e.privateID())
+}
+
+func (r ExprRewriterBase) RewriteIntersect(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructIntersect(left, right)
+}
+
+func (r ExprRewriterBase) RewriteExcept(e *Expr, left GroupID, right GroupID) GroupID {
+	return r.Factory.ConstructExcept(left, right)
+}
+
+func (r ExprRewriterBase) RewriteSort(e *Expr, input GroupID) GroupID {
+	return input
+}
+
+func (r ExprRewriterBase) RewriteArrange(e *Expr, input GroupID) GroupID {
+	return input
+}
+
+func rewriteExpr(r ExprRewriter, e *Expr, children []GroupID) GroupID {
+	switch e.op {
+	case SubqueryOp:
+		return r.RewriteSubquery(e, children[0], children[1])
+
+	case VariableOp:
+		return r.RewriteVariable(e)
+
+	case ConstOp:
+		return r.RewriteConst(e)
+
+	case PlaceholderOp:
+		return r.RewritePlaceholder(e)
+
+	case ListOp:
+		return r.RewriteList(e, children[0:])
+
+	case OrderedListOp:
+		return r.RewriteOrderedList(e, children[0:])
+
+	case TupleOp:
+		return r.RewriteTuple(e, children[0:])
+
+	case FiltersOp:
+		return r.RewriteFilters(e, children[0:])
+
+	case ProjectionsOp:
+		return r.RewriteProjections(e, children[0:])
+
+	case ExistsOp:
+		return r.RewriteExists(e, children[0])
+
+	case AndOp:
+		return r.RewriteAnd(e, children[0], children[1])
+
+	case OrOp:
+		return r.RewriteOr(e, children[0], children[1])
+
+	case NotOp:
+		return r.RewriteNot(e, children[0])
+
+	case EqOp:
+		return r.RewriteEq(e, children[0], children[1])
+
+	case LtOp:
+		return r.RewriteLt(e, children[0], children[1])
+
+	case GtOp:
+		return r.RewriteGt(e, children[0], children[1])
+
+	case LeOp:
+		return r.RewriteLe(e, children[0], children[1])
+
+	case GeOp:
+		return r.RewriteGe(e, children[0], children[1])
+
+	case NeOp:
+		return r.RewriteNe(e, children[0], children[1])
+
+	case InOp:
+		return r.RewriteIn(e, children[0], children[1])
+
+	case NotInOp:
+		return r.RewriteNotIn(e, children[0], children[1])
+
+	case LikeOp:
+		return r.RewriteLike(e, children[0], children[1])
+
+	case NotLikeOp:
+		return r.RewriteNotLike(e, children[0], children[1])
+
+	case ILikeOp:
+		return r.RewriteILike(e, children[0], children[1])
+
+	case NotILikeOp:
+		return r.RewriteNotILike(e, children[0], children[1])
+
+	case SimilarToOp:
+		return r.RewriteSimilarTo(e, children[0], children[1])
+
+	case NotSimilarToOp:
+		return r.RewriteNotSimilarTo(e, children[0], children[1])
+
+	case RegMatchOp:
+		return r.RewriteRegMatch(e, children[0], children[1])
+
+	case NotRegMatchOp:
+		return r.RewriteNotRegMatch(e, children[0], children[1])
+
+	case RegIMatchOp:
+		return r.RewriteRegIMatch(e, children[0], children[1])
+
+	case NotRegIMatchOp:
+		return r.RewriteNotRegIMatch(e, children[0], children[1])
+
+	case IsDistinctFromOp:
+		return r.RewriteIsDistinctFrom(e, children[0], children[1])
+
+	case IsNotDistinctFromOp:
+		return r.RewriteIsNotDistinctFrom(e, children[0], children[1])
+
+	case IsOp:
+		return r.RewriteIs(e, children[0], children[1])
+
+	case IsNotOp:
+		return r.RewriteIsNot(e, children[0], children[1])
+
+	case AnyOp:
+		return r.RewriteAny(e, children[0], children[1])
+
+	case SomeOp:
+		return r.RewriteSome(e, children[0], children[1])
+
+	case AllOp:
+		return r.RewriteAll(e, children[0], children[1])
+
+	case BitandOp:
+		return r.RewriteBitand(e, children[0], children[1])
+
+	case BitorOp:
+		return r.RewriteBitor(e, children[0], children[1])
+
+	case BitxorOp:
+		return r.RewriteBitxor(e, children[0], children[1])
+
+	case PlusOp:
+		return r.RewritePlus(e, children[0], children[1])
+
+	case MinusOp:
+		return r.RewriteMinus(e, children[0], children[1])
+
+	case MultOp:
+		return r.RewriteMult(e, children[0], children[1])
+
+	case DivOp:
+		return r.RewriteDiv(e, children[0], children[1])
+
+	case FloorDivOp:
+		return r.RewriteFloorDiv(e, children[0], children[1])
+
+	case ModOp:
+		return r.RewriteMod(e, children[0], children[1])
+
+	case PowOp:
+		return r.RewritePow(e, children[0], children[1])
+
+	case ConcatOp:
+		return r.RewriteConcat(e, children[0], children[1])
+
+	case LShiftOp:
+		return r.RewriteLShift(e, children[0], children[1])
+
+	case RShiftOp:
+		return r.RewriteRShift(e, children[0], children[1])
+
+	case UnaryPlusOp:
+		return r.RewriteUnaryPlus(e, children[0])
+
+	case UnaryMinusOp:
+		return r.RewriteUnaryMinus(e, children[0])
+
+	case UnaryComplementOp:
+		return r.RewriteUnaryComplement(e, children[0])
+
+	case FunctionOp:
+		return r.RewriteFunction(e, children[0:])
+
+	case ConstAggOp:
+		return r.RewriteConstAgg(e, children[0])
+
+	case TrueOp:
+		return r.RewriteTrue(e)
+
+	case FalseOp:
+		return r.RewriteFalse(e)
+
+	case ScanOp:
+		return r.RewriteScan(e)
+
+	case ValuesOp:
+		return r.RewriteValues(e, children[0:])
+
+	case SelectOp:
+		return r.RewriteSelect(e, children[0], children[1])
+
+	case ProjectOp:
+		return r.RewriteProject(e, children[0], children[1])
+
+	case InnerJoinOp:
+		return r.RewriteInnerJoin(e, children[0], children[1], children[2])
+
+	case LeftJoinOp:
+		return r.RewriteLeftJoin(e, children[0], children[1], children[2])
+
+	case RightJoinOp:
+		return r.RewriteRightJoin(e, children[0], children[1], children[2])
+
+	case FullJoinOp:
+		return r.RewriteFullJoin(e, children[0], children[1], children[2])
+
+	case SemiJoinOp:
+		return r.RewriteSemiJoin(e, children[0], children[1], children[2])
+
+	case AntiJoinOp:
+		return r.RewriteAntiJoin(e, children[0], children[1], children[2])
+
+	case InnerJoinApplyOp:
+		return r.RewriteInnerJoinApply(e, children[0], children[1], children[2])
+
+	case LeftJoinApplyOp:
+		return r.RewriteLeftJoinApply(e, children[0], children[1], children[2])
+
+	case RightJoinApplyOp:
+		return r.RewriteRightJoinApply(e, children[0], children[1], children[2])
+
+	case FullJoinApplyOp:
+		return r.RewriteFullJoinApply(e, children[0], children[1], children[2])
+
+	case SemiJoinApplyOp:
+		return r.RewriteSemiJoinApply(e, children[0], children[1], children[2])
+
+	case AntiJoinApplyOp:
+		return r.RewriteAntiJoinApply(e, children[0], children[1], children[2])
+
+	case GroupByOp:
+		return r.RewriteGroupBy(e, children[0], children[1], children[2])
+
+	case UnionOp:
+		return r.RewriteUnion(e, children[0], children[1])
+
+	case IntersectOp:
+		return r.RewriteIntersect(e, children[0], children[1])
+
+	case ExceptOp:
+		return r.RewriteExcept(e, children[0], children[1])
+
+	case SortOp:
+		return r.RewriteSort(e, children[0])
+
+	case ArrangeOp:
+		return r.RewriteArrange(e, children[0])
+
+	}
+
+	panic("unhandled op " + e.op.String())
+}
@@ -0,0 +1,470 @@
+package optgen
+
+import (
+	"fmt"
+)
+
+// fieldKind classifies define fields by the kinds of expressions that can
+// match or construct them.
+type fieldKind int
+
+const (
+	// unknownKind is the kind of expressions whose field is not known, such
+	// as the arguments of custom functions.
+	unknownKind fieldKind = iota
+	exprKind
+	listKind
+	privateKind
+)
+
+// describe returns a description of a field of this kind, for use in error
+// messages.
+func (k fieldKind) describe() string {
+	switch k {
+	case exprKind:
+		return "an Expr field"
+	case listKind:
+		return "an ExprList field"
+	case privateKind:
+		return "a private field"
+	}
+	return "a field of unknown kind"
+}
+
+func fieldKindOf(field *DefineFieldExpr) fieldKind {
+	if field.IsExprType() {
+		return exprKind
+	}
+	if field.IsListType() {
+		return listKind
+	}
+	return privateKind
+}
+
+// funcUse records the first call of a custom function, so that later calls
+// can be checked against it.
+type funcUse struct {
+	src     *SourceLoc
+	argsLen int
+	match   bool
+}
+
+// checker validates the parsed optgen source before it is compiled. It checks
+// the defines for well-formed fields, and checks every match and replace
+// expression of every rule against the signatures of the defines, so that the
+// generators can assume that the rules are well-formed. Rather than stopping
+// at the first error, the checker collects all of the errors that it finds.
+//
+// Custom functions are implemented in Go, so their signatures are not known
+// to optgen. Instead, the checker verifies that each custom function is called
+// consistently: always with the same number of arguments, and either only as
+// a match function or only as a replace function. If the names of the custom
+// functions are known, then calls to any other function are errors.
+type checker struct {
+	privates   map[string]*PrivateTypeExpr
+	defines    map[string]*DefineExpr
+	defineList []*DefineExpr
+	tags       map[string]bool
+	funcs      map[string]funcUse
+	errors     []error
+
+	// knownFuncs is the set of custom functions that rules can call, or nil
+	// if any function can be called.
+	knownFuncs map[string]bool
+
+	// rule is the rule that is currently being checked, and bindings maps each
+	// label bound so far in the rule to the kind of field that it matched.
+	rule     *RuleExpr
+	bindings map[string]fieldKind
+}
+
+func (c *checker) check(root *RootExpr) []error {
+	c.privates = make(map[string]*PrivateTypeExpr)
+	c.defines = make(map[string]*DefineExpr)
+	c.tags = make(map[string]bool)
+	c.funcs = make(map[string]funcUse)
+
+	for _, elem := range root.PrivateTypes().All() {
+		c.checkPrivateType(elem.(*PrivateTypeExpr))
+	}
+
+	for _, elem := range root.Defines().All() {
+		c.checkDefine(elem.(*DefineExpr))
+	}
+
+	ruleNames := make(map[string]*RuleExpr)
+	for _, elem := range root.Rules().All() {
+		rule := elem.(*RuleExpr)
+		c.rule = rule
+
+		name := rule.Header().Name()
+		if existing, ok := ruleNames[name]; ok {
+			c.addErr(rule, "duplicate rule name (previously declared at %s)", existing.Source())
+		} else {
+			ruleNames[name] = rule
+		}
+
+		c.checkRule(rule)
+	}
+
+	return c.errors
+}
+
+func (c *checker) checkPrivateType(private *PrivateTypeExpr) {
+	if existing, ok := c.privates[private.Name()]; ok {
+		c.addErr(private, "duplicate private type '%s' (previously declared at %s)", private.Name(), existing.Source())
+		return
+	}
+	c.privates[private.Name()] = private
+
+	if _, _, ok := splitGoType(private.GoType()); !ok {
+		c.addErr(private, "invalid Go type \"%s\" for private type '%s'", private.GoType(), private.Name())
+	}
+}
+
+func (c *checker) checkDefine(define *DefineExpr) {
+	if _, ok := c.defines[define.Name()]; ok {
+		c.addErr(define, "duplicate define '%s'", define.Name())
+		return
+	}
+
+	c.defines[define.Name()] = define
+	c.defineList = append(c.defineList, define)
+	for _, elem := range define.Tags().All() {
+		c.tags[elem.(*StringExpr).ValueAsString()] = true
+	}
+
+	// Ensure that fields are defined in the following order:
+	//   (Expr | RelExpr)*
+	//   ExprList?
+	//   Private?
+	//
+	// That is, there can be zero or more expression-typed fields, followed
+	// by zero or one list-typed field, followed by zero or one private field.
+	fieldNames := make(map[string]bool)
+	fields := define.Fields()
+	for i, elem := range fields {
+		field := elem.(*DefineFieldExpr)
+		if fieldNames[field.Name()] {
+			c.addErr(field, "duplicate field '%s' in '%s'", field.Name(), define.Name())
+		}
+		fieldNames[field.Name()] = true
+
+		switch fieldKindOf(field) {
+		case privateKind:
+			if i != len(fields)-1 {
+				c.addErr(field, "private field '%s' is not the last field in '%s'", field.Name(), define.Name())
+			}
+			if _, ok := c.privates[field.Type()]; !ok {
+				c.addErr(field, "unknown private type '%s' for field '%s' in '%s'", field.Type(), field.Name(), define.Name())
+			}
+
+		case listKind:
+			index := len(fields) - 1
+			if fieldKindOf(fields[index].(*DefineFieldExpr)) == privateKind {
+				index--
+			}
+
+			if i != index {
+				c.addErr(field, "list field '%s' is not the last non-private field in '%s'", field.Name(), define.Name())
+			}
+		}
+	}
+}
+
+// ruleTags are the tags that select the rules that are generated. The factory
+// generates the Normalize rules, and the explorer generates the Explore and
+// Implement rules.
+var ruleTags = map[string]bool{
+	"Normalize": true,
+	"Explore":   true,
+	"Implement": true,
+}
+
+func (c *checker) checkRule(rule *RuleExpr) {
+	c.rule = rule
+	c.bindings = make(map[string]fieldKind)
+
+	for _, elem := range rule.Header().Tags().All() {
+		tag := elem.(*StringExpr)
+		if !ruleTags[tag.ValueAsString()] {
+			c.addErr(tag, "unrecognized rule tag '%s'", tag.ValueAsString())
+		}
+	}
+
+	c.checkMatch(rule.Match(), exprKind)
+	c.checkReplace(rule.Replace(), exprKind)
+}
+
+// checkMatch checks a match expression that matches a field of the given
+// kind.
+func (c *checker) checkMatch(match Expr, kind fieldKind) {
+	switch t := match.(type) {
+	case *MatchFieldsExpr:
+		if !c.isCompatible(kind, exprKind) {
+			c.addErr(t, "cannot match an operator against %s", kind.describe())
+			return
+		}
+		c.checkMatchFields(t)
+
+	case *BindExpr:
+		if _, ok := c.bindings[t.Label()]; ok {
+			c.addErr(t, "duplicate binding of label '$%s'", t.Label())
+		}
+
+		// The label is bound before its target is matched, so the target can
+		// refer to it.
+		c.bindings[t.Label()] = kind
+		c.checkMatch(t.Target(), kind)
+
+	case *MatchAndExpr:
+		c.checkMatch(t.Left(), kind)
+		c.checkMatch(t.Right(), kind)
+
+	case *MatchNotExpr:
+		c.checkMatch(t.Input(), kind)
+
+	case *MatchInvokeExpr:
+		c.checkFunc(t, t.FuncName(), len(t.Args()), true)
+		for _, arg := range t.Args() {
+			c.checkRef(arg.(*RefExpr), unknownKind)
+		}
+
+	case *MatchListExpr:
+		if !c.isCompatible(kind, listKind) {
+			c.addErr(t, "cannot match a list against %s", kind.describe())
+			return
+		}
+		c.checkMatch(t.MatchItem(), exprKind)
+
+	case *StringExpr:
+		if !c.isCompatible(kind, privateKind) {
+			c.addErr(t, "cannot match a string against %s", kind.describe())
+		}
+
+	case *MatchAnyExpr:
+
+	default:
+		panic(fmt.Sprintf("unrecognized match expression: %v", match))
+	}
+}
+
+// checkMatchFields checks a match expression that matches the fields of the
+// operators with the given names, each of which must be a define or tag name.
+func (c *checker) checkMatchFields(matchFields *MatchFieldsExpr) {
+	var defines []*DefineExpr
+	for _, elem := range matchFields.Names().(*MatchNamesExpr).All() {
+		name := elem.(*StringExpr)
+		if define, ok := c.defines[name.ValueAsString()]; ok {
+			defines = append(defines, define)
+			continue
+		}
+
+		if !c.tags[name.ValueAsString()] {
+			c.addErr(name, "unrecognized match name '%s'", name.ValueAsString())
+			continue
+		}
+
+		for _, define := range c.defineList {
+			if define.HasTag(name.ValueAsString()) {
+				defines = append(defines, define)
+			}
+		}
+	}
+
+	fields := matchFields.Fields()
+	for _, define := range defines {
+		if len(fields) > len(define.Fields()) {
+			c.addErr(matchFields, "too many fields in match of '%s': expected at most %d, found %d",
+				define.Name(), len(define.Fields()), len(fields))
+			defines = nil
+			break
+		}
+	}
+
+	for index, field := range fields {
+		// If the matched defines disagree on the kind of the field, then only
+		// the kinds that they have in common can be checked. The fields are
+		// still checked when there are too many of them, so that their labels
+		// are bound.
+		kind := unknownKind
+		for i, define := range defines {
+			defineKind := fieldKindOf(define.Fields()[index].(*DefineFieldExpr))
+			if i == 0 {
+				kind = defineKind
+			} else if kind != defineKind {
+				kind = unknownKind
+				break
+			}
+		}
+
+		c.checkMatch(field, kind)
+	}
+}
+
+// checkReplace checks a replace expression that constructs a field of the
+// given kind.
+func (c *checker) checkReplace(replace Expr, kind fieldKind) {
+	switch t := replace.(type) {
+	case *ConstructExpr:
+		c.checkConstruct(t, kind)
+
+	case *ConstructListExpr:
+		if !c.isCompatible(kind, listKind) {
+			c.addErr(t, "cannot construct a list for %s", kind.describe())
+			return
+		}
+		for _, item := range t.Children() {
+			c.checkReplace(item, exprKind)
+		}
+
+	case *RefExpr:
+		c.checkRef(t, kind)
+
+	case *StringExpr:
+		if !c.isCompatible(kind, privateKind) {
+			c.addErr(t, "cannot construct a string for %s", kind.describe())
+		}
+
+	case *ReplaceRootExpr:
+		for _, item := range t.All() {
+			c.checkReplace(item, kind)
+		}
+
+	default:
+		panic(fmt.Sprintf("unrecognized replace expression: %v", replace))
+	}
+}
+
+func (c *checker) checkConstruct(construct *ConstructExpr, kind fieldKind) {
+	name, ok := construct.OpName().(*StringExpr)
+	if !ok {
+		// The operator is constructed dynamically, so its fields are not
+		// known.
+		c.checkReplace(construct.OpName(), unknownKind)
+		for _, arg := range construct.Args() {
+			c.checkReplace(arg, unknownKind)
+		}
+		return
+	}
+
+	switch {
+	case name.ValueAsString() == "OpName":
+		// Built-in function that returns the name of a matched operator.
+		if len(construct.Args()) > 1 {
+			c.addErr(construct, "too many arguments to OpName function")
+			return
+		}
+		if len(construct.Args()) == 1 {
+			ref, ok := construct.Args()[0].(*RefExpr)
+			if !ok {
+				c.addErr(construct, "invalid argument to OpName function: %v", construct.Args()[0])
+				return
+			}
+			c.checkRef(ref, unknownKind)
+		}
+
+	case c.defines[name.ValueAsString()] != nil:
+		define := c.defines[name.ValueAsString()]
+		if !c.isCompatible(kind, exprKind) {
+			c.addErr(construct, "cannot construct operator '%s' for %s", define.Name(), kind.describe())
+			return
+		}
+
+		if len(construct.Args()) != len(define.Fields()) {
+			c.addErr(construct, "wrong number of arguments to '%s': expected %d, found %d",
+				define.Name(), len(define.Fields()), len(construct.Args()))
+			return
+		}
+
+		for index, arg := range construct.Args() {
+			c.checkReplace(arg, fieldKindOf(define.Fields()[index].(*DefineFieldExpr)))
+		}
+
+	case c.tags[name.ValueAsString()]:
+		c.addErr(construct, "cannot construct tag '%s'", name.ValueAsString())
+
+	default:
+		c.checkFunc(construct, name.ValueAsString(), len(construct.Args()), false)
+		for _, arg := range construct.Args() {
+			c.checkReplace(arg, unknownKind)
+		}
+	}
+}
+
+// checkRef checks that the label of a reference has been bound, and that the
+// bound expression can be used for a field of the given kind.
+func (c *checker) checkRef(ref *RefExpr, kind fieldKind) {
+	bound, ok := c.bindings[ref.Label()]
+	if !ok {
+		c.addErr(ref, "unbound label '$%s'", ref.Label())
+		return
+	}
+
+	if !c.isCompatible(kind, bound) {
+		c.addErr(ref, "cannot use '$%s' (bound to %s) for %s", ref.Label(), bound.describe(), kind.describe())
+	}
+}
+
+// checkFunc checks that a custom function is known, and that it is called
+// consistently with its previous calls.
+func (c *checker) checkFunc(call Expr, name string, argsLen int, match bool) {
+	if c.tags[name] {
+		c.addErr(call, "cannot call tag '%s' as a function", name)
+		return
+	}
+
+	if match {
+		if _, ok := c.defines[name]; ok {
+			c.addErr(call, "cannot call operator '%s' as a match function", name)
+			return
+		}
+	}
+
+	if c.knownFuncs != nil && !c.knownFuncs[name] {
+		c.addErr(call, "unknown custom function '%s'", name)
+		return
+	}
+
+	use, ok := c.funcs[name]
+	if !ok {
+		c.funcs[name] = funcUse{src: call.Source(), argsLen: argsLen, match: match}
+		return
+	}
+
+	if use.match != match {
+		c.addErr(call, "function '%s' is used as both a match and a replace function (previously used at %s)", name, use.src)
+		return
+	}
+
+	if use.argsLen != argsLen {
+		c.addErr(call, "function '%s' called with %d arguments, but with %d arguments at %s",
+			name, argsLen, use.argsLen, use.src)
+	}
+}
+
+// isCompatible returns true if an expression of one kind can be used for a
+// field of the other kind. Fields of unknown kind are compatible with every
+// kind.
+func (c *checker) isCompatible(kind, other fieldKind) bool {
+	return kind == unknownKind || other == unknownKind || kind == other
+}
+
+// addErr records an error at the location of the given expression. If the
+// expression has no location, then the location of the current rule is used.
+func (c *checker) addErr(e Expr, format string, args ...interface{}) {
+	src := e.Source()
+	if src == nil && c.rule != nil {
+		src = c.rule.Source()
+	}
+
+	msg := fmt.Sprintf(format, args...)
+	if src != nil {
+		msg = fmt.Sprintf("%s: %s", src, msg)
+	}
+	if c.rule != nil {
+		msg = fmt.Sprintf("%s (rule %s)", msg, c.rule.Header().Name())
+	}
+
+	c.errors = append(c.errors, fmt.Errorf("%s", msg))
+}
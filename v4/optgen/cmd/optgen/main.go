@@ -5,9 +5,16 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"go/ast"
 	"go/format"
+	"go/parser"
+	"go/token"
 	"io"
+	"io/ioutil"
 	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/petermattis/opttoy/v4/optgen"
 )
@@ -20,6 +27,11 @@ var (
 var (
 	pkg = flag.String("pkg", "opt", "package name used in generated files")
 	out = flag.String("out", "", "output file name of generated code")
+
+	funcs = flag.String("funcs", "", "comma-separated Go types in the current directory whose methods are the custom\n"+
+		"functions that rules can call (any function can be called if empty)")
+
+	unusedDefines = flag.Bool("unused-defines", false, "lint: warn about defines that are not referenced by any rule")
 )
 
 const useGoFmt = true
@@ -37,8 +49,11 @@ func main() {
 	cmd := args[0]
 	switch cmd {
 	case "compile":
+	case "explorer":
 	case "exprs":
 	case "factory":
+	case "fmt":
+	case "lint":
 	case "ops":
 	case "visitor":
 
@@ -48,7 +63,19 @@ func main() {
 	}
 
 	sources := flag.Args()[1:]
-	readers := make([]io.Reader, len(sources))
+
+	if cmd == "fmt" {
+		// Format each file separately, without compiling, so that files can be
+		// formatted even if they don't compile on their own.
+		for _, name := range sources {
+			if err := formatFile(name); err != nil {
+				exit(err)
+			}
+		}
+		return
+	}
+
+	files := make([]optgen.SourceFile, len(sources))
 	for i, name := range sources {
 		file, err := os.Open(name)
 		if err != nil {
@@ -56,10 +83,18 @@ func main() {
 		}
 
 		defer file.Close()
-		readers[i] = file
+		files[i] = optgen.SourceFile{Name: name, Reader: file}
+	}
+
+	compiler := optgen.NewFileCompiler(files...)
+	if *funcs != "" {
+		names, err := loadFuncs(".", strings.Split(*funcs, ","))
+		if err != nil {
+			exit(err)
+		}
+		compiler.SetFuncs(names)
 	}
 
-	compiler := optgen.NewCompiler(io.MultiReader(readers...))
 	compiled, err := compiler.Compile()
 	if err != nil {
 		exit(err)
@@ -82,14 +117,23 @@ func main() {
 	case "compile":
 		writer.Write([]byte(compiled.String()))
 
+	case "explorer":
+		err = generateExplorer(compiled, writer)
+
 	case "exprs":
 		err = generateExprs(compiled, writer)
 
 	case "factory":
 		err = generateFactory(compiled, writer)
 
+	case "lint":
+		err = lint(compiled, writer)
+
 	case "ops":
 		err = generateOps(compiled, writer)
+
+	case "visitor":
+		err = generateVisitor(compiled, writer)
 	}
 
 	if err != nil {
@@ -113,7 +157,10 @@ func usage() {
 	fmt.Fprintf(os.Stderr, "\texprs      generates expression definitions and functions\n")
 	fmt.Fprintf(os.Stderr, "\texplorer   generates exploration and implementation functions\n")
 	fmt.Fprintf(os.Stderr, "\tfactory    generates expression tree creation and normalization functions\n")
+	fmt.Fprintf(os.Stderr, "\tfmt        formats source files in place\n")
+	fmt.Fprintf(os.Stderr, "\tlint       reports likely mistakes in source files\n")
 	fmt.Fprintf(os.Stderr, "\tops        generates operator definitions and functions\n")
+	fmt.Fprintf(os.Stderr, "\tvisitor    generates expression visitor and rewriter interfaces\n")
 	fmt.Fprintf(os.Stderr, "\n")
 
 	fmt.Fprintf(os.Stderr, "Flags:\n")
@@ -124,10 +171,99 @@ func usage() {
 }
 
 func exit(err error) {
-	fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+	if errs, ok := err.(optgen.CompileErrors); ok {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+	}
 	os.Exit(2)
 }
 
+// loadFuncs returns the names of the custom functions implemented by the
+// methods of the given types, which are declared in the Go package in dir.
+// Rules call a method with its name in title case, so the method isCorrelated
+// is called as IsCorrelated.
+func loadFuncs(dir string, types []string) ([]string, error) {
+	recvTypes := make(map[string]bool)
+	for _, typ := range types {
+		recvTypes[strings.TrimSpace(typ)] = true
+	}
+
+	notTest := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, notTest, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Recv == nil || len(fn.Recv.List) != 1 {
+					continue
+				}
+
+				recv := fn.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if ident, ok := recv.(*ast.Ident); ok && recvTypes[ident.Name] {
+					r, n := utf8.DecodeRuneInString(fn.Name.Name)
+					names = append(names, string(unicode.ToUpper(r))+fn.Name.Name[n:])
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+// formatFile rewrites the named source file in the canonical format. The file
+// is only written if it parses without error.
+func formatFile(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+
+	parser := optgen.NewFileParser(optgen.SourceFile{Name: name, Reader: file})
+	root, err := parser.Parse()
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	var formatter optgen.Formatter
+	formatter.Format(root, &buf)
+	return ioutil.WriteFile(name, buf.Bytes(), 0644)
+}
+
+// lint writes any warnings about the compiled sources to the writer. It
+// returns an error if there are any warnings, so that the command exits with
+// a non-zero status.
+func lint(compiled optgen.CompiledExpr, w io.Writer) error {
+	linter := optgen.Linter{UnusedDefines: *unusedDefines}
+	warnings := linter.Lint(compiled)
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "%s\n", warning)
+	}
+
+	if len(warnings) != 0 {
+		return fmt.Errorf("%d lint warnings", len(warnings))
+	}
+	return nil
+}
+
+func generateExplorer(compiled optgen.CompiledExpr, w io.Writer) error {
+	var gen optgen.ExplorerGen
+	return generate(compiled, w, gen.Generate)
+}
+
 func generateExprs(compiled optgen.CompiledExpr, w io.Writer) error {
 	var gen optgen.ExprsGen
 	return generate(compiled, w, gen.Generate)
@@ -143,6 +279,11 @@ func generateOps(compiled optgen.CompiledExpr, w io.Writer) error {
 	return generate(compiled, w, gen.Generate)
 }
 
+func generateVisitor(compiled optgen.CompiledExpr, w io.Writer) error {
+	var gen optgen.VisitorGen
+	return generate(compiled, w, gen.Generate)
+}
+
 func generate(compiled optgen.CompiledExpr, w io.Writer, genFunc func(compiled optgen.CompiledExpr, w io.Writer)) error {
 	var buf bytes.Buffer
 
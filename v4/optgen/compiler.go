@@ -7,6 +7,8 @@ import (
 )
 
 type CompiledExpr interface {
+	PrivateTypes() []*PrivateTypeExpr
+	LookupPrivateType(name string) *PrivateTypeExpr
 	Defines() []*DefineExpr
 	Rules() []*RuleExpr
 	DefineTags() []string
@@ -15,11 +17,21 @@ type CompiledExpr interface {
 }
 
 type compiledExpr struct {
-	root    *RootExpr
-	defines []*DefineExpr
-	rules   []*RuleExpr
-	defTags []string
-	opIndex map[string]*DefineExpr
+	root     *RootExpr
+	privates []*PrivateTypeExpr
+	privIdx  map[string]*PrivateTypeExpr
+	defines  []*DefineExpr
+	rules    []*RuleExpr
+	defTags  []string
+	opIndex  map[string]*DefineExpr
+}
+
+func (c *compiledExpr) PrivateTypes() []*PrivateTypeExpr {
+	return c.privates
+}
+
+func (c *compiledExpr) LookupPrivateType(name string) *PrivateTypeExpr {
+	return c.privIdx[name]
 }
 
 func (c *compiledExpr) Defines() []*DefineExpr {
@@ -67,15 +79,50 @@ func (c *compiledExpr) String() string {
 	return buf.String()
 }
 
+// CompileErrors is the list of semantic errors found in the optgen source.
+type CompileErrors []error
+
+func (e CompileErrors) Error() string {
+	var buf bytes.Buffer
+	for i, err := range e {
+		if i != 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(err.Error())
+	}
+	return buf.String()
+}
+
 type Compiler struct {
 	parser   *Parser
 	compiled *compiledExpr
+	funcs    map[string]bool
 	err      error
 }
 
+// NewCompiler creates a compiler for the given unnamed source.
 func NewCompiler(r io.Reader) *Compiler {
-	compiled := &compiledExpr{opIndex: make(map[string]*DefineExpr)}
-	return &Compiler{parser: NewParser(r), compiled: compiled}
+	return NewFileCompiler(SourceFile{Reader: r})
+}
+
+// NewFileCompiler creates a compiler for the given files, which are compiled
+// together, in order.
+func NewFileCompiler(files ...SourceFile) *Compiler {
+	compiled := &compiledExpr{
+		privIdx: make(map[string]*PrivateTypeExpr),
+		opIndex: make(map[string]*DefineExpr),
+	}
+	return &Compiler{parser: NewFileParser(files...), compiled: compiled}
+}
+
+// SetFuncs sets the names of the custom functions that rules can call, which
+// are implemented in Go. Compile reports calls to any other function as
+// errors. If SetFuncs isn't called, then rules can call any function.
+func (c *Compiler) SetFuncs(names []string) {
+	c.funcs = make(map[string]bool, len(names))
+	for _, name := range names {
+		c.funcs[name] = true
+	}
 }
 
 func (c *Compiler) Compile() (CompiledExpr, error) {
@@ -84,6 +131,12 @@ func (c *Compiler) Compile() (CompiledExpr, error) {
 		return nil, c.err
 	}
 
+	checker := checker{knownFuncs: c.funcs}
+	if errs := checker.check(c.compiled.root); len(errs) != 0 {
+		c.err = CompileErrors(errs)
+		return nil, c.err
+	}
+
 	if !c.compileDefines() {
 		return c.compiled, c.err
 	}
@@ -96,6 +149,12 @@ func (c *Compiler) Compile() (CompiledExpr, error) {
 }
 
 func (c *Compiler) compileDefines() bool {
+	for _, elem := range c.compiled.root.PrivateTypes().All() {
+		private := elem.(*PrivateTypeExpr)
+		c.compiled.privIdx[private.Name()] = private
+		c.compiled.privates = append(c.compiled.privates, private)
+	}
+
 	tags := make(map[string]bool)
 
 	for _, elem := range c.compiled.root.Defines().All() {
@@ -110,43 +169,9 @@ func (c *Compiler) compileDefines() bool {
 			}
 		}
 
-		// Record the define in the index for fast lookup.
+		// Record the define in the index for fast lookup. The checker has
+		// already verified that its fields are well-formed.
 		c.compiled.opIndex[define.Name()] = define
-
-		// Ensure that fields are defined in the following order:
-		//   Expr*
-		//   ExprList?
-		//   Private?
-		//
-		// That is, there can be zero or more expression-typed fields, followed
-		// by zero or one list-typed field, followed by zero or one private field.
-		for i, elem2 := range define.Fields() {
-			field := elem2.(*DefineFieldExpr)
-			if field.IsPrivateType() {
-				if i != len(define.Fields())-1 {
-					format := "private field '%s' is not the last field in '%s'"
-					c.err = fmt.Errorf(format, field.Name(), define.Name())
-					return false
-				}
-			}
-		}
-
-		for i, elem2 := range define.Fields() {
-			field := elem2.(*DefineFieldExpr)
-			if field.IsListType() {
-				index := len(define.Fields()) - 1
-				if define.PrivateField() != nil {
-					index--
-				}
-
-				if i != index {
-					format := "list field '%s' is not the last non-private field in '%s'"
-					c.err = fmt.Errorf(format, field.Name(), define.Name())
-					return false
-				}
-			}
-		}
-
 		c.compiled.defines = append(c.compiled.defines, define)
 	}
 
@@ -155,9 +180,12 @@ func (c *Compiler) compileDefines() bool {
 
 func (c *Compiler) compileRules() bool {
 	for _, elem := range c.compiled.root.Rules().All() {
+		rule := elem.(*RuleExpr)
+
 		var ruleCompiler ruleCompiler
-		c.err = ruleCompiler.compile(c.compiled, elem.(*RuleExpr))
+		c.err = ruleCompiler.compile(c.compiled, rule)
 		if c.err != nil {
+			c.err = fmt.Errorf("%s: %v (rule %s)", rule.Source(), c.err, rule.Header().Name())
 			return false
 		}
 	}
@@ -229,6 +257,8 @@ func (c *ruleCompiler) expandRule(opName *OpNameExpr) bool {
 	}
 
 	newRule := NewRuleExpr(c.rule.Header(), match, replace)
+	newRule.src = c.rule.src
+	newRule.comments = c.rule.comments
 	c.compiled.rules = append(c.compiled.rules, newRule)
 
 	return c.err == nil
@@ -83,6 +83,189 @@ func TestCompilerOpNameArg(t *testing.T) {
 		`)
 }
 
+func TestCompilerDefineErrors(t *testing.T) {
+	testCompilerErrors(t,
+		`
+		define Lt {
+			Left  Expr
+			Left  Expr
+		}
+
+		define Lt {
+			Left  Expr
+		}
+
+		private ColIndexes "*ColSet"
+
+		define Projections {
+			Cols  ColIndexes
+			Items ExprList
+		}
+
+		define Values {
+			Rows  ExprList
+			Input Expr
+		}
+
+		define Const {
+			Value Unknown
+		}
+		`,
+		`
+		4:4: duplicate field 'Left' in 'Lt'
+		7:3: duplicate define 'Lt'
+		14:4: private field 'Cols' is not the last field in 'Projections'
+		19:4: list field 'Rows' is not the last non-private field in 'Values'
+		24:4: unknown private type 'Unknown' for field 'Value' in 'Const'
+		`)
+}
+
+func TestCompilerPrivateTypeErrors(t *testing.T) {
+	testCompilerErrors(t,
+		`
+		private ColIndex "ColumnIndex"
+		private ColIndex "int"
+		private Datum "github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+		private ColMap "**Col Map"
+		`,
+		`
+		3:3: duplicate private type 'ColIndex' (previously declared at 2:3)
+		4:3: invalid Go type "github.com/cockroachdb/cockroach/pkg/sql/sem/tree" for private type 'Datum'
+		5:3: invalid Go type "**Col Map" for private type 'ColMap'
+		`)
+}
+
+func TestCompilerMatchErrors(t *testing.T) {
+	testCompilerErrors(t,
+		`
+		[Scalar]
+		define Lt {
+			Left  Expr
+			Right Expr
+		}
+
+		private ColIndex "ColumnIndex"
+
+		define Variable {
+			Col ColIndex
+		}
+
+		[Test]
+		(Lt $left:(Foo) $left:* & (IsBound $right))
+		=>
+		$left
+
+		[Test]
+		(Lt $left:"foo" $right:[ ... * ... ])
+		=>
+		$left
+
+		[Other]
+		(Variable $col:(Lt) *)
+		=>
+		(Variable $col)
+		`,
+		`
+		15:14: unrecognized match name 'Foo' (rule Test)
+		15:19: duplicate binding of label '$left' (rule Test)
+		15:38: unbound label '$right' (rule Test)
+		19:3: duplicate rule name (previously declared at 14:3) (rule Test)
+		20:13: cannot match a string against an Expr field (rule Test)
+		20:26: cannot match a list against an Expr field (rule Test)
+		25:3: too many fields in match of 'Variable': expected at most 1, found 2 (rule Other)
+		`)
+}
+
+func TestCompilerReplaceErrors(t *testing.T) {
+	testCompilerErrors(t,
+		`
+		[Scalar]
+		define Lt {
+			Left  Expr
+			Right Expr
+		}
+
+		private ColIndex "ColumnIndex"
+
+		[Scalar]
+		define Variable {
+			Col ColIndex
+		}
+
+		[Test]
+		(Lt $left:(Variable $col:*) $right:*)
+		=>
+		(Lt $left)
+
+		[Other]
+		(Lt $left:(Variable $col:*) $right:*)
+		=>
+		(Lt $col (Scalar $right))
+
+		[Another]
+		(Lt $left:* $right:* & (IsLower $left $right))
+		=>
+		(Lt (IsLower $right) [ $left ])
+		`,
+		`
+		18:3: wrong number of arguments to 'Lt': expected 2, found 1 (rule Test)
+		23:7: cannot use '$col' (bound to a private field) for an Expr field (rule Other)
+		23:12: cannot construct tag 'Scalar' (rule Other)
+		28:7: function 'IsLower' is used as both a match and a replace function (previously used at 26:26) (rule Another)
+		28:24: cannot construct a list for an Expr field (rule Another)
+		`)
+}
+
+func TestCompilerRuleTagErrors(t *testing.T) {
+	testCompilerErrors(t,
+		`
+		define Not {
+			Input Expr
+		}
+
+		[Test, Normalise]
+		(Not (Not $input:*))
+		=>
+		$input
+
+		[Other, Explore, Scalar]
+		(Not (Not $input:*))
+		=>
+		$input
+		`,
+		`
+		6:3: unrecognized rule tag 'Normalise' (rule Test)
+		11:3: unrecognized rule tag 'Scalar' (rule Other)
+		`)
+}
+
+func TestCompilerFuncErrors(t *testing.T) {
+	testCompilerFuncErrors(t,
+		[]string{"IsCorrelated"},
+		`
+		define Exists {
+			Input Expr
+		}
+
+		define True {
+		}
+
+		[Test, Normalize]
+		(Exists $input:* & (IsCorelated $input))
+		=>
+		(True)
+
+		[Other, Normalize]
+		(Exists $input:* & (IsCorrelated $input))
+		=>
+		(MakeTrue $input)
+		`,
+		`
+		10:22: unknown custom function 'IsCorelated' (rule Test)
+		17:3: unknown custom function 'MakeTrue' (rule Other)
+		`)
+}
+
 func testCompiler(t *testing.T, in, expected string) {
 	r := strings.NewReader(in)
 	c := NewCompiler(r)
@@ -99,3 +282,29 @@ func testCompiler(t *testing.T, in, expected string) {
 		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, compiled.String())
 	}
 }
+
+func testCompilerErrors(t *testing.T, in, expected string) {
+	testCompilerFuncErrors(t, nil, in, expected)
+}
+
+// testCompilerFuncErrors compiles the input with the given custom functions,
+// or with any custom functions if funcs is nil, and checks the errors.
+func testCompilerFuncErrors(t *testing.T, funcs []string, in, expected string) {
+	r := strings.NewReader(in)
+	c := NewCompiler(r)
+	if funcs != nil {
+		c.SetFuncs(funcs)
+	}
+	_, err := c.Compile()
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+
+	if testing.Verbose() {
+		fmt.Printf("%s\n=>\n\n%s\n", in, err)
+	}
+
+	if strings.TrimSpace(err.Error()) != strings.TrimSpace(strings.Replace(expected, "\t", "", -1)) {
+		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, err)
+	}
+}
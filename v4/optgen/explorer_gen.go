@@ -0,0 +1,526 @@
+package optgen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// ExplorerGen generates the exploration functions of the optimizer from the
+// rules that are tagged as Explore or Implement. Unlike normalization rules,
+// which replace an expression with its normalized form, exploration rules add
+// denormalized expressions to the memo group of the matched expression. The
+// generated code consists of an exploreExpr function that dispatches to an
+// explore<Op> function for each operator that is matched by at least one
+// rule.
+//
+// Relational child patterns are matched against every expression in the child
+// group, rather than only against its normalized expression, since that is
+// how alternatives found by exploring the child can combine with the parent.
+// Scalar child patterns are matched against the normalized expression, as in
+// the factory, since scalar groups are not explored.
+type ExplorerGen struct {
+	xformGen
+}
+
+func (g *ExplorerGen) Generate(compiled CompiledExpr, w io.Writer) {
+	g.init(compiled, w, "Explore", "Implement")
+
+	g.genDispatch()
+
+	for _, define := range g.defines {
+		if len(define.rules) == 0 {
+			continue
+		}
+
+		g.w.nest("func (_e *explorer) explore%s(_rootGroup GroupID, _root *%s, pass optimizePass, partlyExplored bool) (fullyExplored bool) {\n", define.name, define.exprType)
+		g.w.writeIndent("fullyExplored = true\n\n")
+
+		for _, rule := range define.rules {
+			g.genRule(rule)
+		}
+
+		g.w.writeIndent("return fullyExplored\n")
+		g.w.unnest(1, "}\n\n")
+	}
+}
+
+// genDispatch generates the exploreExpr function, which calls the explore
+// function of the operator of the given expression.
+func (g *ExplorerGen) genDispatch() {
+	g.w.nest("func (_e *explorer) exploreExpr(_loc memoLoc, pass optimizePass, partlyExplored bool) (fullyExplored bool) {\n")
+	g.w.writeIndent("// Copy the expression, since exploration can add expressions to its\n")
+	g.w.writeIndent("// group, which may reallocate the group's expressions.\n")
+	g.w.writeIndent("_base := *_e.mem.lookupExpr(_loc)\n\n")
+
+	g.w.nest("switch _base.op {\n")
+	for _, define := range g.defines {
+		if len(define.rules) == 0 {
+			continue
+		}
+
+		g.w.writeIndent("case %s:\n", define.opType)
+		g.w.writeIndent("  return _e.explore%s(_loc.group, _base.as%s(), pass, partlyExplored)\n\n", define.name, define.name)
+	}
+	g.w.unnest(1, "}\n\n")
+
+	g.w.writeIndent("// No exploration rules match this operator.\n")
+	g.w.writeIndent("return true\n")
+	g.w.unnest(1, "}\n\n")
+}
+
+// genRule generates the code that matches a single rule and memoizes its
+// replacement expression. The partlyExplored flag indicates that the root
+// expression was already matched against the rule during a previous pass. If
+// the rule matches a single relational child pattern, then only the child
+// expressions that have been added since then need to be matched. If it does
+// not match any relational child patterns, then the rule does not need to be
+// matched again at all. Otherwise, all combinations of child expressions are
+// matched again, and the memo discards the duplicate expressions.
+func (g *ExplorerGen) genRule(rule *xformRule) {
+	g.resetUnique()
+	g.genRuleComment(rule)
+
+	var partly string
+	total, direct := g.countRelationalPatterns(rule.match)
+	switch {
+	case total == 0:
+		g.w.nest("if !partlyExplored {\n")
+
+	case total == 1 && direct == 1:
+		g.w.nest("{\n")
+		partly = "partlyExplored"
+
+	default:
+		g.w.nest("{\n")
+		partly = "false"
+	}
+
+	for index, matchField := range rule.match.Fields() {
+		fieldName := g.lookupFieldName(rule.define.name, index)
+		g.genMatch(matchField, fmt.Sprintf("_root.%s()", fieldName), partly, false)
+	}
+
+	g.genMemoize(rule)
+
+	g.w.unnest(g.w.nesting-1, "}\n")
+	g.w.writeIndent("\n")
+}
+
+// countRelationalPatterns returns the total number of relational operator
+// patterns nested within the given root match expression, as well as the
+// number of those that directly match one of its fields.
+func (g *ExplorerGen) countRelationalPatterns(root *MatchFieldsExpr) (total, direct int) {
+	var count func(match Expr, depth int)
+	count = func(match Expr, depth int) {
+		if matchFields, ok := match.(*MatchFieldsExpr); ok {
+			if g.isRelational(matchFields) {
+				total++
+				if depth == 1 {
+					direct++
+				}
+			}
+			depth++
+		}
+
+		for _, child := range match.Children() {
+			count(child, depth)
+		}
+	}
+
+	for _, field := range root.Fields() {
+		count(field, 1)
+	}
+	return total, direct
+}
+
+// isRelational returns true if the given match expression matches relational
+// operators.
+func (g *ExplorerGen) isRelational(matchFields *MatchFieldsExpr) bool {
+	if opName, ok := matchFields.Names().(*OpNameExpr); ok {
+		return g.compiled.LookupDefine(opName.ValueAsName()).HasTag("Relational")
+	}
+
+	for _, elem := range matchFields.Names().(*MatchNamesExpr).All() {
+		name := elem.(*StringExpr).ValueAsString()
+		if define := g.compiled.LookupDefine(name); define != nil {
+			if define.HasTag("Relational") {
+				return true
+			}
+			continue
+		}
+
+		// Name is a tag name, so check the defines with that tag.
+		for _, define := range g.compiled.Defines() {
+			if define.HasTag(name) && define.HasTag("Relational") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (g *ExplorerGen) genMatch(match Expr, contextName string, partly string, negate bool) {
+	if matchFields, ok := match.(*MatchFieldsExpr); ok {
+		if g.isRelational(matchFields) {
+			g.genRelationalMatchField(matchFields, contextName, partly, negate)
+		} else {
+			g.genScalarMatchField(matchFields, contextName, partly, negate)
+		}
+		return
+	}
+
+	if matchInvoke, ok := match.(*MatchInvokeExpr); ok {
+		g.genMatchInvoke(matchInvoke, negate)
+		return
+	}
+
+	if matchAnd, ok := match.(*MatchAndExpr); ok {
+		if negate {
+			panic("negate is not yet supported by the and match op")
+		}
+
+		g.genMatch(matchAnd.Left(), contextName, partly, negate)
+		g.genMatch(matchAnd.Right(), contextName, partly, negate)
+		return
+	}
+
+	if not, ok := match.(*MatchNotExpr); ok {
+		g.genMatch(not.Input(), contextName, partly, !negate)
+		return
+	}
+
+	if bind, ok := match.(*BindExpr); ok {
+		g.w.writeIndent("%s := %s\n", bind.Label(), contextName)
+		g.genMatch(bind.Target(), bind.Label(), partly, negate)
+		return
+	}
+
+	if str, ok := match.(*StringExpr); ok {
+		if negate {
+			g.w.nest("if %s != _e.mem.internPrivate(\"%s\") {\n", contextName, str.Value())
+		} else {
+			g.w.nest("if %s == _e.mem.internPrivate(\"%s\") {\n", contextName, str.Value())
+		}
+		return
+	}
+
+	if _, ok := match.(*MatchAnyExpr); ok {
+		if negate {
+			g.w.nest("if false {\n")
+		}
+		return
+	}
+
+	if matchList, ok := match.(*MatchListExpr); ok {
+		g.w.nest("for _, _item := range _e.mem.lookupList(%s) {\n", contextName)
+		g.genMatch(matchList.MatchItem(), "_item", partly, negate)
+		return
+	}
+
+	panic(fmt.Sprintf("unrecognized match expression: %v", match))
+}
+
+// genRelationalMatchField generates code that explores the child group, and
+// then matches each of its expressions against the given pattern.
+func (g *ExplorerGen) genRelationalMatchField(matchFields *MatchFieldsExpr, contextName string, partly string, negate bool) {
+	opName, ok := matchFields.Names().(*OpNameExpr)
+	if !ok {
+		panic(fmt.Sprintf("explorer does not yet support matching multiple relational operators: %v", matchFields))
+	}
+	if negate {
+		panic(fmt.Sprintf("explorer does not yet support negated relational patterns: %v", matchFields))
+	}
+
+	name := opName.ValueAsName()
+	groupName := g.makeUnique("_group")
+	exprName := g.makeUnique("_expr")
+	varName := g.makeUnique(fmt.Sprintf("_%s", unTitle(name)))
+
+	g.w.writeIndent("%s := _e.mem.lookupGroup(%s)\n", groupName, contextName)
+	g.w.nest("if !_e.exploreGroup(%s, pass) {\n", groupName)
+	g.w.writeIndent("fullyExplored = false\n")
+	g.w.unnest(1, "}\n\n")
+
+	g.w.nest("for _, %s := range _e.lookupExploreExprs(%s, %s) {\n", exprName, groupName, partly)
+	g.w.writeIndent("%s := %s.as%s()\n", varName, exprName, name)
+	g.w.nest("if %s != nil {\n", varName)
+
+	for index, matchField := range matchFields.Fields() {
+		fieldName := g.lookupFieldName(name, index)
+		g.genMatch(matchField, fmt.Sprintf("%s.%s()", varName, fieldName), partly, false)
+	}
+}
+
+// genScalarMatchField generates code that matches the normalized expression
+// of the child group against the given pattern.
+func (g *ExplorerGen) genScalarMatchField(matchFields *MatchFieldsExpr, contextName string, partly string, negate bool) {
+	numFields := len(matchFields.Fields())
+
+	if negate && numFields != 0 {
+		g.w.writeIndent("_match := false\n")
+	}
+
+	// Save current nesting level, so that negation case can close the right
+	// number of levels.
+	nesting := g.w.nesting
+
+	if opName, ok := matchFields.Names().(*OpNameExpr); ok {
+		name := opName.ValueAsName()
+		varName := g.makeUnique(fmt.Sprintf("_%s", unTitle(name)))
+
+		g.w.writeIndent("%s := _e.mem.lookupNormExpr(%s).as%s()\n", varName, contextName, name)
+
+		if negate && numFields == 0 {
+			g.w.nest("if %s == nil {\n", varName)
+		} else {
+			g.w.nest("if %s != nil {\n", varName)
+		}
+
+		for index, matchField := range matchFields.Fields() {
+			fieldName := g.lookupFieldName(name, index)
+			g.genMatch(matchField, fmt.Sprintf("%s.%s()", varName, fieldName), partly, false)
+		}
+	} else {
+		normName := g.makeUnique("_norm")
+		g.w.writeIndent("%s := _e.mem.lookupNormExpr(%s)\n", normName, contextName)
+
+		var buf bytes.Buffer
+		for i, elem := range matchFields.Names().(*MatchNamesExpr).All() {
+			if i != 0 {
+				buf.WriteString(" || ")
+			}
+
+			name := elem.(*StringExpr).ValueAsString()
+			if g.compiled.LookupDefine(name) != nil {
+				// Match operator name.
+				fmt.Fprintf(&buf, "%s.op == %sOp", normName, name)
+			} else {
+				// Match tag name.
+				fmt.Fprintf(&buf, "is%sLookup[%s.op]", name, normName)
+			}
+		}
+
+		if negate && numFields == 0 {
+			g.w.nest("if !(%s) {\n", buf.String())
+		} else {
+			g.w.nest("if %s {\n", buf.String())
+		}
+
+		if numFields > 0 {
+			// Construct an Expr to use for matching children.
+			exprName := g.makeUnique("_e")
+			g.w.writeIndent("%s := makeExpr(_e.mem, %s, defaultPhysPropsID)\n", exprName, contextName)
+
+			for index, matchField := range matchFields.Fields() {
+				g.genMatch(matchField, fmt.Sprintf("%s.ChildGroup(%d)", exprName, index), partly, false)
+			}
+		}
+	}
+
+	if negate && numFields != 0 {
+		g.w.writeIndent("_match = true\n")
+		g.w.unnest(g.w.nesting-nesting, "}\n")
+		g.w.writeIndent("\n")
+		g.w.nest("if !_match {\n")
+	}
+}
+
+func (g *ExplorerGen) genMatchInvoke(matchInvoke *MatchInvokeExpr, negate bool) {
+	funcName := unTitle(matchInvoke.FuncName())
+
+	if negate {
+		g.w.nest("if !_e.%s(", funcName)
+	} else {
+		g.w.nest("if _e.%s(", funcName)
+	}
+
+	for index, matchArg := range matchInvoke.Args() {
+		ref := matchArg.(*RefExpr)
+
+		if index != 0 {
+			g.w.write(", ")
+		}
+
+		g.w.write(ref.Label())
+	}
+
+	g.w.write(") {\n")
+}
+
+// genMemoize generates code that constructs the root of the replacement
+// expression as a denormalized expression, and adds it to the root group. The
+// children of the replacement are constructed (and normalized) by the
+// factory as usual. A replacement that would contain the root group as one of
+// its children is not added, since a group cannot contain an expression that
+// refers to itself.
+func (g *ExplorerGen) genMemoize(rule *xformRule) {
+	construct, ok := rule.replace.(*ConstructExpr)
+	if !ok {
+		panic(fmt.Sprintf("%s: explore rule must construct a new expression", rule.name))
+	}
+
+	var name string
+	switch t := construct.OpName().(type) {
+	case *OpNameExpr:
+		name = t.ValueAsName()
+
+	case *StringExpr:
+		name = t.ValueAsString()
+	}
+
+	if name == "" || g.compiled.LookupDefine(name) == nil {
+		panic(fmt.Sprintf("%s: explore rule must construct an operator: %v", rule.name, construct.OpName()))
+	}
+
+	// Construct the relational children first, since those could be the root
+	// group, in which case the remaining children are not constructed.
+	args := make([]string, len(construct.Args()))
+	var checks []string
+	for index, arg := range construct.Args() {
+		if g.constructsRelational(arg) {
+			args[index] = g.genMemoizeArg(name, index, arg)
+			checks = append(checks, fmt.Sprintf("%s != _rootGroup", args[index]))
+		}
+	}
+
+	if len(checks) > 0 {
+		g.w.write("\n")
+		g.w.nest("if ")
+		for i, check := range checks {
+			if i != 0 {
+				g.w.write(" && ")
+			}
+			g.w.write("%s", check)
+		}
+		g.w.write(" {\n")
+	}
+
+	for index, arg := range construct.Args() {
+		if args[index] == "" {
+			args[index] = g.genMemoizeArg(name, index, arg)
+		}
+	}
+
+	varName := g.makeUnique(fmt.Sprintf("_%sExpr", unTitle(name)))
+	g.w.writeIndent("%s := make%sExpr(", varName, name)
+	for index, arg := range args {
+		if index != 0 {
+			g.w.write(", ")
+		}
+		g.w.write("%s", arg)
+	}
+	g.w.write(")\n")
+	g.w.writeIndent("_e.mem.memoizeDenormExpr(_rootGroup, (*memoExpr)(&%s))\n", varName)
+}
+
+// genMemoizeArg generates code that constructs the child of the replacement
+// expression at the given index, and returns the name of the variable that
+// holds it. References to matched expressions are used directly.
+func (g *ExplorerGen) genMemoizeArg(name string, index int, arg Expr) string {
+	if ref, ok := arg.(*RefExpr); ok {
+		return ref.Label()
+	}
+
+	field := g.lookupFieldDef(name, index)
+	varName := g.makeUnique(fmt.Sprintf("_%s", unTitle(field.Name())))
+	g.w.writeIndent("%s := ", varName)
+	g.genReplace(arg)
+	g.w.write("\n")
+	return varName
+}
+
+// constructsRelational returns true if the given replace expression constructs
+// a relational operator.
+func (g *ExplorerGen) constructsRelational(replace Expr) bool {
+	construct, ok := replace.(*ConstructExpr)
+	if !ok {
+		return false
+	}
+
+	var name string
+	switch t := construct.OpName().(type) {
+	case *OpNameExpr:
+		name = t.ValueAsName()
+
+	case *StringExpr:
+		name = t.ValueAsString()
+	}
+
+	define := g.compiled.LookupDefine(name)
+	return define != nil && define.HasTag("Relational")
+}
+
+func (g *ExplorerGen) genReplace(replace Expr) {
+	if construct, ok := replace.(*ConstructExpr); ok {
+		if strName, ok := construct.OpName().(*StringExpr); ok {
+			name := strName.ValueAsString()
+			define := g.compiled.LookupDefine(name)
+			if define != nil {
+				// Standard op construction function.
+				g.w.write("_e.factory.%s(", constructFuncName(g.lookupDefine(name)))
+			} else {
+				// Custom function.
+				g.w.write("_e.%s(", unTitle(name))
+			}
+		}
+
+		if opName, ok := construct.OpName().(*OpNameExpr); ok {
+			g.w.write("_e.factory.%s(", constructFuncName(g.lookupDefine(opName.ValueAsName())))
+		}
+
+		if opNameConstruct, ok := construct.OpName().(*ConstructExpr); ok {
+			// Must be the OpName function.
+			ref := opNameConstruct.Args()[0].(*RefExpr)
+			g.w.write("_e.factory.DynamicConstruct(_e.mem.lookupNormExpr(%s).op, []GroupID{", ref.Label())
+		}
+
+		for index, elem := range construct.Args() {
+			if index != 0 {
+				g.w.write(", ")
+			}
+
+			g.genReplace(elem)
+		}
+
+		if construct.OpName().Op() == ConstructOp {
+			g.w.write("}, 0)")
+		} else {
+			g.w.write(")")
+		}
+
+		return
+	}
+
+	if constructList, ok := replace.(*ConstructListExpr); ok {
+		g.w.write("_e.mem.storeList([]GroupID{")
+
+		for index, elem := range constructList.Children() {
+			if index != 0 {
+				g.w.write(", ")
+			}
+
+			g.genReplace(elem)
+		}
+
+		g.w.write("})")
+		return
+	}
+
+	if ref, ok := replace.(*RefExpr); ok {
+		g.w.write(ref.Label())
+		return
+	}
+
+	if str, ok := replace.(*StringExpr); ok {
+		g.w.write("_e.mem.internPrivate(\"%s\")", str.Value())
+		return
+	}
+
+	if opName, ok := replace.(*OpNameExpr); ok {
+		g.w.write(opName.ValueAsName() + "Op")
+		return
+	}
+
+	panic(fmt.Sprintf("unrecognized replace expression: %v", replace))
+}
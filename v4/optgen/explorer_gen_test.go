@@ -0,0 +1,107 @@
+package optgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExplorerGenRelationalChild(t *testing.T) {
+	testExplorer(t,
+		`
+		[Relational]
+		define InnerJoin {
+			Left  Expr
+			Right Expr
+			On    Expr
+		}
+
+		[Relational]
+		define Select {
+			Input  Expr
+			Filter Expr
+		}
+
+		[Test, Explore]
+		(Select $input:(InnerJoin $left:* $right:* $on:*) $filter:* & (IsBound $filter $left))
+		=>
+		(InnerJoin (Select $left $filter) $right $on)
+		`,
+		`
+		// [Test]
+		{
+			input := _root.input()
+			_group := _e.mem.lookupGroup(input)
+			if !_e.exploreGroup(_group, pass) {
+				fullyExplored = false
+			}
+
+			for _, _expr := range _e.lookupExploreExprs(_group, partlyExplored) {
+				_innerJoin := _expr.asInnerJoin()
+				if _innerJoin != nil {
+					left := _innerJoin.left()
+					right := _innerJoin.right()
+					on := _innerJoin.on()
+					filter := _root.filter()
+					if _e.isBound(filter, left) {
+						_left := _e.factory.ConstructSelect(left, filter)
+
+						if _left != _rootGroup {
+							_innerJoinExpr := makeInnerJoinExpr(_left, right, on)
+							_e.mem.memoizeDenormExpr(_rootGroup, (*memoExpr)(&_innerJoinExpr))
+						}
+					}
+				}
+			}
+		}
+		`)
+}
+
+func TestExplorerGenNoRelationalChild(t *testing.T) {
+	testExplorer(t,
+		`
+		[Relational]
+		define InnerJoin {
+			Left  Expr
+			Right Expr
+			On    Expr
+		}
+
+		[Test, Explore]
+		(InnerJoin $left:* $right:* $on:*)
+		=>
+		(InnerJoin $right $left $on)
+		`,
+		`
+		// [Test]
+		if !partlyExplored {
+			left := _root.left()
+			right := _root.right()
+			on := _root.on()
+			_innerJoinExpr := makeInnerJoinExpr(right, left, on)
+			_e.mem.memoizeDenormExpr(_rootGroup, (*memoExpr)(&_innerJoinExpr))
+		}
+		`)
+}
+
+func testExplorer(t *testing.T, in, expected string) {
+	r := strings.NewReader(in)
+	c := NewCompiler(r)
+	compiled, err := c.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gen ExplorerGen
+	var buf bytes.Buffer
+	gen.Generate(compiled, &buf)
+
+	if testing.Verbose() {
+		fmt.Printf("%s\n=>\n\n%s\n", in, buf.String())
+	}
+
+	if !strings.Contains(removeWhitespace(buf.String()), removeWhitespace(expected)) {
+		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, buf.String())
+	}
+}
@@ -8,6 +8,21 @@ import (
 
 type AcceptFunc func(expr Expr) Expr
 
+// SourceLoc is the location of an expression in the optgen source. File is
+// empty if the source was not read from a named file.
+type SourceLoc struct {
+	File string
+	Line int
+	Pos  int
+}
+
+func (l SourceLoc) String() string {
+	if l.File == "" {
+		return fmt.Sprintf("%d:%d", l.Line, l.Pos)
+	}
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Pos)
+}
+
 type Expr interface {
 	Op() Operator
 	Children() []Expr
@@ -15,6 +30,10 @@ type Expr interface {
 	Value() interface{}
 	Visit(accept AcceptFunc) Expr
 
+	// Source returns the location of the expression in the optgen source, or
+	// nil if it was not parsed from the source.
+	Source() *SourceLoc
+
 	String() string
 	Format(buf *bytes.Buffer, level int)
 }
@@ -24,6 +43,8 @@ type expr struct {
 	children []Expr
 	value    interface{}
 	names    map[int]string
+	src      *SourceLoc
+	comments []string
 }
 
 func (e *expr) Op() Operator {
@@ -42,6 +63,10 @@ func (e *expr) Value() interface{} {
 	return e.value
 }
 
+func (e *expr) Source() *SourceLoc {
+	return e.src
+}
+
 func (e *expr) visitChildren(accept AcceptFunc) (children []Expr, replaced bool) {
 	for i, child := range e.children {
 		newChild := child.Visit(accept)
@@ -146,26 +171,98 @@ type RootExpr struct{ expr }
 
 func NewRootExpr() *RootExpr {
 	children := []Expr{
+		NewPrivateTypeSetExpr(),
 		NewDefineSetExpr(),
 		NewRuleSetExpr(),
 	}
 
-	names := map[int]string{0: "Defines", 1: "Rules"}
+	names := map[int]string{0: "PrivateTypes", 1: "Defines", 2: "Rules"}
 
 	return &RootExpr{expr{op: RootOp, children: children, names: names}}
 }
 
+func (e *RootExpr) PrivateTypes() *PrivateTypeSetExpr {
+	return e.children[0].(*PrivateTypeSetExpr)
+}
+
 func (e *RootExpr) Defines() *DefineSetExpr {
-	return e.children[0].(*DefineSetExpr)
+	return e.children[1].(*DefineSetExpr)
 }
 
 func (e *RootExpr) Rules() *RuleSetExpr {
-	return e.children[1].(*RuleSetExpr)
+	return e.children[2].(*RuleSetExpr)
+}
+
+// Comments returns the lines of any comments that follow the last define or
+// rule in the source. Blank lines that separate comments are returned as empty
+// strings.
+func (e *RootExpr) Comments() []string {
+	return e.comments
 }
 
 func (e *RootExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&RootExpr{expr{op: RootOp, children: children, names: e.names}})
+		return accept(&RootExpr{expr{op: RootOp, children: children, names: e.names, src: e.src, comments: e.comments}})
+	}
+	return accept(e)
+}
+
+type PrivateTypeSetExpr struct{ expr }
+
+func NewPrivateTypeSetExpr() *PrivateTypeSetExpr {
+	return &PrivateTypeSetExpr{expr{op: PrivateTypeSetOp}}
+}
+
+func (e *PrivateTypeSetExpr) All() []Expr {
+	return e.children
+}
+
+func (e *PrivateTypeSetExpr) Add(private *PrivateTypeExpr) {
+	e.children = append(e.children, private)
+}
+
+func (e *PrivateTypeSetExpr) Visit(accept AcceptFunc) Expr {
+	if children, replaced := e.visitChildren(accept); replaced {
+		return accept(&PrivateTypeSetExpr{expr{op: PrivateTypeSetOp, children: children, src: e.src}})
+	}
+	return accept(e)
+}
+
+// PrivateTypeExpr declares a type that can be used for the private field of a
+// define, and the Go type of its values. A Go type that is defined in another
+// package is qualified by the import path of the package, as in
+// "*github.com/org/repo/pkg.Type".
+type PrivateTypeExpr struct{ expr }
+
+func NewPrivateTypeExpr(name, goType string) *PrivateTypeExpr {
+	children := []Expr{
+		NewStringExpr(name),
+		NewStringExpr(goType),
+	}
+
+	names := map[int]string{0: "Name", 1: "GoType"}
+
+	return &PrivateTypeExpr{expr{op: PrivateTypeOp, children: children, names: names}}
+}
+
+func (e *PrivateTypeExpr) Name() string {
+	return e.children[0].(*StringExpr).ValueAsString()
+}
+
+func (e *PrivateTypeExpr) GoType() string {
+	return e.children[1].(*StringExpr).ValueAsString()
+}
+
+// Comments returns the lines of the comments that precede the declaration in
+// the source. Blank lines that separate comments are returned as empty
+// strings.
+func (e *PrivateTypeExpr) Comments() []string {
+	return e.comments
+}
+
+func (e *PrivateTypeExpr) Visit(accept AcceptFunc) Expr {
+	if children, replaced := e.visitChildren(accept); replaced {
+		return accept(&PrivateTypeExpr{expr{op: PrivateTypeOp, children: children, names: e.names, src: e.src, comments: e.comments}})
 	}
 	return accept(e)
 }
@@ -186,7 +283,7 @@ func (e *DefineSetExpr) Add(define *DefineExpr) {
 
 func (e *DefineSetExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&DefineSetExpr{expr{op: DefineSetOp, children: children}})
+		return accept(&DefineSetExpr{expr{op: DefineSetOp, children: children, src: e.src}})
 	}
 	return accept(e)
 }
@@ -212,6 +309,12 @@ func (e *DefineExpr) Tags() *TagsExpr {
 	return e.children[1].(*TagsExpr)
 }
 
+// Comments returns the lines of the comments that precede the define in the
+// source. Blank lines that separate comments are returned as empty strings.
+func (e *DefineExpr) Comments() []string {
+	return e.comments
+}
+
 func (e *DefineExpr) ListField() *DefineFieldExpr {
 	// If list-typed field is present, it will be the last field, or the second
 	// to last field if a private field is present.
@@ -268,7 +371,7 @@ func (e *DefineExpr) HasTag(tag string) bool {
 
 func (e *DefineExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&DefineExpr{expr{op: DefineOp, children: children, names: e.names}})
+		return accept(&DefineExpr{expr{op: DefineOp, children: children, names: e.names, src: e.src, comments: e.comments}})
 	}
 	return accept(e)
 }
@@ -294,8 +397,16 @@ func (e *DefineFieldExpr) Type() string {
 	return e.children[1].(*StringExpr).ValueAsString()
 }
 
+// IsExprType returns true if the field is an expression, either scalar (Expr)
+// or relational (RelExpr).
 func (e *DefineFieldExpr) IsExprType() bool {
-	return e.Type() == "Expr"
+	typ := e.Type()
+	return typ == "Expr" || typ == "RelExpr"
+}
+
+// IsRelExprType returns true if the field is a relational expression.
+func (e *DefineFieldExpr) IsRelExprType() bool {
+	return e.Type() == "RelExpr"
 }
 
 func (e *DefineFieldExpr) IsListType() bool {
@@ -303,13 +414,12 @@ func (e *DefineFieldExpr) IsListType() bool {
 }
 
 func (e *DefineFieldExpr) IsPrivateType() bool {
-	typ := e.Type()
-	return typ != "Expr" && typ != "ExprList"
+	return !e.IsExprType() && !e.IsListType()
 }
 
 func (e *DefineFieldExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&DefineFieldExpr{expr{op: DefineFieldOp, children: children, names: e.names}})
+		return accept(&DefineFieldExpr{expr{op: DefineFieldOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -330,7 +440,7 @@ func (e *RuleSetExpr) Add(rule *RuleExpr) {
 
 func (e *RuleSetExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&RuleSetExpr{expr{op: RuleSetOp, children: children}})
+		return accept(&RuleSetExpr{expr{op: RuleSetOp, children: children, src: e.src}})
 	}
 	return accept(e)
 }
@@ -361,9 +471,15 @@ func (e *RuleExpr) Replace() Expr {
 	return e.children[2]
 }
 
+// Comments returns the lines of the comments that precede the rule in the
+// source. Blank lines that separate comments are returned as empty strings.
+func (e *RuleExpr) Comments() []string {
+	return e.comments
+}
+
 func (e *RuleExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&RuleExpr{expr{op: RuleOp, children: children, names: e.names}})
+		return accept(&RuleExpr{expr{op: RuleOp, children: children, names: e.names, src: e.src, comments: e.comments}})
 	}
 	return accept(e)
 }
@@ -391,7 +507,7 @@ func (e *RuleHeaderExpr) Tags() *TagsExpr {
 
 func (e *RuleHeaderExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&RuleHeaderExpr{expr{op: RuleHeaderOp, children: children, names: e.names}})
+		return accept(&RuleHeaderExpr{expr{op: RuleHeaderOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -419,7 +535,7 @@ func (e *BindExpr) Target() Expr {
 
 func (e *BindExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&BindExpr{expr{op: BindOp, children: children, names: e.names}})
+		return accept(&BindExpr{expr{op: BindOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -442,7 +558,7 @@ func (e *RefExpr) Label() string {
 
 func (e *RefExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&RefExpr{expr{op: RefOp, children: children, names: e.names}})
+		return accept(&RefExpr{expr{op: RefOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -467,7 +583,7 @@ func (e *MatchNamesExpr) Add(name *StringExpr) {
 
 func (e *MatchNamesExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&MatchNamesExpr{expr{op: MatchNamesOp, children: children, names: e.names}})
+		return accept(&MatchNamesExpr{expr{op: MatchNamesOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -498,7 +614,7 @@ func (e *MatchFieldsExpr) Add(match Expr) {
 
 func (e *MatchFieldsExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&MatchFieldsExpr{expr{op: MatchFieldsOp, children: children, names: e.names}})
+		return accept(&MatchFieldsExpr{expr{op: MatchFieldsOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -519,7 +635,7 @@ func (e *MatchAndExpr) Right() Expr {
 
 func (e *MatchAndExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&MatchAndExpr{expr{op: MatchAndOp, children: children}})
+		return accept(&MatchAndExpr{expr{op: MatchAndOp, children: children, src: e.src}})
 	}
 	return accept(e)
 }
@@ -550,7 +666,7 @@ func (e *MatchInvokeExpr) Add(match Expr) {
 
 func (e *MatchInvokeExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&MatchInvokeExpr{expr{op: MatchInvokeOp, children: children, names: e.names}})
+		return accept(&MatchInvokeExpr{expr{op: MatchInvokeOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -567,7 +683,7 @@ func (e *MatchNotExpr) Input() Expr {
 
 func (e *MatchNotExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&MatchNotExpr{expr{op: MatchNotOp, children: children}})
+		return accept(&MatchNotExpr{expr{op: MatchNotOp, children: children, src: e.src}})
 	}
 	return accept(e)
 }
@@ -596,7 +712,7 @@ func (e *MatchListExpr) MatchItem() Expr {
 
 func (e *MatchListExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&MatchListExpr{expr{op: MatchListOp, children: children, names: e.names}})
+		return accept(&MatchListExpr{expr{op: MatchListOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -617,7 +733,7 @@ func (e *ReplaceRootExpr) Add(replace Expr) {
 
 func (e *ReplaceRootExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&ReplaceRootExpr{expr{op: ReplaceRootOp, children: children, names: e.names}})
+		return accept(&ReplaceRootExpr{expr{op: ReplaceRootOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -648,7 +764,7 @@ func (e *ConstructExpr) Add(arg Expr) {
 
 func (e *ConstructExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&ConstructExpr{expr{op: ConstructOp, children: children, names: e.names}})
+		return accept(&ConstructExpr{expr{op: ConstructOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -665,7 +781,7 @@ func (e *ConstructListExpr) Add(item Expr) {
 
 func (e *ConstructListExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&ConstructListExpr{expr{op: ConstructListOp, children: children, names: e.names}})
+		return accept(&ConstructListExpr{expr{op: ConstructListOp, children: children, names: e.names, src: e.src}})
 	}
 	return accept(e)
 }
@@ -699,7 +815,7 @@ func (e *TagsExpr) Contains(tag string) bool {
 
 func (e *TagsExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&TagsExpr{expr{op: TagsOp, children: children}})
+		return accept(&TagsExpr{expr{op: TagsOp, children: children, src: e.src}})
 	}
 	return accept(e)
 }
@@ -716,7 +832,7 @@ func (e *StringExpr) ValueAsString() string {
 
 func (e *StringExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&StringExpr{expr{op: StringOp, children: children}})
+		return accept(&StringExpr{expr{op: StringOp, children: children, src: e.src}})
 	}
 	return accept(e)
 }
@@ -733,7 +849,7 @@ func (e *OpNameExpr) ValueAsName() string {
 
 func (e *OpNameExpr) Visit(accept AcceptFunc) Expr {
 	if children, replaced := e.visitChildren(accept); replaced {
-		return accept(&OpNameExpr{expr{op: OpNameOp, children: children}})
+		return accept(&OpNameExpr{expr{op: OpNameOp, children: children, src: e.src}})
 	}
 	return accept(e)
 }
@@ -3,6 +3,7 @@ package optgen
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
 type ExprsGen struct {
@@ -14,9 +15,13 @@ func (g *ExprsGen) Generate(compiled CompiledExpr, w io.Writer) {
 	g.compiled = compiled
 	g.w = w
 
+	genPrivateImports(g.w, g.compiled)
+
 	g.genChildCountLookup()
 	g.genChildGroupLookup()
 	g.genPrivateFieldLookup()
+	g.genPrivateAccessors()
+	g.genStateLayoutLookup()
 	g.genTagLookup()
 	g.genIsTag()
 
@@ -172,6 +177,85 @@ func (g *ExprsGen) genPrivateFieldLookup() {
 	fmt.Fprintf(g.w, "}\n\n")
 }
 
+// genPrivateAccessors generates a strongly-typed accessor method on Expr for
+// each type of private field, which looks up the private value of the
+// expression. For example:
+//
+//   // ColIndexPrivate returns the private field of a Variable expression.
+//   func (e *Expr) ColIndexPrivate() ColumnIndex {
+//     return e.mem.lookupColIndex(e.privateID())
+//   }
+//
+func (g *ExprsGen) genPrivateAccessors() {
+	var types []string
+	opNames := make(map[string][]string)
+	for _, define := range g.compiled.Defines() {
+		private := define.PrivateField()
+		if private == nil {
+			continue
+		}
+
+		typ := private.Type()
+		if _, ok := opNames[typ]; !ok {
+			types = append(types, typ)
+		}
+		opNames[typ] = append(opNames[typ], define.Name())
+	}
+
+	for _, typ := range types {
+		names := opNames[typ]
+		desc := names[0]
+		if len(names) > 1 {
+			desc = strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+		}
+
+		fmt.Fprintf(g.w, "// %sPrivate returns the private field of a %s expression.\n", typ, desc)
+		fmt.Fprintf(g.w, "func (e *Expr) %sPrivate() %s {\n", typ, privateGoType(g.compiled, typ))
+		fmt.Fprintf(g.w, "  return e.mem.lookup%s(e.privateID())\n", typ)
+		fmt.Fprintf(g.w, "}\n\n")
+	}
+}
+
+// genStateLayoutLookup generates a lookup table that describes how the fields
+// of each operator are stored in the state of its memo expressions. It's used
+// to check the state of expressions that are loaded from outside the memo.
+func (g *ExprsGen) genStateLayoutLookup() {
+	fmt.Fprintf(g.w, "var stateLayoutLookup = []stateLayout{\n")
+	fmt.Fprintf(g.w, "  {}, // UnknownOp\n\n")
+
+	for _, define := range g.compiled.Defines() {
+		var fields []string
+		exprs := len(define.Fields())
+		if private := define.PrivateField(); private != nil {
+			exprs--
+			fields = append(fields, fmt.Sprintf("private: %q", private.Type()))
+		}
+		if define.ListField() != nil {
+			exprs--
+			fields = append([]string{"list: true"}, fields...)
+		}
+		if exprs != 0 {
+			// Only list the kinds of the expression fields if one of them is
+			// relational, since most operators only have scalar children.
+			var relational []string
+			anyRelational := false
+			for i := 0; i < exprs; i++ {
+				isRel := define.Fields()[i].(*DefineFieldExpr).IsRelExprType()
+				relational = append(relational, fmt.Sprint(isRel))
+				anyRelational = anyRelational || isRel
+			}
+			if anyRelational {
+				fields = append([]string{fmt.Sprintf("relational: []bool{%s}", strings.Join(relational, ", "))}, fields...)
+			}
+			fields = append([]string{fmt.Sprintf("exprs: %d", exprs)}, fields...)
+		}
+
+		fmt.Fprintf(g.w, "  {%s}, // %sOp\n", strings.Join(fields, ", "), define.Name())
+	}
+
+	fmt.Fprintf(g.w, "}\n\n")
+}
+
 func (g *ExprsGen) genTagLookup() {
 	// Generate lookup tables that indicate whether an expression is associated
 	// with a particular tag.
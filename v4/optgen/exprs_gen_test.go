@@ -0,0 +1,104 @@
+package optgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExprsGenPrivateAccessors(t *testing.T) {
+	testExprs(t,
+		`
+		private ColIndexes "*ColSet"
+		private Datum "github.com/cockroachdb/cockroach/pkg/sql/sem/tree.Datum"
+
+		define Projections {
+			Items ExprList
+			Cols  ColIndexes
+		}
+
+		define Values {
+			Rows ExprList
+			Cols ColIndexes
+		}
+
+		define Const {
+			Value Datum
+		}
+		`,
+		`
+		// ColIndexesPrivate returns the private field of a Projections or Values expression.
+		func (e *Expr) ColIndexesPrivate() *ColSet {
+			return e.mem.lookupColIndexes(e.privateID())
+		}
+
+		// DatumPrivate returns the private field of a Const expression.
+		func (e *Expr) DatumPrivate() tree.Datum {
+			return e.mem.lookupDatum(e.privateID())
+		}
+		`)
+}
+
+func TestExprsGenStateLayout(t *testing.T) {
+	testExprs(t,
+		`
+		private ColIndex "ColumnIndex"
+		private ColIndexes "*ColSet"
+
+		define Variable {
+			Col ColIndex
+		}
+
+		define Select {
+			Input  RelExpr
+			Filter Expr
+		}
+
+		define Eq {
+			Left  Expr
+			Right Expr
+		}
+
+		define Projections {
+			Items ExprList
+			Cols  ColIndexes
+		}
+
+		define Function {
+			Args ExprList
+		}
+		`,
+		`
+		var stateLayoutLookup = []stateLayout{
+			{}, // UnknownOp
+
+			{private: "ColIndex"}, // VariableOp
+			{exprs: 2, relational: []bool{true, false}}, // SelectOp
+			{exprs: 2}, // EqOp
+			{list: true, private: "ColIndexes"}, // ProjectionsOp
+			{list: true}, // FunctionOp
+		}
+		`)
+}
+
+func testExprs(t *testing.T, in, expected string) {
+	r := strings.NewReader(in)
+	c := NewCompiler(r)
+	compiled, err := c.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gen ExprsGen
+	var buf bytes.Buffer
+	gen.Generate(compiled, &buf)
+
+	if testing.Verbose() {
+		fmt.Printf("%s\n=>\n\n%s\n", in, buf.String())
+	}
+
+	if !strings.Contains(removeWhitespace(buf.String()), removeWhitespace(expected)) {
+		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, buf.String())
+	}
+}
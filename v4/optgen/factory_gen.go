@@ -8,13 +8,23 @@ import (
 
 type FactoryGen struct {
 	xformGen
+
+	// rule is the rule that's currently being generated.
+	rule *xformRule
 }
 
 func (g *FactoryGen) Generate(compiled CompiledExpr, w io.Writer) {
 	g.init(compiled, w, "Normalize")
 
+	genPrivateImports(w, compiled)
+	g.genRuleNames()
+
 	for _, define := range g.defines {
-		g.w.writeIndent("func (_f *Factory) Construct%s(\n", define.name)
+		if define.private != nil {
+			g.genTypedConstruct(define)
+		}
+
+		g.w.writeIndent("func (_f *Factory) %s(\n", constructFuncName(define))
 
 		for _, field := range define.fields {
 			g.w.writeIndent("  %s %s,\n", field.name, field.typ)
@@ -58,10 +68,44 @@ func (g *FactoryGen) Generate(compiled CompiledExpr, w io.Writer) {
 	g.genDynamicConstructLookup()
 }
 
+// genTypedConstruct generates the exported construct method for a define that
+// has a private field. It interns the strongly-typed private value, and then
+// calls the unexported construct method, which takes the interned PrivateID.
+// Rules use the unexported method, since they only deal with PrivateIDs.
+func (g *FactoryGen) genTypedConstruct(define *xformDefine) {
+	g.w.writeIndent("func (_f *Factory) Construct%s(\n", define.name)
+
+	for _, field := range define.fields {
+		if field.isPrivate() {
+			g.w.writeIndent("  %s %s,\n", field.name, privateGoType(g.compiled, field.privateType))
+		} else {
+			g.w.writeIndent("  %s %s,\n", field.name, field.typ)
+		}
+	}
+
+	g.w.nest(") GroupID {\n")
+	g.w.writeIndent("return _f.construct%s(", define.name)
+
+	for i, field := range define.fields {
+		if i != 0 {
+			g.w.write(", ")
+		}
+		if field.isPrivate() {
+			g.w.write("_f.mem.intern%s(%s)", field.privateType, field.name)
+		} else {
+			g.w.write("%s", field.name)
+		}
+	}
+
+	g.w.write(")\n")
+	g.w.unnest(1, "}\n\n")
+}
+
 func (g *FactoryGen) genRule(rule *xformRule) {
 	g.resetUnique()
-	g.w.writeIndent("// [%s]\n", rule.name)
-	g.w.nest("{\n")
+	g.rule = rule
+	g.genRuleComment(rule)
+	g.w.nest("if _f.allowRule(%s) {\n", rule.name)
 
 	for index, matchField := range rule.match.Fields() {
 		fieldName := g.lookupFieldName(rule.match.Names().(*OpNameExpr).ValueAsName(), index)
@@ -73,12 +117,46 @@ func (g *FactoryGen) genRule(rule *xformRule) {
 	g.genReplace(rule, rule.replace)
 	g.w.write("\n")
 	g.w.writeIndent("_f.mem.addAltFingerprint(%s.fingerprint(), _group)\n", rule.define.varName)
+	g.w.writeIndent("_f.reportAppliedRule(%s, %sOp, _group)\n", rule.name, rule.define.name)
 	g.w.writeIndent("return _group\n")
 
 	g.w.unnest(g.w.nesting-1, "}\n")
 	g.w.writeIndent("\n")
 }
 
+// genRuleNames generates a RuleName constant for each normalization rule, in
+// the order in which the rules are first defined, along with the names that
+// are returned by RuleName.String.
+func (g *FactoryGen) genRuleNames() {
+	g.w.write("const (\n")
+	g.w.write("  UnknownRule RuleName = iota\n\n")
+
+	var names bytes.Buffer
+	var indexes bytes.Buffer
+
+	fmt.Fprint(&names, "UnknownRule")
+	fmt.Fprint(&indexes, "0, ")
+
+	seen := make(map[string]bool)
+	for _, rule := range g.rules {
+		if seen[rule.name] {
+			continue
+		}
+		seen[rule.name] = true
+
+		g.w.write("  %s\n", rule.name)
+		fmt.Fprintf(&indexes, "%d, ", names.Len())
+		fmt.Fprint(&names, rule.name)
+	}
+
+	g.w.write("\n  // NumRules is one greater than the largest rule name.\n")
+	g.w.write("  NumRules\n")
+	g.w.write(")\n\n")
+
+	g.w.write("const ruleNames = \"%s\"\n\n", names.String())
+	g.w.write("var ruleIndexes = [...]uint32{%s%d}\n\n", indexes.String(), names.Len())
+}
+
 func (g *FactoryGen) genMatch(match Expr, contextName string, negate bool) {
 	if matchFields, ok := match.(*MatchFieldsExpr); ok {
 		g.genMatchField(matchFields, contextName, negate)
@@ -222,12 +300,13 @@ func (g *FactoryGen) genDynamicMatchField(matchFields *MatchFieldsExpr, names *M
 
 func (g *FactoryGen) genMatchInvoke(matchInvoke *MatchInvokeExpr, negate bool) {
 	funcName := unTitle(matchInvoke.FuncName())
+	startName := g.makeUnique("_start")
+	resultName := g.makeUnique("_result")
 
-	if negate {
-		g.w.nest("if !_f.%s(", funcName)
-	} else {
-		g.w.nest("if _f.%s(", funcName)
-	}
+	// Time the custom match function, so that expensive functions show up in
+	// the rule profile.
+	g.w.writeIndent("%s := _f.startMatchFunc()\n", startName)
+	g.w.writeIndent("%s := _f.%s(", resultName, funcName)
 
 	for index, matchArg := range matchInvoke.Args() {
 		ref := matchArg.(*RefExpr)
@@ -239,7 +318,14 @@ func (g *FactoryGen) genMatchInvoke(matchInvoke *MatchInvokeExpr, negate bool) {
 		g.w.write(ref.Label())
 	}
 
-	g.w.write(") {\n")
+	g.w.write(")\n")
+	g.w.writeIndent("_f.stopMatchFunc(%s, %s)\n", g.rule.name, startName)
+
+	if negate {
+		g.w.nest("if !%s {\n", resultName)
+	} else {
+		g.w.nest("if %s {\n", resultName)
+	}
 }
 
 func (g *FactoryGen) genReplace(rule *xformRule, replace Expr) {
@@ -249,7 +335,7 @@ func (g *FactoryGen) genReplace(rule *xformRule, replace Expr) {
 			define := g.compiled.LookupDefine(name)
 			if define != nil {
 				// Standard op construction function.
-				g.w.write("_f.Construct%s(", name)
+				g.w.write("_f.%s(", constructFuncName(g.lookupDefine(name)))
 			} else {
 				// Custom function.
 				g.w.write("_f.%s(", unTitle(name))
@@ -257,7 +343,7 @@ func (g *FactoryGen) genReplace(rule *xformRule, replace Expr) {
 		}
 
 		if opName, ok := construct.OpName().(*OpNameExpr); ok {
-			g.w.write("_f.Construct%s(", opName.ValueAsName())
+			g.w.write("_f.%s(", constructFuncName(g.lookupDefine(opName.ValueAsName())))
 		}
 
 		if opNameConstruct, ok := construct.OpName().(*ConstructExpr); ok {
@@ -332,7 +418,7 @@ func (g *FactoryGen) genDynamicConstructLookup() {
 		g.w.writeIndent("// %sOp\n", define.name)
 		g.w.nest("dynConstructLookup[%sOp] = func(f *Factory, children []GroupID, private PrivateID) GroupID {\n", define.name)
 
-		g.w.writeIndent("return f.Construct%s(", define.name)
+		g.w.writeIndent("return f.%s(", constructFuncName(define))
 		for i, field := range define.fields {
 			if i != 0 {
 				g.w.write(", ")
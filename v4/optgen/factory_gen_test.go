@@ -24,7 +24,7 @@ func TestFactoryGenNegate(t *testing.T) {
 		`,
 		`
 		// [Test]
-		{
+		if _f.allowRule(Test) {
 			_lt := _f.mem.lookupNormExpr(left).asLt()
 			if _lt != nil {
 				left2 := _lt.left()
@@ -44,6 +44,7 @@ func TestFactoryGenNegate(t *testing.T) {
 					_f.maxSteps--
 					_group = _f.ConstructLt(left, right2)
 					_f.mem.addAltFingerprint(_ltExpr.fingerprint(), _group)
+					_f.reportAppliedRule(Test, LtOp, _group)
 					return _group
 				}
 			}
@@ -73,7 +74,7 @@ func TestFactoryGenDynamic(t *testing.T) {
 		`,
 		`
 		// [Test]
-		{
+		if _f.allowRule(Test) {
 			_norm := _f.mem.lookupNormExpr(left)
 			if isJoinLookup[_norm.op] || _norm.op == InnerJoinOp {
 				_e := makeExpr(_f.mem, left, defaultPhysPropsID)
@@ -82,12 +83,173 @@ func TestFactoryGenDynamic(t *testing.T) {
 				_f.maxSteps--
 				_group = _f.DynamicConstruct(_f.mem.lookupNormExpr(left).op, []GroupID{lowerRight, lowerLeft}, 0)
 				_f.mem.addAltFingerprint(_innerJoinExpr.fingerprint(), _group)
+				_f.reportAppliedRule(Test, InnerJoinOp, _group)
 				return _group
 			}
 		}
 		`)
 }
 
+func TestFactoryGenMatchFunc(t *testing.T) {
+	testFactory(t,
+		`
+		define Lt {
+			Left  Expr
+			Right Expr
+		}
+
+		[Test, Normalize]
+		(Lt $left:* & (IsLower $left) $right:* & ^(IsLower $right)) => $right
+		`,
+		`
+		// [Test]
+		if _f.allowRule(Test) {
+			_start := _f.startMatchFunc()
+			_result := _f.isLower(left)
+			_f.stopMatchFunc(Test, _start)
+			if _result {
+				_start2 := _f.startMatchFunc()
+				_result2 := _f.isLower(right)
+				_f.stopMatchFunc(Test, _start2)
+				if !_result2 {
+					_f.maxSteps--
+					_group = right
+					_f.mem.addAltFingerprint(_ltExpr.fingerprint(), _group)
+					_f.reportAppliedRule(Test, LtOp, _group)
+					return _group
+				}
+			}
+		}
+		`)
+}
+
+func TestFactoryGenPrivate(t *testing.T) {
+	testFactory(t,
+		`
+		private ColIndex "ColumnIndex"
+		private Datum "github.com/cockroachdb/cockroach/pkg/sql/sem/tree.Datum"
+
+		define Variable {
+			Col ColIndex
+		}
+
+		define Const {
+			Value Datum
+		}
+
+		[Test, Normalize]
+		(Variable $col:*) => (Variable $col)
+		`,
+		`
+		func (_f *Factory) ConstructVariable(
+			col ColumnIndex,
+		) GroupID {
+			return _f.constructVariable(_f.mem.internColIndex(col))
+		}
+
+		func (_f *Factory) constructVariable(
+			col PrivateID,
+		) GroupID {
+		`)
+
+	testFactory(t,
+		`
+		private Datum "github.com/cockroachdb/cockroach/pkg/sql/sem/tree.Datum"
+
+		define Const {
+			Value Datum
+		}
+		`,
+		`
+		import (
+			"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+		)
+		`)
+
+	testFactory(t,
+		`
+		private ColIndex "ColumnIndex"
+
+		define Variable {
+			Col ColIndex
+		}
+
+		[Test, Normalize]
+		(Variable $col:*) => (Variable $col)
+		`,
+		`
+		_group = _f.constructVariable(col)
+		`)
+}
+
+func TestFactoryGenRuleNames(t *testing.T) {
+	testFactory(t,
+		`
+		[Join]
+		define InnerJoin {
+			Left  Expr
+			Right Expr
+		}
+
+		[Join]
+		define LeftJoin {
+			Left  Expr
+			Right Expr
+		}
+
+		[First, Normalize]
+		(Join $left:* $right:*) => $left
+
+		[Ignored, Explore]
+		(InnerJoin $left:* $right:*) => $right
+
+		[Second, Normalize]
+		(InnerJoin $left:* $right:*) => $right
+		`,
+		`
+		const (
+			UnknownRule RuleName = iota
+
+			First
+			Second
+
+			// NumRules is one greater than the largest rule name.
+			NumRules
+		)
+
+		const ruleNames = "UnknownRuleFirstSecond"
+
+		var ruleIndexes = [...]uint32{0, 11, 16, 22}
+		`)
+}
+
+func TestFactoryGenRuleLocation(t *testing.T) {
+	in := `
+	define Not {
+		Input Expr
+	}
+
+	[EliminateNot, Normalize]
+	(Not (Not $input:*))
+	=>
+	$input
+	`
+
+	compiled, err := NewFileCompiler(SourceFile{Name: "norm.opt", Reader: strings.NewReader(in)}).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gen FactoryGen
+	var buf bytes.Buffer
+	gen.Generate(compiled, &buf)
+
+	expected := "// [EliminateNot] norm.opt:6"
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, buf.String())
+	}
+}
+
 func testFactory(t *testing.T, in, expected string) {
 	r := strings.NewReader(in)
 	c := NewCompiler(r)
@@ -0,0 +1,332 @@
+package optgen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxLineLen is the number of columns that the formatter tries to fit each line
+// within. Match function invocations are never broken, so lines that contain
+// long invocations may exceed it.
+const maxLineLen = 80
+
+const formatIndent = "    "
+
+// Formatter prints parsed optgen source in a canonical format, so that source
+// files that are edited by hand stay consistent with one another. Private
+// types, defines and rules are printed in source order, separated by blank
+// lines, and preceded by their comments:
+//
+//   - The fields of a define are indented and their types are aligned.
+//   - The match and replace expressions of a rule are always printed on
+//     separate lines around a "=>" line. If the whole rule would fit on one
+//     line, the match expression is printed on one line. Otherwise, each field
+//     of the root match expression is printed on its own line.
+//   - Nested expressions are printed on one line if they fit, and otherwise
+//     are broken so that each field or argument is printed on its own line.
+//
+// Comments within a define or rule are not preserved in place; they're printed
+// before the next define or rule.
+type Formatter struct {
+	buf bytes.Buffer
+}
+
+// Format writes the canonical source of the given root expression to the
+// writer.
+func (f *Formatter) Format(root *RootExpr, w io.Writer) {
+	f.buf.Reset()
+
+	for i, item := range f.sortItems(root) {
+		switch t := item.(type) {
+		case *PrivateTypeExpr:
+			f.formatComments(t.Comments(), i == 0)
+			fmt.Fprintf(&f.buf, "private %s \"%s\"\n", t.Name(), t.GoType())
+
+		case *DefineExpr:
+			f.formatComments(t.Comments(), i == 0)
+			f.formatDefine(t)
+
+		case *RuleExpr:
+			f.formatComments(t.Comments(), i == 0)
+			f.formatRule(t)
+		}
+	}
+
+	if len(root.Comments()) != 0 {
+		f.formatComments(root.Comments(), f.buf.Len() == 0)
+	}
+
+	w.Write(f.buf.Bytes())
+}
+
+// sortItems returns the private types, defines and rules of the root
+// expression in the order in which they appear in the source.
+func (f *Formatter) sortItems(root *RootExpr) []Expr {
+	var items []Expr
+	items = append(items, root.PrivateTypes().All()...)
+	items = append(items, root.Defines().All()...)
+	items = append(items, root.Rules().All()...)
+
+	sort.SliceStable(items, func(i, j int) bool {
+		left, right := items[i].Source(), items[j].Source()
+		if left == nil || right == nil || left.File != right.File {
+			return false
+		}
+		return left.Line < right.Line
+	})
+
+	return items
+}
+
+func (f *Formatter) formatComments(comments []string, first bool) {
+	if first {
+		// Don't start the file with blank lines.
+		for len(comments) != 0 && comments[0] == "" {
+			comments = comments[1:]
+		}
+	} else if len(comments) == 0 || comments[0] != "" {
+		// Separate each define or rule from the previous one by at least one
+		// blank line.
+		f.buf.WriteByte('\n')
+	}
+
+	for _, line := range comments {
+		f.buf.WriteString(strings.TrimRightFunc(line, unicode.IsSpace))
+		f.buf.WriteByte('\n')
+	}
+}
+
+func (f *Formatter) formatDefine(define *DefineExpr) {
+	if len(define.Tags().All()) != 0 {
+		f.formatTags("", define.Tags())
+	}
+
+	fmt.Fprintf(&f.buf, "define %s {\n", define.Name())
+
+	width := 0
+	for _, elem := range define.Fields() {
+		if n := len(elem.(*DefineFieldExpr).Name()); n > width {
+			width = n
+		}
+	}
+
+	for _, elem := range define.Fields() {
+		field := elem.(*DefineFieldExpr)
+		fmt.Fprintf(&f.buf, "%s%-*s %s\n", formatIndent, width, field.Name(), field.Type())
+	}
+
+	f.buf.WriteString("}\n")
+}
+
+func (f *Formatter) formatRule(rule *RuleExpr) {
+	f.formatTags(rule.Header().Name(), rule.Header().Tags())
+
+	// A rule that would fit on one line prints its match expression on one
+	// line. Otherwise, the root match expression is always broken.
+	match := f.inline(rule.Match())
+	replace := f.inline(rule.Replace())
+	if len(match)+len(" => ")+len(replace) <= maxLineLen && !f.mustBreak(rule.Replace()) {
+		f.buf.WriteString(match)
+	} else {
+		f.buf.WriteString(f.broken(rule.Match(), 0, 0))
+	}
+	f.buf.WriteString("\n=>\n")
+	f.buf.WriteString(f.format(rule.Replace(), 0, 0))
+	f.buf.WriteByte('\n')
+}
+
+func (f *Formatter) formatTags(name string, tags *TagsExpr) {
+	all := make([]string, 0, len(tags.All())+1)
+	if name != "" {
+		all = append(all, name)
+	}
+	for _, elem := range tags.All() {
+		all = append(all, elem.(*StringExpr).ValueAsString())
+	}
+	fmt.Fprintf(&f.buf, "[%s]\n", strings.Join(all, ", "))
+}
+
+// mustBreak returns true if the expression, or any expression within it, is
+// a construct expression with several arguments, at least one of which is a
+// construct expression with arguments of its own. Such expressions are always broken across lines, even
+// if they fit on one line, so that the structure of the result is easy to see.
+func (f *Formatter) mustBreak(e Expr) bool {
+	if construct, ok := e.(*ConstructExpr); ok && len(construct.Args()) > 1 {
+		for _, arg := range construct.Args() {
+			if t, ok := arg.(*ConstructExpr); ok && len(t.Args()) != 0 {
+				return true
+			}
+		}
+	}
+
+	for _, child := range e.Children() {
+		if f.mustBreak(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// format returns the source of the given expression, which starts at the given
+// column of a line indented to the given level. The expression is printed on
+// one line if it fits, and is otherwise broken across lines.
+func (f *Formatter) format(e Expr, level, col int) string {
+	s := f.inline(e)
+	if col+len(s) <= maxLineLen && !f.mustBreak(e) {
+		return s
+	}
+	return f.broken(e, level, col)
+}
+
+// broken returns the source of the given expression broken across lines. Only
+// match fields, construct, and construct list expressions are broken; other
+// expressions break their operands as needed.
+func (f *Formatter) broken(e Expr, level, col int) string {
+	switch t := e.(type) {
+	case *MatchFieldsExpr:
+		return f.brokenList("("+f.inline(t.Names()), t.Fields(), ")", level)
+
+	case *ConstructExpr:
+		head := "(" + f.constructName(t, level, col+1)
+		return f.brokenList(head, t.Args(), ")", level)
+
+	case *ConstructListExpr:
+		return f.brokenList("[", t.Children(), "]", level)
+
+	case *ReplaceRootExpr:
+		lines := make([]string, len(t.All()))
+		for i, item := range t.All() {
+			lines[i] = f.format(item, level, level*len(formatIndent))
+		}
+		return strings.Join(lines, "\n"+f.indent(level))
+
+	case *BindExpr:
+		prefix := fmt.Sprintf("$%s:", t.Label())
+		return prefix + f.format(t.Target(), level, col+len(prefix))
+
+	case *MatchAndExpr:
+		left := f.format(t.Left(), level, col)
+		return left + " & " + f.format(t.Right(), level, f.endCol(left, col)+3)
+
+	case *MatchNotExpr:
+		return "^" + f.format(t.Input(), level, col+1)
+
+	case *MatchListExpr:
+		return "[ ... " + f.format(t.MatchItem(), level, col+6) + " ... ]"
+	}
+
+	return f.inline(e)
+}
+
+// brokenList prints the head on the current line, each of the items on its own
+// line indented one more level, and the tail on its own line at the current
+// level. If there are no items, then the list is printed on one line.
+func (f *Formatter) brokenList(head string, items []Expr, tail string, level int) string {
+	if len(items) == 0 {
+		return head + tail
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(head)
+	for _, item := range items {
+		indent := f.indent(level + 1)
+		buf.WriteByte('\n')
+		buf.WriteString(indent)
+		buf.WriteString(f.format(item, level+1, len(indent)))
+	}
+	buf.WriteByte('\n')
+	buf.WriteString(f.indent(level))
+	buf.WriteString(tail)
+	return buf.String()
+}
+
+// inline returns the source of the given expression on one line.
+func (f *Formatter) inline(e Expr) string {
+	switch t := e.(type) {
+	case *MatchFieldsExpr:
+		return f.inlineList("("+f.inline(t.Names()), t.Fields(), ")")
+
+	case *MatchNamesExpr:
+		names := make([]string, len(t.All()))
+		for i := range names {
+			names[i] = t.Name(i)
+		}
+		return strings.Join(names, " | ")
+
+	case *BindExpr:
+		return fmt.Sprintf("$%s:%s", t.Label(), f.inline(t.Target()))
+
+	case *RefExpr:
+		return "$" + t.Label()
+
+	case *MatchAndExpr:
+		return f.inline(t.Left()) + " & " + f.inline(t.Right())
+
+	case *MatchNotExpr:
+		return "^" + f.inline(t.Input())
+
+	case *MatchInvokeExpr:
+		return f.inlineList("("+t.FuncName(), t.Args(), ")")
+
+	case *MatchAnyExpr:
+		return "*"
+
+	case *MatchListExpr:
+		return "[ ... " + f.inline(t.MatchItem()) + " ... ]"
+
+	case *ConstructExpr:
+		return f.inlineList("("+f.constructName(t, 0, 0), t.Args(), ")")
+
+	case *ConstructListExpr:
+		if len(t.Children()) == 0 {
+			return "[]"
+		}
+		return f.inlineList("[", t.Children(), " ]")
+
+	case *ReplaceRootExpr:
+		return f.inlineList("", t.All(), "")[1:]
+
+	case *StringExpr:
+		return fmt.Sprintf("\"%s\"", t.ValueAsString())
+	}
+
+	panic(fmt.Sprintf("unhandled expression: %s", e.Op()))
+}
+
+// constructName returns the source of the name of the construct expression.
+// The name is either an identifier, which is stored as a string, or another
+// construct expression that computes the name.
+func (f *Formatter) constructName(construct *ConstructExpr, level, col int) string {
+	if s, ok := construct.OpName().(*StringExpr); ok {
+		return s.ValueAsString()
+	}
+	return f.format(construct.OpName(), level, col)
+}
+
+func (f *Formatter) inlineList(head string, items []Expr, tail string) string {
+	var buf bytes.Buffer
+	buf.WriteString(head)
+	for _, item := range items {
+		buf.WriteByte(' ')
+		buf.WriteString(f.inline(item))
+	}
+	buf.WriteString(tail)
+	return buf.String()
+}
+
+// endCol returns the column at which the given source ends, if it starts at
+// the given column.
+func (f *Formatter) endCol(s string, col int) int {
+	if i := strings.LastIndexByte(s, '\n'); i != -1 {
+		return len(s) - i - 1
+	}
+	return col + len(s)
+}
+
+func (f *Formatter) indent(level int) string {
+	return strings.Repeat(formatIndent, level)
+}
@@ -0,0 +1,169 @@
+package optgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormatterDefine(t *testing.T) {
+	testFormatter(t,
+		`
+		# File header.
+
+
+		# Leading blank lines are removed, and runs of blank lines are collapsed.
+
+
+
+		# ColIndex is the index of a column.
+		private   ColIndex    "ColumnIndex"
+		# Variable is a reference to a column.
+		[Scalar]
+		define Variable { Col ColIndex }
+		define Lt {
+		  Left Expr
+		        Right   Expr
+		}
+
+		define Empty {}
+		# Trailing comment.
+		`,
+		`
+		# File header.
+
+
+		# Leading blank lines are removed, and runs of blank lines are collapsed.
+
+
+		# ColIndex is the index of a column.
+		private ColIndex "ColumnIndex"
+
+		# Variable is a reference to a column.
+		[Scalar]
+		define Variable {
+		    Col ColIndex
+		}
+
+		define Lt {
+		    Left  Expr
+		    Right Expr
+		}
+
+		define Empty {
+		}
+
+		# Trailing comment.
+		`)
+}
+
+func TestFormatterRule(t *testing.T) {
+	testFormatter(t,
+		`
+		# Short rules print the match on one line, but still break around =>.
+		[EliminateNot, Normalize]
+		(Not
+		    (Not $input:*)
+		)
+		=>
+		$input
+
+		# Long rules break the root match and the replace.
+		[PushDownSelectJoin, Normalize]
+		(Select $input:(InnerJoin|InnerJoinApply $left:* $right:* $on:*) $filter:*)
+		=> ((OpName $input) $left $right (ConcatFilterConditions $on $filter))
+
+		# Nested constructs with several arguments are always broken.
+		[Nested, Normalize]
+		(Select $input:* $filter:^(Filters) & (UseFilters $filter))
+		=>
+		(Select $input (FlattenFilterCondition [$input] $filter))
+
+		# Nested matches are broken only if they do not fit.
+		[HoistJoinFilterSubquery, Normalize]
+		(Join $left:* $right:* (Filters $list:[... $subquery:(Subquery $subqueryInput:* $projection:*) ...]))
+		=>
+		(Ignored "string" [] (True))
+		`,
+		`
+		# Short rules print the match on one line, but still break around =>.
+		[EliminateNot, Normalize]
+		(Not (Not $input:*))
+		=>
+		$input
+
+		# Long rules break the root match and the replace.
+		[PushDownSelectJoin, Normalize]
+		(Select
+		    $input:(InnerJoin | InnerJoinApply $left:* $right:* $on:*)
+		    $filter:*
+		)
+		=>
+		((OpName $input)
+		    $left
+		    $right
+		    (ConcatFilterConditions $on $filter)
+		)
+
+		# Nested constructs with several arguments are always broken.
+		[Nested, Normalize]
+		(Select
+		    $input:*
+		    $filter:^(Filters) & (UseFilters $filter)
+		)
+		=>
+		(Select
+		    $input
+		    (FlattenFilterCondition [ $input ] $filter)
+		)
+
+		# Nested matches are broken only if they do not fit.
+		[HoistJoinFilterSubquery, Normalize]
+		(Join
+		    $left:*
+		    $right:*
+		    (Filters
+		        $list:[ ... $subquery:(Subquery $subqueryInput:* $projection:*) ... ]
+		    )
+		)
+		=>
+		(Ignored "string" [] (True))
+		`)
+}
+
+func testFormatter(t *testing.T, in, expected string) {
+	p := NewParser(strings.NewReader(in))
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	var f Formatter
+	f.Format(root, &buf)
+	actual := buf.String()
+
+	if testing.Verbose() {
+		fmt.Printf("%s\n=>\n\n%s\n", in, actual)
+	}
+
+	// The expected source is indented with tabs, which the formatter never
+	// writes.
+	expected = strings.Replace(expected, "\t", "", -1)
+	if strings.TrimSpace(actual) != strings.TrimSpace(expected) {
+		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, actual)
+	}
+
+	// Formatting the formatted source should not change it.
+	p = NewParser(strings.NewReader(actual))
+	if root, err = p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	f.Format(root, &buf)
+	if buf.String() != actual {
+		t.Fatalf("\nformatting is not idempotent:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,270 @@
+package optgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Linter reports likely mistakes in compiled optgen source that are not
+// errors:
+//
+//   - A normalization rule that can never match, because an earlier rule for
+//     the same operator matches everything that it matches. Normalization
+//     rules are tried in order and only the first match is applied, so the
+//     later rule is dead.
+//   - A rule that is not preceded by a comment that documents it.
+//   - A define that is never matched or constructed by any rule, if
+//     UnusedDefines is set. Many operators are only constructed by Go code,
+//     which the linter cannot see, so this check is not done by default.
+//
+// Each warning is prefixed with the location of the offending expression.
+type Linter struct {
+	// UnusedDefines enables warnings about defines that are never matched or
+	// constructed by any rule.
+	UnusedDefines bool
+
+	compiled CompiledExpr
+	warnings []string
+}
+
+// Lint returns the warnings for the given compiled source, in source order.
+func (l *Linter) Lint(compiled CompiledExpr) []string {
+	l.compiled = compiled
+	l.warnings = nil
+
+	// Rules that match several operators are compiled into one rule per
+	// operator, so only warn once for each source rule.
+	seen := make(map[string]bool)
+	for _, rule := range compiled.Rules() {
+		name := rule.Header().Name()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		l.lintComments(rule)
+		l.lintShadowed(rule)
+	}
+
+	if l.UnusedDefines {
+		l.lintUnusedDefines()
+	}
+	return l.warnings
+}
+
+// lintComments warns if the rule is not immediately preceded by a comment. A
+// comment that is separated from the rule by a blank line, such as a file
+// header, does not count.
+func (l *Linter) lintComments(rule *RuleExpr) {
+	comments := rule.Comments()
+	if len(comments) == 0 || comments[len(comments)-1] == "" {
+		l.addWarning(rule, rule, "rule has no leading comment")
+	}
+}
+
+// lintShadowed warns if an earlier normalization rule always matches first for
+// any of the operators that the given rule matches.
+func (l *Linter) lintShadowed(rule *RuleExpr) {
+	if !rule.Header().Tags().Contains("Normalize") {
+		return
+	}
+
+	// Map each shadowing rule to the operators that it shadows, in the order
+	// in which the shadowing rules are found.
+	var shadows []*RuleExpr
+	shadowed := make(map[*RuleExpr][]string)
+
+	for _, later := range l.compiled.Rules() {
+		if later.Header().Name() != rule.Header().Name() {
+			continue
+		}
+
+		opName := later.Match().(*MatchFieldsExpr).Names().(*OpNameExpr).ValueAsName()
+		for _, earlier := range l.compiled.Rules() {
+			if earlier.Header().Name() == rule.Header().Name() {
+				// Only consider rules that precede this one.
+				break
+			}
+
+			if !earlier.Header().Tags().Contains("Normalize") {
+				continue
+			}
+
+			earlierName := earlier.Match().(*MatchFieldsExpr).Names().(*OpNameExpr).ValueAsName()
+			if earlierName != opName || !l.subsumes(earlier.Match(), later.Match()) {
+				continue
+			}
+
+			// Map the compiled rule to the first compiled rule for the same
+			// source rule, so that shadowing rules are reported once.
+			first := l.firstRule(earlier)
+			if _, ok := shadowed[first]; !ok {
+				shadows = append(shadows, first)
+			}
+			shadowed[first] = append(shadowed[first], opName)
+			break
+		}
+	}
+
+	for _, shadow := range shadows {
+		l.addWarning(
+			rule,
+			rule,
+			"rule can never match %s, since rule %s (at %s) always matches first",
+			strings.Join(shadowed[shadow], ", "),
+			shadow.Header().Name(),
+			shadow.Source(),
+		)
+	}
+}
+
+// subsumes returns true if the earlier match expression matches every
+// expression that the later match expression matches. It is conservative: it
+// returns false if the earlier expression has any conditions, such as custom
+// match functions, since they may fail to match.
+func (l *Linter) subsumes(earlier, later Expr) bool {
+	if bind, ok := earlier.(*BindExpr); ok {
+		return l.subsumes(bind.Target(), later)
+	}
+
+	switch t := later.(type) {
+	case *BindExpr:
+		return l.subsumes(earlier, t.Target())
+
+	case *MatchAndExpr:
+		// The later expression matches a subset of what its left side
+		// matches.
+		return l.subsumes(earlier, t.Left())
+	}
+
+	switch t := earlier.(type) {
+	case *MatchAnyExpr:
+		return true
+
+	case *MatchFieldsExpr:
+		laterFields, ok := later.(*MatchFieldsExpr)
+		if !ok {
+			return false
+		}
+
+		laterNames := l.matchNames(laterFields.Names())
+		earlierNames := l.matchNames(t.Names())
+		for name := range laterNames {
+			if !earlierNames[name] {
+				return false
+			}
+		}
+
+		// Fields that are not given in a match expression match anything.
+		for i, field := range t.Fields() {
+			var laterField Expr = NewMatchAnyExpr()
+			if i < len(laterFields.Fields()) {
+				laterField = laterFields.Fields()[i]
+			}
+
+			if !l.subsumes(field, laterField) {
+				return false
+			}
+		}
+		return true
+
+	case *MatchListExpr:
+		laterList, ok := later.(*MatchListExpr)
+		return ok && l.subsumes(t.MatchItem(), laterList.MatchItem())
+
+	case *StringExpr:
+		laterStr, ok := later.(*StringExpr)
+		return ok && t.ValueAsString() == laterStr.ValueAsString()
+	}
+
+	return false
+}
+
+// matchNames returns the set of operators that are matched by the given names
+// expression, expanding any tags into the operators that have them.
+func (l *Linter) matchNames(names Expr) map[string]bool {
+	set := make(map[string]bool)
+
+	if opName, ok := names.(*OpNameExpr); ok {
+		set[opName.ValueAsName()] = true
+		return set
+	}
+
+	for _, elem := range names.(*MatchNamesExpr).All() {
+		name := elem.(*StringExpr).ValueAsString()
+		if l.compiled.LookupDefine(name) != nil {
+			set[name] = true
+			continue
+		}
+
+		for _, define := range l.compiled.Defines() {
+			if define.HasTag(name) {
+				set[define.Name()] = true
+			}
+		}
+	}
+
+	return set
+}
+
+// firstRule returns the first compiled rule that has the same name as the
+// given rule.
+func (l *Linter) firstRule(rule *RuleExpr) *RuleExpr {
+	for _, other := range l.compiled.Rules() {
+		if other.Header().Name() == rule.Header().Name() {
+			return other
+		}
+	}
+	panic(fmt.Sprintf("rule %s was not compiled", rule.Header().Name()))
+}
+
+// lintUnusedDefines warns about defines that are never matched or constructed
+// by any rule. Enforcers are only created by the optimizer, so they're never
+// expected to be referenced by rules.
+func (l *Linter) lintUnusedDefines() {
+	used := make(map[string]bool)
+
+	var visit func(e Expr)
+	visit = func(e Expr) {
+		switch t := e.(type) {
+		case *MatchFieldsExpr:
+			for name := range l.matchNames(t.Names()) {
+				used[name] = true
+			}
+
+		case *ConstructExpr:
+			if name, ok := t.OpName().(*StringExpr); ok {
+				used[name.ValueAsString()] = true
+			}
+
+		case *OpNameExpr:
+			used[t.ValueAsName()] = true
+		}
+
+		for _, child := range e.Children() {
+			visit(child)
+		}
+	}
+
+	for _, rule := range l.compiled.Rules() {
+		visit(rule.Match())
+		visit(rule.Replace())
+	}
+
+	for _, define := range l.compiled.Defines() {
+		if !used[define.Name()] && !define.HasTag("Enforcer") {
+			l.addWarning(nil, define, "define %s is never matched or constructed by any rule", define.Name())
+		}
+	}
+}
+
+func (l *Linter) addWarning(rule *RuleExpr, e Expr, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if src := e.Source(); src != nil {
+		msg = fmt.Sprintf("%s: %s", src, msg)
+	}
+	if rule != nil {
+		msg = fmt.Sprintf("%s (rule %s)", msg, rule.Header().Name())
+	}
+	l.warnings = append(l.warnings, msg)
+}
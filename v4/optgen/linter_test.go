@@ -0,0 +1,113 @@
+package optgen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLinterShadowed(t *testing.T) {
+	testLinter(t,
+		false,
+		`
+		[Scalar, Comparison]
+		define Eq {
+			Left  Expr
+			Right Expr
+		}
+
+		[Scalar, Comparison]
+		define Ne {
+			Left  Expr
+			Right Expr
+		}
+
+		private ColIndex "ColumnIndex"
+
+		[Scalar]
+		define Variable {
+			Col ColIndex
+		}
+
+		# Shadows matches every Eq expression.
+		[Shadows, Normalize]
+		(Eq $left:* $right:*) => $left
+
+		# Shadowed can never match Eq expressions, since Shadows matches first.
+		[Shadowed, Normalize]
+		(Comparison $left:(Variable) $right:* & (IsLower $left $right)) => $right
+
+		# Conditional does not shadow Unconditional, since its match function
+		# may fail.
+		[Conditional, Normalize]
+		(Ne $left:* & (IsLower $left $left)) => $left
+
+		# Unconditional is not shadowed.
+		[Unconditional, Normalize]
+		(Ne $left:(Variable)) => $left
+
+		# Explore rules are never shadowed, since all of them are applied.
+		[Explore, Explore]
+		(Eq $left:* $right:*) => $right
+		`,
+		`
+		26:3: rule can never match Eq, since rule Shadows (at 22:3) always matches first (rule Shadowed)
+		`)
+}
+
+func TestLinterComments(t *testing.T) {
+	testLinter(t,
+		true,
+		`
+		# =====================================================================
+		# File header, which doesn't document the first rule.
+		# =====================================================================
+
+		private ColIndex "ColumnIndex"
+
+		[Scalar]
+		define Variable {
+			Col ColIndex
+		}
+
+		define Unused {
+			Input Expr
+		}
+
+		[Enforcer]
+		define Sort {
+			Input Expr
+		}
+
+		[Undocumented, Normalize]
+		(Variable $col:*) => (Variable $col)
+
+		# Documented has a leading comment.
+		[Documented, Normalize]
+		(Variable $col:*) => (Variable $col)
+		`,
+		`
+		22:3: rule has no leading comment (rule Undocumented)
+		26:3: rule can never match Variable, since rule Undocumented (at 22:3) always matches first (rule Documented)
+		13:3: define Unused is never matched or constructed by any rule
+		`)
+}
+
+func testLinter(t *testing.T, unusedDefines bool, in, expected string) {
+	c := NewCompiler(strings.NewReader(in))
+	compiled, err := c.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := Linter{UnusedDefines: unusedDefines}
+	actual := strings.Join(l.Lint(compiled), "\n")
+
+	if testing.Verbose() {
+		fmt.Printf("%s\n=>\n\n%s\n", in, actual)
+	}
+
+	if actual != strings.TrimSpace(strings.Replace(expected, "\t", "", -1)) {
+		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
@@ -8,6 +8,9 @@ const (
 
 	RootOp
 
+	PrivateTypeSetOp
+	PrivateTypeOp
+
 	DefineSetOp
 	DefineOp
 	DefineFieldOp
@@ -4,9 +4,9 @@ package optgen
 
 import "strconv"
 
-const _Operator_name = "UnknownOpRootOpDefineSetOpDefineOpDefineFieldOpRuleSetOpRuleHeaderOpRuleOpBindOpRefOpMatchNamesOpMatchInvokeOpMatchFieldsOpMatchAndOpMatchNotOpMatchAnyOpMatchListOpReplaceRootOpConstructOpConstructListOpTagsOpStringOpOpNameOp"
+const _Operator_name = "UnknownOpRootOpPrivateTypeSetOpPrivateTypeOpDefineSetOpDefineOpDefineFieldOpRuleSetOpRuleHeaderOpRuleOpBindOpRefOpMatchNamesOpMatchInvokeOpMatchFieldsOpMatchAndOpMatchNotOpMatchAnyOpMatchListOpReplaceRootOpConstructOpConstructListOpTagsOpStringOpOpNameOp"
 
-var _Operator_index = [...]uint8{0, 9, 15, 26, 34, 47, 56, 68, 74, 80, 85, 97, 110, 123, 133, 143, 153, 164, 177, 188, 203, 209, 217, 225}
+var _Operator_index = [...]uint8{0, 9, 15, 31, 44, 55, 63, 76, 85, 97, 103, 109, 114, 126, 139, 152, 162, 172, 182, 193, 206, 217, 232, 238, 246, 254}
 
 func (i Operator) String() string {
 	if i < 0 || i >= Operator(len(_Operator_index)-1) {
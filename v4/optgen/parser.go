@@ -3,18 +3,44 @@ package optgen
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
+// SourceFile is a named optgen source file.
+type SourceFile struct {
+	Name   string
+	Reader io.Reader
+}
+
 type Parser struct {
-	s   *Scanner
-	err error
+	files []SourceFile
+	file  int
+	s     *Scanner
+	err   error
 
 	// True if the last token was unscanned (put back to be reparsed).
 	unscanned bool
+
+	// comments accumulates the lines of comments that have been scanned, but
+	// not yet attached to a define or rule. blanks is the number of blank lines
+	// that were scanned since the last token.
+	comments []string
+	blanks   int
 }
 
+// NewParser creates a parser that parses the given unnamed source.
 func NewParser(r io.Reader) *Parser {
-	return &Parser{s: NewScanner(r)}
+	return NewFileParser(SourceFile{Reader: r})
+}
+
+// NewFileParser creates a parser that parses the given files in order, as if
+// they were one source. Each file is scanned separately, so that the locations
+// of the parsed expressions and of any errors refer to the file in which they
+// appear.
+func NewFileParser(files ...SourceFile) *Parser {
+	p := &Parser{files: files}
+	p.s = p.newScanner(0)
+	return p
 }
 
 func (p *Parser) Parse() (*RootExpr, error) {
@@ -23,11 +49,18 @@ func (p *Parser) Parse() (*RootExpr, error) {
 
 func (p *Parser) parseRoot() *RootExpr {
 	rootOp := NewRootExpr()
+	src := SourceLoc{File: p.s.file, Line: 1, Pos: 1}
+	rootOp.src = &src
+	rootOp.Defines().src = &src
+	rootOp.Rules().src = &src
 
 	for {
 		var tags []string
 
-		switch p.scan() {
+		tok := p.scan()
+		src := p.s.Source()
+
+		switch tok {
 		case LBRACKET:
 			p.unscan()
 
@@ -39,7 +72,7 @@ func (p *Parser) parseRoot() *RootExpr {
 			if p.scan() != DEFINE {
 				p.unscan()
 
-				rule := p.parseRule(tags)
+				rule := p.parseRule(tags, src)
 				if rule == nil {
 					return nil
 				}
@@ -53,14 +86,25 @@ func (p *Parser) parseRoot() *RootExpr {
 		case DEFINE:
 			p.unscan()
 
-			define := p.parseDefine(tags)
+			define := p.parseDefine(tags, src)
 			if define == nil {
 				return nil
 			}
 
 			rootOp.Defines().Add(define)
 
+		case PRIVATE:
+			p.unscan()
+
+			private := p.parsePrivateType(src)
+			if private == nil {
+				return nil
+			}
+
+			rootOp.PrivateTypes().Add(private)
+
 		case EOF:
+			rootOp.comments = p.takeComments()
 			return rootOp
 
 		default:
@@ -70,7 +114,33 @@ func (p *Parser) parseRoot() *RootExpr {
 	}
 }
 
-func (p *Parser) parseDefine(tags []string) *DefineExpr {
+// parsePrivateType parses the declaration of a private type and its Go type:
+//
+//	private ColIndexes "*ColSet"
+func (p *Parser) parsePrivateType(src SourceLoc) *PrivateTypeExpr {
+	if !p.scanToken(PRIVATE) {
+		return nil
+	}
+
+	if !p.scanToken(IDENT) {
+		return nil
+	}
+
+	name := p.s.Literal()
+	comments := p.takeComments()
+
+	goType := p.parseString()
+	if goType == nil {
+		return nil
+	}
+
+	private := NewPrivateTypeExpr(name, goType.ValueAsString())
+	private.src = &src
+	private.comments = comments
+	return private
+}
+
+func (p *Parser) parseDefine(tags []string, src SourceLoc) *DefineExpr {
 	if !p.scanToken(DEFINE) {
 		return nil
 	}
@@ -81,6 +151,9 @@ func (p *Parser) parseDefine(tags []string) *DefineExpr {
 
 	name := p.s.Literal()
 	define := NewDefineExpr(name, tags)
+	define.src = &src
+	define.comments = p.takeComments()
+	define.Tags().src = &src
 
 	if !p.scanToken(LBRACE) {
 		return nil
@@ -106,6 +179,7 @@ func (p *Parser) parseDefineField() *DefineFieldExpr {
 	}
 
 	name := p.s.Literal()
+	src := p.s.Source()
 
 	if !p.scanToken(IDENT) {
 		return nil
@@ -113,11 +187,19 @@ func (p *Parser) parseDefineField() *DefineFieldExpr {
 
 	typ := p.s.Literal()
 
-	return NewDefineFieldExpr(name, typ)
+	defineField := NewDefineFieldExpr(name, typ)
+	defineField.src = &src
+	return defineField
 }
 
-func (p *Parser) parseRule(tags []string) *RuleExpr {
+func (p *Parser) parseRule(tags []string, src SourceLoc) *RuleExpr {
+	// Take the comments before parsing the rule, since parsing the replace
+	// expression scans ahead into the comments of the next rule.
+	comments := p.takeComments()
+
 	ruleHeader := NewRuleHeaderExpr(tags[0], tags[1:])
+	ruleHeader.src = &src
+	ruleHeader.Tags().src = &src
 
 	match := p.parseMatchFields()
 	if match == nil {
@@ -133,7 +215,10 @@ func (p *Parser) parseRule(tags []string) *RuleExpr {
 		return nil
 	}
 
-	return NewRuleExpr(ruleHeader, match, replace)
+	rule := NewRuleExpr(ruleHeader, match, replace)
+	rule.src = &src
+	rule.comments = comments
+	return rule
 }
 
 func (p *Parser) parseMatchFields() *MatchFieldsExpr {
@@ -141,12 +226,15 @@ func (p *Parser) parseMatchFields() *MatchFieldsExpr {
 		return nil
 	}
 
+	src := p.s.Source()
+
 	names := p.parseMatchNames()
 	if names == nil {
 		return nil
 	}
 
 	matchFields := NewMatchFieldsExpr(names)
+	matchFields.src = &src
 	for {
 		if p.scan() == RPAREN {
 			return matchFields
@@ -169,7 +257,14 @@ func (p *Parser) parseMatchNames() *MatchNamesExpr {
 			return nil
 		}
 
-		names.Add(NewStringExpr(p.s.Literal()))
+		src := p.s.Source()
+		if names.src == nil {
+			names.src = &src
+		}
+
+		name := NewStringExpr(p.s.Literal())
+		name.src = &src
+		names.Add(name)
 
 		if p.scan() != PIPE {
 			p.unscan()
@@ -198,12 +293,16 @@ func (p *Parser) parseMatchFieldsArg() Expr {
 		return match
 	}
 
+	src := p.s.Source()
+
 	and := p.parseMatchAndExpr()
 	if and == nil {
 		return nil
 	}
 
-	return NewMatchAndExpr(match, and)
+	matchAnd := NewMatchAndExpr(match, and)
+	matchAnd.src = &src
+	return matchAnd
 }
 
 func (p *Parser) parseMatchExpr() Expr {
@@ -217,11 +316,21 @@ func (p *Parser) parseMatchExpr() Expr {
 		return p.parseString()
 
 	case CARET:
+		src := p.s.Source()
 		input := p.parseMatchExpr()
-		return NewMatchNotExpr(input)
+		if input == nil {
+			return nil
+		}
+
+		not := NewMatchNotExpr(input)
+		not.src = &src
+		return not
 
 	case ASTERISK:
-		return NewMatchAnyExpr()
+		// Don't use the MatchAny singleton, since the location of each
+		// occurrence is recorded.
+		src := p.s.Source()
+		return &MatchAnyExpr{expr{op: MatchAnyOp, src: &src}}
 
 	case LBRACKET:
 		p.unscan()
@@ -238,6 +347,8 @@ func (p *Parser) parseMatchBind() *BindExpr {
 		return nil
 	}
 
+	src := p.s.Source()
+
 	if !p.scanToken(IDENT) {
 		return nil
 	}
@@ -253,7 +364,9 @@ func (p *Parser) parseMatchBind() *BindExpr {
 		return nil
 	}
 
-	return NewBindExpr(label, target)
+	bind := NewBindExpr(label, target)
+	bind.src = &src
+	return bind
 }
 
 func (p *Parser) parseMatchAndExpr() Expr {
@@ -267,11 +380,16 @@ func (p *Parser) parseMatchAndExpr() Expr {
 		return left
 	}
 
+	src := p.s.Source()
+
 	right := p.parseMatchAndExpr()
 	if right == nil {
 		return nil
 	}
-	return NewMatchAndExpr(left, right)
+
+	matchAnd := NewMatchAndExpr(left, right)
+	matchAnd.src = &src
+	return matchAnd
 }
 
 func (p *Parser) parseMatchNotExpr() Expr {
@@ -281,11 +399,15 @@ func (p *Parser) parseMatchNotExpr() Expr {
 		return p.parseMatchInvoke()
 
 	case CARET:
+		src := p.s.Source()
 		input := p.parseMatchNotExpr()
 		if input == nil {
 			return nil
 		}
-		return NewMatchNotExpr(input)
+
+		not := NewMatchNotExpr(input)
+		not.src = &src
+		return not
 
 	default:
 		p.setTokenErr(p.s.Literal())
@@ -298,11 +420,14 @@ func (p *Parser) parseMatchInvoke() *MatchInvokeExpr {
 		return nil
 	}
 
+	src := p.s.Source()
+
 	if !p.scanToken(IDENT) {
 		return nil
 	}
 
 	matchInvoke := NewMatchInvokeExpr(p.s.Literal())
+	matchInvoke.src = &src
 
 	for {
 		switch p.scan() {
@@ -330,6 +455,8 @@ func (p *Parser) parseMatchListExpr() Expr {
 		return nil
 	}
 
+	src := p.s.Source()
+
 	if !p.scanToken(ELLIPSES) {
 		return nil
 	}
@@ -347,14 +474,22 @@ func (p *Parser) parseMatchListExpr() Expr {
 		return nil
 	}
 
-	return NewMatchListExpr(matchItem)
+	matchList := NewMatchListExpr(matchItem)
+	matchList.src = &src
+	return matchList
 }
 
 func (p *Parser) parseReplace() Expr {
 	replaceRoot := NewReplaceRootExpr()
 
 	for {
-		switch p.scan() {
+		tok := p.scan()
+		if replaceRoot.src == nil {
+			src := p.s.Source()
+			replaceRoot.src = &src
+		}
+
+		switch tok {
 		case LPAREN:
 			fallthrough
 
@@ -415,12 +550,15 @@ func (p *Parser) parseConstruct() *ConstructExpr {
 		return nil
 	}
 
+	src := p.s.Source()
+
 	name := p.parseConstructName()
 	if name == nil {
 		return nil
 	}
 
 	replaceResult := NewConstructExpr(name)
+	replaceResult.src = &src
 	for {
 		if p.scan() == RPAREN {
 			return replaceResult
@@ -439,7 +577,10 @@ func (p *Parser) parseConstruct() *ConstructExpr {
 func (p *Parser) parseConstructName() Expr {
 	switch p.scan() {
 	case IDENT:
-		return NewStringExpr(p.s.Literal())
+		src := p.s.Source()
+		name := NewStringExpr(p.s.Literal())
+		name.src = &src
+		return name
 
 	case LPAREN:
 		// Constructed name.
@@ -456,7 +597,9 @@ func (p *Parser) parseConstructList() *ConstructListExpr {
 		return nil
 	}
 
+	src := p.s.Source()
 	replaceResult := NewConstructListExpr()
+	replaceResult.src = &src
 
 	for {
 		if p.scan() == RBRACKET {
@@ -478,11 +621,15 @@ func (p *Parser) parseRef() *RefExpr {
 		return nil
 	}
 
+	src := p.s.Source()
+
 	if !p.scanToken(IDENT) {
 		return nil
 	}
 
-	return NewRefExpr(p.s.Literal())
+	ref := NewRefExpr(p.s.Literal())
+	ref.src = &src
+	return ref
 }
 
 func (p *Parser) parseString() *StringExpr {
@@ -490,11 +637,15 @@ func (p *Parser) parseString() *StringExpr {
 		return nil
 	}
 
+	src := p.s.Source()
+
 	// Strip quotes.
 	s := p.s.Literal()
 	s = s[1 : len(s)-1]
 
-	return NewStringExpr(s)
+	str := NewStringExpr(s)
+	str.src = &src
+	return str
 }
 
 func (p *Parser) parseTags() []string {
@@ -533,7 +684,8 @@ func (p *Parser) scanToken(expected Token) bool {
 }
 
 // scan returns the next non-whitespace, non-comment token from the underlying
-// scanner. If a token has been unscanned then read that instead.
+// scanner. If a token has been unscanned then read that instead. Comments are
+// accumulated so that they can be attached to the next define or rule.
 func (p *Parser) scan() Token {
 	// If we have a token on the buffer, then return it.
 	if p.unscanned {
@@ -541,16 +693,63 @@ func (p *Parser) scan() Token {
 		return p.s.Token()
 	}
 
-	// Otherwise read the next token from the scanner.
+	// Otherwise read the next token from the scanner, moving on to the next
+	// file when the current one is exhausted.
 	for {
 		tok := p.s.Scan()
 
-		if tok != WHITESPACE && tok != COMMENT {
+		if tok == EOF && p.file < len(p.files)-1 {
+			p.file++
+			p.s = p.newScanner(p.file)
+			p.blanks = 0
+			continue
+		}
+
+		switch tok {
+		case WHITESPACE:
+			// Each newline after the first ends a blank line. Collapse runs of
+			// more than two blank lines.
+			p.blanks = strings.Count(p.s.Literal(), "\n") - 1
+			if p.blanks > 2 {
+				p.blanks = 2
+			}
+
+		case COMMENT:
+			p.addBlanks()
+			p.comments = append(p.comments, p.s.Literal())
+
+		default:
+			// Keep blank lines between a comment and the token that follows it,
+			// so that detached comments stay detached.
+			if len(p.comments) != 0 {
+				p.addBlanks()
+			}
+			p.blanks = 0
 			return tok
 		}
 	}
 }
 
+func (p *Parser) addBlanks() {
+	for ; p.blanks > 0; p.blanks-- {
+		p.comments = append(p.comments, "")
+	}
+}
+
+// takeComments returns the comments that have accumulated since the last call,
+// and resets the accumulator.
+func (p *Parser) takeComments() []string {
+	comments := p.comments
+	p.comments = nil
+	return comments
+}
+
+func (p *Parser) newScanner(file int) *Scanner {
+	s := NewScanner(p.files[file].Reader)
+	s.file = p.files[file].Name
+	return s
+}
+
 // unscan pushes the previously read token back onto the buffer.
 func (p *Parser) unscan() {
 	if p.unscanned {
@@ -561,6 +760,5 @@ func (p *Parser) unscan() {
 }
 
 func (p *Parser) setTokenErr(lit string) {
-	line, pos := p.s.LineInfo()
-	p.err = fmt.Errorf("unexpected token '%s' (line %d, pos %d)", lit, line, pos)
+	p.err = fmt.Errorf("%s: unexpected token '%s'", p.s.Source(), lit)
 }
@@ -18,6 +18,7 @@ func TestParserDefine(t *testing.T) {
 		`,
 		`
 		(Root
+			PrivateTypes=(PrivateTypeSet)
 			Defines=(DefineSet
 				(Define
 					Name="Lt"
@@ -31,6 +32,26 @@ func TestParserDefine(t *testing.T) {
 		`)
 }
 
+func TestParserPrivateType(t *testing.T) {
+	testParser(t,
+		`
+		# ColIndexes is a set of columns.
+		private ColIndexes "*ColSet"
+
+		private Datum "github.com/cockroachdb/cockroach/pkg/sql/sem/tree.Datum"
+		`,
+		`
+		(Root
+			PrivateTypes=(PrivateTypeSet
+				(PrivateType Name="ColIndexes" GoType="*ColSet")
+				(PrivateType Name="Datum" GoType="github.com/cockroachdb/cockroach/pkg/sql/sem/tree.Datum")
+			)
+			Defines=(DefineSet)
+			Rules=(RuleSet)
+		)
+		`)
+}
+
 func TestParserPattern(t *testing.T) {
 	testParser(t,
 		`
@@ -39,6 +60,7 @@ func TestParserPattern(t *testing.T) {
 		`,
 		`
 		(Root
+			PrivateTypes=(PrivateTypeSet)
 			Defines=(DefineSet)
 			Rules=(RuleSet
 				(Rule
@@ -104,6 +126,53 @@ func TestParserMatchList(t *testing.T) {
 		`)
 }
 
+func TestParserFileLocations(t *testing.T) {
+	p := NewFileParser(
+		SourceFile{Name: "ops.opt", Reader: strings.NewReader("define Lt {\n    Left  Expr\n    Right Expr\n}\n")},
+		SourceFile{Name: "norm.opt", Reader: strings.NewReader("# Comment.\n[Test]\n(Lt $left:* $right:*)\n=>\n(Lt $right $left)\n")},
+	)
+
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	define := root.Defines().All()[0].(*DefineExpr)
+	rule := root.Rules().All()[0].(*RuleExpr)
+	right := rule.Match().(*MatchFieldsExpr).Fields()[1]
+	ref := rule.Replace().(*ConstructExpr).Args()[1]
+
+	locs := []struct {
+		expr     Expr
+		expected string
+	}{
+		{expr: define, expected: "ops.opt:1:1"},
+		{expr: define.Fields()[1], expected: "ops.opt:3:5"},
+		{expr: rule, expected: "norm.opt:2:1"},
+		{expr: right, expected: "norm.opt:3:13"},
+		{expr: right.(*BindExpr).Target(), expected: "norm.opt:3:20"},
+		{expr: ref, expected: "norm.opt:5:12"},
+	}
+
+	for _, loc := range locs {
+		if actual := loc.expr.Source().String(); actual != loc.expected {
+			t.Errorf("%v: expected %s, actual %s", loc.expr, loc.expected, actual)
+		}
+	}
+}
+
+func TestParserFileError(t *testing.T) {
+	p := NewFileParser(
+		SourceFile{Name: "ops.opt", Reader: strings.NewReader("define Lt {\n    Left Expr\n}\n")},
+		SourceFile{Name: "norm.opt", Reader: strings.NewReader("[Test]\n(Lt $left:*)\n=>\n")},
+	)
+
+	_, err := p.Parse()
+	if err == nil || err.Error() != "norm.opt:4:1: unexpected token ''" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func testParser(t *testing.T, in, expected string) {
 	r := strings.NewReader(in)
 	p := NewParser(r)
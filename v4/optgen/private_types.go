@@ -0,0 +1,103 @@
+package optgen
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A private type is a type that can be used for the private field of a define.
+// It's declared in the optgen source, along with the Go type of its values:
+//
+//   private ColIndexes "*ColSet"
+//   private Datum "github.com/cockroachdb/cockroach/pkg/sql/sem/tree.Datum"
+//
+// The generated code interns values of the type using the memo's intern<Name>
+// method, and looks them up using its lookup<Name> method, where <Name> is the
+// name of the private type.
+
+// splitGoType splits the Go type of a private type declaration into the type
+// as it's written in the generated code, and the import path of the package
+// that defines it, if it's not defined by the generated package. For example,
+// "*github.com/org/repo/pkg.Type" is written as "*pkg.Type", and is defined by
+// the "github.com/org/repo/pkg" package. The last return value is false if the
+// Go type is not well-formed.
+func splitGoType(goType string) (typ, pkg string, ok bool) {
+	name := strings.TrimLeft(goType, "*")
+	prefix := goType[:len(goType)-len(name)]
+
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot != -1 {
+		pkg = name[:slash+1+dot]
+		name = name[slash+1+dot+1:]
+		if !isIdent(pkg[slash+1:]) {
+			return "", "", false
+		}
+		typ = prefix + pkg[slash+1:] + "." + name
+	} else if slash != -1 {
+		return "", "", false
+	} else {
+		typ = goType
+	}
+
+	if !isIdent(name) {
+		return "", "", false
+	}
+	return typ, pkg, true
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, ch := range s {
+		if !unicode.IsLetter(ch) && ch != '_' && (i == 0 || !unicode.IsDigit(ch)) {
+			return false
+		}
+	}
+	return true
+}
+
+// privateGoType returns the Go type of the given private type, as it's written
+// in the generated code.
+func privateGoType(compiled CompiledExpr, name string) string {
+	private := compiled.LookupPrivateType(name)
+	if private == nil {
+		panic(fmt.Sprintf("unknown private type: %s", name))
+	}
+	typ, _, _ := splitGoType(private.GoType())
+	return typ
+}
+
+// genPrivateImports writes an import declaration for the packages that define
+// the Go types of the private fields of the compiled defines, if there are
+// any.
+func genPrivateImports(w io.Writer, compiled CompiledExpr) {
+	pkgs := make(map[string]bool)
+	for _, define := range compiled.Defines() {
+		if field := define.PrivateField(); field != nil {
+			private := compiled.LookupPrivateType(field.Type())
+			if _, pkg, _ := splitGoType(private.GoType()); pkg != "" {
+				pkgs[pkg] = true
+			}
+		}
+	}
+
+	if len(pkgs) == 0 {
+		return
+	}
+
+	var sorted []string
+	for pkg := range pkgs {
+		sorted = append(sorted, pkg)
+	}
+	sort.Strings(sorted)
+
+	fmt.Fprintf(w, "import (\n")
+	for _, pkg := range sorted {
+		fmt.Fprintf(w, "  %q\n", pkg)
+	}
+	fmt.Fprintf(w, ")\n\n")
+}
@@ -39,6 +39,7 @@ const (
 
 	// Keywords.
 	DEFINE
+	PRIVATE
 )
 
 type LineInfo struct {
@@ -56,8 +57,10 @@ func (li LineInfo) MergeWith(other LineInfo) LineInfo {
 
 type Scanner struct {
 	r        *bufio.Reader
+	file     string
 	tok      Token
 	lit      string
+	src      SourceLoc
 	lineInfo struct {
 		line int
 		pos  int
@@ -81,7 +84,14 @@ func (s *Scanner) LineInfo() (line, pos int) {
 	return s.lineInfo.line + 1, s.lineInfo.pos
 }
 
+// Source returns the location of the first character of the current token.
+func (s *Scanner) Source() SourceLoc {
+	return s.src
+}
+
 func (s *Scanner) Scan() Token {
+	s.src = SourceLoc{File: s.file, Line: s.lineInfo.line + 1, Pos: s.lineInfo.pos + 1}
+
 	// Read the next rune.
 	ch := s.read()
 
@@ -284,6 +294,9 @@ func (s *Scanner) scanIdentifier() Token {
 	case "define":
 		s.tok = DEFINE
 
+	case "private":
+		s.tok = PRIVATE
+
 	default:
 		s.tok = IDENT
 	}
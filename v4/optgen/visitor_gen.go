@@ -0,0 +1,162 @@
+package optgen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// VisitorGen generates typed visitor and rewriter interfaces over all defined
+// operators, along with the functions that dispatch to their methods. This
+// allows analyses and transformations of expression trees to be written
+// without switching on the operator of each expression by hand.
+type VisitorGen struct {
+	compiled CompiledExpr
+	w        io.Writer
+}
+
+func (g *VisitorGen) Generate(compiled CompiledExpr, w io.Writer) {
+	g.compiled = compiled
+	g.w = w
+
+	g.genVisitor()
+	g.genRewriter()
+}
+
+// genVisitor generates the ExprVisitor interface, a no-op implementation of
+// it, and the visitExpr function that calls the method that corresponds to
+// the operator of an expression.
+func (g *VisitorGen) genVisitor() {
+	fmt.Fprintf(g.w, "// ExprVisitor has a Visit method for each operator, which is called by\n")
+	fmt.Fprintf(g.w, "// WalkExpr for each expression with that operator. If the method returns\n")
+	fmt.Fprintf(g.w, "// false, then the children of the expression are not walked.\n")
+	fmt.Fprintf(g.w, "type ExprVisitor interface {\n")
+	for _, define := range g.compiled.Defines() {
+		fmt.Fprintf(g.w, "  Visit%s(e *Expr) bool\n", define.Name())
+	}
+	fmt.Fprintf(g.w, "}\n\n")
+
+	fmt.Fprintf(g.w, "// ExprVisitorBase implements ExprVisitor by walking every expression.\n")
+	fmt.Fprintf(g.w, "// Visitors can embed it and override only the methods for the operators\n")
+	fmt.Fprintf(g.w, "// that they analyze.\n")
+	fmt.Fprintf(g.w, "type ExprVisitorBase struct{}\n\n")
+
+	for _, define := range g.compiled.Defines() {
+		fmt.Fprintf(g.w, "func (ExprVisitorBase) Visit%s(e *Expr) bool {\n", define.Name())
+		fmt.Fprintf(g.w, "  return true\n")
+		fmt.Fprintf(g.w, "}\n\n")
+	}
+
+	fmt.Fprintf(g.w, "func visitExpr(v ExprVisitor, e *Expr) bool {\n")
+	fmt.Fprintf(g.w, "  switch e.op {\n")
+	for _, define := range g.compiled.Defines() {
+		fmt.Fprintf(g.w, "  case %sOp:\n", define.Name())
+		fmt.Fprintf(g.w, "    return v.Visit%s(e)\n\n", define.Name())
+	}
+	fmt.Fprintf(g.w, "  }\n\n")
+	fmt.Fprintf(g.w, "  panic(\"unhandled op \" + e.op.String())\n")
+	fmt.Fprintf(g.w, "}\n\n")
+}
+
+// genRewriter generates the ExprRewriter interface, an implementation of it
+// that reconstructs each expression using the factory, and the rewriteExpr
+// function that calls the method that corresponds to the operator of an
+// expression.
+func (g *VisitorGen) genRewriter() {
+	fmt.Fprintf(g.w, "// ExprRewriter has a Rewrite method for each operator, which is called by\n")
+	fmt.Fprintf(g.w, "// RewriteExpr for each expression with that operator. The method is passed\n")
+	fmt.Fprintf(g.w, "// the groups of the rewritten children of the expression, and returns the\n")
+	fmt.Fprintf(g.w, "// group of the rewritten expression.\n")
+	fmt.Fprintf(g.w, "type ExprRewriter interface {\n")
+	for _, define := range g.compiled.Defines() {
+		fmt.Fprintf(g.w, "  Rewrite%s(%s) GroupID\n", define.Name(), g.rewriteParams(define))
+	}
+	fmt.Fprintf(g.w, "}\n\n")
+
+	fmt.Fprintf(g.w, "// ExprRewriterBase implements ExprRewriter by constructing each expression\n")
+	fmt.Fprintf(g.w, "// from its rewritten children, and by removing enforcers, since they are\n")
+	fmt.Fprintf(g.w, "// not part of the logical expression tree. Rewriters can embed it and\n")
+	fmt.Fprintf(g.w, "// override only the methods for the operators that they transform.\n")
+	fmt.Fprintf(g.w, "type ExprRewriterBase struct {\n")
+	fmt.Fprintf(g.w, "  Factory *Factory\n")
+	fmt.Fprintf(g.w, "}\n\n")
+
+	for _, define := range g.compiled.Defines() {
+		fmt.Fprintf(g.w, "func (r ExprRewriterBase) Rewrite%s(%s) GroupID {\n", define.Name(), g.rewriteParams(define))
+
+		if define.HasTag("Enforcer") {
+			// Enforcers have a single child which is the same group they're in.
+			fmt.Fprintf(g.w, "  return %s\n", unTitle(define.Fields()[0].(*DefineFieldExpr).Name()))
+			fmt.Fprintf(g.w, "}\n\n")
+			continue
+		}
+
+		// Use the construct method that takes the PrivateID of the private
+		// field, if there is one, since it's already been interned.
+		if define.PrivateField() != nil {
+			fmt.Fprintf(g.w, "  return r.Factory.construct%s(", define.Name())
+		} else {
+			fmt.Fprintf(g.w, "  return r.Factory.Construct%s(", define.Name())
+		}
+		for index, elem := range define.Fields() {
+			field := elem.(*DefineFieldExpr)
+			if index != 0 {
+				fmt.Fprintf(g.w, ", ")
+			}
+
+			if field.IsListType() {
+				fmt.Fprintf(g.w, "r.Factory.StoreList(%s)", unTitle(field.Name()))
+			} else if field.IsPrivateType() {
+				fmt.Fprintf(g.w, "e.privateID()")
+			} else {
+				fmt.Fprintf(g.w, "%s", unTitle(field.Name()))
+			}
+		}
+		fmt.Fprintf(g.w, ")\n")
+		fmt.Fprintf(g.w, "}\n\n")
+	}
+
+	fmt.Fprintf(g.w, "func rewriteExpr(r ExprRewriter, e *Expr, children []GroupID) GroupID {\n")
+	fmt.Fprintf(g.w, "  switch e.op {\n")
+	for _, define := range g.compiled.Defines() {
+		fmt.Fprintf(g.w, "  case %sOp:\n", define.Name())
+		fmt.Fprintf(g.w, "    return r.Rewrite%s(e", define.Name())
+
+		// The list field is always the last child field, so it consists of
+		// the remaining children.
+		index := 0
+		for _, elem := range define.Fields() {
+			field := elem.(*DefineFieldExpr)
+			if field.IsListType() {
+				fmt.Fprintf(g.w, ", children[%d:]", index)
+			} else if !field.IsPrivateType() {
+				fmt.Fprintf(g.w, ", children[%d]", index)
+				index++
+			}
+		}
+		fmt.Fprintf(g.w, ")\n\n")
+	}
+	fmt.Fprintf(g.w, "  }\n\n")
+	fmt.Fprintf(g.w, "  panic(\"unhandled op \" + e.op.String())\n")
+	fmt.Fprintf(g.w, "}\n\n")
+}
+
+// rewriteParams returns the parameter list of the Rewrite method for the
+// given define. Each child field is passed as the group of its rewritten
+// child, or as a slice of groups in the case of a list field. Private fields
+// are not passed, since the method can get them from the expression.
+func (g *VisitorGen) rewriteParams(define *DefineExpr) string {
+	var buf bytes.Buffer
+	buf.WriteString("e *Expr")
+
+	for _, elem := range define.Fields() {
+		field := elem.(*DefineFieldExpr)
+		if field.IsListType() {
+			fmt.Fprintf(&buf, ", %s []GroupID", unTitle(field.Name()))
+		} else if !field.IsPrivateType() {
+			fmt.Fprintf(&buf, ", %s GroupID", unTitle(field.Name()))
+		}
+	}
+
+	return buf.String()
+}
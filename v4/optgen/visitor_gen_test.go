@@ -0,0 +1,82 @@
+package optgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestVisitorGen(t *testing.T) {
+	testVisitor(t,
+		`
+		private ColIndexes "*ColSet"
+
+		[Scalar]
+		define Projections {
+			Items ExprList
+			Cols  ColIndexes
+		}
+
+		[Relational]
+		define Project {
+			Input       Expr
+			Projections Expr
+		}
+
+		[Relational, Enforcer]
+		define Sort {
+			Input Expr
+		}
+		`,
+		`
+		func (r ExprRewriterBase) RewriteProjections(e *Expr, items []GroupID) GroupID {
+			return r.Factory.constructProjections(r.Factory.StoreList(items), e.privateID())
+		}
+
+		func (r ExprRewriterBase) RewriteProject(e *Expr, input GroupID, projections GroupID) GroupID {
+			return r.Factory.ConstructProject(input, projections)
+		}
+
+		func (r ExprRewriterBase) RewriteSort(e *Expr, input GroupID) GroupID {
+			return input
+		}
+
+		func rewriteExpr(r ExprRewriter, e *Expr, children []GroupID) GroupID {
+			switch e.op {
+			case ProjectionsOp:
+				return r.RewriteProjections(e, children[0:])
+
+			case ProjectOp:
+				return r.RewriteProject(e, children[0], children[1])
+
+			case SortOp:
+				return r.RewriteSort(e, children[0])
+
+			}
+
+			panic("unhandled op " + e.op.String())
+		}
+		`)
+}
+
+func testVisitor(t *testing.T, in, expected string) {
+	r := strings.NewReader(in)
+	c := NewCompiler(r)
+	compiled, err := c.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gen VisitorGen
+	var buf bytes.Buffer
+	gen.Generate(compiled, &buf)
+
+	if testing.Verbose() {
+		fmt.Printf("%s\n=>\n\n%s\n", in, buf.String())
+	}
+
+	if !strings.Contains(removeWhitespace(buf.String()), removeWhitespace(expected)) {
+		t.Fatalf("\nexpected:\n%s\nactual:\n%s", expected, buf.String())
+	}
+}
@@ -31,6 +31,10 @@ type xformDefineField struct {
 	define *xformDefine
 	name   string
 	typ    string
+
+	// privateType is the type of a private field in the optgen source, such
+	// as ColIndex.
+	privateType string
 }
 
 type xformRule struct {
@@ -39,12 +43,13 @@ type xformRule struct {
 	tags    tagList
 	match   *MatchFieldsExpr
 	replace Expr
+	src     *SourceLoc
 }
 
-func (x *xformGen) init(compiled CompiledExpr, w io.Writer, ruleType string) {
+func (x *xformGen) init(compiled CompiledExpr, w io.Writer, ruleTypes ...string) {
 	x.compiled = compiled
 	x.w = matchWriter{writer: w}
-	x.rules = x.createRules(ruleType)
+	x.rules = x.createRules(ruleTypes)
 	x.defines = x.createDefines()
 	x.unique = make(map[string]bool)
 }
@@ -81,14 +86,22 @@ func (x *xformGen) lookupFieldName(opName string, index int) string {
 	return unTitle(x.lookupFieldDef(opName, index).Name())
 }
 
-func (x *xformGen) createRules(ruleType string) []*xformRule {
+func (x *xformGen) createRules(ruleTypes []string) []*xformRule {
 	var xrulesList []*xformRule
 
 	for _, rule := range x.compiled.Rules() {
 		var xrule xformRule
 
-		// Only add rules of the specified type.
-		if !rule.Header().Tags().Contains(ruleType) {
+		// Only add rules of the specified types.
+		found := false
+		for _, ruleType := range ruleTypes {
+			if rule.Header().Tags().Contains(ruleType) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
 			continue
 		}
 
@@ -99,12 +112,23 @@ func (x *xformGen) createRules(ruleType string) []*xformRule {
 		xrule.name = rule.Header().Name()
 		xrule.match = rule.Match().(*MatchFieldsExpr)
 		xrule.replace = rule.Replace()
+		xrule.src = rule.Source()
 		xrulesList = append(xrulesList, &xrule)
 	}
 
 	return xrulesList
 }
 
+// genRuleComment generates a comment that names the given rule. If the rule
+// was read from a file, then the comment also points back to its location.
+func (x *xformGen) genRuleComment(rule *xformRule) {
+	if rule.src != nil && rule.src.File != "" {
+		x.w.writeIndent("// [%s] %s:%d\n", rule.name, rule.src.File, rule.src.Line)
+	} else {
+		x.w.writeIndent("// [%s]\n", rule.name)
+	}
+}
+
 func (x *xformGen) createDefines() []*xformDefine {
 	var xdefineList []*xformDefine
 
@@ -137,6 +161,7 @@ func (x *xformGen) createDefines() []*xformDefine {
 				xdefine.list = xfield
 			} else if field.IsPrivateType() {
 				xdefine.private = xfield
+				xfield.privateType = field.Type()
 			}
 		}
 		xdefine.fields = xfieldList
@@ -158,6 +183,27 @@ func (x *xformGen) createDefines() []*xformDefine {
 	return xdefineList
 }
 
+func (x *xformGen) lookupDefine(name string) *xformDefine {
+	for _, define := range x.defines {
+		if define.name == name {
+			return define
+		}
+	}
+	panic(fmt.Sprintf("cannot find define: %s", name))
+}
+
+// constructFuncName returns the name of the factory method that constructs
+// expressions of the given define from their group, list and PrivateID
+// fields. The exported Construct methods of defines with a private field take
+// the strongly-typed private value instead, so their PrivateID variant is
+// unexported.
+func constructFuncName(define *xformDefine) string {
+	if define.private != nil {
+		return fmt.Sprintf("construct%s", define.name)
+	}
+	return fmt.Sprintf("Construct%s", define.name)
+}
+
 func (x *xformDefineField) isList() bool {
 	return x.define.list == x
 }
@@ -184,7 +230,7 @@ func unTitle(name string) string {
 
 func mapType(typ string) string {
 	switch typ {
-	case "Expr":
+	case "Expr", "RelExpr":
 		return "GroupID"
 
 	case "ExprList":